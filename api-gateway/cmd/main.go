@@ -80,6 +80,8 @@ var serviceCircuitBreakers = make(map[string]*CircuitBreaker)
 
 func main() {
 	app := fiber.New(fiber.Config{
+		// Menu image uploads are up to 5 MB plus their multipart envelope
+		BodyLimit: 8 * 1024 * 1024,
 		ErrorHandler: func(c *fiber.Ctx, err error) error {
 			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
 				"error": err.Error(),
@@ -150,6 +152,18 @@ func healthCheck(c *fiber.Ctx) error {
 	return c.Status(fiber.StatusOK).JSON(health)
 }
 
+// copyRequestHeaders forwards the client's headers to a backend. X-Real-IP is
+// always set to the address the gateway saw, so clients cannot choose the IP
+// that rate limits and login throttling count against.
+func copyRequestHeaders(c *fiber.Ctx, req *http.Request) {
+	for key, values := range c.GetReqHeaders() {
+		for _, value := range values {
+			req.Header.Add(key, value)
+		}
+	}
+	req.Header.Set("X-Real-IP", c.IP())
+}
+
 func createServiceProxy(service string, port int) fiber.Handler {
 	client := &http.Client{
 		Timeout: time.Second * 10,
@@ -214,12 +228,7 @@ func createServiceProxy(service string, port int) fiber.Handler {
 			})
 		}
 
-		// copying headers
-		for key, values := range c.GetReqHeaders() {
-			for _, value := range values {
-				req.Header.Add(key, value)
-			}
-		}
+		copyRequestHeaders(c, req)
 
 		// executing the request with retries
 		var resp *http.Response
@@ -238,11 +247,7 @@ func createServiceProxy(service string, port int) fiber.Handler {
 				time.Sleep(time.Duration(retryCount*200) * time.Millisecond)
 				// need to create a new request with body for retry
 				req, _ = http.NewRequest(c.Method(), targetURL, bytes.NewReader(c.Body()))
-				for key, values := range c.GetReqHeaders() {
-					for _, value := range values {
-						req.Header.Add(key, value)
-					}
-				}
+				copyRequestHeaders(c, req)
 			}
 		}
 
@@ -4,14 +4,19 @@ import (
 	"encoding/json"
 	"log"
 	"os"
+	"slices"
 	"time"
 
+	"github.com/darkhyper24/blaban/auth-service/internal/clients"
 	"github.com/darkhyper24/blaban/auth-service/internal/db"
+	"github.com/darkhyper24/blaban/auth-service/internal/identities"
+	"github.com/darkhyper24/blaban/auth-service/internal/roles"
 	"github.com/darkhyper24/blaban/auth-service/internal/tokens"
+	"github.com/darkhyper24/blaban/auth-service/internal/userclient"
+	"github.com/darkhyper24/blaban/shared/auth"
 	"github.com/gofiber/fiber/v2"
 	"github.com/gofiber/fiber/v2/middleware/cors"
 	"github.com/gofiber/fiber/v2/middleware/logger"
-	"github.com/golang-jwt/jwt"
 	"github.com/joho/godotenv"
 	"golang.org/x/oauth2"
 	"golang.org/x/oauth2/google"
@@ -29,6 +34,10 @@ func init() {
 var (
 	googleOauthConfig *oauth2.Config
 	tokenService      *tokens.TokenService
+	roleService       *roles.RoleService
+	clientService     *clients.ClientService
+	identityService   *identities.IdentityService
+	userClient        *userclient.UserClient
 )
 
 type GoogleUser struct {
@@ -54,6 +63,18 @@ func main() {
 	}
 	defer database.Close()
 
+	if len(os.Args) > 1 && os.Args[1] == "migrate" {
+		if err := runMigrations(database, os.Args[2:]); err != nil {
+			log.Fatal("migration failed: ", err)
+		}
+		return
+	}
+
+	// Bring the schema up to date before serving
+	if err := runMigrations(database, []string{"up"}); err != nil {
+		log.Fatal("migration failed: ", err)
+	}
+
 	googleOauthConfig = &oauth2.Config{
 		RedirectURL:  "http://localhost:8082/api/auth/google/callback",
 		ClientID:     os.Getenv("GOOGLE_CLIENT_ID"),
@@ -65,13 +86,42 @@ func main() {
 		Endpoint: google.Endpoint,
 	}
 
-	// Initialize token service
-	tokenService = tokens.NewTokenService(
+	// Initialize role and token services
+	roleService = roles.NewRoleService(database)
+	// Every instance must sign with the same persistent key, or tokens stop
+	// verifying after a restart or on another replica
+	keyFile := os.Getenv("JWT_PRIVATE_KEY_FILE")
+	if keyFile == "" {
+		log.Fatal("JWT_PRIVATE_KEY_FILE must point to the PEM encoded RSA signing key")
+	}
+	signingKey, err := tokens.LoadSigningKey(keyFile)
+	if err != nil {
+		log.Fatal("failed to load signing key:", err)
+	}
+	tokenService, err = tokens.NewTokenService(
 		database,
-		os.Getenv("JWT_SECRET"),
+		roleService,
+		signingKey,
 		15*time.Minute, // Access token expiry
 		7*24*time.Hour, // Refresh token expiry
 	)
+	if err != nil {
+		log.Fatal("failed to initialize token service:", err)
+	}
+
+	clientService = clients.NewClientService(database)
+	identityService = identities.NewIdentityService(database)
+	if err := registerClientsFromEnv(os.Getenv("OAUTH_CLIENTS")); err != nil {
+		log.Fatal("failed to register OAuth clients:", err)
+	}
+
+	userServiceURL := os.Getenv("USER_SERVICE_URL")
+	if userServiceURL == "" {
+		userServiceURL = "http://localhost:8081"
+	}
+	userClient = userclient.NewUserClient(userServiceURL, func() (string, error) {
+		return tokenService.GenerateServiceToken(serviceClientID, []string{auth.ScopeUsersRead})
+	})
 
 	app := fiber.New()
 	app.Use(cors.New(cors.Config{
@@ -82,12 +132,23 @@ func main() {
 	app.Use(logger.New())
 
 	// Auth routes
-	app.Post("/api/auth/tokens", handleUserRegistration)
+	app.Post("/api/auth/tokens", requireServiceScope(auth.ScopeTokensIssue), handleUserRegistration)
 	app.Get("/api/auth/google/login", handleGoogleLogin)
 	app.Get("/api/auth/google/callback", handleGoogleCallback)
 	app.Post("/api/auth/refresh", handleRefreshToken)
 	app.Get("/api/auth/verify", handleVerifyToken)
 	app.Post("/api/auth/logout", handleLogout)
+	app.Post("/api/auth/users/:id/revoke", requireServiceScope(auth.ScopeTokensRevoke), handleRevokeUserTokens)
+	app.Get("/api/auth/users/:id/sessions", requireServiceScope(auth.ScopeSessionsRead), handleGetUserSessions)
+	app.Get("/api/auth/users/:id/data", requireServiceScope(auth.ScopeUserDataRead), handleExportUserData)
+	app.Delete("/api/auth/users/:id/data", requireServiceScope(auth.ScopeUserDataErase), handleEraseUserData)
+	app.Get("/api/auth/roles", handleGetRoles)
+	app.Get("/.well-known/jwks.json", handleJWKS)
+
+	// OAuth2 / OpenID Connect routes
+	app.Post("/oauth/token", handleOAuthToken)
+	app.Post("/oauth/introspect", handleIntrospect)
+	app.Get("/userinfo", handleUserInfo)
 
 	// Health check route
 	app.Get("/health", func(c *fiber.Ctx) error {
@@ -137,7 +198,8 @@ func handleGoogleCallback(c *fiber.Ctx) error {
 	}
 
 	// Generate tokens
-	accessToken, refreshToken, err := tokenService.GenerateTokens(googleUser.ID, "user")
+	amr := []string{"fed"}
+	accessToken, refreshToken, err := tokenService.GenerateTokens(googleUser.ID, auth.RoleCustomer, amr, googleUser.VerifiedEmail, false)
 	if err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
 			"error": "Failed to generate tokens",
@@ -145,11 +207,14 @@ func handleGoogleCallback(c *fiber.Ctx) error {
 	}
 
 	// Store refresh token
-	if err := tokenService.StoreRefreshToken(googleUser.ID, refreshToken); err != nil {
+	if err := tokenService.StoreRefreshToken(googleUser.ID, refreshToken, amr, googleUser.VerifiedEmail); err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
 			"error": "Failed to store refresh token",
 		})
 	}
+	if err := identityService.RecordLogin(identities.ProviderGoogle, googleUser.ID, googleUser.ID, googleUser.Email); err != nil {
+		log.Printf("Failed to record Google identity: %v", err)
+	}
 	responseData := fiber.Map{
 		"access_token":  accessToken,
 		"refresh_token": refreshToken,
@@ -183,15 +248,44 @@ func handleRefreshToken(c *fiber.Ctx) error {
 	}
 
 	// Validate refresh token
-	userID, err := tokenService.ValidateRefreshToken(req.RefreshToken)
+	rt, err := tokenService.ValidateRefreshToken(req.RefreshToken)
 	if err != nil {
 		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
 			"error": "Invalid refresh token",
 		})
 	}
+	userID := rt.UserID
+
+	// Re-read the account so role changes, email verification and disabling
+	// take effect on the next refresh
+	role := auth.RoleCustomer
+	emailVerified := rt.EmailVerified
+	mfaEnrolled := false
+	user, err := userClient.GetUser(userID)
+	switch {
+	case err == nil:
+		if user.Disabled {
+			if err := tokenService.RevokeRefreshToken(req.RefreshToken); err != nil {
+				log.Printf("Failed to revoke refresh token of disabled user: %v", err)
+			}
+			return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+				"error": "Account is disabled",
+			})
+		}
+		role = user.Role
+		emailVerified = user.EmailVerified
+		mfaEnrolled = user.MFAEnabled
+	case err == userclient.ErrUserNotFound:
+		// Accounts created through Google login have no user-service profile
+	default:
+		log.Printf("Failed to fetch user for refresh: %v", err)
+		return c.Status(fiber.StatusServiceUnavailable).JSON(fiber.Map{
+			"error": "User service unavailable",
+		})
+	}
 
 	// Generate new tokens
-	accessToken, newRefreshToken, err := tokenService.GenerateTokens(userID, "user")
+	accessToken, newRefreshToken, err := tokenService.GenerateTokens(userID, role, rt.AMR, emailVerified, mfaEnrolled)
 	if err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
 			"error": "Failed to generate tokens",
@@ -203,7 +297,7 @@ func handleRefreshToken(c *fiber.Ctx) error {
 		log.Printf("Failed to revoke old refresh token: %v", err)
 	}
 
-	if err := tokenService.StoreRefreshToken(userID, newRefreshToken); err != nil {
+	if err := tokenService.StoreRefreshToken(userID, newRefreshToken, rt.AMR, emailVerified); err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
 			"error": "Failed to store refresh token",
 		})
@@ -230,38 +324,31 @@ func handleVerifyToken(c *fiber.Ctx) error {
 		tokenString = authHeader[7:]
 	}
 
-	// Parse and validate token
-	token, err := jwt.ParseWithClaims(tokenString, &tokens.CustomClaims{}, func(token *jwt.Token) (interface{}, error) {
-		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
-			return nil, fiber.NewError(fiber.StatusUnauthorized, "Invalid token signing method")
-		}
-		return []byte(os.Getenv("JWT_SECRET")), nil
-	})
-
+	claims, err := tokenService.ParseAccessToken(tokenString)
 	if err != nil {
 		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
 			"error": "Invalid token",
 		})
 	}
 
-	claims, ok := token.Claims.(*tokens.CustomClaims)
-	if !ok || !token.Valid {
-		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
-			"error": "Invalid token claims",
-		})
-	}
-
 	return c.JSON(fiber.Map{
-		"valid":   true,
-		"user_id": claims.UserID,
-		"roles":   claims.Role,
+		"valid":          true,
+		"user_id":        claims.UserID,
+		"roles":          claims.Role,
+		"permissions":    claims.Permissions,
+		"amr":            claims.AMR,
+		"email_verified": claims.EmailVerified,
+		"mfa_enrolled":   claims.MFAEnrolled,
 	})
 }
 
 func handleUserRegistration(c *fiber.Ctx) error {
 	var req struct {
-		UserID string `json:"user_id"`
-		Role   string `json:"role"`
+		UserID        string   `json:"user_id"`
+		Role          string   `json:"role"`
+		AMR           []string `json:"amr"`
+		EmailVerified bool     `json:"email_verified"`
+		MFAEnrolled   bool     `json:"mfa_enrolled"`
 	}
 
 	if err := c.BodyParser(&req); err != nil {
@@ -271,7 +358,7 @@ func handleUserRegistration(c *fiber.Ctx) error {
 	}
 
 	// Generate tokens
-	accessToken, refreshToken, err := tokenService.GenerateTokens(req.UserID, req.Role)
+	accessToken, refreshToken, err := tokenService.GenerateTokens(req.UserID, req.Role, req.AMR, req.EmailVerified, req.MFAEnrolled)
 	if err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
 			"error": "Failed to generate tokens",
@@ -279,11 +366,16 @@ func handleUserRegistration(c *fiber.Ctx) error {
 	}
 
 	// Store refresh token
-	if err := tokenService.StoreRefreshToken(req.UserID, refreshToken); err != nil {
+	if err := tokenService.StoreRefreshToken(req.UserID, refreshToken, req.AMR, req.EmailVerified); err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
 			"error": "Failed to store refresh token",
 		})
 	}
+	if slices.Contains(req.AMR, "pwd") {
+		if err := identityService.RecordLogin(identities.ProviderPassword, req.UserID, req.UserID, ""); err != nil {
+			log.Printf("Failed to record password identity: %v", err)
+		}
+	}
 
 	return c.Status(fiber.StatusCreated).JSON(fiber.Map{
 		"access_token":  accessToken,
@@ -313,3 +405,63 @@ func handleLogout(c *fiber.Ctx) error {
 		"message": "Successfully logged out",
 	})
 }
+
+// handleRevokeUserTokens ends every session of a user, e.g. after a password reset.
+// Access tokens already issued stay valid until they expire.
+func handleRevokeUserTokens(c *fiber.Ctx) error {
+	revoked, err := tokenService.RevokeUserTokens(c.Params("id"))
+	if err != nil {
+		log.Printf("Failed to revoke user tokens: %v", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to revoke tokens",
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"revoked": revoked,
+	})
+}
+
+// handleGetUserSessions reports the active sessions and linked identities of a user
+// for the account view in the admin console
+func handleGetUserSessions(c *fiber.Ctx) error {
+	userID := c.Params("id")
+	count, err := tokenService.CountActiveSessions(userID)
+	if err != nil {
+		log.Printf("Failed to count sessions: %v", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to fetch sessions",
+		})
+	}
+	linked, err := identityService.ForUser(userID)
+	if err != nil {
+		log.Printf("Failed to fetch identities: %v", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to fetch identities",
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"active_sessions": count,
+		"identities":      linked,
+	})
+}
+
+func handleGetRoles(c *fiber.Ctx) error {
+	roleList, err := roleService.GetRoles()
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to fetch roles",
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"roles": roleList,
+	})
+}
+
+// handleJWKS publishes the public keys services use to verify access tokens locally
+func handleJWKS(c *fiber.Ctx) error {
+	c.Set(fiber.HeaderCacheControl, "public, max-age=300")
+	return c.JSON(tokenService.JWKS())
+}
@@ -0,0 +1,19 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"os"
+
+	"github.com/darkhyper24/blaban/auth-service/migrations"
+	"github.com/darkhyper24/blaban/shared/migrate"
+)
+
+// runMigrations handles "auth-service migrate <status|up|down [n]|to <version>>"
+func runMigrations(database *sql.DB, args []string) error {
+	migrator, err := migrate.New(database, migrations.FS, "auth-service")
+	if err != nil {
+		return err
+	}
+	return migrate.Run(context.Background(), migrator, args, os.Stdout)
+}
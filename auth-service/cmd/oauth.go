@@ -0,0 +1,238 @@
+package main
+
+import (
+	"encoding/base64"
+	"fmt"
+	"log"
+	"net/url"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/darkhyper24/blaban/auth-service/internal/clients"
+	"github.com/darkhyper24/blaban/auth-service/internal/tokens"
+	"github.com/darkhyper24/blaban/auth-service/internal/userclient"
+	"github.com/darkhyper24/blaban/shared/auth"
+	"github.com/gofiber/fiber/v2"
+)
+
+// registerClientsFromEnv registers confidential clients described as
+// comma-separated "client_id:secret[:scope scope ...]" entries
+func registerClientsFromEnv(spec string) error {
+	for _, entry := range strings.Split(spec, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+
+		parts := strings.SplitN(entry, ":", 3)
+		if len(parts) < 2 || parts[0] == "" || parts[1] == "" {
+			return fmt.Errorf("malformed client entry %q", entry)
+		}
+
+		var scopes []string
+		if len(parts) == 3 {
+			scopes = strings.Fields(parts[2])
+		}
+
+		if err := clientService.RegisterClient(parts[0], parts[1], scopes); err != nil {
+			return err
+		}
+		log.Printf("Registered OAuth client %s", parts[0])
+	}
+	return nil
+}
+
+// serviceClientID is the client ID auth-service uses for its own calls to other services
+const serviceClientID = "auth-service"
+
+// authenticateClient reads client credentials from HTTP Basic auth or the
+// request body (RFC 6749 section 2.3.1)
+func authenticateClient(c *fiber.Ctx) (*clients.Client, error) {
+	clientID, secret := c.FormValue("client_id"), c.FormValue("client_secret")
+
+	if header := c.Get("Authorization"); strings.HasPrefix(header, "Basic ") {
+		decoded, err := base64.StdEncoding.DecodeString(strings.TrimPrefix(header, "Basic "))
+		if err != nil {
+			return nil, clients.ErrInvalidClient
+		}
+		id, pass, ok := strings.Cut(string(decoded), ":")
+		if !ok {
+			return nil, clients.ErrInvalidClient
+		}
+		clientID, _ = url.QueryUnescape(id)
+		secret, _ = url.QueryUnescape(pass)
+	}
+
+	return clientService.Authenticate(clientID, secret)
+}
+
+func invalidClient(c *fiber.Ctx) error {
+	c.Set("WWW-Authenticate", `Basic realm="auth-service"`)
+	return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+		"error": "invalid_client",
+	})
+}
+
+// handleOAuthToken implements the OAuth2 client-credentials grant (RFC 6749 section 4.4)
+func handleOAuthToken(c *fiber.Ctx) error {
+	client, err := authenticateClient(c)
+	if err != nil {
+		if err != clients.ErrInvalidClient {
+			log.Printf("Client authentication error: %v", err)
+		}
+		return invalidClient(c)
+	}
+
+	if c.FormValue("grant_type") != "client_credentials" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "unsupported_grant_type",
+		})
+	}
+
+	// Grant the requested scopes, or every registered scope when none are requested
+	scopes := client.Scopes
+	if requested := strings.Fields(c.FormValue("scope")); len(requested) > 0 {
+		for _, scope := range requested {
+			if !slices.Contains(client.Scopes, scope) {
+				return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+					"error":             "invalid_scope",
+					"error_description": "scope not allowed for client: " + scope,
+				})
+			}
+		}
+		scopes = requested
+	}
+
+	accessToken, err := tokenService.GenerateServiceToken(client.ID, scopes)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "server_error",
+		})
+	}
+
+	c.Set(fiber.HeaderCacheControl, "no-store")
+	return c.JSON(fiber.Map{
+		"access_token": accessToken,
+		"token_type":   "bearer",
+		"expires_in":   int(tokenService.AccessTokenExpiry().Seconds()),
+		"scope":        strings.Join(scopes, " "),
+	})
+}
+
+// requireServiceScope only lets through requests made with a service token carrying scope
+func requireServiceScope(scope string) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		claims, err := tokenService.ParseAccessToken(auth.BearerToken(c.Get("Authorization")))
+		if err != nil || claims.UserID != "" {
+			return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+				"error": "Service token required",
+			})
+		}
+
+		if !slices.Contains(strings.Fields(claims.Scope), scope) {
+			return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
+				"error": "insufficient scope: requires " + scope,
+			})
+		}
+
+		return c.Next()
+	}
+}
+
+// handleIntrospect implements RFC 7662 token introspection for confidential clients
+func handleIntrospect(c *fiber.Ctx) error {
+	if _, err := authenticateClient(c); err != nil {
+		if err != clients.ErrInvalidClient {
+			log.Printf("Client authentication error: %v", err)
+		}
+		return invalidClient(c)
+	}
+
+	token := c.FormValue("token")
+	if token == "" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "invalid_request",
+		})
+	}
+
+	c.Set(fiber.HeaderCacheControl, "no-store")
+
+	hint := c.FormValue("token_type_hint")
+	if hint != "refresh_token" {
+		if resp, ok := introspectAccessToken(token); ok {
+			return c.JSON(resp)
+		}
+	}
+	if resp, ok := introspectRefreshToken(token); ok {
+		return c.JSON(resp)
+	}
+
+	return c.JSON(fiber.Map{"active": false})
+}
+
+func introspectAccessToken(token string) (fiber.Map, bool) {
+	resp, ok := tokenService.IntrospectAccessToken(token)
+	return fiber.Map(resp), ok
+}
+
+func introspectRefreshToken(token string) (fiber.Map, bool) {
+	rt, err := tokenService.GetRefreshToken(token)
+	if err != nil || time.Now().After(rt.ExpiresAt) {
+		return nil, false
+	}
+
+	return fiber.Map{
+		"active":     true,
+		"sub":        rt.UserID,
+		"client_id":  tokens.WebClientID,
+		"token_type": "refresh_token",
+		"exp":        rt.ExpiresAt.Unix(),
+	}, true
+}
+
+// handleUserInfo implements the OpenID Connect UserInfo endpoint
+func handleUserInfo(c *fiber.Ctx) error {
+	authHeader := c.Get("Authorization")
+	if !strings.HasPrefix(authHeader, "Bearer ") {
+		c.Set("WWW-Authenticate", `Bearer realm="auth-service"`)
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+			"error": "invalid_token",
+		})
+	}
+
+	// Service tokens have no user to describe and are invalid here
+	claims, err := tokenService.ParseUserAccessToken(strings.TrimPrefix(authHeader, "Bearer "))
+	if err != nil {
+		c.Set("WWW-Authenticate", `Bearer realm="auth-service", error="invalid_token"`)
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+			"error": "invalid_token",
+		})
+	}
+
+	info := fiber.Map{
+		"sub":            claims.UserID,
+		"role":           claims.Role,
+		"email_verified": claims.EmailVerified,
+	}
+
+	user, err := userClient.GetUser(claims.UserID)
+	switch {
+	case err == nil:
+		info["name"] = user.Name
+		info["email"] = user.Email
+		info["email_verified"] = user.EmailVerified
+		if user.Bio != "" {
+			info["bio"] = user.Bio
+		}
+	case err == userclient.ErrUserNotFound:
+		// Accounts created through Google login have no user-service profile
+	default:
+		log.Printf("Failed to fetch user profile: %v", err)
+		return c.Status(fiber.StatusBadGateway).JSON(fiber.Map{
+			"error": "User service unavailable",
+		})
+	}
+
+	return c.JSON(info)
+}
@@ -0,0 +1,69 @@
+package main
+
+import (
+	"log"
+	"time"
+
+	"github.com/darkhyper24/blaban/shared/userdata"
+	"github.com/gofiber/fiber/v2"
+)
+
+// handleExportUserData returns the sessions and linked identities of a user.
+// Refresh token values are credentials and are never exported.
+func handleExportUserData(c *fiber.Ctx) error {
+	userID := c.Params("id")
+	sessions, err := tokenService.UserSessions(userID)
+	if err != nil {
+		log.Printf("Failed to export sessions: %v", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to export sessions",
+		})
+	}
+	linked, err := identityService.ForUser(userID)
+	if err != nil {
+		log.Printf("Failed to export identities: %v", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to export identities",
+		})
+	}
+
+	exported := make([]fiber.Map, 0, len(sessions))
+	for _, s := range sessions {
+		exported = append(exported, fiber.Map{
+			"expires_at": s.ExpiresAt.Format(time.RFC3339),
+			"amr":        s.AMR,
+		})
+	}
+
+	return c.JSON(userdata.Export{
+		Service: serviceClientID,
+		Data: fiber.Map{
+			"sessions":   exported,
+			"identities": linked,
+		},
+	})
+}
+
+// handleEraseUserData ends every session of a user and unlinks their identities
+func handleEraseUserData(c *fiber.Ctx) error {
+	userID := c.Params("id")
+	revoked, err := tokenService.RevokeUserTokens(userID)
+	if err != nil {
+		log.Printf("Failed to erase sessions: %v", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to erase sessions",
+		})
+	}
+	unlinked, err := identityService.DeleteForUser(userID)
+	if err != nil {
+		log.Printf("Failed to erase identities: %v", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to erase identities",
+		})
+	}
+
+	return c.JSON(userdata.ErasureResult{
+		Service: serviceClientID,
+		Deleted: revoked + unlinked,
+	})
+}
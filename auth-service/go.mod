@@ -3,13 +3,20 @@ module github.com/darkhyper24/blaban/auth-service
 go 1.24.1
 
 require (
+	github.com/darkhyper24/blaban/shared v0.0.0
 	github.com/gofiber/fiber/v2 v2.52.6
-	github.com/golang-jwt/jwt v3.2.2+incompatible
 	golang.org/x/oauth2 v0.29.0
 )
 
 require github.com/joho/godotenv v1.5.1
 
+require (
+	github.com/davecgh/go-spew v1.1.1 // indirect
+	github.com/pmezard/go-difflib v1.0.0 // indirect
+	github.com/stretchr/testify v1.10.0
+	gopkg.in/yaml.v3 v3.0.1 // indirect
+)
+
 require (
 	cloud.google.com/go/compute/metadata v0.3.0 // indirect
 	github.com/andybalholm/brotli v1.1.0 // indirect
@@ -26,3 +33,5 @@ require (
 	github.com/valyala/tcplisten v1.0.0 // indirect
 	golang.org/x/sys v0.28.0 // indirect
 )
+
+replace github.com/darkhyper24/blaban/shared => ../shared
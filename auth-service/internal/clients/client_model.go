@@ -0,0 +1,7 @@
+package clients
+
+// Client is a confidential OAuth2 client such as another backend service
+type Client struct {
+	ID     string   `json:"client_id"`
+	Scopes []string `json:"scopes"`
+}
@@ -0,0 +1,62 @@
+package clients
+
+import (
+	"crypto/sha256"
+	"crypto/subtle"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"strings"
+)
+
+var ErrInvalidClient = errors.New("invalid client credentials")
+
+type ClientService struct {
+	db *sql.DB
+}
+
+func NewClientService(db *sql.DB) *ClientService {
+	return &ClientService{db: db}
+}
+
+// RegisterClient creates the client or replaces its secret and scopes.
+// Client secrets are random and high entropy, so a SHA-256 digest is enough to store them.
+func (cs *ClientService) RegisterClient(clientID, secret string, scopes []string) error {
+	_, err := cs.db.Exec(`
+        INSERT INTO oauth_clients (client_id, secret_hash, scope)
+        VALUES ($1, $2, $3)
+        ON CONFLICT (client_id) DO UPDATE SET secret_hash = EXCLUDED.secret_hash, scope = EXCLUDED.scope
+    `, clientID, hashSecret(secret), strings.Join(scopes, " "))
+	return err
+}
+
+// Authenticate checks the client's secret and returns the registered client
+func (cs *ClientService) Authenticate(clientID, secret string) (*Client, error) {
+	if clientID == "" || secret == "" {
+		return nil, ErrInvalidClient
+	}
+
+	var secretHash, scope string
+	row := cs.db.QueryRow(`
+        SELECT secret_hash, scope
+        FROM oauth_clients
+        WHERE client_id = $1
+    `, clientID)
+	if err := row.Scan(&secretHash, &scope); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, ErrInvalidClient
+		}
+		return nil, err
+	}
+
+	if subtle.ConstantTimeCompare([]byte(secretHash), []byte(hashSecret(secret))) != 1 {
+		return nil, ErrInvalidClient
+	}
+
+	return &Client{ID: clientID, Scopes: strings.Fields(scope)}, nil
+}
+
+func hashSecret(secret string) string {
+	sum := sha256.Sum256([]byte(secret))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,19 @@
+package identities
+
+import "time"
+
+// Providers an account can sign in with
+const (
+	ProviderPassword = "password"
+	ProviderGoogle   = "google"
+)
+
+// Identity links a login provider account to a user
+type Identity struct {
+	Provider    string    `json:"provider"`
+	Subject     string    `json:"subject"`
+	UserID      string    `json:"user_id"`
+	Email       string    `json:"email,omitempty"`
+	CreatedAt   time.Time `json:"created_at"`
+	LastLoginAt time.Time `json:"last_login_at"`
+}
@@ -0,0 +1,59 @@
+package identities
+
+import (
+	"database/sql"
+)
+
+type IdentityService struct {
+	db *sql.DB
+}
+
+func NewIdentityService(db *sql.DB) *IdentityService {
+	return &IdentityService{db: db}
+}
+
+// RecordLogin links the provider subject to userID, creating the identity on first use
+func (is *IdentityService) RecordLogin(provider, subject, userID, email string) error {
+	_, err := is.db.Exec(`
+        INSERT INTO user_identities (provider, subject, user_id, email)
+        VALUES ($1, $2, $3, $4)
+        ON CONFLICT (provider, subject)
+        DO UPDATE SET user_id = EXCLUDED.user_id,
+                      email = CASE WHEN EXCLUDED.email = '' THEN user_identities.email ELSE EXCLUDED.email END,
+                      last_login_at = NOW()
+    `, provider, subject, userID, email)
+	return err
+}
+
+// ForUser returns every identity linked to userID, oldest first
+func (is *IdentityService) ForUser(userID string) ([]Identity, error) {
+	rows, err := is.db.Query(`
+        SELECT provider, subject, user_id, email, created_at, last_login_at
+        FROM user_identities
+        WHERE user_id = $1
+        ORDER BY created_at
+    `, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	identities := []Identity{}
+	for rows.Next() {
+		var identity Identity
+		if err := rows.Scan(&identity.Provider, &identity.Subject, &identity.UserID, &identity.Email, &identity.CreatedAt, &identity.LastLoginAt); err != nil {
+			return nil, err
+		}
+		identities = append(identities, identity)
+	}
+	return identities, rows.Err()
+}
+
+// DeleteForUser unlinks every identity of userID
+func (is *IdentityService) DeleteForUser(userID string) (int64, error) {
+	result, err := is.db.Exec("DELETE FROM user_identities WHERE user_id = $1", userID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
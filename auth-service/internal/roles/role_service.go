@@ -0,0 +1,67 @@
+package roles
+
+import (
+	"database/sql"
+)
+
+type RoleService struct {
+	db *sql.DB
+}
+
+func NewRoleService(db *sql.DB) *RoleService {
+	return &RoleService{db: db}
+}
+
+// PermissionsForRole returns the permissions granted to role. Unknown roles have none.
+func (rs *RoleService) PermissionsForRole(role string) ([]string, error) {
+	rows, err := rs.db.Query(`
+        SELECT permission
+        FROM role_permissions
+        WHERE role = $1
+        ORDER BY permission
+    `, role)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	permissions := []string{}
+	for rows.Next() {
+		var permission string
+		if err := rows.Scan(&permission); err != nil {
+			return nil, err
+		}
+		permissions = append(permissions, permission)
+	}
+	return permissions, rows.Err()
+}
+
+// GetRoles returns every role together with its permissions
+func (rs *RoleService) GetRoles() ([]Role, error) {
+	rows, err := rs.db.Query(`
+        SELECT r.name, r.description, COALESCE(rp.permission, '')
+        FROM roles r
+        LEFT JOIN role_permissions rp ON rp.role = r.name
+        ORDER BY r.name, rp.permission
+    `)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	roles := []Role{}
+	for rows.Next() {
+		var name, description, permission string
+		if err := rows.Scan(&name, &description, &permission); err != nil {
+			return nil, err
+		}
+		if len(roles) == 0 || roles[len(roles)-1].Name != name {
+			roles = append(roles, Role{Name: name, Description: description, Permissions: []string{}})
+		}
+		if permission != "" {
+			last := &roles[len(roles)-1]
+			last.Permissions = append(last.Permissions, permission)
+		}
+	}
+	return roles, rows.Err()
+}
@@ -3,7 +3,9 @@ package tokens
 import "time"
 
 type RefreshToken struct {
-	Token     string    `json:"token"`
-	UserID    string    `json:"user_id"`
-	ExpiresAt time.Time `json:"expires_at"`
+	Token         string    `json:"token"`
+	UserID        string    `json:"user_id"`
+	ExpiresAt     time.Time `json:"expires_at"`
+	AMR           []string  `json:"amr"`
+	EmailVerified bool      `json:"email_verified"`
 }
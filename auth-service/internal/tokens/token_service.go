@@ -1,59 +1,108 @@
 package tokens
 
 import (
+	"crypto/rsa"
 	"database/sql"
 	"errors"
 	"fmt"
+	"os"
+	"strings"
 	"time"
 
+	"github.com/darkhyper24/blaban/auth-service/internal/roles"
+	"github.com/darkhyper24/blaban/shared/auth"
 	"github.com/golang-jwt/jwt/v4"
 	"github.com/google/uuid"
 )
 
+// ErrNotUserToken is returned for valid access tokens that were issued to a
+// service client rather than to a user
+var ErrNotUserToken = errors.New("access token does not belong to a user")
+
 type TokenService struct {
 	db                *sql.DB
-	hmacSecret        []byte
+	roles             *roles.RoleService
+	signingKey        *rsa.PrivateKey
+	keyID             string
 	accessTokenExpiry time.Duration
 	refreshExpiry     time.Duration
 }
 
-func NewTokenService(db *sql.DB, secret string, accessExp, refreshExp time.Duration) *TokenService {
+// NewTokenService creates a token service that signs access tokens with
+// signingKey using RS256. Other services verify them with the public half,
+// published through JWKS().
+func NewTokenService(db *sql.DB, roleService *roles.RoleService, signingKey *rsa.PrivateKey, accessExp, refreshExp time.Duration) (*TokenService, error) {
+	keyID, err := auth.KeyID(&signingKey.PublicKey)
+	if err != nil {
+		return nil, err
+	}
+
 	return &TokenService{
 		db:                db,
-		hmacSecret:        []byte(secret),
+		roles:             roleService,
+		signingKey:        signingKey,
+		keyID:             keyID,
 		accessTokenExpiry: accessExp,
 		refreshExpiry:     refreshExp,
+	}, nil
+}
+
+// LoadSigningKey reads a PEM encoded RSA private key from path
+func LoadSigningKey(path string) (*rsa.PrivateKey, error) {
+	pem, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read signing key: %w", err)
+	}
+	key, err := jwt.ParseRSAPrivateKeyFromPEM(pem)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse signing key: %w", err)
 	}
+	return key, nil
 }
 
-type CustomClaims struct {
-	UserID string `json:"user_id"`
-	Role   string `json:"role"`
-	jwt.RegisteredClaims
+// WebClientID identifies the first-party web client user tokens are issued to
+const WebClientID = "blaban-web"
+
+// JWKS returns the public keys other services verify access tokens with
+func (ts *TokenService) JWKS() auth.JWKSet {
+	return auth.JWKSet{Keys: []auth.JWK{auth.NewRSAJWK(ts.keyID, &ts.signingKey.PublicKey)}}
 }
 
-func (c *CustomClaims) Valid() error {
-	// Check expiration if set
-	if c.ExpiresAt != nil {
-		if !c.VerifyExpiresAt(time.Now(), true) {
-			return errors.New("token is expired or missing exp claim")
-		}
-	}
-	return nil
+func (ts *TokenService) sign(claims *auth.Claims) (string, error) {
+	token := jwt.NewWithClaims(jwt.SigningMethodRS256, claims)
+	token.Header["kid"] = ts.keyID
+	return token.SignedString(ts.signingKey)
 }
 
-func (ts *TokenService) GenerateTokens(userID string, role string) (string, string, error) {
+// GenerateTokens issues an access and refresh token pair. amr lists the
+// authentication methods (RFC 8176) the user completed, such as "pwd" and "otp".
+// mfaEnrolled records whether the user has set up a second factor, which
+// makes it required for some actions.
+func (ts *TokenService) GenerateTokens(userID string, role string, amr []string, emailVerified, mfaEnrolled bool) (string, string, error) {
+	permissions, err := ts.roles.PermissionsForRole(role)
+	if err != nil {
+		return "", "", fmt.Errorf("failed to load role permissions: %w", err)
+	}
+
 	// 1) access token
-	atClaims := &CustomClaims{
-		UserID: userID,
-		Role:   role,
+	now := time.Now()
+	atClaims := &auth.Claims{
+		UserID:        userID,
+		Role:          role,
+		Permissions:   permissions,
+		ClientID:      WebClientID,
+		AMR:           amr,
+		EmailVerified: emailVerified,
+		MFAEnrolled:   mfaEnrolled,
 		RegisteredClaims: jwt.RegisteredClaims{
-			ExpiresAt: jwt.NewNumericDate(time.Now().Add(ts.accessTokenExpiry)),
-			Issuer:    "auth-service",
+			ID:        uuid.NewString(),
+			Subject:   userID,
+			IssuedAt:  jwt.NewNumericDate(now),
+			ExpiresAt: jwt.NewNumericDate(now.Add(ts.accessTokenExpiry)),
+			Issuer:    auth.Issuer,
 		},
 	}
-	at := jwt.NewWithClaims(jwt.SigningMethodHS256, atClaims)
-	accessToken, err := at.SignedString(ts.hmacSecret)
+	accessToken, err := ts.sign(atClaims)
 	if err != nil {
 		return "", "", fmt.Errorf("failed to sign access token: %w", err)
 	}
@@ -62,39 +111,193 @@ func (ts *TokenService) GenerateTokens(userID string, role string) (string, stri
 	return accessToken, refreshToken, nil
 }
 
-func (ts *TokenService) StoreRefreshToken(userID, refreshToken string) error {
+// GenerateServiceToken issues an access token to a service client through the
+// client-credentials grant. Service tokens carry scopes instead of a user.
+func (ts *TokenService) GenerateServiceToken(clientID string, scopes []string) (string, error) {
+	now := time.Now()
+	claims := &auth.Claims{
+		ClientID: clientID,
+		Scope:    strings.Join(scopes, " "),
+		RegisteredClaims: jwt.RegisteredClaims{
+			ID:        uuid.NewString(),
+			Subject:   clientID,
+			IssuedAt:  jwt.NewNumericDate(now),
+			ExpiresAt: jwt.NewNumericDate(now.Add(ts.accessTokenExpiry)),
+			Issuer:    auth.Issuer,
+		},
+	}
+
+	token, err := ts.sign(claims)
+	if err != nil {
+		return "", fmt.Errorf("failed to sign service token: %w", err)
+	}
+	return token, nil
+}
+
+// AccessTokenExpiry returns the lifetime of issued access tokens
+func (ts *TokenService) AccessTokenExpiry() time.Duration {
+	return ts.accessTokenExpiry
+}
+
+// ParseAccessToken validates the signature and expiry of an access token and returns its claims
+func (ts *TokenService) ParseAccessToken(tokenString string) (*auth.Claims, error) {
+	token, err := jwt.ParseWithClaims(tokenString, &auth.Claims{}, func(token *jwt.Token) (interface{}, error) {
+		return &ts.signingKey.PublicKey, nil
+	}, jwt.WithValidMethods([]string{jwt.SigningMethodRS256.Alg()}))
+	if err != nil {
+		return nil, err
+	}
+
+	claims, ok := token.Claims.(*auth.Claims)
+	if !ok || !token.Valid {
+		return nil, errors.New("invalid token claims")
+	}
+	return claims, nil
+}
+
+// ParseUserAccessToken is ParseAccessToken for endpoints that act on behalf of
+// a user. Service tokens from the client-credentials grant have no user and
+// are rejected with ErrNotUserToken.
+func (ts *TokenService) ParseUserAccessToken(tokenString string) (*auth.Claims, error) {
+	claims, err := ts.ParseAccessToken(tokenString)
+	if err != nil {
+		return nil, err
+	}
+	if claims.UserID == "" || claims.Subject != claims.UserID {
+		return nil, ErrNotUserToken
+	}
+	return claims, nil
+}
+
+// IntrospectAccessToken describes an access token as an RFC 7662
+// introspection response. Tokens that fail to parse or have expired are not
+// active, which is reported through ok.
+func (ts *TokenService) IntrospectAccessToken(token string) (resp map[string]interface{}, ok bool) {
+	claims, err := ts.ParseAccessToken(token)
+	if err != nil {
+		return nil, false
+	}
+
+	resp = map[string]interface{}{
+		"active":     true,
+		"sub":        claims.Subject,
+		"client_id":  claims.ClientID,
+		"token_type": "Bearer",
+		"iss":        claims.Issuer,
+	}
+	if claims.UserID != "" {
+		resp["user_id"] = claims.UserID
+		resp["scope"] = strings.Join(claims.Permissions, " ")
+		resp["role"] = claims.Role
+		resp["permissions"] = claims.Permissions
+		resp["amr"] = claims.AMR
+		resp["email_verified"] = claims.EmailVerified
+		resp["mfa_enrolled"] = claims.MFAEnrolled
+	} else {
+		resp["scope"] = claims.Scope
+	}
+	if claims.ExpiresAt != nil {
+		resp["exp"] = claims.ExpiresAt.Unix()
+	}
+	if claims.IssuedAt != nil {
+		resp["iat"] = claims.IssuedAt.Unix()
+	}
+	if claims.ID != "" {
+		resp["jti"] = claims.ID
+	}
+	return resp, true
+}
+
+// StoreRefreshToken persists a refresh token together with the authentication
+// methods of the login, so refreshed access tokens keep the same amr
+func (ts *TokenService) StoreRefreshToken(userID, refreshToken string, amr []string, emailVerified bool) error {
 	rtExpiresAt := time.Now().Add(ts.refreshExpiry)
-	return ts.insertRefreshToken(refreshToken, userID, rtExpiresAt)
+	return ts.insertRefreshToken(refreshToken, userID, rtExpiresAt, amr, emailVerified)
 }
 
-func (ts *TokenService) insertRefreshToken(token, userID string, exp time.Time) error {
+func (ts *TokenService) insertRefreshToken(token, userID string, exp time.Time, amr []string, emailVerified bool) error {
 	_, err := ts.db.Exec(`
-        INSERT INTO refresh_tokens (token, user_id, expires_at)
-        VALUES ($1, $2, $3)
-    `, token, userID, exp)
+        INSERT INTO refresh_tokens (token, user_id, expires_at, amr, email_verified)
+        VALUES ($1, $2, $3, $4, $5)
+    `, token, userID, exp, strings.Join(amr, " "), emailVerified)
 	return err
 }
 
-func (ts *TokenService) ValidateRefreshToken(refreshToken string) (string, error) {
-	var userID string
-	var expiresAt time.Time
+func (ts *TokenService) ValidateRefreshToken(refreshToken string) (*RefreshToken, error) {
+	rt, err := ts.GetRefreshToken(refreshToken)
+	if err != nil {
+		return nil, err
+	}
+	if time.Now().After(rt.ExpiresAt) {
+		return nil, errors.New("refresh token expired")
+	}
+	return rt, nil
+}
+
+// GetRefreshToken returns a stored refresh token without checking its expiry
+func (ts *TokenService) GetRefreshToken(refreshToken string) (*RefreshToken, error) {
+	rt := RefreshToken{Token: refreshToken}
+	var amr string
 
 	row := ts.db.QueryRow(`
-        SELECT user_id, expires_at
+        SELECT user_id, expires_at, amr, email_verified
         FROM refresh_tokens
         WHERE token = $1
     `, refreshToken)
 
-	if err := row.Scan(&userID, &expiresAt); err != nil {
-		return "", errors.New("invalid refresh token or not found")
-	}
-	if time.Now().After(expiresAt) {
-		return "", errors.New("refresh token expired")
+	if err := row.Scan(&rt.UserID, &rt.ExpiresAt, &amr, &rt.EmailVerified); err != nil {
+		return nil, errors.New("invalid refresh token or not found")
 	}
-	return userID, nil
+	rt.AMR = strings.Fields(amr)
+	return &rt, nil
 }
 
 func (ts *TokenService) RevokeRefreshToken(refreshToken string) error {
 	_, err := ts.db.Exec("DELETE FROM refresh_tokens WHERE token = $1", refreshToken)
 	return err
 }
+
+// RevokeUserTokens revokes every refresh token of the user, ending all of their sessions
+func (ts *TokenService) RevokeUserTokens(userID string) (int64, error) {
+	result, err := ts.db.Exec("DELETE FROM refresh_tokens WHERE user_id = $1", userID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+// UserSessions returns the unexpired refresh tokens of the user, soonest to expire first
+func (ts *TokenService) UserSessions(userID string) ([]RefreshToken, error) {
+	rows, err := ts.db.Query(`
+        SELECT token, user_id, expires_at, amr, email_verified
+        FROM refresh_tokens
+        WHERE user_id = $1 AND expires_at > NOW()
+        ORDER BY expires_at
+    `, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	sessions := []RefreshToken{}
+	for rows.Next() {
+		var rt RefreshToken
+		var amr string
+		if err := rows.Scan(&rt.Token, &rt.UserID, &rt.ExpiresAt, &amr, &rt.EmailVerified); err != nil {
+			return nil, err
+		}
+		rt.AMR = strings.Fields(amr)
+		sessions = append(sessions, rt)
+	}
+	return sessions, rows.Err()
+}
+
+// CountActiveSessions returns how many unexpired refresh tokens the user holds
+func (ts *TokenService) CountActiveSessions(userID string) (int, error) {
+	var count int
+	err := ts.db.QueryRow(
+		"SELECT COUNT(*) FROM refresh_tokens WHERE user_id = $1 AND expires_at > NOW()",
+		userID,
+	).Scan(&count)
+	return count, err
+}
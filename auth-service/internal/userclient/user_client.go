@@ -0,0 +1,76 @@
+package userclient
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+)
+
+var ErrUserNotFound = errors.New("user not found")
+
+// User is the profile user-service exposes to other services
+type User struct {
+	ID            string `json:"id"`
+	Name          string `json:"name"`
+	Email         string `json:"email"`
+	Role          string `json:"role"`
+	Bio           string `json:"bio"`
+	EmailVerified bool   `json:"email_verified"`
+	Disabled      bool   `json:"disabled"`
+	MFAEnabled    bool   `json:"mfa_enabled"`
+}
+
+// TokenSource returns a service token authorising calls to user-service
+type TokenSource func() (string, error)
+
+// UserClient fetches user profiles from user-service
+type UserClient struct {
+	baseURL string
+	client  *http.Client
+	token   TokenSource
+}
+
+func NewUserClient(baseURL string, token TokenSource) *UserClient {
+	return &UserClient{
+		baseURL: strings.TrimRight(baseURL, "/"),
+		client:  &http.Client{Timeout: 5 * time.Second},
+		token:   token,
+	}
+}
+
+func (uc *UserClient) GetUser(userID string) (*User, error) {
+	req, err := http.NewRequest(http.MethodGet, fmt.Sprintf("%s/internal/users/%s", uc.baseURL, userID), nil)
+	if err != nil {
+		return nil, err
+	}
+
+	token, err := uc.token()
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Authorization", "Bearer "+token)
+
+	resp, err := uc.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotFound {
+		return nil, ErrUserNotFound
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("user service returned status %d", resp.StatusCode)
+	}
+
+	var body struct {
+		User User `json:"user"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return nil, err
+	}
+	return &body.User, nil
+}
@@ -0,0 +1,39 @@
+CREATE TABLE IF NOT EXISTS roles (
+  name TEXT PRIMARY KEY,
+  description TEXT NOT NULL DEFAULT ''
+);
+
+CREATE TABLE IF NOT EXISTS role_permissions (
+  role TEXT NOT NULL REFERENCES roles(name) ON DELETE CASCADE,
+  permission TEXT NOT NULL,
+  PRIMARY KEY (role, permission)
+);
+
+INSERT INTO roles (name, description) VALUES
+  ('user', 'Customer'),
+  ('kitchen', 'Kitchen staff preparing orders'),
+  ('cashier', 'Front counter staff'),
+  ('delivery', 'Delivery drivers'),
+  ('manager', 'Restaurant manager'),
+  ('admin', 'System administrator')
+ON CONFLICT (name) DO NOTHING;
+
+INSERT INTO role_permissions (role, permission) VALUES
+  ('kitchen', 'orders:read_all'),
+  ('kitchen', 'orders:transition'),
+  ('cashier', 'orders:read_all'),
+  ('cashier', 'orders:transition'),
+  ('cashier', 'payments:refund'),
+  ('delivery', 'orders:read_all'),
+  ('delivery', 'orders:transition'),
+  ('manager', 'menu:write'),
+  ('manager', 'orders:read_all'),
+  ('manager', 'orders:transition'),
+  ('manager', 'reviews:moderate'),
+  ('manager', 'payments:refund'),
+  ('admin', 'menu:write'),
+  ('admin', 'orders:read_all'),
+  ('admin', 'orders:transition'),
+  ('admin', 'reviews:moderate'),
+  ('admin', 'payments:refund')
+ON CONFLICT (role, permission) DO NOTHING;
@@ -0,0 +1 @@
+ALTER TABLE refresh_tokens ADD COLUMN IF NOT EXISTS amr TEXT NOT NULL DEFAULT '';
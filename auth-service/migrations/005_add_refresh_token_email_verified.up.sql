@@ -0,0 +1 @@
+ALTER TABLE refresh_tokens ADD COLUMN IF NOT EXISTS email_verified BOOLEAN NOT NULL DEFAULT FALSE;
@@ -0,0 +1 @@
+DELETE FROM role_permissions WHERE role = 'admin' AND permission = 'users:manage';
@@ -0,0 +1,3 @@
+INSERT INTO role_permissions (role, permission) VALUES
+  ('admin', 'users:manage')
+ON CONFLICT (role, permission) DO NOTHING;
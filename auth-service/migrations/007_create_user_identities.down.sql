@@ -0,0 +1,3 @@
+DELETE FROM role_permissions WHERE permission = 'users:view';
+
+DROP TABLE IF EXISTS user_identities;
@@ -0,0 +1,16 @@
+CREATE TABLE IF NOT EXISTS user_identities (
+  provider TEXT NOT NULL,
+  subject TEXT NOT NULL,
+  user_id TEXT NOT NULL,
+  email TEXT NOT NULL DEFAULT '',
+  created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+  last_login_at TIMESTAMP NOT NULL DEFAULT NOW(),
+  PRIMARY KEY (provider, subject)
+);
+
+CREATE INDEX IF NOT EXISTS idx_user_identities_user_id ON user_identities(user_id);
+
+INSERT INTO role_permissions (role, permission) VALUES
+  ('manager', 'users:view'),
+  ('admin', 'users:view')
+ON CONFLICT (role, permission) DO NOTHING;
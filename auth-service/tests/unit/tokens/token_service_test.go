@@ -0,0 +1,228 @@
+package tokens
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/darkhyper24/blaban/auth-service/internal/tokens"
+	"github.com/darkhyper24/blaban/shared/auth"
+	"github.com/golang-jwt/jwt/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newSigningKey(t *testing.T) *rsa.PrivateKey {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	return key
+}
+
+func newTokenService(t *testing.T, key *rsa.PrivateKey, accessExpiry time.Duration) *tokens.TokenService {
+	ts, err := tokens.NewTokenService(nil, nil, key, accessExpiry, time.Hour)
+	require.NoError(t, err)
+	return ts
+}
+
+// signUserToken signs user claims the way GenerateTokens does, without
+// needing the role table for permissions
+func signUserToken(t *testing.T, key *rsa.PrivateKey, claims *auth.Claims) string {
+	kid, err := auth.KeyID(&key.PublicKey)
+	require.NoError(t, err)
+	token := jwt.NewWithClaims(jwt.SigningMethodRS256, claims)
+	token.Header["kid"] = kid
+	signed, err := token.SignedString(key)
+	require.NoError(t, err)
+	return signed
+}
+
+func TestIntrospectAccessToken(t *testing.T) {
+	key := newSigningKey(t)
+	ts := newTokenService(t, key, time.Minute)
+
+	t.Run("Service token reports its scopes", func(t *testing.T) {
+		token, err := ts.GenerateServiceToken("order-service", []string{auth.ScopeMenuRead, auth.ScopeStockReserve})
+		require.NoError(t, err)
+
+		resp, ok := ts.IntrospectAccessToken(token)
+
+		assert.True(t, ok)
+		assert.Equal(t, true, resp["active"])
+		assert.Equal(t, "order-service", resp["sub"])
+		assert.Equal(t, "order-service", resp["client_id"])
+		assert.Equal(t, "menu:read stock:reserve", resp["scope"])
+		assert.Equal(t, auth.Issuer, resp["iss"])
+		assert.NotEmpty(t, resp["jti"])
+		assert.NotContains(t, resp, "user_id")
+	})
+
+	t.Run("User token reports its permissions as scope", func(t *testing.T) {
+		now := time.Now()
+		token := signUserToken(t, key, &auth.Claims{
+			UserID:        "user-1",
+			Role:          auth.RoleManager,
+			Permissions:   []string{auth.PermMenuWrite, auth.PermOrdersReadAll},
+			ClientID:      tokens.WebClientID,
+			AMR:           []string{"pwd", "otp"},
+			EmailVerified: true,
+			MFAEnrolled:   true,
+			RegisteredClaims: jwt.RegisteredClaims{
+				Subject:   "user-1",
+				IssuedAt:  jwt.NewNumericDate(now),
+				ExpiresAt: jwt.NewNumericDate(now.Add(time.Minute)),
+				Issuer:    auth.Issuer,
+			},
+		})
+
+		resp, ok := ts.IntrospectAccessToken(token)
+
+		assert.True(t, ok)
+		assert.Equal(t, "user-1", resp["user_id"])
+		assert.Equal(t, "menu:write orders:read_all", resp["scope"])
+		assert.Equal(t, auth.RoleManager, resp["role"])
+		assert.Equal(t, []string{"pwd", "otp"}, resp["amr"])
+		assert.Equal(t, true, resp["email_verified"])
+		assert.Equal(t, true, resp["mfa_enrolled"])
+		assert.Equal(t, now.Add(time.Minute).Unix(), resp["exp"])
+	})
+
+	t.Run("Expired token is not active", func(t *testing.T) {
+		expired := newTokenService(t, key, -time.Minute)
+		token, err := expired.GenerateServiceToken("order-service", nil)
+		require.NoError(t, err)
+
+		_, ok := ts.IntrospectAccessToken(token)
+
+		assert.False(t, ok)
+	})
+
+	t.Run("Token signed with another key is not active", func(t *testing.T) {
+		other := newTokenService(t, newSigningKey(t), time.Minute)
+		token, err := other.GenerateServiceToken("order-service", nil)
+		require.NoError(t, err)
+
+		_, ok := ts.IntrospectAccessToken(token)
+
+		assert.False(t, ok)
+	})
+
+	t.Run("Token with a symmetric algorithm is not active", func(t *testing.T) {
+		token := jwt.NewWithClaims(jwt.SigningMethodHS256, &auth.Claims{ClientID: "order-service"})
+		signed, err := token.SignedString([]byte("secret"))
+		require.NoError(t, err)
+
+		_, ok := ts.IntrospectAccessToken(signed)
+
+		assert.False(t, ok)
+	})
+
+	t.Run("Garbage is not active", func(t *testing.T) {
+		_, ok := ts.IntrospectAccessToken("not-a-token")
+
+		assert.False(t, ok)
+	})
+}
+
+func TestLoadSigningKey(t *testing.T) {
+	key := newSigningKey(t)
+	dir := t.TempDir()
+
+	pkcs8, err := x509.MarshalPKCS8PrivateKey(key)
+	require.NoError(t, err)
+	files := map[string][]byte{
+		"pkcs1.pem": pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(key)}),
+		"pkcs8.pem": pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: pkcs8}),
+		"bad.pem":   []byte("not a key"),
+	}
+	for name, data := range files {
+		require.NoError(t, os.WriteFile(filepath.Join(dir, name), data, 0o600))
+	}
+
+	for _, name := range []string{"pkcs1.pem", "pkcs8.pem"} {
+		t.Run("Loads "+name, func(t *testing.T) {
+			loaded, err := tokens.LoadSigningKey(filepath.Join(dir, name))
+			require.NoError(t, err)
+			assert.True(t, key.Equal(loaded))
+		})
+	}
+
+	t.Run("Rejects a file that is not a key", func(t *testing.T) {
+		_, err := tokens.LoadSigningKey(filepath.Join(dir, "bad.pem"))
+		assert.Error(t, err)
+	})
+
+	t.Run("Rejects a missing file", func(t *testing.T) {
+		_, err := tokens.LoadSigningKey(filepath.Join(dir, "missing.pem"))
+		assert.Error(t, err)
+	})
+
+	t.Run("Rejects an empty path", func(t *testing.T) {
+		_, err := tokens.LoadSigningKey("")
+		assert.Error(t, err)
+	})
+}
+
+func TestParseUserAccessToken(t *testing.T) {
+	key := newSigningKey(t)
+	ts := newTokenService(t, key, time.Minute)
+	now := time.Now()
+
+	t.Run("User token", func(t *testing.T) {
+		token := signUserToken(t, key, &auth.Claims{
+			UserID: "user-1",
+			Role:   auth.RoleCustomer,
+			RegisteredClaims: jwt.RegisteredClaims{
+				Subject:   "user-1",
+				ExpiresAt: jwt.NewNumericDate(now.Add(time.Minute)),
+			},
+		})
+
+		claims, err := ts.ParseUserAccessToken(token)
+
+		require.NoError(t, err)
+		assert.Equal(t, "user-1", claims.UserID)
+	})
+
+	t.Run("Service token is not a user token", func(t *testing.T) {
+		token, err := ts.GenerateServiceToken("order-service", []string{auth.ScopeMenuRead})
+		require.NoError(t, err)
+
+		_, err = ts.ParseUserAccessToken(token)
+
+		assert.ErrorIs(t, err, tokens.ErrNotUserToken)
+	})
+
+	t.Run("User ID must match the subject", func(t *testing.T) {
+		token := signUserToken(t, key, &auth.Claims{
+			UserID: "user-1",
+			RegisteredClaims: jwt.RegisteredClaims{
+				Subject:   "order-service",
+				ExpiresAt: jwt.NewNumericDate(now.Add(time.Minute)),
+			},
+		})
+
+		_, err := ts.ParseUserAccessToken(token)
+
+		assert.ErrorIs(t, err, tokens.ErrNotUserToken)
+	})
+
+	t.Run("Expired user token", func(t *testing.T) {
+		token := signUserToken(t, key, &auth.Claims{
+			UserID: "user-1",
+			RegisteredClaims: jwt.RegisteredClaims{
+				Subject:   "user-1",
+				ExpiresAt: jwt.NewNumericDate(now.Add(-time.Minute)),
+			},
+		})
+
+		_, err := ts.ParseUserAccessToken(token)
+
+		assert.Error(t, err)
+		assert.NotErrorIs(t, err, tokens.ErrNotUserToken)
+	})
+}
@@ -36,17 +36,34 @@ const menuApi = {
     }
   },
   
-  // Get menu with items grouped by category
+  // Get menu with items grouped by category, following every page
   getMenu: async (): Promise<MenuCategory[]> => {
     try {
-      const response = await fetch(`${apiConfig.menuUrl}/api/menu`);
-      
-      if (!response.ok) {
-        throw new Error('Failed to fetch menu');
-      }
-      
-      const data = await response.json();
-      return data.menu || [];
+      const categories: MenuCategory[] = [];
+      let cursor: string | null = null;
+
+      do {
+        const query: string = cursor ? `&cursor=${encodeURIComponent(cursor)}` : '';
+        const response = await fetch(`${apiConfig.menuUrl}/api/menu?limit=100${query}`);
+
+        if (!response.ok) {
+          throw new Error('Failed to fetch menu');
+        }
+
+        const data = await response.json();
+        for (const category of (data.menu || []) as MenuCategory[]) {
+          // A category can continue from the previous page
+          const existing = categories.find((c) => c.id === category.id);
+          if (existing) {
+            existing.items = [...(existing.items || []), ...(category.items || [])];
+          } else {
+            categories.push(category);
+          }
+        }
+        cursor = data.pagination?.next_cursor || null;
+      } while (cursor);
+
+      return categories;
     } catch (error) {
       console.error('Error fetching menu:', error);
       return [];
@@ -4,7 +4,9 @@ package main
 import (
 	"context"
 	"log"
+	"os"
 	"time"
+	_ "time/tzdata" // recurring discounts need time zones on images without tzdata
 
 	"github.com/go-redis/redis/v8"
 	"github.com/gofiber/fiber/v2"
@@ -12,35 +14,88 @@ import (
 	"github.com/gofiber/fiber/v2/middleware/logger"
 
 	"github.com/darkhyper24/blaban/menu-service/internal/db"
+	"github.com/darkhyper24/blaban/menu-service/internal/images"
+	"github.com/darkhyper24/blaban/menu-service/internal/models"
 	"github.com/darkhyper24/blaban/menu-service/internal/routes"
+	"github.com/darkhyper24/blaban/shared/auth"
 )
 
 var redisClient *redis.Client
 var cacheTTL = 15 * time.Minute
 
 func main() {
-	app := fiber.New()
+	// Leave room for the multipart envelope around a maximum size image
+	app := fiber.New(fiber.Config{
+		BodyLimit: images.MaxUploadSize + 1<<20,
+	})
 
 	app.Use(cors.New())
 	app.Use(logger.New())
 
+	// Recurring discounts follow the restaurant's clock
+	if err := models.SetRestaurantTimezone(os.Getenv("RESTAURANT_TIMEZONE")); err != nil {
+		log.Fatalf("RESTAURANT_TIMEZONE must be a time zone such as Africa/Cairo: %v", err)
+	}
+
 	menuDB, err := db.NewMenuDB()
 	if err != nil {
 		log.Fatalf("Failed to connect to database: %v", err)
 	}
 	defer menuDB.Close()
 
+	if len(os.Args) > 1 && os.Args[1] == "migrate" {
+		if err := runMigrations(menuDB, os.Args[2:]); err != nil {
+			log.Fatalf("Migration failed: %v", err)
+		}
+		return
+	}
+
+	// Bring the schema up to date before serving
+	if err := runMigrations(menuDB, []string{"up"}); err != nil {
+		log.Fatalf("Migration failed: %v", err)
+	}
+
+	redisHost := os.Getenv("REDIS_HOST")
+	if redisHost == "" {
+		redisHost = "redis"
+	}
+	redisPort := os.Getenv("REDIS_PORT")
+	if redisPort == "" {
+		redisPort = "6379"
+	}
 	redisClient = redis.NewClient(&redis.Options{
-		Addr: "redis:6379",
+		Addr: redisHost + ":" + redisPort,
 	})
 	_, err = redisClient.Ping(context.Background()).Result()
 	if err != nil {
-		log.Printf("Warning: Redis connection failed: %v", err)
+		log.Printf("Warning: Redis connection failed, menu reads go straight to Postgres: %v", err)
 	} else {
 		log.Println("Connected to Redis")
 	}
+	if ttl := os.Getenv("MENU_CACHE_TTL"); ttl != "" {
+		if cacheTTL, err = time.ParseDuration(ttl); err != nil || cacheTTL <= 0 {
+			log.Fatalf("MENU_CACHE_TTL must be a positive duration such as 15m")
+		}
+	}
+	cachedDB := db.NewCachedMenuDB(menuDB, redisClient, cacheTTL)
+
+	verifier, err := auth.VerifierFromEnv()
+	if err != nil {
+		log.Fatalf("Failed to configure token verification: %v", err)
+	}
+
+	imageStore, err := images.NewBlobStoreFromEnv()
+	if err != nil {
+		log.Fatalf("Failed to configure image storage: %v", err)
+	}
+	if local, ok := imageStore.(*images.LocalStore); ok {
+		app.Static(images.LocalRoute, local.Dir(), fiber.Static{MaxAge: 365 * 24 * 60 * 60})
+	}
+
+	routes.SetupRoutes(app, cachedDB, verifier, imageStore)
+	routes.SetupInternalRoutes(app, cachedDB, verifier)
 
-	routes.SetupRoutes(app, menuDB)
+	go expireReservations(cachedDB, reservationSweepInterval)
 
 	app.Get("/health", func(c *fiber.Ctx) error {
 		return c.JSON(fiber.Map{"status": "OK"})
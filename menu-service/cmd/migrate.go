@@ -0,0 +1,24 @@
+package main
+
+import (
+	"context"
+	"os"
+
+	"github.com/jackc/pgx/v5/stdlib"
+
+	"github.com/darkhyper24/blaban/menu-service/internal/db"
+	"github.com/darkhyper24/blaban/menu-service/migrations"
+	"github.com/darkhyper24/blaban/shared/migrate"
+)
+
+// runMigrations handles "menu-service migrate <status|up|down [n]|to <version>>"
+func runMigrations(menuDB *db.MenuDB, args []string) error {
+	sqlDB := stdlib.OpenDBFromPool(menuDB.Pool)
+	defer sqlDB.Close()
+
+	migrator, err := migrate.New(sqlDB, migrations.FS, "menu-service")
+	if err != nil {
+		return err
+	}
+	return migrate.Run(context.Background(), migrator, args, os.Stdout)
+}
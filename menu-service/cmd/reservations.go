@@ -0,0 +1,28 @@
+package main
+
+import (
+	"log"
+	"time"
+
+	"github.com/darkhyper24/blaban/menu-service/internal/db"
+)
+
+// reservationSweepInterval is how often stock held by expired reservations is returned
+const reservationSweepInterval = 30 * time.Second
+
+// expireReservations returns the stock of reservations nobody committed in
+// time. It runs for the life of the process.
+func expireReservations(menuDB db.MenuDBOperations, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		expired, err := menuDB.ExpireReservations()
+		if err != nil {
+			log.Printf("Failed to expire stock reservations: %v", err)
+		}
+		if len(expired) > 0 {
+			log.Printf("Expired %d stock reservations", len(expired))
+		}
+	}
+}
@@ -0,0 +1,473 @@
+package db
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log"
+	"math/rand"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/darkhyper24/blaban/menu-service/internal/models"
+	"github.com/go-redis/redis/v8"
+	"golang.org/x/sync/singleflight"
+)
+
+const (
+	cacheKeyCategories = "menu:categories"
+	cacheKeyMenu       = "menu:all"
+	cacheKeyItemPrefix = "menu:item:"
+	cacheKeyPattern    = "menu:*"
+
+	// Redis calls give up quickly so a slow Redis cannot slow the menu down
+	cacheOpTimeout    = 100 * time.Millisecond
+	cacheFlushTimeout = 2 * time.Second
+	// After a Redis error the cache is bypassed for a while instead of timing out on every request
+	cacheRetryInterval = 5 * time.Second
+)
+
+// setIfCurrent stores a value only while the generation of its key is still the
+// one observed before loading it from Postgres. A write that invalidated the key
+// in the meantime bumps the generation, so a stale load is never cached.
+var setIfCurrent = redis.NewScript(`
+local gen = redis.call("GET", KEYS[2]) or "0"
+if gen == ARGV[1] then
+  redis.call("SET", KEYS[1], ARGV[2], "PX", ARGV[3])
+  return 1
+end
+return 0
+`)
+
+// CachedMenuDB is a read-through Redis cache in front of MenuDBOperations.
+// Categories, pages of the menu and single items are cached; searches and filters
+// always go to Postgres. Writes invalidate exactly the keys they affect. When
+// Redis is unavailable every call falls through to Postgres.
+type CachedMenuDB struct {
+	MenuDBOperations
+	redis *redis.Client
+	ttl   time.Duration
+	group singleflight.Group
+	// bypassUntil holds the unix nano time until which Redis is skipped
+	bypassUntil atomic.Int64
+	// flushPending is set when an invalidation could not reach Redis
+	flushPending atomic.Bool
+}
+
+// NewCachedMenuDB wraps dbOps with a cache whose entries live for about ttl
+func NewCachedMenuDB(dbOps MenuDBOperations, client *redis.Client, ttl time.Duration) *CachedMenuDB {
+	return &CachedMenuDB{
+		MenuDBOperations: dbOps,
+		redis:            client,
+		ttl:              ttl,
+	}
+}
+
+func itemCacheKey(itemID string) string {
+	return cacheKeyItemPrefix + itemID
+}
+
+func generationKey(key string) string {
+	return key + ":gen"
+}
+
+type cachedMenu struct {
+	Items         []models.MenuItem `json:"items"`
+	CategoryNames []string          `json:"category_names"`
+	Page          PageInfo          `json:"page"`
+}
+
+type cachedItem struct {
+	Item         models.MenuItem `json:"item"`
+	CategoryName string          `json:"category_name"`
+}
+
+// available reports whether Redis should be used. If an earlier invalidation
+// was lost, the whole menu cache is flushed before it is trusted again.
+func (c *CachedMenuDB) available() bool {
+	if c.redis == nil || time.Now().UnixNano() < c.bypassUntil.Load() {
+		return false
+	}
+	if c.flushPending.Load() {
+		flushCtx, cancel := context.WithTimeout(context.Background(), cacheFlushTimeout)
+		defer cancel()
+		if err := c.flush(flushCtx); err != nil {
+			c.markDown(err)
+			return false
+		}
+		c.flushPending.Store(false)
+	}
+	return true
+}
+
+func (c *CachedMenuDB) markDown(err error) {
+	log.Printf("Menu cache unavailable, serving from Postgres: %v", err)
+	c.bypassUntil.Store(time.Now().Add(cacheRetryInterval).UnixNano())
+}
+
+func (c *CachedMenuDB) flush(ctx context.Context) error {
+	iter := c.redis.Scan(ctx, 0, cacheKeyPattern, 100).Iterator()
+	for iter.Next(ctx) {
+		// Generation counters are bumped rather than deleted so in-flight loads stay rejected
+		key := iter.Val()
+		if strings.HasSuffix(key, ":gen") {
+			if err := c.redis.Incr(ctx, key).Err(); err != nil {
+				return err
+			}
+			continue
+		}
+		if err := c.redis.Del(ctx, key).Err(); err != nil {
+			return err
+		}
+	}
+	return iter.Err()
+}
+
+// jitteredTTL spreads expiry so entries written together do not expire together
+func (c *CachedMenuDB) jitteredTTL() time.Duration {
+	return c.ttl + time.Duration(rand.Int63n(int64(c.ttl/10)+1))
+}
+
+// readThrough returns the cached value of key, loading and caching it on a
+// miss. Concurrent misses for the same key share a single load.
+func readThrough[T any](c *CachedMenuDB, key string, load func() (T, error)) (T, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), cacheOpTimeout)
+	defer cancel()
+
+	var generation string
+	if c.available() {
+		values, err := c.redis.MGet(ctx, key, generationKey(key)).Result()
+		if err != nil {
+			c.markDown(err)
+		} else {
+			if data, ok := values[0].(string); ok {
+				var cached T
+				if err := json.Unmarshal([]byte(data), &cached); err == nil {
+					return cached, nil
+				}
+			}
+			generation = "0"
+			if gen, ok := values[1].(string); ok {
+				generation = gen
+			}
+		}
+	}
+
+	return loadAndStore(c, key, key, generationKey(key), generation, load)
+}
+
+// readThroughVersioned caches one variant of namespace, such as a single page
+// of the menu. The namespace generation is part of the key, so invalidating
+// the namespace retires every variant at once; old ones simply expire.
+func readThroughVersioned[T any](c *CachedMenuDB, namespace, variant string, load func() (T, error)) (T, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), cacheOpTimeout)
+	defer cancel()
+
+	var generation, key string
+	if c.available() {
+		gen, err := c.redis.Get(ctx, generationKey(namespace)).Result()
+		if err == redis.Nil {
+			gen, err = "0", nil
+		}
+		if err != nil {
+			c.markDown(err)
+		} else {
+			key = namespace + ":" + gen + ":" + variant
+			data, err := c.redis.Get(ctx, key).Result()
+			if err != nil && err != redis.Nil {
+				c.markDown(err)
+				key = ""
+			} else {
+				if err == nil {
+					var cached T
+					if err := json.Unmarshal([]byte(data), &cached); err == nil {
+						return cached, nil
+					}
+				}
+				generation = gen
+			}
+		}
+	}
+
+	return loadAndStore(c, namespace+":"+variant, key, generationKey(namespace), generation, load)
+}
+
+// loadAndStore runs load once for all concurrent callers sharing flightKey and
+// caches the result under key unless generation is empty or has moved on
+func loadAndStore[T any](c *CachedMenuDB, flightKey, key, genKey, generation string, load func() (T, error)) (T, error) {
+	result, err, _ := c.group.Do(flightKey, func() (interface{}, error) {
+		value, err := load()
+		if err != nil || generation == "" {
+			return value, err
+		}
+
+		data, err := json.Marshal(value)
+		if err != nil {
+			return value, nil
+		}
+		storeCtx, cancel := context.WithTimeout(context.Background(), cacheOpTimeout)
+		defer cancel()
+		ttl := c.jitteredTTL().Milliseconds()
+		if err := setIfCurrent.Run(storeCtx, c.redis, []string{key, genKey}, generation, data, ttl).Err(); err != nil {
+			c.markDown(err)
+		}
+		return value, nil
+	})
+	if err != nil {
+		var zero T
+		return zero, err
+	}
+	return result.(T), nil
+}
+
+// invalidate drops keys after a write. If Redis cannot be reached the whole
+// menu cache is flushed once it is back, so no stale entry survives.
+func (c *CachedMenuDB) invalidate(keys ...string) {
+	if c.redis == nil {
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), cacheOpTimeout)
+	defer cancel()
+
+	_, err := c.redis.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		for _, key := range keys {
+			pipe.Incr(ctx, generationKey(key))
+			pipe.Del(ctx, key)
+		}
+		return nil
+	})
+	if err != nil {
+		c.flushPending.Store(true)
+		c.markDown(err)
+	}
+}
+
+// invalidateAll drops every cached menu entry, for writes such as a category
+// rename that change many items at once
+func (c *CachedMenuDB) invalidateAll() {
+	if c.redis == nil {
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), cacheFlushTimeout)
+	defer cancel()
+
+	if err := c.flush(ctx); err != nil {
+		c.flushPending.Store(true)
+		c.markDown(err)
+		return
+	}
+	// The shared keys get a generation even if nothing was cached yet, so a
+	// load that started before the write cannot store its stale result
+	c.invalidate(cacheKeyCategories, cacheKeyMenu)
+}
+
+// GetAllCategories returns the cached category list
+func (c *CachedMenuDB) GetAllCategories() ([]models.Category, error) {
+	return readThrough(c, cacheKeyCategories, c.MenuDBOperations.GetAllCategories)
+}
+
+// GetAllMenuItems returns a cached page of the menu
+func (c *CachedMenuDB) GetAllMenuItems(page PageRequest) ([]models.MenuItem, []string, PageInfo, error) {
+	page, err := NewPageRequest(page.Limit, page.Cursor, page.Sort)
+	if err != nil {
+		return nil, nil, PageInfo{}, err
+	}
+
+	variant := page.Sort + ":" + strconv.Itoa(page.Limit) + ":" + page.Cursor
+	menu, err := readThroughVersioned(c, cacheKeyMenu, variant, func() (cachedMenu, error) {
+		items, categoryNames, info, err := c.MenuDBOperations.GetAllMenuItems(page)
+		return cachedMenu{Items: items, CategoryNames: categoryNames, Page: info}, err
+	})
+	return menu.Items, menu.CategoryNames, menu.Page, err
+}
+
+// GetMenuItemByID returns a cached item. Missing items are not cached.
+func (c *CachedMenuDB) GetMenuItemByID(itemID string) (models.MenuItem, string, error) {
+	entry, err := readThrough(c, itemCacheKey(itemID), func() (cachedItem, error) {
+		item, categoryName, err := c.MenuDBOperations.GetMenuItemByID(itemID)
+		return cachedItem{Item: item, CategoryName: categoryName}, err
+	})
+	return entry.Item, entry.CategoryName, err
+}
+
+// CreateMenuItem adds an item and invalidates the full menu
+func (c *CachedMenuDB) CreateMenuItem(item models.MenuItem, categoryID string) (string, error) {
+	id, err := c.MenuDBOperations.CreateMenuItem(item, categoryID)
+	if err == nil {
+		c.invalidate(cacheKeyMenu)
+	}
+	return id, err
+}
+
+// UpdateMenuItem updates an item and invalidates it and the full menu
+func (c *CachedMenuDB) UpdateMenuItem(item models.MenuItem) error {
+	err := c.MenuDBOperations.UpdateMenuItem(item)
+	if err == nil {
+		c.invalidate(itemCacheKey(item.ID), cacheKeyMenu)
+	}
+	return err
+}
+
+// DeleteMenuItem removes an item and invalidates it and the full menu
+func (c *CachedMenuDB) DeleteMenuItem(itemID string) (int64, error) {
+	rows, err := c.MenuDBOperations.DeleteMenuItem(itemID)
+	if err == nil && rows > 0 {
+		c.invalidate(itemCacheKey(itemID), cacheKeyMenu)
+	}
+	return rows, err
+}
+
+// UpdateItemDiscount changes a discount and invalidates the item and the full menu
+func (c *CachedMenuDB) UpdateItemDiscount(itemID string, discountValue float64, active bool) (int64, error) {
+	rows, err := c.MenuDBOperations.UpdateItemDiscount(itemID, discountValue, active)
+	if err == nil && rows > 0 {
+		c.invalidate(itemCacheKey(itemID), cacheKeyMenu)
+	}
+	return rows, err
+}
+
+// SetMenuItemImage changes an item's image and invalidates the item and the full menu
+func (c *CachedMenuDB) SetMenuItemImage(itemID string, image *models.Image) (*models.Image, error) {
+	previous, err := c.MenuDBOperations.SetMenuItemImage(itemID, image)
+	if err == nil {
+		c.invalidate(itemCacheKey(itemID), cacheKeyMenu)
+	}
+	return previous, err
+}
+
+// SetItemOptionGroups replaces an item's options and invalidates the item and the full menu
+func (c *CachedMenuDB) SetItemOptionGroups(itemID string, groups []models.OptionGroup) ([]models.OptionGroup, error) {
+	saved, err := c.MenuDBOperations.SetItemOptionGroups(itemID, groups)
+	if err == nil {
+		c.invalidate(itemCacheKey(itemID), cacheKeyMenu)
+	}
+	return saved, err
+}
+
+// ReserveStock reserves stock and invalidates the reserved items and the full menu
+func (c *CachedMenuDB) ReserveStock(items []models.ReservationItem, reference string, ttl time.Duration) (models.Reservation, error) {
+	reservation, err := c.MenuDBOperations.ReserveStock(items, reference, ttl)
+	if err == nil {
+		c.invalidateReservation(reservation)
+	}
+	return reservation, err
+}
+
+// CommitReservation commits a reservation. Stock only changes when the
+// reservation turned out to have expired, so only then is anything invalidated.
+func (c *CachedMenuDB) CommitReservation(reservationID string) (models.Reservation, error) {
+	reservation, err := c.MenuDBOperations.CommitReservation(reservationID)
+	if errors.Is(err, ErrReservationExpired) {
+		c.invalidateReservation(reservation)
+	}
+	return reservation, err
+}
+
+// ReleaseReservation returns a reservation's stock and invalidates its items and the full menu
+func (c *CachedMenuDB) ReleaseReservation(reservationID string) (models.Reservation, error) {
+	reservation, err := c.MenuDBOperations.ReleaseReservation(reservationID)
+	if err == nil {
+		c.invalidateReservation(reservation)
+	}
+	return reservation, err
+}
+
+// ExpireReservations expires overdue reservations and invalidates their items and the full menu
+func (c *CachedMenuDB) ExpireReservations() ([]models.Reservation, error) {
+	expired, err := c.MenuDBOperations.ExpireReservations()
+	for _, reservation := range expired {
+		c.invalidateReservation(reservation)
+	}
+	return expired, err
+}
+
+// invalidateReservation drops the cached entries of every item in a reservation
+func (c *CachedMenuDB) invalidateReservation(reservation models.Reservation) {
+	keys := make([]string, 0, len(reservation.Items)+1)
+	for _, item := range reservation.Items {
+		keys = append(keys, itemCacheKey(item.ItemID))
+	}
+	c.invalidate(append(keys, cacheKeyMenu)...)
+}
+
+// CreateDiscount schedules a discount and invalidates what it covers
+func (c *CachedMenuDB) CreateDiscount(discount models.Discount) (models.Discount, error) {
+	created, err := c.MenuDBOperations.CreateDiscount(discount)
+	if err == nil {
+		c.invalidateDiscount(created)
+	}
+	return created, err
+}
+
+// CancelDiscount ends a discount and invalidates what it covered
+func (c *CachedMenuDB) CancelDiscount(discountID string) (models.Discount, error) {
+	discount, err := c.MenuDBOperations.CancelDiscount(discountID)
+	if err == nil {
+		c.invalidateDiscount(discount)
+	}
+	return discount, err
+}
+
+// invalidateDiscount drops the item a discount covers, or everything for a
+// category discount since any cached item may belong to the category
+func (c *CachedMenuDB) invalidateDiscount(discount models.Discount) {
+	if discount.ItemID != "" {
+		c.invalidate(itemCacheKey(discount.ItemID), cacheKeyMenu)
+		return
+	}
+	c.invalidateAll()
+}
+
+// CreateCategory adds a category and invalidates the category list
+func (c *CachedMenuDB) CreateCategory(name, picture string) (models.Category, error) {
+	category, err := c.MenuDBOperations.CreateCategory(name, picture)
+	if err == nil {
+		c.invalidate(cacheKeyCategories)
+	}
+	return category, err
+}
+
+// UpdateCategory renames a category or changes its picture. Cached items carry
+// their category name, so everything is invalidated.
+func (c *CachedMenuDB) UpdateCategory(category models.Category) error {
+	err := c.MenuDBOperations.UpdateCategory(category)
+	if err == nil {
+		c.invalidateAll()
+	}
+	return err
+}
+
+// ReorderCategories changes display positions and invalidates the category list and the menu
+func (c *CachedMenuDB) ReorderCategories(categoryIDs []string) error {
+	err := c.MenuDBOperations.ReorderCategories(categoryIDs)
+	if err == nil {
+		c.invalidate(cacheKeyCategories, cacheKeyMenu)
+	}
+	return err
+}
+
+// DeleteCategory removes a category. Moving its items changes them, so
+// everything is invalidated when any were moved.
+func (c *CachedMenuDB) DeleteCategory(categoryID, reassignTo string) (int64, error) {
+	moved, err := c.MenuDBOperations.DeleteCategory(categoryID, reassignTo)
+	if err != nil {
+		return moved, err
+	}
+	if moved > 0 {
+		c.invalidateAll()
+	} else {
+		c.invalidate(cacheKeyCategories)
+	}
+	return moved, nil
+}
+
+// SetCategoryImage changes a category's image and invalidates the category list
+func (c *CachedMenuDB) SetCategoryImage(categoryID string, image *models.Image, picture string) (*models.Image, error) {
+	previous, err := c.MenuDBOperations.SetCategoryImage(categoryID, image, picture)
+	if err == nil {
+		c.invalidate(cacheKeyCategories)
+	}
+	return previous, err
+}
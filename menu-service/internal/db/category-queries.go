@@ -0,0 +1,197 @@
+package db
+
+import (
+	"context"
+	"errors"
+
+	"github.com/darkhyper24/blaban/menu-service/internal/models"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+var (
+	ErrCategoryNotFound      = errors.New("category not found")
+	ErrCategoryExists        = errors.New("a category with this name already exists")
+	ErrCategoryNotEmpty      = errors.New("category still has items")
+	ErrInvalidCategoryOrder  = errors.New("order must list every category exactly once")
+	ErrReassignToSelf        = errors.New("items cannot be reassigned to the category being deleted")
+	ErrReassignTargetMissing = errors.New("category to reassign items to not found")
+)
+
+// isUniqueViolation reports whether err is a Postgres unique constraint error
+func isUniqueViolation(err error) bool {
+	var pgErr *pgconn.PgError
+	return errors.As(err, &pgErr) && pgErr.Code == "23505"
+}
+
+// GetCategoryByID retrieves a category by ID
+func (db *MenuDB) GetCategoryByID(categoryID string) (models.Category, error) {
+	var category models.Category
+	err := db.Pool.QueryRow(context.Background(), `
+        SELECT category_id, name, category_pic, position, image
+        FROM category
+        WHERE category_id = $1
+    `, categoryID).Scan(&category.ID, &category.Name, &category.Picture, &category.Position, &category.Image)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return category, ErrCategoryNotFound
+	}
+	return category, err
+}
+
+// CreateCategory adds a category after the existing ones and returns it with
+// its generated ID and position
+func (db *MenuDB) CreateCategory(name, picture string) (models.Category, error) {
+	category := models.Category{Name: name, Picture: picture}
+	err := db.Pool.QueryRow(context.Background(), `
+        INSERT INTO category (name, category_pic, position)
+        VALUES ($1, $2, (SELECT COALESCE(MAX(position) + 1, 0) FROM category))
+        RETURNING category_id, position
+    `, name, picture).Scan(&category.ID, &category.Position)
+	if isUniqueViolation(err) {
+		return category, ErrCategoryExists
+	}
+	return category, err
+}
+
+// UpdateCategory saves a category's name and picture
+func (db *MenuDB) UpdateCategory(category models.Category) error {
+	cmdTag, err := db.Pool.Exec(context.Background(), `
+        UPDATE category
+        SET name = $2, category_pic = $3
+        WHERE category_id = $1
+    `, category.ID, category.Name, category.Picture)
+	if isUniqueViolation(err) {
+		return ErrCategoryExists
+	}
+	if err != nil {
+		return err
+	}
+	if cmdTag.RowsAffected() == 0 {
+		return ErrCategoryNotFound
+	}
+	return nil
+}
+
+// ReorderCategories sets the display position of every category to its index
+// in categoryIDs, which must list each category exactly once
+func (db *MenuDB) ReorderCategories(categoryIDs []string) error {
+	ctx := context.Background()
+	tx, err := db.Pool.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	// Locking the table keeps categories from being added mid-reorder
+	if _, err := tx.Exec(ctx, "LOCK TABLE category IN SHARE ROW EXCLUSIVE MODE"); err != nil {
+		return err
+	}
+
+	var total, listed int
+	err = tx.QueryRow(ctx, `
+        SELECT (SELECT COUNT(*) FROM category),
+               (SELECT COUNT(*) FROM category WHERE category_id = ANY($1))
+    `, categoryIDs).Scan(&total, &listed)
+	if err != nil {
+		return err
+	}
+	seen := make(map[string]bool, len(categoryIDs))
+	for _, id := range categoryIDs {
+		if seen[id] {
+			return ErrInvalidCategoryOrder
+		}
+		seen[id] = true
+	}
+	if listed != len(categoryIDs) || total != len(categoryIDs) {
+		return ErrInvalidCategoryOrder
+	}
+
+	_, err = tx.Exec(ctx, `
+        UPDATE category
+        SET position = ordered.position - 1
+        FROM UNNEST($1::text[]) WITH ORDINALITY AS ordered(category_id, position)
+        WHERE category.category_id = ordered.category_id
+    `, categoryIDs)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit(ctx)
+}
+
+// DeleteCategory removes a category. A category that still has items is only
+// deleted when reassignTo names another category to move them to; it returns
+// how many items were moved.
+func (db *MenuDB) DeleteCategory(categoryID, reassignTo string) (int64, error) {
+	if reassignTo == categoryID {
+		return 0, ErrReassignToSelf
+	}
+
+	ctx := context.Background()
+	tx, err := db.Pool.Begin(ctx)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback(ctx)
+
+	var exists bool
+	err = tx.QueryRow(ctx, "SELECT true FROM category WHERE category_id = $1 FOR UPDATE", categoryID).Scan(&exists)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return 0, ErrCategoryNotFound
+	}
+	if err != nil {
+		return 0, err
+	}
+
+	var moved int64
+	if reassignTo != "" {
+		err = tx.QueryRow(ctx, "SELECT true FROM category WHERE category_id = $1 FOR SHARE", reassignTo).Scan(&exists)
+		if errors.Is(err, pgx.ErrNoRows) {
+			return 0, ErrReassignTargetMissing
+		}
+		if err != nil {
+			return 0, err
+		}
+
+		cmdTag, err := tx.Exec(ctx, "UPDATE items SET category_id = $2 WHERE category_id = $1", categoryID, reassignTo)
+		if err != nil {
+			return 0, err
+		}
+		moved = cmdTag.RowsAffected()
+	} else {
+		var hasItems bool
+		err = tx.QueryRow(ctx, "SELECT EXISTS (SELECT 1 FROM items WHERE category_id = $1)", categoryID).Scan(&hasItems)
+		if err != nil {
+			return 0, err
+		}
+		if hasItems {
+			return 0, ErrCategoryNotEmpty
+		}
+	}
+
+	if _, err := tx.Exec(ctx, "DELETE FROM category WHERE category_id = $1", categoryID); err != nil {
+		return 0, err
+	}
+
+	return moved, tx.Commit(ctx)
+}
+
+// SetCategoryImage replaces a category's image, or removes it when image is
+// nil, and sets its picture. It returns the image that was replaced.
+func (db *MenuDB) SetCategoryImage(categoryID string, image *models.Image, picture string) (*models.Image, error) {
+	var previous *models.Image
+	err := db.Pool.QueryRow(context.Background(), `
+        WITH old AS (
+            SELECT category_id, image FROM category WHERE category_id = $1 FOR UPDATE
+        )
+        UPDATE category
+        SET image = $2, category_pic = $3
+        FROM old
+        WHERE category.category_id = old.category_id
+        RETURNING old.image
+    `, categoryID, image, picture).Scan(&previous)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrCategoryNotFound
+	}
+	return previous, err
+}
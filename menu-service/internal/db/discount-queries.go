@@ -0,0 +1,205 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/darkhyper24/blaban/menu-service/internal/models"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+var (
+	ErrDiscountNotFound = errors.New("discount not found")
+	ErrDiscountEnded    = errors.New("discount has already ended")
+)
+
+// currentDiscountSQL matches discounts that are running or yet to start
+const currentDiscountSQL = "cancelled_at IS NULL AND (ends_at IS NULL OR ends_at > NOW())"
+
+// discountColumns are selected by every discount query, in scanDiscount's order
+const discountColumns = `discount_id, COALESCE(item_id, ''), COALESCE(category_id, ''), percentage,
+    starts_at, ends_at, weekdays, to_char(start_time, 'HH24:MI'), to_char(end_time, 'HH24:MI'),
+    manual, created_at, cancelled_at`
+
+// DiscountFilter selects discounts to list. History lists ended and cancelled
+// discounts, newest first; otherwise running and upcoming ones are listed.
+type DiscountFilter struct {
+	ItemID     string
+	CategoryID string
+	History    bool
+	Limit      int
+}
+
+func isForeignKeyViolation(err error) bool {
+	var pgErr *pgconn.PgError
+	return errors.As(err, &pgErr) && pgErr.Code == "23503"
+}
+
+func scanDiscount(row pgx.Row) (models.Discount, error) {
+	var discount models.Discount
+	var weekdays []int
+	var startTime, endTime *string
+	err := row.Scan(&discount.ID, &discount.ItemID, &discount.CategoryID, &discount.Percentage,
+		&discount.StartsAt, &discount.EndsAt, &weekdays, &startTime, &endTime,
+		&discount.Manual, &discount.CreatedAt, &discount.CancelledAt)
+	if err != nil {
+		return discount, err
+	}
+	if weekdays != nil && startTime != nil && endTime != nil {
+		discount.Recurrence = &models.Recurrence{Weekdays: weekdays, StartTime: *startTime, EndTime: *endTime}
+	}
+	return discount, nil
+}
+
+// CreateDiscount schedules a discount on an item or a category
+func (db *MenuDB) CreateDiscount(discount models.Discount) (models.Discount, error) {
+	var weekdays []int
+	var startTime, endTime interface{}
+	if discount.Recurrence != nil {
+		weekdays = discount.Recurrence.Weekdays
+		startTime, endTime = discount.Recurrence.StartTime, discount.Recurrence.EndTime
+	}
+
+	created, err := scanDiscount(db.Pool.QueryRow(context.Background(), `
+        INSERT INTO discounts (discount_id, item_id, category_id, percentage, starts_at, ends_at,
+                               weekdays, start_time, end_time)
+        VALUES ($1, $2, $3, $4, $5, $6, $7, $8::time, $9::time)
+        RETURNING `+discountColumns,
+		uuid.New().String(), NullIfEmpty(discount.ItemID), NullIfEmpty(discount.CategoryID), discount.Percentage,
+		discount.StartsAt, discount.EndsAt, weekdays, startTime, endTime))
+	if isForeignKeyViolation(err) {
+		if discount.ItemID != "" {
+			return created, ErrMenuItemNotFound
+		}
+		return created, ErrCategoryNotFound
+	}
+	return created, err
+}
+
+// ListDiscounts lists discounts, manual ones included
+func (db *MenuDB) ListDiscounts(filter DiscountFilter) ([]models.Discount, error) {
+	conditions := currentDiscountSQL
+	orderBy := "starts_at NULLS FIRST, created_at"
+	if filter.History {
+		conditions = "NOT (" + currentDiscountSQL + ")"
+		orderBy = "COALESCE(cancelled_at, ends_at) DESC, created_at DESC"
+	}
+
+	args := []interface{}{}
+	if filter.ItemID != "" {
+		args = append(args, filter.ItemID)
+		conditions += fmt.Sprintf(" AND item_id = $%d", len(args))
+	}
+	if filter.CategoryID != "" {
+		args = append(args, filter.CategoryID)
+		conditions += fmt.Sprintf(" AND category_id = $%d", len(args))
+	}
+	if filter.Limit <= 0 || filter.Limit > MaxPageSize {
+		filter.Limit = MaxPageSize
+	}
+	args = append(args, filter.Limit)
+
+	rows, err := db.Pool.Query(context.Background(), `
+        SELECT `+discountColumns+`
+        FROM discounts
+        WHERE `+conditions+`
+        ORDER BY `+orderBy+fmt.Sprintf(`
+        LIMIT $%d`, len(args)), args...)
+	if err != nil {
+		return nil, err
+	}
+	return pgx.CollectRows(rows, func(row pgx.CollectableRow) (models.Discount, error) {
+		return scanDiscount(row)
+	})
+}
+
+// CancelDiscount ends a discount now, keeping it in the history. Cancelling
+// a manual discount also takes it off the item.
+func (db *MenuDB) CancelDiscount(discountID string) (models.Discount, error) {
+	ctx := context.Background()
+	tx, err := db.Pool.Begin(ctx)
+	if err != nil {
+		return models.Discount{}, err
+	}
+	defer tx.Rollback(ctx)
+
+	var current bool
+	err = tx.QueryRow(ctx, `
+        SELECT `+currentDiscountSQL+`
+        FROM discounts
+        WHERE discount_id = $1
+        FOR UPDATE
+    `, discountID).Scan(&current)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return models.Discount{}, ErrDiscountNotFound
+	}
+	if err != nil {
+		return models.Discount{}, err
+	}
+	if !current {
+		return models.Discount{}, ErrDiscountEnded
+	}
+
+	discount, err := scanDiscount(tx.QueryRow(ctx, `
+        UPDATE discounts
+        SET cancelled_at = NOW()
+        WHERE discount_id = $1
+        RETURNING `+discountColumns, discountID))
+	if err != nil {
+		return discount, err
+	}
+
+	if discount.Manual {
+		_, err := tx.Exec(ctx,
+			"UPDATE items SET has_discount = false, discount_value = 0 WHERE item_id = $1",
+			discount.ItemID)
+		if err != nil {
+			return discount, err
+		}
+	}
+	return discount, tx.Commit(ctx)
+}
+
+// attachDiscounts loads the scheduled discounts on each item or its category
+// that have not ended
+func (db *MenuDB) attachDiscounts(items []*models.MenuItem) error {
+	if len(items) == 0 {
+		return nil
+	}
+	itemIDs := make([]string, len(items))
+	categoryIDs := make([]string, len(items))
+	for i, item := range items {
+		itemIDs[i] = item.ID
+		categoryIDs[i] = item.CategoryID
+	}
+
+	rows, err := db.Pool.Query(context.Background(), `
+        SELECT `+discountColumns+`
+        FROM discounts
+        WHERE NOT manual AND `+currentDiscountSQL+`
+          AND (item_id = ANY($1) OR category_id = ANY($2))
+        ORDER BY starts_at NULLS FIRST, created_at
+    `, itemIDs, categoryIDs)
+	if err != nil {
+		return err
+	}
+	discounts, err := pgx.CollectRows(rows, func(row pgx.CollectableRow) (models.Discount, error) {
+		return scanDiscount(row)
+	})
+	if err != nil {
+		return err
+	}
+
+	for _, item := range items {
+		item.Discounts = nil
+		for _, discount := range discounts {
+			if discount.ItemID == item.ID || discount.CategoryID == item.CategoryID {
+				item.Discounts = append(item.Discounts, discount)
+			}
+		}
+	}
+	return nil
+}
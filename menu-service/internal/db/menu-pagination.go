@@ -0,0 +1,301 @@
+package db
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/darkhyper24/blaban/menu-service/internal/models"
+)
+
+const (
+	DefaultPageSize = 20
+	MaxPageSize     = 100
+)
+
+// Sort orders accepted by the listing, search and filter endpoints
+const (
+	SortCategory           = "category"
+	SortName               = "name"
+	SortNameDesc           = "-name"
+	SortPrice              = "price"
+	SortPriceDesc          = "-price"
+	SortEffectivePrice     = "effective_price"
+	SortEffectivePriceDesc = "-effective_price"
+	SortNewest             = "newest"
+	// SortRelevance ranks search matches and is only valid when searching
+	SortRelevance = "relevance"
+)
+
+var (
+	ErrInvalidSort   = errors.New("invalid sort")
+	ErrInvalidCursor = errors.New("invalid cursor")
+)
+
+// discountSQL mirrors models.MenuItem.CurrentDiscount
+const discountSQL = "GREATEST(CASE WHEN i.has_discount THEN i.discount_value ELSE 0 END, scheduled_discount(i.item_id, i.category_id))"
+
+// effectivePriceSQL mirrors models.MenuItem.GetEffectivePrice. Cached pages
+// keep their order until they expire even if a scheduled discount starts.
+const effectivePriceSQL = "i.price * (1 - " + discountSQL + " / 100)"
+
+// sortOrder lists the expressions an order sorts by, ending with the item id
+// so every row has a unique position. All keys share one direction, which lets
+// a cursor be compared as a single row value.
+type sortOrder struct {
+	exprs []string
+	types []string
+	desc  bool
+}
+
+var sortOrders = map[string]sortOrder{
+	SortCategory:           {exprs: []string{"c.position", "c.name", "i.name", "i.item_id"}, types: []string{"int", "text", "text", "text"}},
+	SortName:               {exprs: []string{"i.name", "i.item_id"}, types: []string{"text", "text"}},
+	SortNameDesc:           {exprs: []string{"i.name", "i.item_id"}, types: []string{"text", "text"}, desc: true},
+	SortPrice:              {exprs: []string{"i.price", "i.item_id"}, types: []string{"numeric", "text"}},
+	SortPriceDesc:          {exprs: []string{"i.price", "i.item_id"}, types: []string{"numeric", "text"}, desc: true},
+	SortEffectivePrice:     {exprs: []string{effectivePriceSQL, "i.item_id"}, types: []string{"numeric", "text"}},
+	SortEffectivePriceDesc: {exprs: []string{effectivePriceSQL, "i.item_id"}, types: []string{"numeric", "text"}, desc: true},
+	SortNewest:             {exprs: []string{"i.created_at", "i.item_id"}, types: []string{"timestamptz", "text"}, desc: true},
+}
+
+// PageRequest selects one page of items. An empty Sort orders by category
+// display position then item name; an empty Cursor starts at the first page.
+type PageRequest struct {
+	Limit  int
+	Cursor string
+	Sort   string
+}
+
+// PageInfo describes the page returned alongside a list of items
+type PageInfo struct {
+	Total      int    `json:"total"`
+	NextCursor string `json:"next_cursor,omitempty"`
+	PrevCursor string `json:"prev_cursor,omitempty"`
+}
+
+// NewPageRequest validates the sort and clamps the limit into range
+func NewPageRequest(limit int, cursor, sort string) (PageRequest, error) {
+	if sort == "" {
+		sort = SortCategory
+	}
+	if _, ok := sortOrders[sort]; !ok && sort != SortRelevance {
+		return PageRequest{}, ErrInvalidSort
+	}
+	if limit <= 0 {
+		limit = DefaultPageSize
+	}
+	if limit > MaxPageSize {
+		limit = MaxPageSize
+	}
+	return PageRequest{Limit: limit, Cursor: cursor, Sort: sort}, nil
+}
+
+// pageCursor is the position of the row a page continues from. Keys are the
+// row's sort keys as Postgres renders them, so comparing against them is exact.
+type pageCursor struct {
+	Sort     string   `json:"s"`
+	Keys     []string `json:"k"`
+	Backward bool     `json:"b,omitempty"`
+}
+
+func encodeCursor(c pageCursor) string {
+	data, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+func decodeCursor(s string, sort string, order sortOrder) (*pageCursor, error) {
+	data, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+	var c pageCursor
+	if err := json.Unmarshal(data, &c); err != nil {
+		return nil, ErrInvalidCursor
+	}
+	// A cursor only makes sense for the order it was issued for
+	if c.Sort != sort || len(c.Keys) != len(order.exprs) {
+		return nil, ErrInvalidCursor
+	}
+	return &c, nil
+}
+
+// itemQuery describes the items a page is drawn from
+type itemQuery struct {
+	conditions []string
+	args       []interface{}
+	// orders adds sort orders only this query supports, such as relevance
+	orders map[string]sortOrder
+	// columns are extra expressions selected as text for every row
+	columns []string
+}
+
+// itemRow is an item on a page together with its category and extra columns
+type itemRow struct {
+	item         models.MenuItem
+	categoryName string
+	categoryPic  string
+	columns      []string
+}
+
+// splitRows separates rows into the parallel slices most queries return
+func splitRows(rows []itemRow) ([]models.MenuItem, []string, []string) {
+	items := make([]models.MenuItem, len(rows))
+	categoryNames := make([]string, len(rows))
+	categoryPics := make([]string, len(rows))
+	for i, row := range rows {
+		items[i] = row.item
+		categoryNames[i] = row.categoryName
+		categoryPics[i] = row.categoryPic
+	}
+	return items, categoryNames, categoryPics
+}
+
+// queryItemPage returns one page of the items matching q, together with the
+// total number of matches
+func (db *MenuDB) queryItemPage(q itemQuery, page PageRequest) ([]itemRow, PageInfo, error) {
+	var info PageInfo
+
+	page, err := NewPageRequest(page.Limit, page.Cursor, page.Sort)
+	if err != nil {
+		return nil, info, err
+	}
+	order, ok := q.orders[page.Sort]
+	if !ok {
+		if order, ok = sortOrders[page.Sort]; !ok {
+			return nil, info, ErrInvalidSort
+		}
+	}
+
+	var cursor *pageCursor
+	if page.Cursor != "" {
+		if cursor, err = decodeCursor(page.Cursor, page.Sort, order); err != nil {
+			return nil, info, err
+		}
+	}
+
+	where := "TRUE"
+	if len(q.conditions) > 0 {
+		where = strings.Join(q.conditions, " AND ")
+	}
+	args := append([]interface{}{}, q.args...)
+
+	if err := db.Pool.QueryRow(context.Background(), `
+        SELECT COUNT(*)
+        FROM items i
+        JOIN category c ON i.category_id = c.category_id
+        WHERE `+where, args...).Scan(&info.Total); err != nil {
+		return nil, info, err
+	}
+
+	// Walking backwards reverses the order; the rows are flipped back below
+	backward := cursor != nil && cursor.Backward
+	desc := order.desc != backward
+	direction, comparison := "ASC", ">"
+	if desc {
+		direction, comparison = "DESC", "<"
+	}
+
+	if cursor != nil {
+		placeholders := make([]string, len(cursor.Keys))
+		for i, key := range cursor.Keys {
+			args = append(args, key)
+			placeholders[i] = fmt.Sprintf("$%d::text::%s", len(args), order.types[i])
+		}
+		where += fmt.Sprintf(" AND (%s) %s (%s)", strings.Join(order.exprs, ", "), comparison, strings.Join(placeholders, ", "))
+	}
+
+	keys := make([]string, len(order.exprs))
+	orderBy := make([]string, len(order.exprs))
+	for i, expr := range order.exprs {
+		keys[i] = "(" + expr + ")::text"
+		orderBy[i] = expr + " " + direction
+	}
+	columns := make([]string, len(q.columns))
+	for i, column := range q.columns {
+		columns[i] = "(" + column + ")::text"
+	}
+	extra := "ARRAY[]::text[]"
+	if len(columns) > 0 {
+		extra = "ARRAY[" + strings.Join(columns, ", ") + "]"
+	}
+
+	args = append(args, page.Limit+1)
+	rows, err := db.Pool.Query(context.Background(), `
+        SELECT i.item_id, i.name, i.description, i.price, i.is_available, i.quantity,
+               i.has_discount, i.discount_value, i.category_id, i.image,
+               i.allergens, i.dietary_tags, i.nutrition, i.spice_level, i.track_stock,
+               EXISTS (SELECT 1 FROM option_groups g WHERE g.item_id = i.item_id),
+               c.name as category_name, c.category_pic,
+               ARRAY[`+strings.Join(keys, ", ")+`], `+extra+`
+        FROM items i
+        JOIN category c ON i.category_id = c.category_id
+        WHERE `+where+`
+        ORDER BY `+strings.Join(orderBy, ", ")+fmt.Sprintf(`
+        LIMIT $%d`, len(args)), args...)
+	if err != nil {
+		return nil, info, err
+	}
+	defer rows.Close()
+
+	results := []itemRow{}
+	sortKeys := [][]string{}
+
+	for rows.Next() {
+		var row itemRow
+		var rowKeys []string
+
+		err := rows.Scan(
+			&row.item.ID, &row.item.Name, &row.item.Description, &row.item.Price, &row.item.IsAvailable,
+			&row.item.Quantity, &row.item.HasDiscount, &row.item.DiscountValue,
+			&row.item.CategoryID, &row.item.Image,
+			&row.item.Allergens, &row.item.DietaryTags, &row.item.Nutrition, &row.item.SpiceLevel, &row.item.TrackStock,
+			&row.item.HasOptions, &row.categoryName, &row.categoryPic, &rowKeys, &row.columns,
+		)
+		if err != nil {
+			return nil, info, err
+		}
+
+		results = append(results, row)
+		sortKeys = append(sortKeys, rowKeys)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, info, err
+	}
+
+	hasMore := len(results) > page.Limit
+	if hasMore {
+		results = results[:page.Limit]
+		sortKeys = sortKeys[:page.Limit]
+	}
+	if backward {
+		for i, j := 0, len(results)-1; i < j; i, j = i+1, j-1 {
+			results[i], results[j] = results[j], results[i]
+			sortKeys[i], sortKeys[j] = sortKeys[j], sortKeys[i]
+		}
+	}
+
+	items := make([]*models.MenuItem, len(results))
+	for i := range results {
+		items[i] = &results[i].item
+	}
+	if err := db.attachDiscounts(items); err != nil {
+		return nil, info, err
+	}
+
+	if len(results) > 0 {
+		// Coming from a later page guarantees rows after this one, and
+		// coming from an earlier page guarantees rows before it
+		if (!backward && hasMore) || backward {
+			info.NextCursor = encodeCursor(pageCursor{Sort: page.Sort, Keys: sortKeys[len(sortKeys)-1]})
+		}
+		if (backward && hasMore) || (!backward && cursor != nil) {
+			info.PrevCursor = encodeCursor(pageCursor{Sort: page.Sort, Keys: sortKeys[0], Backward: true})
+		}
+	}
+
+	return results, info, nil
+}
@@ -2,90 +2,80 @@ package db
 
 import (
 	"context"
-	"encoding/json"
+	"errors"
 	"fmt"
-	"net/http"
 	"strconv"
+	"time"
 
 	"github.com/darkhyper24/blaban/menu-service/internal/models"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgxpool"
 )
 
+var ErrMenuItemNotFound = errors.New("menu item not found")
+
+// MetadataFilter narrows items down by their dietary metadata. Zero values
+// do not filter.
+type MetadataFilter struct {
+	// Tags are dietary tags an item must all have
+	Tags []string
+	// ExcludeAllergens are allergens an item must have none of
+	ExcludeAllergens []string
+	MaxSpiceLevel    *int
+	MaxCalories      *int
+}
+
 // MenuDBOperations defines the interface for database operations that need to be mockable for testing
 type MenuDBOperations interface {
 	GetMenuItemByID(itemID string) (models.MenuItem, string, error)
 	GetCategoryID(categoryName string) (string, error)
 	GetAllCategories() ([]models.Category, error)
-	GetAllMenuItems() ([]models.MenuItem, []string, error)
-	SearchMenuItems(query string) ([]models.MenuItem, []string, []string, error)
+	GetCategoryByID(categoryID string) (models.Category, error)
+	CreateCategory(name, picture string) (models.Category, error)
+	UpdateCategory(category models.Category) error
+	ReorderCategories(categoryIDs []string) error
+	DeleteCategory(categoryID, reassignTo string) (int64, error)
+	GetAllMenuItems(page PageRequest) ([]models.MenuItem, []string, PageInfo, error)
+	SearchMenuItems(query string, page PageRequest) ([]models.SearchResult, PageInfo, error)
+	SuggestMenuItems(prefix string, limit int) ([]models.Suggestion, error)
 	CreateMenuItem(item models.MenuItem, categoryID string) (string, error)
 	UpdateMenuItem(item models.MenuItem) error
 	DeleteMenuItem(itemID string) (int64, error)
 	UpdateItemDiscount(itemID string, discountValue float64, active bool) (int64, error)
-	FilterMenuItems(categoryID, minPrice, maxPrice, hasDiscount, isAvailable string) ([]models.MenuItem, []string, []string, error)
+	SetMenuItemImage(itemID string, image *models.Image) (*models.Image, error)
+	SetCategoryImage(categoryID string, image *models.Image, picture string) (*models.Image, error)
+	SetItemOptionGroups(itemID string, groups []models.OptionGroup) ([]models.OptionGroup, error)
+	FilterMenuItems(categoryID, minPrice, maxPrice, hasDiscount, isAvailable string, metadata MetadataFilter, page PageRequest) ([]models.MenuItem, []string, []string, PageInfo, error)
+	ReserveStock(items []models.ReservationItem, reference string, ttl time.Duration) (models.Reservation, error)
+	CommitReservation(reservationID string) (models.Reservation, error)
+	ReleaseReservation(reservationID string) (models.Reservation, error)
+	ExpireReservations() ([]models.Reservation, error)
+	CreateDiscount(discount models.Discount) (models.Discount, error)
+	ListDiscounts(filter DiscountFilter) ([]models.Discount, error)
+	CancelDiscount(discountID string) (models.Discount, error)
+	CreatePromoCode(promo models.PromoCode) (models.PromoCode, error)
+	ListPromoCodes() ([]models.PromoCode, error)
+	DeactivatePromoCode(promoID string) (models.PromoCode, error)
+	QuotePromoCode(code, userID string, lines []models.PromoLine) (models.PromoQuote, error)
+	RedeemPromoCode(code, userID, reference string, lines []models.PromoLine) (models.PromoRedemption, error)
+	ReleasePromoRedemption(redemptionID string) (models.PromoRedemption, error)
 
 	GetPool() *pgxpool.Pool
 }
 
-// AuthVerifier defines the interface for authentication verification operations
+// AuthVerifier defines the interface for authentication verification operations.
+// auth.LocalVerifier from the shared module implements it.
 type AuthVerifier interface {
 	VerifyManagerRole(authHeader string) error
 }
 
-// DefaultAuthVerifier is the default implementation of AuthVerifier using HTTP requests to auth service
-type DefaultAuthVerifier struct{}
-
-// VerifyManagerRole checks if the request is from a manager
-func (a *DefaultAuthVerifier) VerifyManagerRole(authHeader string) error {
-	if authHeader == "" {
-		return fmt.Errorf("missing authorization header")
-	}
-
-	tokenString := authHeader
-	if len(authHeader) > 7 && authHeader[:7] == "Bearer " {
-		tokenString = authHeader[7:]
-	}
-
-	client := &http.Client{}
-	req, err := http.NewRequest("GET", "http://localhost:8082/api/auth/verify", nil)
-	if err != nil {
-		return fmt.Errorf("failed to create auth request")
-	}
-	req.Header.Add("Authorization", "Bearer "+tokenString)
-
-	resp, err := client.Do(req)
-	if err != nil {
-		return fmt.Errorf("failed to verify token: %v", err)
-	}
-	defer resp.Body.Close()
-
-	var authResp struct {
-		Valid bool   `json:"valid"`
-		Roles string `json:"roles"`
-	}
-	if err := json.NewDecoder(resp.Body).Decode(&authResp); err != nil {
-		return fmt.Errorf("failed to decode auth response")
-	}
-
-	if !authResp.Valid || authResp.Roles != "manager" {
-		return fmt.Errorf("only managers can perform this action")
-	}
-
-	return nil
-}
-
-// For backward compatibility with existing code
-func VerifyManagerRole(authHeader string) error {
-	verifier := &DefaultAuthVerifier{}
-	return verifier.VerifyManagerRole(authHeader)
-}
-
 // GetAllCategories retrieves all menu categories
 func (db *MenuDB) GetAllCategories() ([]models.Category, error) {
 	rows, err := db.Pool.Query(context.Background(), `
-        SELECT category_id, name, category_pic 
+        SELECT category_id, name, category_pic, position, image
         FROM category
-        ORDER BY name
+        ORDER BY position, name
     `)
 	if err != nil {
 		return nil, err
@@ -96,7 +86,7 @@ func (db *MenuDB) GetAllCategories() ([]models.Category, error) {
 
 	for rows.Next() {
 		var category models.Category
-		err := rows.Scan(&category.ID, &category.Name, &category.Picture)
+		err := rows.Scan(&category.ID, &category.Name, &category.Picture, &category.Position, &category.Image)
 		if err != nil {
 			return nil, err
 		}
@@ -106,83 +96,14 @@ func (db *MenuDB) GetAllCategories() ([]models.Category, error) {
 	return categories, nil
 }
 
-// GetAllMenuItems retrieves all menu items with their categories
-func (db *MenuDB) GetAllMenuItems() ([]models.MenuItem, []string, error) {
-	rows, err := db.Pool.Query(context.Background(), `
-        SELECT i.item_id, i.name, i.price, i.is_available, i.quantity, 
-               i.has_discount, i.discount_value, i.category_id, 
-               c.name as category_name
-        FROM items i
-        JOIN category c ON i.category_id = c.category_id
-        ORDER BY c.name, i.name
-    `)
+// GetAllMenuItems retrieves one page of menu items with their categories
+func (db *MenuDB) GetAllMenuItems(page PageRequest) ([]models.MenuItem, []string, PageInfo, error) {
+	rows, info, err := db.queryItemPage(itemQuery{}, page)
 	if err != nil {
-		return nil, nil, err
+		return nil, nil, info, err
 	}
-	defer rows.Close()
-
-	items := []models.MenuItem{}
-	categoryNames := []string{}
-
-	for rows.Next() {
-		var item models.MenuItem
-		var categoryName string
-
-		err := rows.Scan(
-			&item.ID, &item.Name, &item.Price, &item.IsAvailable,
-			&item.Quantity, &item.HasDiscount, &item.DiscountValue,
-			&item.CategoryID, &categoryName,
-		)
-		if err != nil {
-			return nil, nil, err
-		}
-
-		items = append(items, item)
-		categoryNames = append(categoryNames, categoryName)
-	}
-
-	return items, categoryNames, nil
-}
-
-// SearchMenuItems searches for menu items matching the query
-func (db *MenuDB) SearchMenuItems(query string) ([]models.MenuItem, []string, []string, error) {
-	rows, err := db.Pool.Query(context.Background(), `
-        SELECT i.item_id, i.name, i.price, i.is_available, i.quantity, 
-               i.has_discount, i.discount_value, i.category_id, 
-               c.name as category_name, c.category_pic
-        FROM items i
-        JOIN category c ON i.category_id = c.category_id
-        WHERE i.name LIKE $1 AND i.is_available = true
-        ORDER BY i.name
-    `, "%"+query+"%")
-	if err != nil {
-		return nil, nil, nil, err
-	}
-	defer rows.Close()
-
-	items := []models.MenuItem{}
-	categoryNames := []string{}
-	categoryPics := []string{}
-
-	for rows.Next() {
-		var item models.MenuItem
-		var categoryName, categoryPic string
-
-		err := rows.Scan(
-			&item.ID, &item.Name, &item.Price, &item.IsAvailable,
-			&item.Quantity, &item.HasDiscount, &item.DiscountValue,
-			&item.CategoryID, &categoryName, &categoryPic,
-		)
-		if err != nil {
-			return nil, nil, nil, err
-		}
-
-		items = append(items, item)
-		categoryNames = append(categoryNames, categoryName)
-		categoryPics = append(categoryPics, categoryPic)
-	}
-
-	return items, categoryNames, categoryPics, nil
+	items, categoryNames, _ := splitRows(rows)
+	return items, categoryNames, info, nil
 }
 
 // CreateMenuItem creates a new menu item
@@ -190,10 +111,13 @@ func (db *MenuDB) CreateMenuItem(item models.MenuItem, categoryID string) (strin
 	_, err := db.Pool.Exec(context.Background(), `
 		INSERT INTO items (
 			item_id, name, price, is_available, quantity, 
-			has_discount, discount_value, category_id
-		) VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+			has_discount, discount_value, category_id, description,
+			allergens, dietary_tags, nutrition, spice_level, track_stock
+		) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9,
+			COALESCE($10::text[], '{}'), COALESCE($11::text[], '{}'), $12, $13, $14)
 	`, item.ID, item.Name, item.Price, item.IsAvailable, item.Quantity,
-		item.HasDiscount, item.DiscountValue, categoryID)
+		item.HasDiscount, item.DiscountValue, categoryID, item.Description,
+		item.Allergens, item.DietaryTags, item.Nutrition, item.SpiceLevel, item.TrackStock)
 
 	if err != nil {
 		return "", err
@@ -207,11 +131,14 @@ func (db *MenuDB) UpdateMenuItem(item models.MenuItem) error {
 	_, err := db.Pool.Exec(context.Background(), `
 		UPDATE items 
 		SET name = $1, price = $2, is_available = $3, quantity = $4, category_id = $5,
-		    has_discount = $6, discount_value = $7
+		    has_discount = $6, discount_value = $7, description = $9,
+		    allergens = COALESCE($10::text[], '{}'), dietary_tags = COALESCE($11::text[], '{}'),
+		    nutrition = $12, spice_level = $13, track_stock = $14
 		WHERE item_id = $8
 	`,
 		item.Name, item.Price, item.IsAvailable,
-		item.Quantity, item.CategoryID, item.HasDiscount, item.DiscountValue, item.ID)
+		item.Quantity, item.CategoryID, item.HasDiscount, item.DiscountValue, item.ID, item.Description,
+		item.Allergens, item.DietaryTags, item.Nutrition, item.SpiceLevel, item.TrackStock)
 
 	return err
 }
@@ -229,8 +156,16 @@ func (db *MenuDB) DeleteMenuItem(itemID string) (int64, error) {
 	return cmdTag.RowsAffected(), nil
 }
 
-// UpdateItemDiscount updates the discount on a menu item
+// UpdateItemDiscount switches an item's manual discount on or off right away.
+// Each change is recorded in the discount history.
 func (db *MenuDB) UpdateItemDiscount(itemID string, discountValue float64, active bool) (int64, error) {
+	ctx := context.Background()
+	tx, err := db.Pool.Begin(ctx)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback(ctx)
+
 	var query string
 	var args []interface{}
 
@@ -242,94 +177,116 @@ func (db *MenuDB) UpdateItemDiscount(itemID string, discountValue float64, activ
 		args = []interface{}{itemID, discountValue}
 	}
 
-	cmdTag, err := db.Pool.Exec(context.Background(), query, args...)
+	cmdTag, err := tx.Exec(ctx, query, args...)
+	if err != nil || cmdTag.RowsAffected() == 0 {
+		return 0, err
+	}
+
+	// The manual discount being replaced, if any, ends now
+	_, err = tx.Exec(ctx, `
+        UPDATE discounts SET ends_at = NOW()
+        WHERE item_id = $1 AND manual AND `+currentDiscountSQL, itemID)
 	if err != nil {
 		return 0, err
 	}
+	if active {
+		_, err = tx.Exec(ctx, `
+            INSERT INTO discounts (discount_id, item_id, percentage, starts_at, manual)
+            VALUES ($1, $2, $3, NOW(), true)
+        `, uuid.New().String(), itemID, discountValue)
+		if err != nil {
+			return 0, err
+		}
+	}
 
-	return cmdTag.RowsAffected(), nil
+	return cmdTag.RowsAffected(), tx.Commit(ctx)
+}
+
+// SetMenuItemImage replaces an item's image, or removes it when image is nil,
+// and returns the image that was replaced
+func (db *MenuDB) SetMenuItemImage(itemID string, image *models.Image) (*models.Image, error) {
+	var previous *models.Image
+	err := db.Pool.QueryRow(context.Background(), `
+		WITH old AS (
+			SELECT item_id, image FROM items WHERE item_id = $1 FOR UPDATE
+		)
+		UPDATE items
+		SET image = $2
+		FROM old
+		WHERE items.item_id = old.item_id
+		RETURNING old.image
+	`, itemID, image).Scan(&previous)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrMenuItemNotFound
+	}
+	return previous, err
 }
 
 // FilterMenuItems filters menu items based on various criteria
-func (db *MenuDB) FilterMenuItems(categoryID, minPrice, maxPrice, hasDiscount, isAvailable string) ([]models.MenuItem, []string, []string, error) {
-	query := `
-		SELECT i.item_id, i.name, i.price, i.is_available, i.quantity, 
-			   i.has_discount, i.discount_value, i.category_id, 
-			   c.name as category_name, c.category_pic
-		FROM items i
-		JOIN category c ON i.category_id = c.category_id
-		WHERE 1=1
-	`
+func (db *MenuDB) FilterMenuItems(categoryID, minPrice, maxPrice, hasDiscount, isAvailable string, metadata MetadataFilter, page PageRequest) ([]models.MenuItem, []string, []string, PageInfo, error) {
+	var conditions []string
 	var args []interface{}
-	argPosition := 1
 
 	if categoryID != "" {
-		query += fmt.Sprintf(" AND i.category_id = $%d", argPosition)
 		args = append(args, categoryID)
-		argPosition++
+		conditions = append(conditions, fmt.Sprintf("i.category_id = $%d", len(args)))
 	}
 
 	if minPrice != "" {
 		minPriceFloat, err := strconv.ParseFloat(minPrice, 64)
 		if err == nil && minPriceFloat > 0 {
-			query += fmt.Sprintf(" AND i.price >= $%d", argPosition)
 			args = append(args, minPriceFloat)
-			argPosition++
+			conditions = append(conditions, fmt.Sprintf("i.price >= $%d", len(args)))
 		}
 	}
 
 	if maxPrice != "" {
 		maxPriceFloat, err := strconv.ParseFloat(maxPrice, 64)
 		if err == nil && maxPriceFloat > 0 {
-			query += fmt.Sprintf(" AND i.price <= $%d", argPosition)
 			args = append(args, maxPriceFloat)
-			argPosition++
+			conditions = append(conditions, fmt.Sprintf("i.price <= $%d", len(args)))
 		}
 	}
 
 	if hasDiscount == "true" {
-		query += " AND i.has_discount = true"
+		conditions = append(conditions, discountSQL+" > 0")
 	} else if hasDiscount == "false" {
-		query += " AND i.has_discount = false"
+		conditions = append(conditions, discountSQL+" = 0")
 	}
 
 	if isAvailable == "true" {
-		query += " AND i.is_available = true"
+		conditions = append(conditions, "i.is_available = true")
 	} else if isAvailable == "false" {
-		query += " AND i.is_available = false"
+		conditions = append(conditions, "i.is_available = false")
 	}
 
-	query += " ORDER BY c.name, i.name"
-
-	rows, err := db.Pool.Query(context.Background(), query, args...)
-	if err != nil {
-		return nil, nil, nil, err
+	if len(metadata.Tags) > 0 {
+		args = append(args, metadata.Tags)
+		conditions = append(conditions, fmt.Sprintf("i.dietary_tags @> $%d::text[]", len(args)))
 	}
-	defer rows.Close()
-
-	items := []models.MenuItem{}
-	categoryNames := []string{}
-	categoryPics := []string{}
 
-	for rows.Next() {
-		var item models.MenuItem
-		var categoryName, categoryPic string
+	if len(metadata.ExcludeAllergens) > 0 {
+		args = append(args, metadata.ExcludeAllergens)
+		conditions = append(conditions, fmt.Sprintf("NOT i.allergens && $%d::text[]", len(args)))
+	}
 
-		err := rows.Scan(
-			&item.ID, &item.Name, &item.Price, &item.IsAvailable,
-			&item.Quantity, &item.HasDiscount, &item.DiscountValue,
-			&item.CategoryID, &categoryName, &categoryPic,
-		)
-		if err != nil {
-			return nil, nil, nil, err
-		}
+	if metadata.MaxSpiceLevel != nil {
+		args = append(args, *metadata.MaxSpiceLevel)
+		conditions = append(conditions, fmt.Sprintf("i.spice_level <= $%d::int", len(args)))
+	}
 
-		items = append(items, item)
-		categoryNames = append(categoryNames, categoryName)
-		categoryPics = append(categoryPics, categoryPic)
+	// Items without nutrition information never match a calorie limit
+	if metadata.MaxCalories != nil {
+		args = append(args, *metadata.MaxCalories)
+		conditions = append(conditions, fmt.Sprintf("(i.nutrition->>'calories')::int <= $%d::int", len(args)))
 	}
 
-	return items, categoryNames, categoryPics, nil
+	rows, info, err := db.queryItemPage(itemQuery{conditions: conditions, args: args}, page)
+	if err != nil {
+		return nil, nil, nil, info, err
+	}
+	items, categoryNames, categoryPics := splitRows(rows)
+	return items, categoryNames, categoryPics, info, nil
 }
 
 // GetCategoryID gets the category ID from the category name
@@ -355,19 +312,30 @@ func (db *MenuDB) GetMenuItemByID(itemID string) (models.MenuItem, string, error
 	var categoryName string
 
 	err := db.Pool.QueryRow(context.Background(), `
-		SELECT i.item_id, i.name, i.price, i.is_available, i.quantity, 
-			i.has_discount, i.discount_value, i.category_id, 
+		SELECT i.item_id, i.name, i.description, i.price, i.is_available, i.quantity, 
+			i.has_discount, i.discount_value, i.category_id, i.image,
+			i.allergens, i.dietary_tags, i.nutrition, i.spice_level, i.track_stock,
 			c.name as category_name
 		FROM items i
 		JOIN category c ON i.category_id = c.category_id
 		WHERE i.item_id = $1
 	`, itemID).Scan(
-		&item.ID, &item.Name, &item.Price, &item.IsAvailable,
+		&item.ID, &item.Name, &item.Description, &item.Price, &item.IsAvailable,
 		&item.Quantity, &item.HasDiscount, &item.DiscountValue,
-		&item.CategoryID, &categoryName,
+		&item.CategoryID, &item.Image,
+		&item.Allergens, &item.DietaryTags, &item.Nutrition, &item.SpiceLevel, &item.TrackStock,
+		&categoryName,
 	)
+	if err != nil {
+		return item, categoryName, err
+	}
 
-	return item, categoryName, err
+	item.OptionGroups, err = db.GetItemOptionGroups(itemID)
+	if err != nil {
+		return item, categoryName, err
+	}
+	item.HasOptions = len(item.OptionGroups) > 0
+	return item, categoryName, db.attachDiscounts([]*models.MenuItem{&item})
 }
 
 // Helper functions for NULL handling
@@ -0,0 +1,141 @@
+package db
+
+import (
+	"context"
+	"html"
+	"strings"
+	"unicode"
+
+	"github.com/darkhyper24/blaban/menu-service/internal/models"
+)
+
+const (
+	DefaultSuggestLimit = 8
+	MaxSuggestLimit     = 20
+)
+
+// Highlights are produced with plain-text markers, HTML escaped and only then
+// turned into <mark> tags, so item names cannot inject markup
+const (
+	highlightStart = "[[[hl]]]"
+	highlightStop  = "[[[/hl]]]"
+)
+
+const (
+	// searchDocumentSQL is what full-text search matches: the item name and
+	// description, weighted A and B by the generated column, and the category name
+	searchDocumentSQL = "(i.search_vector || setweight(to_tsvector('english', c.name), 'C'))"
+	searchQuerySQL    = "websearch_to_tsquery('english', $1)"
+	// searchRankSQL adds name similarity so fuzzy matches rank below real ones
+	searchRankSQL = "(ts_rank_cd(" + searchDocumentSQL + ", " + searchQuerySQL + ") + word_similarity($1, i.name))::float8"
+	// headlineOptionsSQL opens the ts_headline options; callers add their own and close the quote
+	headlineOptionsSQL = "'StartSel=" + highlightStart + ", StopSel=" + highlightStop
+)
+
+var searchOrders = map[string]sortOrder{
+	SortRelevance: {exprs: []string{searchRankSQL, "i.item_id"}, types: []string{"float8", "text"}, desc: true},
+}
+
+// renderHighlight escapes a headline and turns its markers into <mark> tags
+func renderHighlight(headline string) string {
+	escaped := html.EscapeString(headline)
+	escaped = strings.ReplaceAll(escaped, highlightStart, "<mark>")
+	return strings.ReplaceAll(escaped, highlightStop, "</mark>")
+}
+
+// SearchMenuItems finds available items whose name, description or category
+// matches query. Words are stemmed, so "burgers" finds "Burger", and names
+// similar to the query match too, so typos like "shawerma" still find
+// "Shawarma". Results sort by relevance unless page asks otherwise.
+func (db *MenuDB) SearchMenuItems(query string, page PageRequest) ([]models.SearchResult, PageInfo, error) {
+	rows, info, err := db.queryItemPage(itemQuery{
+		conditions: []string{
+			"i.is_available = true",
+			"(" + searchDocumentSQL + " @@ " + searchQuerySQL + " OR $1 <% i.name)",
+		},
+		args:   []interface{}{query},
+		orders: searchOrders,
+		columns: []string{
+			"ts_headline('english', i.name, " + searchQuerySQL + ", " + headlineOptionsSQL + ", HighlightAll=true')",
+			"ts_headline('english', i.description, " + searchQuerySQL + ", " + headlineOptionsSQL + ", MaxFragments=2, MaxWords=20, MinWords=5')",
+		},
+	}, page)
+	if err != nil {
+		return nil, info, err
+	}
+
+	results := make([]models.SearchResult, len(rows))
+	for i, row := range rows {
+		results[i] = models.SearchResult{
+			Item:            row.item,
+			CategoryName:    row.categoryName,
+			CategoryPicture: row.categoryPic,
+			Highlight:       renderHighlight(row.columns[0]),
+			Snippet:         renderHighlight(row.columns[1]),
+		}
+	}
+	return results, info, nil
+}
+
+// prefixQuery turns typed text into a tsquery where every word, including the
+// unfinished last one, matches as a prefix. Anything but letters and digits is
+// dropped, so user input cannot form tsquery operators.
+func prefixQuery(text string) string {
+	words := strings.FieldsFunc(text, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	for i, word := range words {
+		words[i] = word + ":*"
+	}
+	return strings.Join(words, " & ")
+}
+
+// SuggestMenuItems returns up to limit available items for autocomplete.
+// Names starting with prefix come first, then other word-prefix matches,
+// then names that are merely similar.
+func (db *MenuDB) SuggestMenuItems(prefix string, limit int) ([]models.Suggestion, error) {
+	if limit <= 0 {
+		limit = DefaultSuggestLimit
+	}
+	if limit > MaxSuggestLimit {
+		limit = MaxSuggestLimit
+	}
+
+	suggestions := []models.Suggestion{}
+	tsquery := prefixQuery(prefix)
+	if tsquery == "" {
+		return suggestions, nil
+	}
+
+	rows, err := db.Pool.Query(context.Background(), `
+        SELECT i.item_id, i.name, i.category_id, c.name as category_name
+        FROM items i
+        JOIN category c ON i.category_id = c.category_id
+        WHERE i.is_available = true
+          AND (i.search_vector @@ to_tsquery('english', $2) OR $1 <% i.name)
+        ORDER BY lower(i.name) LIKE (lower($3) || '%') DESC,
+                 i.search_vector @@ to_tsquery('english', $2) DESC,
+                 word_similarity($1, i.name) DESC,
+                 i.name, i.item_id
+        LIMIT $4
+    `, prefix, tsquery, likePrefix(prefix), limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var s models.Suggestion
+		if err := rows.Scan(&s.ID, &s.Name, &s.CategoryID, &s.CategoryName); err != nil {
+			return nil, err
+		}
+		suggestions = append(suggestions, s)
+	}
+	return suggestions, rows.Err()
+}
+
+// likePrefix escapes LIKE wildcards in text
+func likePrefix(text string) string {
+	replacer := strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
+	return replacer.Replace(strings.TrimSpace(text))
+}
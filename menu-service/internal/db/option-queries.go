@@ -0,0 +1,108 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/darkhyper24/blaban/menu-service/internal/models"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+)
+
+// GetItemOptionGroups retrieves an item's option groups and their options in display order
+func (db *MenuDB) GetItemOptionGroups(itemID string) ([]models.OptionGroup, error) {
+	rows, err := db.Pool.Query(context.Background(), `
+        SELECT g.group_id, g.name, g.min_select, g.max_select,
+               o.option_id, o.name, o.price_delta, o.is_available
+        FROM option_groups g
+        JOIN options o ON o.group_id = g.group_id
+        WHERE g.item_id = $1
+        ORDER BY g.position, o.position
+    `, itemID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	groups := []models.OptionGroup{}
+	for rows.Next() {
+		var group models.OptionGroup
+		var option models.Option
+		err := rows.Scan(&group.ID, &group.Name, &group.MinSelect, &group.MaxSelect,
+			&option.ID, &option.Name, &option.PriceDelta, &option.IsAvailable)
+		if err != nil {
+			return nil, err
+		}
+		if len(groups) == 0 || groups[len(groups)-1].ID != group.ID {
+			groups = append(groups, group)
+		}
+		last := &groups[len(groups)-1]
+		last.Options = append(last.Options, option)
+	}
+	return groups, rows.Err()
+}
+
+// SetItemOptionGroups replaces all option groups of an item. Groups and
+// options that carry the ID of an existing one keep it, so carts holding
+// those IDs stay valid; the rest get new IDs. It returns the saved groups.
+func (db *MenuDB) SetItemOptionGroups(itemID string, groups []models.OptionGroup) ([]models.OptionGroup, error) {
+	ctx := context.Background()
+	tx, err := db.Pool.Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback(ctx)
+
+	var exists bool
+	err = tx.QueryRow(ctx, "SELECT true FROM items WHERE item_id = $1 FOR UPDATE", itemID).Scan(&exists)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrMenuItemNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := tx.Exec(ctx, "DELETE FROM option_groups WHERE item_id = $1", itemID); err != nil {
+		return nil, err
+	}
+
+	saved := make([]models.OptionGroup, len(groups))
+	for i, group := range groups {
+		if group.ID == "" {
+			group.ID = uuid.New().String()
+		}
+		_, err := tx.Exec(ctx, `
+            INSERT INTO option_groups (group_id, item_id, name, min_select, max_select, position)
+            VALUES ($1, $2, $3, $4, $5, $6)
+        `, group.ID, itemID, group.Name, group.MinSelect, group.MaxSelect, i)
+		if isUniqueViolation(err) {
+			return nil, fmt.Errorf("%w: group ID %s belongs to another item", models.ErrInvalidOptions, group.ID)
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		options := make([]models.Option, len(group.Options))
+		for j, option := range group.Options {
+			if option.ID == "" {
+				option.ID = uuid.New().String()
+			}
+			_, err := tx.Exec(ctx, `
+                INSERT INTO options (option_id, group_id, name, price_delta, is_available, position)
+                VALUES ($1, $2, $3, $4, $5, $6)
+            `, option.ID, group.ID, option.Name, option.PriceDelta, option.IsAvailable, j)
+			if isUniqueViolation(err) {
+				return nil, fmt.Errorf("%w: option ID %s is already in use", models.ErrInvalidOptions, option.ID)
+			}
+			if err != nil {
+				return nil, err
+			}
+			options[j] = option
+		}
+		group.Options = options
+		saved[i] = group
+	}
+
+	return saved, tx.Commit(ctx)
+}
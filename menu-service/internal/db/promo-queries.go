@@ -0,0 +1,230 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/darkhyper24/blaban/menu-service/internal/models"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+)
+
+var (
+	ErrPromoCodeNotFound  = errors.New("promo code not found")
+	ErrPromoCodeExists    = errors.New("a promo code with this code already exists")
+	ErrPromoUsedUp        = errors.New("promo code has reached its usage limit")
+	ErrPromoUserLimit     = errors.New("you have already used this promo code the maximum number of times")
+	ErrRedemptionNotFound = errors.New("promo redemption not found")
+)
+
+// promoColumns are selected by every promo code query, in scanPromoCode's order
+const promoColumns = `p.promo_id, p.code, p.kind, p.value, p.min_order_value, p.max_uses, p.max_uses_per_user,
+    p.starts_at, p.ends_at, p.item_ids, p.category_ids, p.is_active, p.created_at,
+    (SELECT COUNT(*) FROM promo_redemptions r WHERE r.promo_id = p.promo_id AND r.released_at IS NULL)`
+
+// queryer is what pools and transactions have in common
+type queryer interface {
+	QueryRow(ctx context.Context, sql string, args ...any) pgx.Row
+	Query(ctx context.Context, sql string, args ...any) (pgx.Rows, error)
+}
+
+func scanPromoCode(row pgx.Row) (models.PromoCode, error) {
+	var promo models.PromoCode
+	err := row.Scan(&promo.ID, &promo.Code, &promo.Kind, &promo.Value, &promo.MinOrderValue,
+		&promo.MaxUses, &promo.MaxUsesPerUser, &promo.StartsAt, &promo.EndsAt,
+		&promo.ItemIDs, &promo.CategoryIDs, &promo.IsActive, &promo.CreatedAt, &promo.Uses)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return promo, ErrPromoCodeNotFound
+	}
+	return promo, err
+}
+
+// CreatePromoCode adds a promo code
+func (db *MenuDB) CreatePromoCode(promo models.PromoCode) (models.PromoCode, error) {
+	created, err := scanPromoCode(db.Pool.QueryRow(context.Background(), `
+        INSERT INTO promo_codes AS p (promo_id, code, kind, value, min_order_value, max_uses,
+                                      max_uses_per_user, starts_at, ends_at, item_ids, category_ids)
+        VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)
+        RETURNING `+promoColumns,
+		uuid.New().String(), promo.Code, promo.Kind, promo.Value, promo.MinOrderValue, promo.MaxUses,
+		promo.MaxUsesPerUser, promo.StartsAt, promo.EndsAt, promo.ItemIDs, promo.CategoryIDs))
+	if isUniqueViolation(err) {
+		return created, ErrPromoCodeExists
+	}
+	return created, err
+}
+
+// ListPromoCodes lists every promo code, newest first
+func (db *MenuDB) ListPromoCodes() ([]models.PromoCode, error) {
+	rows, err := db.Pool.Query(context.Background(), `
+        SELECT `+promoColumns+`
+        FROM promo_codes p
+        ORDER BY p.created_at DESC
+    `)
+	if err != nil {
+		return nil, err
+	}
+	return pgx.CollectRows(rows, func(row pgx.CollectableRow) (models.PromoCode, error) {
+		return scanPromoCode(row)
+	})
+}
+
+// DeactivatePromoCode stops a promo code from being redeemed. Its past
+// redemptions are kept.
+func (db *MenuDB) DeactivatePromoCode(promoID string) (models.PromoCode, error) {
+	return scanPromoCode(db.Pool.QueryRow(context.Background(), `
+        UPDATE promo_codes AS p
+        SET is_active = false
+        WHERE p.promo_id = $1
+        RETURNING `+promoColumns, promoID))
+}
+
+// QuotePromoCode works out what a code would take off an order for a user
+// without redeeming it
+func (db *MenuDB) QuotePromoCode(code, userID string, lines []models.PromoLine) (models.PromoQuote, error) {
+	return quotePromoCode(context.Background(), db.Pool, code, userID, lines)
+}
+
+// RedeemPromoCode applies a code to an order and records the use. The code
+// is locked for the transaction so concurrent orders cannot exceed its
+// usage limits.
+func (db *MenuDB) RedeemPromoCode(code, userID, reference string, lines []models.PromoLine) (models.PromoRedemption, error) {
+	ctx := context.Background()
+	tx, err := db.Pool.Begin(ctx)
+	if err != nil {
+		return models.PromoRedemption{}, err
+	}
+	defer tx.Rollback(ctx)
+
+	var promoID string
+	err = tx.QueryRow(ctx, "SELECT promo_id FROM promo_codes WHERE code = $1 FOR UPDATE",
+		models.NormalizePromoCode(code)).Scan(&promoID)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return models.PromoRedemption{}, ErrPromoCodeNotFound
+	}
+	if err != nil {
+		return models.PromoRedemption{}, err
+	}
+
+	quote, err := quotePromoCode(ctx, tx, code, userID, lines)
+	if err != nil {
+		return models.PromoRedemption{}, err
+	}
+
+	redemption := models.PromoRedemption{
+		ID:        uuid.New().String(),
+		UserID:    userID,
+		Reference: reference,
+		Quote:     quote,
+	}
+	err = tx.QueryRow(ctx, `
+        INSERT INTO promo_redemptions (redemption_id, promo_id, user_id, reference, discount)
+        VALUES ($1, $2, $3, $4, $5)
+        RETURNING created_at
+    `, redemption.ID, quote.PromoID, userID, reference, quote.Discount).Scan(&redemption.CreatedAt)
+	if err != nil {
+		return redemption, err
+	}
+	return redemption, tx.Commit(ctx)
+}
+
+// ReleasePromoRedemption gives a use of a promo code back, e.g. when its order
+// is cancelled. Releasing twice changes nothing.
+func (db *MenuDB) ReleasePromoRedemption(redemptionID string) (models.PromoRedemption, error) {
+	var redemption models.PromoRedemption
+	err := db.Pool.QueryRow(context.Background(), `
+        WITH r AS (
+            UPDATE promo_redemptions
+            SET released_at = COALESCE(released_at, NOW())
+            WHERE redemption_id = $1
+            RETURNING *
+        )
+        SELECT r.redemption_id, r.user_id, r.reference, r.discount, r.created_at, r.released_at,
+               p.promo_id, p.code, p.kind, p.value
+        FROM r
+        JOIN promo_codes p ON p.promo_id = r.promo_id
+    `, redemptionID).Scan(&redemption.ID, &redemption.UserID, &redemption.Reference,
+		&redemption.Quote.Discount, &redemption.CreatedAt, &redemption.ReleasedAt,
+		&redemption.Quote.PromoID, &redemption.Quote.Code, &redemption.Quote.Kind, &redemption.Quote.Value)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return redemption, ErrRedemptionNotFound
+	}
+	return redemption, err
+}
+
+// quotePromoCode applies a code to lines after checking its usage limits.
+// Item categories come from the menu so callers cannot widen a code's scope.
+func quotePromoCode(ctx context.Context, q queryer, code, userID string, lines []models.PromoLine) (models.PromoQuote, error) {
+	if len(lines) == 0 {
+		return models.PromoQuote{}, fmt.Errorf("%w: the order has no items", models.ErrPromoNotApplicable)
+	}
+	itemIDs := make([]string, len(lines))
+	for i, line := range lines {
+		if line.Quantity <= 0 || line.UnitPrice < 0 {
+			return models.PromoQuote{}, fmt.Errorf("%w: every item needs a positive quantity and price", models.ErrPromoNotApplicable)
+		}
+		itemIDs[i] = line.ItemID
+	}
+
+	promo, err := scanPromoCode(q.QueryRow(ctx, `
+        SELECT `+promoColumns+`
+        FROM promo_codes p
+        WHERE p.code = $1
+    `, models.NormalizePromoCode(code)))
+	if err != nil {
+		return models.PromoQuote{}, err
+	}
+
+	rows, err := q.Query(ctx, "SELECT item_id, category_id FROM items WHERE item_id = ANY($1)", itemIDs)
+	if err != nil {
+		return models.PromoQuote{}, err
+	}
+	categories := map[string]string{}
+	for rows.Next() {
+		var itemID, categoryID string
+		if err := rows.Scan(&itemID, &categoryID); err != nil {
+			rows.Close()
+			return models.PromoQuote{}, err
+		}
+		categories[itemID] = categoryID
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return models.PromoQuote{}, err
+	}
+
+	scoped := make([]models.PromoLine, len(lines))
+	for i, line := range lines {
+		categoryID, ok := categories[line.ItemID]
+		if !ok {
+			return models.PromoQuote{}, ErrMenuItemNotFound
+		}
+		line.CategoryID = categoryID
+		scoped[i] = line
+	}
+
+	quote, err := promo.Apply(scoped, time.Now())
+	if err != nil {
+		return quote, err
+	}
+
+	if promo.MaxUses != nil && promo.Uses >= *promo.MaxUses {
+		return quote, ErrPromoUsedUp
+	}
+	if promo.MaxUsesPerUser != nil {
+		var userUses int
+		err := q.QueryRow(ctx, `
+            SELECT COUNT(*) FROM promo_redemptions
+            WHERE promo_id = $1 AND user_id = $2 AND released_at IS NULL
+        `, promo.ID, userID).Scan(&userUses)
+		if err != nil {
+			return quote, err
+		}
+		if userUses >= *promo.MaxUsesPerUser {
+			return quote, ErrPromoUserLimit
+		}
+	}
+	return quote, nil
+}
@@ -0,0 +1,334 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/darkhyper24/blaban/menu-service/internal/models"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+)
+
+var (
+	ErrInvalidReservation  = errors.New("a reservation needs at least one item with a positive quantity")
+	ErrInsufficientStock   = errors.New("not enough stock")
+	ErrItemUnavailable     = errors.New("item is not available")
+	ErrReservationNotFound = errors.New("reservation not found")
+	ErrReservationExpired  = errors.New("reservation has expired")
+	ErrReservationReleased = errors.New("reservation was released")
+)
+
+// expireBatchSize bounds how many reservations one ExpireReservations call handles
+const expireBatchSize = 100
+
+// ReserveStock sets stock aside for ttl. Items are locked in ID order so
+// concurrent reservations cannot deadlock, and either every item is reserved
+// or none is. Items that track stock are counted down and become unavailable
+// when they reach zero.
+func (db *MenuDB) ReserveStock(items []models.ReservationItem, reference string, ttl time.Duration) (models.Reservation, error) {
+	requested := make(map[string]int, len(items))
+	for _, item := range items {
+		if item.ItemID == "" || item.Quantity <= 0 {
+			return models.Reservation{}, ErrInvalidReservation
+		}
+		requested[item.ItemID] += item.Quantity
+	}
+	if len(requested) == 0 {
+		return models.Reservation{}, ErrInvalidReservation
+	}
+
+	itemIDs := make([]string, 0, len(requested))
+	for id := range requested {
+		itemIDs = append(itemIDs, id)
+	}
+	sort.Strings(itemIDs)
+
+	ctx := context.Background()
+	tx, err := db.Pool.Begin(ctx)
+	if err != nil {
+		return models.Reservation{}, err
+	}
+	defer tx.Rollback(ctx)
+
+	rows, err := tx.Query(ctx, `
+        SELECT item_id, name, quantity, is_available, track_stock
+        FROM items
+        WHERE item_id = ANY($1)
+        ORDER BY item_id
+        FOR UPDATE
+    `, itemIDs)
+	if err != nil {
+		return models.Reservation{}, err
+	}
+
+	tracked := make(map[string]bool, len(itemIDs))
+	found := 0
+	for rows.Next() {
+		var id, name string
+		var quantity int
+		var available, trackStock bool
+		if err := rows.Scan(&id, &name, &quantity, &available, &trackStock); err != nil {
+			rows.Close()
+			return models.Reservation{}, err
+		}
+		found++
+		if !available {
+			rows.Close()
+			return models.Reservation{}, fmt.Errorf("%w: %s", ErrItemUnavailable, name)
+		}
+		if trackStock && quantity < requested[id] {
+			rows.Close()
+			return models.Reservation{}, fmt.Errorf("%w: %s has %d left", ErrInsufficientStock, name, quantity)
+		}
+		tracked[id] = trackStock
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return models.Reservation{}, err
+	}
+	if found != len(itemIDs) {
+		return models.Reservation{}, ErrMenuItemNotFound
+	}
+
+	reservation := models.Reservation{
+		ID:        uuid.New().String(),
+		Reference: reference,
+		Status:    models.ReservationHeld,
+		Items:     make([]models.ReservationItem, len(itemIDs)),
+	}
+	err = tx.QueryRow(ctx, `
+        INSERT INTO stock_reservations (reservation_id, reference, status, expires_at)
+        VALUES ($1, $2, $3, NOW() + make_interval(secs => $4))
+        RETURNING expires_at, created_at
+    `, reservation.ID, reference, models.ReservationHeld, ttl.Seconds()).Scan(&reservation.ExpiresAt, &reservation.CreatedAt)
+	if err != nil {
+		return models.Reservation{}, err
+	}
+
+	quantities := make([]int, len(itemIDs))
+	deducted := make([]bool, len(itemIDs))
+	for i, id := range itemIDs {
+		quantities[i] = requested[id]
+		deducted[i] = tracked[id]
+		reservation.Items[i] = models.ReservationItem{ItemID: id, Quantity: requested[id]}
+	}
+
+	_, err = tx.Exec(ctx, `
+        INSERT INTO stock_reservation_items (reservation_id, item_id, quantity, deducted)
+        SELECT $1, r.item_id, r.quantity, r.deducted
+        FROM UNNEST($2::text[], $3::int[], $4::bool[]) AS r(item_id, quantity, deducted)
+    `, reservation.ID, itemIDs, quantities, deducted)
+	if err != nil {
+		return models.Reservation{}, err
+	}
+
+	_, err = tx.Exec(ctx, `
+        UPDATE items
+        SET quantity = items.quantity - r.quantity,
+            is_available = items.quantity - r.quantity > 0
+        FROM stock_reservation_items r
+        WHERE r.reservation_id = $1 AND r.deducted AND items.item_id = r.item_id
+    `, reservation.ID)
+	if err != nil {
+		return models.Reservation{}, err
+	}
+
+	return reservation, tx.Commit(ctx)
+}
+
+// CommitReservation makes a held reservation final. Committing twice is
+// harmless. A reservation that expired before it was committed has its stock
+// returned and ErrReservationExpired is returned along with it.
+func (db *MenuDB) CommitReservation(reservationID string) (models.Reservation, error) {
+	ctx := context.Background()
+	tx, err := db.Pool.Begin(ctx)
+	if err != nil {
+		return models.Reservation{}, err
+	}
+	defer tx.Rollback(ctx)
+
+	reservation, expired, err := lockReservation(ctx, tx, reservationID)
+	if err != nil {
+		return reservation, err
+	}
+
+	switch reservation.Status {
+	case models.ReservationCommitted:
+		return reservation, nil
+	case models.ReservationReleased:
+		return reservation, ErrReservationReleased
+	case models.ReservationExpired:
+		return reservation, ErrReservationExpired
+	}
+
+	if expired {
+		if err := closeReservation(ctx, tx, &reservation, models.ReservationExpired); err != nil {
+			return reservation, err
+		}
+		if err := tx.Commit(ctx); err != nil {
+			return reservation, err
+		}
+		return reservation, ErrReservationExpired
+	}
+
+	if err := setReservationStatus(ctx, tx, &reservation, models.ReservationCommitted); err != nil {
+		return reservation, err
+	}
+	return reservation, tx.Commit(ctx)
+}
+
+// ReleaseReservation gives a held or committed reservation's stock back, so an
+// order cancelled before it was prepared returns what it took. Releasing a
+// reservation that was already released or expired changes nothing.
+func (db *MenuDB) ReleaseReservation(reservationID string) (models.Reservation, error) {
+	ctx := context.Background()
+	tx, err := db.Pool.Begin(ctx)
+	if err != nil {
+		return models.Reservation{}, err
+	}
+	defer tx.Rollback(ctx)
+
+	reservation, _, err := lockReservation(ctx, tx, reservationID)
+	if err != nil {
+		return reservation, err
+	}
+	if reservation.Status == models.ReservationReleased || reservation.Status == models.ReservationExpired {
+		return reservation, nil
+	}
+
+	if err := closeReservation(ctx, tx, &reservation, models.ReservationReleased); err != nil {
+		return reservation, err
+	}
+	return reservation, tx.Commit(ctx)
+}
+
+// ExpireReservations returns the stock of held reservations past their expiry
+// and reports which reservations it expired. Each is expired in its own
+// transaction so one sweep never holds many item locks at once.
+func (db *MenuDB) ExpireReservations() ([]models.Reservation, error) {
+	ctx := context.Background()
+	rows, err := db.Pool.Query(ctx, `
+        SELECT reservation_id
+        FROM stock_reservations
+        WHERE status = $1 AND expires_at <= NOW()
+        ORDER BY expires_at
+        LIMIT $2
+    `, models.ReservationHeld, expireBatchSize)
+	if err != nil {
+		return nil, err
+	}
+	ids, err := pgx.CollectRows(rows, pgx.RowTo[string])
+	if err != nil {
+		return nil, err
+	}
+
+	expired := make([]models.Reservation, 0, len(ids))
+	for _, id := range ids {
+		reservation, ok, err := db.expireReservation(ctx, id)
+		if err != nil {
+			return expired, err
+		}
+		if ok {
+			expired = append(expired, reservation)
+		}
+	}
+	return expired, nil
+}
+
+// expireReservation expires one reservation unless it was committed or
+// released since it was found
+func (db *MenuDB) expireReservation(ctx context.Context, reservationID string) (models.Reservation, bool, error) {
+	tx, err := db.Pool.Begin(ctx)
+	if err != nil {
+		return models.Reservation{}, false, err
+	}
+	defer tx.Rollback(ctx)
+
+	reservation, expired, err := lockReservation(ctx, tx, reservationID)
+	if err != nil || reservation.Status != models.ReservationHeld || !expired {
+		return reservation, false, err
+	}
+	if err := closeReservation(ctx, tx, &reservation, models.ReservationExpired); err != nil {
+		return reservation, false, err
+	}
+	return reservation, true, tx.Commit(ctx)
+}
+
+// lockReservation loads a reservation and its items, locking it for the rest
+// of tx, and reports whether it is past its expiry
+func lockReservation(ctx context.Context, tx pgx.Tx, reservationID string) (models.Reservation, bool, error) {
+	reservation := models.Reservation{ID: reservationID}
+	var expired bool
+	err := tx.QueryRow(ctx, `
+        SELECT reference, status, expires_at, created_at, expires_at <= NOW()
+        FROM stock_reservations
+        WHERE reservation_id = $1
+        FOR UPDATE
+    `, reservationID).Scan(&reservation.Reference, &reservation.Status, &reservation.ExpiresAt, &reservation.CreatedAt, &expired)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return reservation, false, ErrReservationNotFound
+	}
+	if err != nil {
+		return reservation, false, err
+	}
+
+	rows, err := tx.Query(ctx, `
+        SELECT item_id, quantity
+        FROM stock_reservation_items
+        WHERE reservation_id = $1
+        ORDER BY item_id
+    `, reservationID)
+	if err != nil {
+		return reservation, false, err
+	}
+	reservation.Items, err = pgx.CollectRows(rows, func(row pgx.CollectableRow) (models.ReservationItem, error) {
+		var item models.ReservationItem
+		err := row.Scan(&item.ItemID, &item.Quantity)
+		return item, err
+	})
+	return reservation, expired, err
+}
+
+// closeReservation returns a reservation's stock and moves it to status.
+// Items that ran out are made available again once stock is back; items in
+// stock keep whatever availability a manager gave them.
+func closeReservation(ctx context.Context, tx pgx.Tx, reservation *models.Reservation, status string) error {
+	// Items are locked in ID order first, like ReserveStock does, to avoid deadlocks
+	_, err := tx.Exec(ctx, `
+        SELECT 1 FROM items
+        WHERE item_id IN (SELECT item_id FROM stock_reservation_items WHERE reservation_id = $1 AND deducted)
+        ORDER BY item_id
+        FOR UPDATE
+    `, reservation.ID)
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.Exec(ctx, `
+        UPDATE items
+        SET quantity = items.quantity + r.quantity,
+            is_available = items.is_available OR items.quantity <= 0
+        FROM stock_reservation_items r
+        WHERE r.reservation_id = $1 AND r.deducted
+          AND items.item_id = r.item_id AND items.track_stock
+    `, reservation.ID)
+	if err != nil {
+		return err
+	}
+	return setReservationStatus(ctx, tx, reservation, status)
+}
+
+func setReservationStatus(ctx context.Context, tx pgx.Tx, reservation *models.Reservation, status string) error {
+	_, err := tx.Exec(ctx, `
+        UPDATE stock_reservations
+        SET status = $2, updated_at = NOW()
+        WHERE reservation_id = $1
+    `, reservation.ID, status)
+	if err == nil {
+		reservation.Status = status
+	}
+	return err
+}
@@ -0,0 +1,57 @@
+// Package images validates uploaded menu images, renders their variants and
+// stores them in a BlobStore
+package images
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+)
+
+// BlobStore saves image files under keys and turns keys into public URLs
+type BlobStore interface {
+	Put(ctx context.Context, key string, r io.Reader, size int64, contentType string) error
+	Delete(ctx context.Context, key string) error
+	URL(key string) string
+}
+
+// NewBlobStoreFromEnv picks the backend named by IMAGE_STORE: "local" (the
+// default) writes to IMAGE_DIR and serves files under IMAGE_BASE_URL, "s3"
+// uses any S3-compatible service configured through the S3_* variables.
+func NewBlobStoreFromEnv() (BlobStore, error) {
+	switch backend := os.Getenv("IMAGE_STORE"); backend {
+	case "", "local":
+		dir := os.Getenv("IMAGE_DIR")
+		if dir == "" {
+			dir = "/data/images"
+		}
+		baseURL := os.Getenv("IMAGE_BASE_URL")
+		if baseURL == "" {
+			baseURL = LocalRoute
+		}
+		return NewLocalStore(dir, baseURL)
+	case "s3":
+		useSSL := true
+		if raw := os.Getenv("S3_USE_SSL"); raw != "" {
+			parsed, err := strconv.ParseBool(raw)
+			if err != nil {
+				return nil, errors.New("S3_USE_SSL must be true or false")
+			}
+			useSSL = parsed
+		}
+		return NewS3Store(S3Config{
+			Endpoint:  os.Getenv("S3_ENDPOINT"),
+			AccessKey: os.Getenv("S3_ACCESS_KEY"),
+			SecretKey: os.Getenv("S3_SECRET_KEY"),
+			Bucket:    os.Getenv("S3_BUCKET"),
+			Region:    os.Getenv("S3_REGION"),
+			UseSSL:    useSSL,
+			PublicURL: os.Getenv("S3_PUBLIC_URL"),
+		})
+	default:
+		return nil, fmt.Errorf("unknown IMAGE_STORE %q, expected local or s3", backend)
+	}
+}
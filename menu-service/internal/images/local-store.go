@@ -0,0 +1,89 @@
+package images
+
+import (
+	"context"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// LocalRoute is where menu-service serves files of a LocalStore by default
+const LocalRoute = "/media"
+
+// LocalStore keeps images on the local filesystem. It suits a single instance
+// or a shared volume; the service serves the directory itself.
+type LocalStore struct {
+	dir     string
+	baseURL string
+}
+
+// NewLocalStore stores files below dir and builds URLs from baseURL
+func NewLocalStore(dir, baseURL string) (*LocalStore, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+	return &LocalStore{dir: dir, baseURL: strings.TrimSuffix(baseURL, "/")}, nil
+}
+
+// Dir returns the directory files are stored in
+func (s *LocalStore) Dir() string {
+	return s.dir
+}
+
+func (s *LocalStore) path(key string) (string, error) {
+	path := filepath.Join(s.dir, filepath.FromSlash(key))
+	if !strings.HasPrefix(path, filepath.Clean(s.dir)+string(filepath.Separator)) {
+		return "", errors.New("invalid image key")
+	}
+	return path, nil
+}
+
+// Put writes the file through a temporary name so readers never see it half written
+func (s *LocalStore) Put(ctx context.Context, key string, r io.Reader, size int64, contentType string) error {
+	path, err := s.path(key)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".upload-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := io.Copy(tmp, r); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if err := os.Chmod(tmp.Name(), 0o644); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+// Delete removes a file; deleting a missing file is not an error
+func (s *LocalStore) Delete(ctx context.Context, key string) error {
+	path, err := s.path(key)
+	if err != nil {
+		return err
+	}
+	if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	// Drop the image's directory once its last variant is gone
+	os.Remove(filepath.Dir(path))
+	return nil
+}
+
+// URL returns the public URL of a stored file
+func (s *LocalStore) URL(key string) string {
+	return s.baseURL + "/" + key
+}
@@ -0,0 +1,152 @@
+package images
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"image"
+	"image/jpeg"
+	"image/png"
+	"net/http"
+
+	// Registered for image.Decode
+	_ "golang.org/x/image/webp"
+
+	"github.com/HugoSmits86/nativewebp"
+	"golang.org/x/image/draw"
+
+	"github.com/darkhyper24/blaban/menu-service/internal/models"
+)
+
+const (
+	MaxUploadSize = 5 << 20
+	// MaxPixels rejects images that are small files but huge once decoded
+	MaxPixels     = 40_000_000
+	FullSize      = 1200
+	ThumbnailSize = 320
+	jpegQuality   = 85
+)
+
+var (
+	ErrTooLarge        = fmt.Errorf("image must be at most %d MB", MaxUploadSize>>20)
+	ErrUnsupportedType = errors.New("image must be a JPEG, PNG or WebP file")
+	ErrInvalidImage    = errors.New("image could not be decoded")
+	ErrTooManyPixels   = errors.New("image dimensions are too large")
+)
+
+// allowedTypes are judged from the file contents, never the declared type
+var allowedTypes = map[string]bool{
+	"image/jpeg": true,
+	"image/png":  true,
+	"image/webp": true,
+}
+
+// Variant is one rendered file of an image
+type Variant struct {
+	Key         string
+	ContentType string
+	Data        []byte
+}
+
+// Process validates an uploaded image and renders the variants to store under
+// prefix: a full size and a thumbnail, each as JPEG (PNG when the image has
+// transparency) and as lossless WebP. Re-encoding also strips any metadata,
+// such as the location a photo was taken at.
+func Process(data []byte, prefix string) ([]Variant, *models.Image, error) {
+	if len(data) > MaxUploadSize {
+		return nil, nil, ErrTooLarge
+	}
+	if !allowedTypes[http.DetectContentType(data)] {
+		return nil, nil, ErrUnsupportedType
+	}
+
+	config, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return nil, nil, ErrInvalidImage
+	}
+	if config.Width*config.Height > MaxPixels {
+		return nil, nil, ErrTooManyPixels
+	}
+	src, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, nil, ErrInvalidImage
+	}
+
+	ext, contentType := ".jpg", "image/jpeg"
+	if !isOpaque(src) {
+		ext, contentType = ".png", "image/png"
+	}
+
+	img := &models.Image{
+		Full:          prefix + "/full" + ext,
+		FullWebP:      prefix + "/full.webp",
+		Thumbnail:     prefix + "/thumb" + ext,
+		ThumbnailWebP: prefix + "/thumb.webp",
+	}
+
+	var variants []Variant
+	for _, size := range []struct {
+		max       int
+		key, webp string
+	}{
+		{FullSize, img.Full, img.FullWebP},
+		{ThumbnailSize, img.Thumbnail, img.ThumbnailWebP},
+	} {
+		resized := fit(src, size.max)
+
+		var buf bytes.Buffer
+		if contentType == "image/png" {
+			err = png.Encode(&buf, resized)
+		} else {
+			err = jpeg.Encode(&buf, resized, &jpeg.Options{Quality: jpegQuality})
+		}
+		if err != nil {
+			return nil, nil, err
+		}
+		variants = append(variants, Variant{Key: size.key, ContentType: contentType, Data: buf.Bytes()})
+
+		var webp bytes.Buffer
+		if err := nativewebp.Encode(&webp, resized, nil); err != nil {
+			return nil, nil, err
+		}
+		variants = append(variants, Variant{Key: size.webp, ContentType: "image/webp", Data: webp.Bytes()})
+	}
+
+	return variants, img, nil
+}
+
+// fit scales img down to fit within max by max pixels, keeping its aspect
+// ratio. Smaller images are returned unchanged rather than upscaled.
+func fit(img image.Image, max int) image.Image {
+	bounds := img.Bounds()
+	width, height := bounds.Dx(), bounds.Dy()
+	if width <= max && height <= max {
+		return img
+	}
+
+	if width >= height {
+		height = height * max / width
+		width = max
+	} else {
+		width = width * max / height
+		height = max
+	}
+	if width < 1 {
+		width = 1
+	}
+	if height < 1 {
+		height = 1
+	}
+
+	dst := image.NewNRGBA(image.Rect(0, 0, width, height))
+	draw.CatmullRom.Scale(dst, dst.Bounds(), img, bounds, draw.Src, nil)
+	return dst
+}
+
+// isOpaque reports whether img has no transparent pixels
+func isOpaque(img image.Image) bool {
+	if o, ok := img.(interface{ Opaque() bool }); ok {
+		return o.Opaque()
+	}
+	return true
+}
@@ -0,0 +1,77 @@
+package images
+
+import (
+	"context"
+	"errors"
+	"io"
+	"strings"
+
+	"github.com/minio/minio-go/v7"
+	"github.com/minio/minio-go/v7/pkg/credentials"
+)
+
+// S3Config configures an S3Store
+type S3Config struct {
+	Endpoint  string
+	AccessKey string
+	SecretKey string
+	Bucket    string
+	Region    string
+	UseSSL    bool
+	// PublicURL is the base URL clients fetch images from, such as a CDN in
+	// front of the bucket. It defaults to the bucket's path on Endpoint.
+	PublicURL string
+}
+
+// S3Store keeps images in a bucket of any S3-compatible service
+type S3Store struct {
+	client    *minio.Client
+	bucket    string
+	publicURL string
+}
+
+// NewS3Store connects to the bucket described by cfg
+func NewS3Store(cfg S3Config) (*S3Store, error) {
+	if cfg.Endpoint == "" || cfg.Bucket == "" {
+		return nil, errors.New("S3_ENDPOINT and S3_BUCKET are required for the s3 image store")
+	}
+
+	client, err := minio.New(cfg.Endpoint, &minio.Options{
+		Creds:  credentials.NewStaticV4(cfg.AccessKey, cfg.SecretKey, ""),
+		Secure: cfg.UseSSL,
+		Region: cfg.Region,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	publicURL := strings.TrimSuffix(cfg.PublicURL, "/")
+	if publicURL == "" {
+		scheme := "http://"
+		if cfg.UseSSL {
+			scheme = "https://"
+		}
+		publicURL = scheme + cfg.Endpoint + "/" + cfg.Bucket
+	}
+
+	return &S3Store{client: client, bucket: cfg.Bucket, publicURL: publicURL}, nil
+}
+
+// Put uploads a file. Image keys are never reused, so it can be cached forever.
+func (s *S3Store) Put(ctx context.Context, key string, r io.Reader, size int64, contentType string) error {
+	_, err := s.client.PutObject(ctx, s.bucket, key, r, size, minio.PutObjectOptions{
+		ContentType:  contentType,
+		CacheControl: "public, max-age=31536000, immutable",
+	})
+	return err
+}
+
+// Delete removes a file; deleting a missing file is not an error
+func (s *S3Store) Delete(ctx context.Context, key string) error {
+	return s.client.RemoveObject(ctx, s.bucket, key, minio.RemoveObjectOptions{})
+}
+
+// URL returns the public URL of a stored file
+func (s *S3Store) URL(key string) string {
+	return s.publicURL + "/" + key
+}
@@ -2,7 +2,9 @@ package models
 
 // Category represents a menu category
 type Category struct {
-	ID      string `json:"id"`
-	Name    string `json:"name"`
-	Picture string `json:"picture"`
+	ID       string `json:"id"`
+	Name     string `json:"name"`
+	Picture  string `json:"picture"`
+	Position int    `json:"position"`
+	Image    *Image `json:"image,omitempty"`
 }
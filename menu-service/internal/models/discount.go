@@ -0,0 +1,148 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"time"
+)
+
+var ErrInvalidDiscount = errors.New("invalid discount")
+
+// RestaurantLocation is the time zone recurring discounts are read in
+var RestaurantLocation = time.UTC
+
+// SetRestaurantTimezone sets RestaurantLocation from an IANA name such as
+// "Africa/Cairo". An empty name keeps UTC.
+func SetRestaurantTimezone(name string) error {
+	if name == "" {
+		return nil
+	}
+	location, err := time.LoadLocation(name)
+	if err != nil {
+		return err
+	}
+	RestaurantLocation = location
+	return nil
+}
+
+// Discount statuses as of a point in time
+const (
+	DiscountUpcoming  = "upcoming"
+	DiscountActive    = "active"
+	DiscountInactive  = "inactive" // recurring and outside its window
+	DiscountEnded     = "ended"
+	DiscountCancelled = "cancelled"
+)
+
+// Discount takes Percentage off the price of one item or of every item in a
+// category. It runs from StartsAt to EndsAt, either of which may be open, and
+// when Recurrence is set only inside its weekly window. Manual discounts are
+// the immediate ones managers switch on and off per item.
+type Discount struct {
+	ID          string      `json:"id"`
+	ItemID      string      `json:"item_id,omitempty"`
+	CategoryID  string      `json:"category_id,omitempty"`
+	Percentage  float64     `json:"percentage"`
+	StartsAt    *time.Time  `json:"starts_at,omitempty"`
+	EndsAt      *time.Time  `json:"ends_at,omitempty"`
+	Recurrence  *Recurrence `json:"recurrence,omitempty"`
+	Manual      bool        `json:"manual"`
+	CreatedAt   time.Time   `json:"created_at"`
+	CancelledAt *time.Time  `json:"cancelled_at,omitempty"`
+}
+
+// Recurrence is a weekly window in the restaurant's time zone, such as
+// weekdays 15:00 to 17:00. Weekdays count from 0 for Sunday. A window whose
+// end is before its start runs past midnight into the next day.
+type Recurrence struct {
+	Weekdays  []int  `json:"weekdays"`
+	StartTime string `json:"start_time"`
+	EndTime   string `json:"end_time"`
+}
+
+// Validate checks a discount and sorts its recurrence weekdays. Every error
+// wraps ErrInvalidDiscount.
+func (d *Discount) Validate() error {
+	if (d.ItemID == "") == (d.CategoryID == "") {
+		return fmt.Errorf("%w: set either an item or a category", ErrInvalidDiscount)
+	}
+	if d.Percentage <= 0 || d.Percentage >= 100 {
+		return fmt.Errorf("%w: percentage must be between 0 and 100", ErrInvalidDiscount)
+	}
+	if d.StartsAt != nil && d.EndsAt != nil && !d.EndsAt.After(*d.StartsAt) {
+		return fmt.Errorf("%w: ends_at must be after starts_at", ErrInvalidDiscount)
+	}
+	if d.Recurrence != nil {
+		return d.Recurrence.validate()
+	}
+	return nil
+}
+
+func (r *Recurrence) validate() error {
+	if len(r.Weekdays) == 0 {
+		return fmt.Errorf("%w: a recurring discount needs at least one weekday", ErrInvalidDiscount)
+	}
+	for _, day := range r.Weekdays {
+		if day < 0 || day > 6 {
+			return fmt.Errorf("%w: weekdays run from 0 (Sunday) to 6 (Saturday)", ErrInvalidDiscount)
+		}
+	}
+	slices.Sort(r.Weekdays)
+	r.Weekdays = slices.Compact(r.Weekdays)
+
+	start, err := parseClock(r.StartTime)
+	if err != nil {
+		return fmt.Errorf("%w: start_time must look like 15:00", ErrInvalidDiscount)
+	}
+	end, err := parseClock(r.EndTime)
+	if err != nil {
+		return fmt.Errorf("%w: end_time must look like 17:00", ErrInvalidDiscount)
+	}
+	if start == end {
+		return fmt.Errorf("%w: start_time and end_time must differ", ErrInvalidDiscount)
+	}
+	return nil
+}
+
+// parseClock turns "15:04" into minutes past midnight
+func parseClock(clock string) (int, error) {
+	t, err := time.Parse("15:04", clock)
+	if err != nil {
+		return 0, err
+	}
+	return t.Hour()*60 + t.Minute(), nil
+}
+
+// covers reports whether local, a time in the restaurant's zone, falls in the window
+func (r *Recurrence) covers(local time.Time) bool {
+	start, _ := parseClock(r.StartTime)
+	end, _ := parseClock(r.EndTime)
+	minute := local.Hour()*60 + local.Minute()
+	day := int(local.Weekday())
+	if start < end {
+		return slices.Contains(r.Weekdays, day) && minute >= start && minute < end
+	}
+	return (slices.Contains(r.Weekdays, day) && minute >= start) ||
+		(slices.Contains(r.Weekdays, (day+6)%7) && minute < end)
+}
+
+// StatusAt describes the discount at t
+func (d *Discount) StatusAt(t time.Time) string {
+	switch {
+	case d.CancelledAt != nil && !t.Before(*d.CancelledAt):
+		return DiscountCancelled
+	case d.EndsAt != nil && !t.Before(*d.EndsAt):
+		return DiscountEnded
+	case d.StartsAt != nil && t.Before(*d.StartsAt):
+		return DiscountUpcoming
+	case d.Recurrence != nil && !d.Recurrence.covers(t.In(RestaurantLocation)):
+		return DiscountInactive
+	}
+	return DiscountActive
+}
+
+// ActiveAt reports whether the discount applies at t
+func (d *Discount) ActiveAt(t time.Time) bool {
+	return d.StatusAt(t) == DiscountActive
+}
@@ -0,0 +1,17 @@
+package models
+
+// Image holds the storage keys of the variants rendered from an uploaded image
+type Image struct {
+	Full          string `json:"full"`
+	FullWebP      string `json:"full_webp"`
+	Thumbnail     string `json:"thumbnail"`
+	ThumbnailWebP string `json:"thumbnail_webp"`
+}
+
+// Keys lists the keys of every stored variant
+func (i *Image) Keys() []string {
+	if i == nil {
+		return nil
+	}
+	return []string{i.Full, i.FullWebP, i.Thumbnail, i.ThumbnailWebP}
+}
@@ -1,20 +1,56 @@
 package models
 
+import "time"
+
 type MenuItem struct {
-	ID            string  `json:"item_id"`
-	CategoryID    string  `json:"category_id"`
-	Name          string  `json:"name"`
-	Price         float64 `json:"price"`
-	IsAvailable   bool    `json:"is_available"`
-	Quantity      int     `json:"quantity"`
-	HasDiscount   bool    `json:"has_discount"`
-	DiscountValue float64 `json:"discount_value"`
+	ID          string  `json:"item_id"`
+	CategoryID  string  `json:"category_id"`
+	Name        string  `json:"name"`
+	Description string  `json:"description"`
+	Price       float64 `json:"price"`
+	IsAvailable bool    `json:"is_available"`
+	Quantity    int     `json:"quantity"`
+	// TrackStock limits orders to Quantity, which reservations count down
+	TrackStock    bool       `json:"track_stock"`
+	HasDiscount   bool       `json:"has_discount"`
+	DiscountValue float64    `json:"discount_value"`
+	Image         *Image     `json:"image,omitempty"`
+	Allergens     []string   `json:"allergens"`
+	DietaryTags   []string   `json:"dietary_tags"`
+	Nutrition     *Nutrition `json:"nutrition,omitempty"`
+	SpiceLevel    int        `json:"spice_level"`
+	HasOptions    bool       `json:"has_options"`
+	// OptionGroups are only loaded for single items, not menu pages
+	OptionGroups []OptionGroup `json:"option_groups,omitempty"`
+	// Discounts are the scheduled discounts on the item or its category that
+	// have not ended. HasDiscount and DiscountValue hold the manual one.
+	Discounts []Discount `json:"discounts,omitempty"`
 }
 
-// GetEffectivePrice returns the price after applying any discount
-func (m *MenuItem) GetEffectivePrice() float64 {
+// DiscountAt returns the percentage taken off the price at t, the largest of
+// the manual discount and the scheduled discounts running then
+func (m *MenuItem) DiscountAt(t time.Time) float64 {
+	var best float64
 	if m.HasDiscount {
-		return m.Price * (1 - m.DiscountValue/100)
+		best = m.DiscountValue
+	}
+	for i := range m.Discounts {
+		if m.Discounts[i].ActiveAt(t) && m.Discounts[i].Percentage > best {
+			best = m.Discounts[i].Percentage
+		}
+	}
+	return best
+}
+
+// CurrentDiscount returns the percentage taken off the price right now
+func (m *MenuItem) CurrentDiscount() float64 {
+	return m.DiscountAt(time.Now())
+}
+
+// GetEffectivePrice returns the price after applying the discount active right now
+func (m *MenuItem) GetEffectivePrice() float64 {
+	if discount := m.CurrentDiscount(); discount > 0 {
+		return m.Price * (1 - discount/100)
 	}
 	return m.Price
 }
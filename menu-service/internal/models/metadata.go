@@ -0,0 +1,71 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// MaxSpiceLevel is the hottest spice level: 0 is not spicy, 1 mild, 2 medium, 3 hot
+const MaxSpiceLevel = 3
+
+// Allergens are the allergens an item can be labelled with
+var Allergens = []string{
+	"celery", "crustaceans", "eggs", "fish", "gluten", "lupin", "milk", "molluscs",
+	"mustard", "peanut", "sesame", "soy", "sulphites", "tree_nuts",
+}
+
+// DietaryTags are the diets and traits an item can be tagged with
+var DietaryTags = []string{
+	"dairy_free", "gluten_free", "halal", "nut_free", "vegan", "vegetarian",
+}
+
+var ErrInvalidNutrition = errors.New("nutrition values cannot be negative")
+
+// Nutrition is what one serving of an item contains
+type Nutrition struct {
+	Calories int     `json:"calories"`
+	Protein  float64 `json:"protein_g"`
+	Carbs    float64 `json:"carbs_g"`
+	Fat      float64 `json:"fat_g"`
+}
+
+// Validate rejects negative values
+func (n *Nutrition) Validate() error {
+	if n != nil && (n.Calories < 0 || n.Protein < 0 || n.Carbs < 0 || n.Fat < 0) {
+		return ErrInvalidNutrition
+	}
+	return nil
+}
+
+// NormalizeAllergens lower-cases, dedupes and sorts allergens, rejecting unknown ones
+func NormalizeAllergens(values []string) ([]string, error) {
+	return normalizeLabels(values, Allergens, "allergen")
+}
+
+// NormalizeDietaryTags lower-cases, dedupes and sorts dietary tags, rejecting unknown ones
+func NormalizeDietaryTags(values []string) ([]string, error) {
+	return normalizeLabels(values, DietaryTags, "dietary tag")
+}
+
+// normalizeLabels accepts labels written with spaces or dashes, so
+// "Tree Nuts" and "tree-nuts" both become tree_nuts. The result is never nil.
+func normalizeLabels(values, known []string, kind string) ([]string, error) {
+	seen := make(map[string]bool, len(values))
+	labels := []string{}
+	for _, value := range values {
+		label := strings.ToLower(strings.TrimSpace(value))
+		label = strings.NewReplacer(" ", "_", "-", "_").Replace(label)
+		if label == "" || seen[label] {
+			continue
+		}
+		if !slices.Contains(known, label) {
+			return nil, fmt.Errorf("unknown %s %q, expected one of: %s", kind, value, strings.Join(known, ", "))
+		}
+		seen[label] = true
+		labels = append(labels, label)
+	}
+	slices.Sort(labels)
+	return labels, nil
+}
@@ -0,0 +1,93 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"slices"
+	"strings"
+)
+
+var ErrInvalidOptions = errors.New("invalid option groups")
+
+// OptionGroup is a choice customers make when ordering an item, such as its
+// size or add-ons. Between MinSelect and MaxSelect options must be picked.
+type OptionGroup struct {
+	ID        string   `json:"id"`
+	Name      string   `json:"name"`
+	MinSelect int      `json:"min_select"`
+	MaxSelect int      `json:"max_select"`
+	Options   []Option `json:"options"`
+}
+
+// Option is one choice in an option group. PriceDelta is added to the item's
+// price when it is picked.
+type Option struct {
+	ID          string  `json:"id"`
+	Name        string  `json:"name"`
+	PriceDelta  float64 `json:"price_delta"`
+	IsAvailable bool    `json:"is_available"`
+}
+
+// ValidateOptionGroups checks the option groups of an item and trims their
+// names. Every error wraps ErrInvalidOptions.
+func ValidateOptionGroups(groups []OptionGroup) error {
+	groupNames := make(map[string]bool, len(groups))
+	for i := range groups {
+		group := &groups[i]
+		group.Name = strings.TrimSpace(group.Name)
+		if group.Name == "" {
+			return fmt.Errorf("%w: every group needs a name", ErrInvalidOptions)
+		}
+		if groupNames[strings.ToLower(group.Name)] {
+			return fmt.Errorf("%w: group %q appears twice", ErrInvalidOptions, group.Name)
+		}
+		groupNames[strings.ToLower(group.Name)] = true
+
+		if len(group.Options) == 0 {
+			return fmt.Errorf("%w: group %q has no options", ErrInvalidOptions, group.Name)
+		}
+		if group.MinSelect < 0 || group.MaxSelect < 1 || group.MinSelect > group.MaxSelect {
+			return fmt.Errorf("%w: group %q needs 0 <= min_select <= max_select and max_select >= 1", ErrInvalidOptions, group.Name)
+		}
+		if group.MaxSelect > len(group.Options) {
+			return fmt.Errorf("%w: group %q allows more selections than it has options", ErrInvalidOptions, group.Name)
+		}
+
+		optionNames := make(map[string]bool, len(group.Options))
+		for j := range group.Options {
+			option := &group.Options[j]
+			option.Name = strings.TrimSpace(option.Name)
+			if option.Name == "" {
+				return fmt.Errorf("%w: every option in %q needs a name", ErrInvalidOptions, group.Name)
+			}
+			if optionNames[strings.ToLower(option.Name)] {
+				return fmt.Errorf("%w: option %q appears twice in %q", ErrInvalidOptions, option.Name, group.Name)
+			}
+			optionNames[strings.ToLower(option.Name)] = true
+			if math.IsNaN(option.PriceDelta) || math.IsInf(option.PriceDelta, 0) {
+				return fmt.Errorf("%w: option %q has an invalid price_delta", ErrInvalidOptions, option.Name)
+			}
+		}
+	}
+	return nil
+}
+
+// MinPriceDelta returns the lowest total price delta a valid selection from
+// groups can add to an item, which is negative when options lower the price
+func MinPriceDelta(groups []OptionGroup) float64 {
+	var total float64
+	for _, group := range groups {
+		var negative []float64
+		for _, option := range group.Options {
+			if option.PriceDelta < 0 {
+				negative = append(negative, option.PriceDelta)
+			}
+		}
+		slices.Sort(negative)
+		for i := 0; i < len(negative) && i < group.MaxSelect; i++ {
+			total += negative[i]
+		}
+	}
+	return total
+}
@@ -0,0 +1,174 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"slices"
+	"strings"
+	"time"
+)
+
+// Promo code kinds
+const (
+	PromoPercentage = "percentage"
+	PromoFixed      = "fixed"
+)
+
+var (
+	ErrInvalidPromoCode = errors.New("invalid promo code")
+	// ErrPromoNotApplicable wraps every reason a valid code cannot be used on an order
+	ErrPromoNotApplicable = errors.New("promo code cannot be used")
+)
+
+// PromoCode takes a percentage or a fixed amount off the items it covers.
+// Empty ItemIDs and CategoryIDs cover the whole order. Nil limits are unlimited.
+type PromoCode struct {
+	ID             string     `json:"id"`
+	Code           string     `json:"code"`
+	Kind           string     `json:"kind"`
+	Value          float64    `json:"value"`
+	MinOrderValue  float64    `json:"min_order_value"`
+	MaxUses        *int       `json:"max_uses,omitempty"`
+	MaxUsesPerUser *int       `json:"max_uses_per_user,omitempty"`
+	StartsAt       *time.Time `json:"starts_at,omitempty"`
+	EndsAt         *time.Time `json:"ends_at,omitempty"`
+	ItemIDs        []string   `json:"item_ids"`
+	CategoryIDs    []string   `json:"category_ids"`
+	IsActive       bool       `json:"is_active"`
+	// Uses counts redemptions that have not been released
+	Uses      int       `json:"uses"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// PromoLine is one line of an order a promo code is applied to. CategoryID
+// is looked up from the menu, not taken from the caller.
+type PromoLine struct {
+	ItemID     string  `json:"item_id"`
+	CategoryID string  `json:"-"`
+	Quantity   int     `json:"quantity"`
+	UnitPrice  float64 `json:"unit_price"`
+}
+
+// PromoQuote is what a promo code takes off an order
+type PromoQuote struct {
+	PromoID          string  `json:"promo_id"`
+	Code             string  `json:"code"`
+	Kind             string  `json:"kind"`
+	Value            float64 `json:"value"`
+	Subtotal         float64 `json:"subtotal"`
+	EligibleSubtotal float64 `json:"eligible_subtotal"`
+	Discount         float64 `json:"discount"`
+	Total            float64 `json:"total"`
+}
+
+// PromoRedemption is a use of a promo code by a customer for an order
+type PromoRedemption struct {
+	ID         string     `json:"id"`
+	UserID     string     `json:"user_id"`
+	Reference  string     `json:"reference"`
+	Quote      PromoQuote `json:"quote"`
+	CreatedAt  time.Time  `json:"created_at"`
+	ReleasedAt *time.Time `json:"released_at,omitempty"`
+}
+
+// NormalizePromoCode makes codes case-insensitive
+func NormalizePromoCode(code string) string {
+	return strings.ToUpper(strings.TrimSpace(code))
+}
+
+// Validate checks a promo code, normalizes its code and dedupes its scope.
+// Every error wraps ErrInvalidPromoCode.
+func (p *PromoCode) Validate() error {
+	p.Code = NormalizePromoCode(p.Code)
+	if p.Code == "" || strings.ContainsAny(p.Code, " \t\n") {
+		return fmt.Errorf("%w: code must be a single word", ErrInvalidPromoCode)
+	}
+	switch p.Kind {
+	case PromoPercentage:
+		if p.Value <= 0 || p.Value >= 100 {
+			return fmt.Errorf("%w: percentage must be between 0 and 100", ErrInvalidPromoCode)
+		}
+	case PromoFixed:
+		if p.Value <= 0 {
+			return fmt.Errorf("%w: amount must be positive", ErrInvalidPromoCode)
+		}
+	default:
+		return fmt.Errorf("%w: kind must be %s or %s", ErrInvalidPromoCode, PromoPercentage, PromoFixed)
+	}
+	if p.MinOrderValue < 0 {
+		return fmt.Errorf("%w: minimum order value cannot be negative", ErrInvalidPromoCode)
+	}
+	if (p.MaxUses != nil && *p.MaxUses <= 0) || (p.MaxUsesPerUser != nil && *p.MaxUsesPerUser <= 0) {
+		return fmt.Errorf("%w: usage limits must be positive", ErrInvalidPromoCode)
+	}
+	if p.StartsAt != nil && p.EndsAt != nil && !p.EndsAt.After(*p.StartsAt) {
+		return fmt.Errorf("%w: ends_at must be after starts_at", ErrInvalidPromoCode)
+	}
+	p.ItemIDs = compactIDs(p.ItemIDs)
+	p.CategoryIDs = compactIDs(p.CategoryIDs)
+	return nil
+}
+
+func compactIDs(ids []string) []string {
+	result := []string{}
+	for _, id := range ids {
+		if id = strings.TrimSpace(id); id != "" && !slices.Contains(result, id) {
+			result = append(result, id)
+		}
+	}
+	return result
+}
+
+// covers reports whether a line is in the promo code's scope
+func (p *PromoCode) covers(line PromoLine) bool {
+	if len(p.ItemIDs) == 0 && len(p.CategoryIDs) == 0 {
+		return true
+	}
+	return slices.Contains(p.ItemIDs, line.ItemID) || slices.Contains(p.CategoryIDs, line.CategoryID)
+}
+
+// Apply works out what the code takes off an order placed at t. Usage limits
+// are checked by the caller, which knows the redemption counts. Every error
+// wraps ErrPromoNotApplicable.
+func (p *PromoCode) Apply(lines []PromoLine, t time.Time) (PromoQuote, error) {
+	quote := PromoQuote{PromoID: p.ID, Code: p.Code, Kind: p.Kind, Value: p.Value}
+
+	switch {
+	case !p.IsActive:
+		return quote, fmt.Errorf("%w: %s is no longer active", ErrPromoNotApplicable, p.Code)
+	case p.StartsAt != nil && t.Before(*p.StartsAt):
+		return quote, fmt.Errorf("%w: %s is not valid yet", ErrPromoNotApplicable, p.Code)
+	case p.EndsAt != nil && !t.Before(*p.EndsAt):
+		return quote, fmt.Errorf("%w: %s has expired", ErrPromoNotApplicable, p.Code)
+	}
+
+	for _, line := range lines {
+		amount := line.UnitPrice * float64(line.Quantity)
+		quote.Subtotal += amount
+		if p.covers(line) {
+			quote.EligibleSubtotal += amount
+		}
+	}
+	quote.Subtotal = roundCents(quote.Subtotal)
+	quote.EligibleSubtotal = roundCents(quote.EligibleSubtotal)
+
+	if quote.Subtotal < p.MinOrderValue {
+		return quote, fmt.Errorf("%w: %s needs an order of at least %.2f", ErrPromoNotApplicable, p.Code, p.MinOrderValue)
+	}
+	if quote.EligibleSubtotal <= 0 {
+		return quote, fmt.Errorf("%w: %s does not apply to any item in this order", ErrPromoNotApplicable, p.Code)
+	}
+
+	if p.Kind == PromoPercentage {
+		quote.Discount = roundCents(quote.EligibleSubtotal * p.Value / 100)
+	} else {
+		quote.Discount = math.Min(p.Value, quote.EligibleSubtotal)
+	}
+	quote.Total = roundCents(quote.Subtotal - quote.Discount)
+	return quote, nil
+}
+
+func roundCents(amount float64) float64 {
+	return math.Round(amount*100) / 100
+}
@@ -0,0 +1,28 @@
+package models
+
+import "time"
+
+// Reservation statuses. A held reservation keeps its stock until it is
+// committed, released or expires; releasing a committed one returns its stock.
+const (
+	ReservationHeld      = "held"
+	ReservationCommitted = "committed"
+	ReservationReleased  = "released"
+	ReservationExpired   = "expired"
+)
+
+// Reservation is stock set aside for an order
+type Reservation struct {
+	ID        string            `json:"id"`
+	Reference string            `json:"reference"`
+	Status    string            `json:"status"`
+	Items     []ReservationItem `json:"items"`
+	ExpiresAt time.Time         `json:"expires_at"`
+	CreatedAt time.Time         `json:"created_at"`
+}
+
+// ReservationItem is how many of one item a reservation holds
+type ReservationItem struct {
+	ItemID   string `json:"item_id"`
+	Quantity int    `json:"quantity"`
+}
@@ -0,0 +1,20 @@
+package models
+
+// SearchResult is a menu item matched by a search. Highlight is the item name
+// and Snippet an excerpt of its description, both HTML escaped with the
+// matching words wrapped in <mark> tags.
+type SearchResult struct {
+	Item            MenuItem
+	CategoryName    string
+	CategoryPicture string
+	Highlight       string
+	Snippet         string
+}
+
+// Suggestion is an autocomplete entry for a partially typed search
+type Suggestion struct {
+	ID           string `json:"id"`
+	Name         string `json:"name"`
+	CategoryID   string `json:"category_id"`
+	CategoryName string `json:"category_name"`
+}
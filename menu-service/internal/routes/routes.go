@@ -2,19 +2,55 @@ package routes
 
 import (
 	"github.com/darkhyper24/blaban/menu-service/internal/db"
+	"github.com/darkhyper24/blaban/menu-service/internal/images"
 	"github.com/darkhyper24/blaban/menu-service/services"
+	"github.com/darkhyper24/blaban/shared/auth"
 	"github.com/gofiber/fiber/v2"
 )
 
-func SetupRoutes(app *fiber.App, menuDB db.MenuDBOperations) {
-	menuService := services.NewMenuService(menuDB)
+func SetupRoutes(app *fiber.App, menuDB db.MenuDBOperations, verifier db.AuthVerifier, store images.BlobStore) {
+	menuService := services.NewMenuService(menuDB, verifier)
+	menuService.Images = store
 	app.Get("/api/categories", menuService.HandleGetCategories)
+	app.Post("/api/categories", menuService.HandleCreateCategory)
+	app.Put("/api/categories/order", menuService.HandleReorderCategories)
+	app.Patch("/api/categories/:id", menuService.HandleUpdateCategory)
+	app.Delete("/api/categories/:id", menuService.HandleDeleteCategory)
+	app.Put("/api/categories/:id/image", menuService.HandleUploadCategoryImage)
+	app.Delete("/api/categories/:id/image", menuService.HandleDeleteCategoryImage)
 	app.Get("/api/menu", menuService.HandleGetMenu)
 	app.Get("/api/menu/search", menuService.HandleSearchItems)
+	app.Get("/api/menu/suggest", menuService.HandleSuggestItems)
 	app.Get("/api/menu/filter", menuService.HandleFilterItems)
 	app.Get("/api/menu/:id", menuService.HandleGetMenuItem)
 	app.Post("/api/menu", menuService.HandleCreateMenuItem)
 	app.Patch("/api/menu/:id", menuService.HandleUpdateMenuItem)
 	app.Delete("/api/menu/:id", menuService.HandleDeleteMenuItem)
 	app.Post("/api/menu/:id/discount", menuService.HandleAddDiscount)
+	app.Put("/api/menu/:id/image", menuService.HandleUploadMenuItemImage)
+	app.Delete("/api/menu/:id/image", menuService.HandleDeleteMenuItemImage)
+	app.Put("/api/menu/:id/options", menuService.HandleSetItemOptions)
+	app.Get("/api/discounts", menuService.HandleListDiscounts)
+	app.Post("/api/discounts", menuService.HandleCreateDiscount)
+	app.Delete("/api/discounts/:id", menuService.HandleCancelDiscount)
+	app.Get("/api/promo-codes", menuService.HandleListPromoCodes)
+	app.Post("/api/promo-codes", menuService.HandleCreatePromoCode)
+	app.Delete("/api/promo-codes/:id", menuService.HandleDeactivatePromoCode)
+}
+
+// SetupInternalRoutes registers routes that only other services may call
+func SetupInternalRoutes(app *fiber.App, menuDB db.MenuDBOperations, verifier auth.Verifier) {
+	menuService := services.NewMenuService(menuDB)
+	internal := app.Group("/internal", auth.Authenticate(verifier))
+	internal.Get("/menu/:id", auth.RequireScope(auth.ScopeMenuRead), menuService.HandleGetMenuItem)
+
+	reservations := internal.Group("/reservations", auth.RequireScope(auth.ScopeStockReserve))
+	reservations.Post("/", menuService.HandleReserveStock)
+	reservations.Post("/:id/commit", menuService.HandleCommitReservation)
+	reservations.Post("/:id/release", menuService.HandleReleaseReservation)
+
+	promos := internal.Group("/promo-codes", auth.RequireScope(auth.ScopePromoRedeem))
+	promos.Post("/quote", menuService.HandleQuotePromoCode)
+	promos.Post("/redeem", menuService.HandleRedeemPromoCode)
+	promos.Post("/redemptions/:id/release", menuService.HandleReleasePromoRedemption)
 }
@@ -0,0 +1,5 @@
+CREATE TABLE IF NOT EXISTS category (
+    category_id TEXT PRIMARY KEY DEFAULT gen_random_uuid()::text,
+    name TEXT UNIQUE NOT NULL,
+    category_pic TEXT NOT NULL DEFAULT ''
+);
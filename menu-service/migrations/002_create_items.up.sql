@@ -0,0 +1,12 @@
+CREATE TABLE IF NOT EXISTS items (
+    item_id TEXT PRIMARY KEY,
+    name TEXT NOT NULL,
+    price NUMERIC(10, 2) NOT NULL CHECK (price > 0),
+    is_available BOOLEAN NOT NULL DEFAULT true,
+    quantity INT NOT NULL DEFAULT 0,
+    has_discount BOOLEAN NOT NULL DEFAULT false,
+    discount_value NUMERIC(5, 2) NOT NULL DEFAULT 0,
+    category_id TEXT NOT NULL REFERENCES category(category_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_items_category_id ON items (category_id);
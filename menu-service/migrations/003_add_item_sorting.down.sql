@@ -0,0 +1,5 @@
+DROP INDEX IF EXISTS idx_items_created_at;
+DROP INDEX IF EXISTS idx_items_price;
+DROP INDEX IF EXISTS idx_items_name;
+
+ALTER TABLE items DROP COLUMN IF EXISTS created_at;
@@ -0,0 +1,5 @@
+ALTER TABLE items ADD COLUMN IF NOT EXISTS created_at TIMESTAMPTZ NOT NULL DEFAULT NOW();
+
+CREATE INDEX IF NOT EXISTS idx_items_name ON items (name, item_id);
+CREATE INDEX IF NOT EXISTS idx_items_price ON items (price, item_id);
+CREATE INDEX IF NOT EXISTS idx_items_created_at ON items (created_at DESC, item_id DESC);
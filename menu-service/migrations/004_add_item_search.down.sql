@@ -0,0 +1,6 @@
+DROP INDEX IF EXISTS idx_category_name_search;
+DROP INDEX IF EXISTS idx_items_name_trgm;
+DROP INDEX IF EXISTS idx_items_search_vector;
+
+ALTER TABLE items DROP COLUMN IF EXISTS search_vector;
+ALTER TABLE items DROP COLUMN IF EXISTS description;
@@ -0,0 +1,13 @@
+CREATE EXTENSION IF NOT EXISTS pg_trgm;
+
+ALTER TABLE items ADD COLUMN IF NOT EXISTS description TEXT NOT NULL DEFAULT '';
+
+-- Names weigh more than descriptions when ranking matches
+ALTER TABLE items ADD COLUMN IF NOT EXISTS search_vector tsvector GENERATED ALWAYS AS (
+    setweight(to_tsvector('english', name), 'A') ||
+    setweight(to_tsvector('english', description), 'B')
+) STORED;
+
+CREATE INDEX IF NOT EXISTS idx_items_search_vector ON items USING gin (search_vector);
+CREATE INDEX IF NOT EXISTS idx_items_name_trgm ON items USING gin (name gin_trgm_ops);
+CREATE INDEX IF NOT EXISTS idx_category_name_search ON category USING gin (to_tsvector('english', name));
@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS idx_category_position;
+
+ALTER TABLE category DROP COLUMN IF EXISTS position;
@@ -0,0 +1,12 @@
+ALTER TABLE category ADD COLUMN IF NOT EXISTS position INT NOT NULL DEFAULT 0;
+
+-- Existing categories keep their alphabetical order
+UPDATE category
+SET position = ordered.position
+FROM (
+    SELECT category_id, ROW_NUMBER() OVER (ORDER BY name) - 1 AS position
+    FROM category
+) AS ordered
+WHERE category.category_id = ordered.category_id;
+
+CREATE INDEX IF NOT EXISTS idx_category_position ON category (position, name);
@@ -0,0 +1,2 @@
+ALTER TABLE category DROP COLUMN IF EXISTS image;
+ALTER TABLE items DROP COLUMN IF EXISTS image;
@@ -0,0 +1,3 @@
+-- Storage keys of the rendered image variants, NULL when there is no image
+ALTER TABLE items ADD COLUMN IF NOT EXISTS image JSONB;
+ALTER TABLE category ADD COLUMN IF NOT EXISTS image JSONB;
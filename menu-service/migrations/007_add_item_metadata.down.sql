@@ -0,0 +1,7 @@
+DROP INDEX IF EXISTS idx_items_dietary_tags;
+DROP INDEX IF EXISTS idx_items_allergens;
+
+ALTER TABLE items DROP COLUMN IF EXISTS spice_level;
+ALTER TABLE items DROP COLUMN IF EXISTS nutrition;
+ALTER TABLE items DROP COLUMN IF EXISTS dietary_tags;
+ALTER TABLE items DROP COLUMN IF EXISTS allergens;
@@ -0,0 +1,9 @@
+ALTER TABLE items ADD COLUMN IF NOT EXISTS allergens TEXT[] NOT NULL DEFAULT '{}';
+ALTER TABLE items ADD COLUMN IF NOT EXISTS dietary_tags TEXT[] NOT NULL DEFAULT '{}';
+-- Calories and macros per serving, NULL when unknown
+ALTER TABLE items ADD COLUMN IF NOT EXISTS nutrition JSONB;
+ALTER TABLE items ADD COLUMN IF NOT EXISTS spice_level SMALLINT NOT NULL DEFAULT 0
+    CHECK (spice_level BETWEEN 0 AND 3);
+
+CREATE INDEX IF NOT EXISTS idx_items_allergens ON items USING gin (allergens);
+CREATE INDEX IF NOT EXISTS idx_items_dietary_tags ON items USING gin (dietary_tags);
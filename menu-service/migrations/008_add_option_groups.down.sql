@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS options;
+DROP TABLE IF EXISTS option_groups;
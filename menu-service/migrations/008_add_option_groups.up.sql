@@ -0,0 +1,24 @@
+-- Option groups let customers pick a size or add-ons for an item. Customers
+-- choose between min_select and max_select of a group's options.
+CREATE TABLE IF NOT EXISTS option_groups (
+    group_id TEXT PRIMARY KEY,
+    item_id TEXT NOT NULL REFERENCES items(item_id) ON DELETE CASCADE,
+    name TEXT NOT NULL,
+    min_select INT NOT NULL DEFAULT 0 CHECK (min_select >= 0),
+    max_select INT NOT NULL DEFAULT 1 CHECK (max_select >= 1 AND max_select >= min_select),
+    position INT NOT NULL DEFAULT 0
+);
+
+CREATE INDEX IF NOT EXISTS idx_option_groups_item_id ON option_groups (item_id, position);
+
+-- price_delta is added to the item price and may be negative, e.g. for a small size
+CREATE TABLE IF NOT EXISTS options (
+    option_id TEXT PRIMARY KEY,
+    group_id TEXT NOT NULL REFERENCES option_groups(group_id) ON DELETE CASCADE,
+    name TEXT NOT NULL,
+    price_delta NUMERIC(10, 2) NOT NULL DEFAULT 0,
+    is_available BOOLEAN NOT NULL DEFAULT true,
+    position INT NOT NULL DEFAULT 0
+);
+
+CREATE INDEX IF NOT EXISTS idx_options_group_id ON options (group_id, position);
@@ -0,0 +1,4 @@
+DROP TABLE IF EXISTS stock_reservation_items;
+DROP TABLE IF EXISTS stock_reservations;
+
+ALTER TABLE items DROP COLUMN IF EXISTS track_stock;
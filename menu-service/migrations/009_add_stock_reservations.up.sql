@@ -0,0 +1,24 @@
+-- Only items that track stock are limited by their quantity; the rest can
+-- always be ordered while available
+ALTER TABLE items ADD COLUMN IF NOT EXISTS track_stock BOOLEAN NOT NULL DEFAULT false;
+
+CREATE TABLE IF NOT EXISTS stock_reservations (
+    reservation_id TEXT PRIMARY KEY,
+    reference TEXT NOT NULL DEFAULT '',
+    status TEXT NOT NULL DEFAULT 'held' CHECK (status IN ('held', 'committed', 'released', 'expired')),
+    expires_at TIMESTAMPTZ NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_stock_reservations_held ON stock_reservations (expires_at) WHERE status = 'held';
+
+-- deducted records whether the item tracked stock when it was reserved, so
+-- only what was taken is given back
+CREATE TABLE IF NOT EXISTS stock_reservation_items (
+    reservation_id TEXT NOT NULL REFERENCES stock_reservations(reservation_id) ON DELETE CASCADE,
+    item_id TEXT NOT NULL REFERENCES items(item_id) ON DELETE CASCADE,
+    quantity INT NOT NULL CHECK (quantity > 0),
+    deducted BOOLEAN NOT NULL,
+    PRIMARY KEY (reservation_id, item_id)
+);
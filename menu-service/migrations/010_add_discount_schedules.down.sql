@@ -0,0 +1,2 @@
+DROP FUNCTION IF EXISTS scheduled_discount(TEXT, TEXT);
+DROP TABLE IF EXISTS discounts;
@@ -0,0 +1,51 @@
+-- Discounts that start and end at set times, recur weekly (happy hours) or
+-- cover a whole category. Rows are never deleted so they double as history;
+-- manual rows record the immediate per-item discount kept on items.
+CREATE TABLE IF NOT EXISTS discounts (
+    discount_id TEXT PRIMARY KEY,
+    item_id TEXT REFERENCES items(item_id) ON DELETE CASCADE,
+    category_id TEXT REFERENCES category(category_id) ON DELETE CASCADE,
+    percentage NUMERIC(5, 2) NOT NULL CHECK (percentage > 0 AND percentage < 100),
+    starts_at TIMESTAMPTZ,
+    ends_at TIMESTAMPTZ,
+    -- weekdays count from 0 for Sunday; a window whose end is not after its
+    -- start runs past midnight into the next day
+    weekdays SMALLINT[],
+    start_time TIME,
+    end_time TIME,
+    manual BOOLEAN NOT NULL DEFAULT false,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    cancelled_at TIMESTAMPTZ,
+    CHECK ((item_id IS NULL) <> (category_id IS NULL)),
+    CHECK (starts_at IS NULL OR ends_at IS NULL OR ends_at > starts_at),
+    CHECK ((weekdays IS NULL) = (start_time IS NULL) AND (start_time IS NULL) = (end_time IS NULL))
+);
+
+CREATE INDEX IF NOT EXISTS idx_discounts_item_id ON discounts (item_id) WHERE item_id IS NOT NULL;
+CREATE INDEX IF NOT EXISTS idx_discounts_category_id ON discounts (category_id) WHERE category_id IS NOT NULL;
+
+-- Discounts already on items start the history
+INSERT INTO discounts (discount_id, item_id, percentage, starts_at, manual)
+SELECT gen_random_uuid()::text, item_id, discount_value, NOW(), true
+FROM items
+WHERE has_discount AND discount_value > 0 AND discount_value < 100;
+
+-- scheduled_discount mirrors models.Discount.ActiveAt: the largest scheduled
+-- discount running now on an item or its category. Recurring windows are read
+-- in the session time zone, which menu-service sets to the restaurant's.
+CREATE OR REPLACE FUNCTION scheduled_discount(p_item_id TEXT, p_category_id TEXT) RETURNS NUMERIC AS $$
+    SELECT COALESCE(MAX(d.percentage), 0)
+    FROM discounts d,
+         LATERAL (SELECT EXTRACT(DOW FROM LOCALTIMESTAMP)::int AS dow, LOCALTIME AS clock) l
+    WHERE (d.item_id = p_item_id OR d.category_id = p_category_id)
+      AND NOT d.manual
+      AND d.cancelled_at IS NULL
+      AND (d.starts_at IS NULL OR d.starts_at <= NOW())
+      AND (d.ends_at IS NULL OR d.ends_at > NOW())
+      AND (d.weekdays IS NULL
+           OR (d.start_time < d.end_time
+               AND l.dow = ANY(d.weekdays) AND l.clock >= d.start_time AND l.clock < d.end_time)
+           OR (d.start_time >= d.end_time
+               AND ((l.dow = ANY(d.weekdays) AND l.clock >= d.start_time)
+                    OR ((l.dow + 6) % 7 = ANY(d.weekdays) AND l.clock < d.end_time))))
+$$ LANGUAGE sql STABLE;
@@ -0,0 +1,34 @@
+-- Promo codes customers enter at checkout. value is a percentage or a fixed
+-- amount off the items in scope; an empty scope covers the whole order.
+CREATE TABLE IF NOT EXISTS promo_codes (
+    promo_id TEXT PRIMARY KEY,
+    code TEXT UNIQUE NOT NULL,
+    kind TEXT NOT NULL CHECK (kind IN ('percentage', 'fixed')),
+    value NUMERIC(10, 2) NOT NULL CHECK (value > 0),
+    min_order_value NUMERIC(10, 2) NOT NULL DEFAULT 0 CHECK (min_order_value >= 0),
+    max_uses INT CHECK (max_uses > 0),
+    max_uses_per_user INT CHECK (max_uses_per_user > 0),
+    starts_at TIMESTAMPTZ,
+    ends_at TIMESTAMPTZ,
+    item_ids TEXT[] NOT NULL DEFAULT '{}',
+    category_ids TEXT[] NOT NULL DEFAULT '{}',
+    is_active BOOLEAN NOT NULL DEFAULT true,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    CHECK (kind <> 'percentage' OR value < 100),
+    CHECK (starts_at IS NULL OR ends_at IS NULL OR ends_at > starts_at)
+);
+
+-- A redemption counts towards the usage limits until it is released, e.g.
+-- because its order was cancelled
+CREATE TABLE IF NOT EXISTS promo_redemptions (
+    redemption_id TEXT PRIMARY KEY,
+    promo_id TEXT NOT NULL REFERENCES promo_codes(promo_id) ON DELETE CASCADE,
+    user_id TEXT NOT NULL,
+    reference TEXT NOT NULL DEFAULT '',
+    discount NUMERIC(10, 2) NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    released_at TIMESTAMPTZ
+);
+
+CREATE INDEX IF NOT EXISTS idx_promo_redemptions_active
+    ON promo_redemptions (promo_id, user_id) WHERE released_at IS NULL;
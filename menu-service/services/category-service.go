@@ -0,0 +1,181 @@
+package services
+
+import (
+	"errors"
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+
+	"github.com/darkhyper24/blaban/menu-service/internal/db"
+	"github.com/darkhyper24/blaban/menu-service/internal/models"
+)
+
+// categoryResponse is the JSON form of a category
+func (s *MenuService) categoryResponse(category models.Category) fiber.Map {
+	return fiber.Map{
+		"id":       category.ID,
+		"name":     category.Name,
+		"picture":  category.Picture,
+		"position": category.Position,
+		"image":    s.imageResponse(category.Image),
+	}
+}
+
+// categoryError maps category errors to responses
+func categoryError(c *fiber.Ctx, err error, message string) error {
+	switch {
+	case errors.Is(err, db.ErrCategoryNotFound):
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": "Category not found",
+		})
+	case errors.Is(err, db.ErrCategoryExists), errors.Is(err, db.ErrCategoryNotEmpty):
+		return c.Status(fiber.StatusConflict).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	case errors.Is(err, db.ErrInvalidCategoryOrder), errors.Is(err, db.ErrReassignToSelf), errors.Is(err, db.ErrReassignTargetMissing):
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+	return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+		"error": message + ": " + err.Error(),
+	})
+}
+
+// HandleCreateCategory creates a category, listed after the existing ones
+func (s *MenuService) HandleCreateCategory(c *fiber.Ctx) error {
+	if err := s.Verifier.VerifyManagerRole(c.Get("Authorization")); err != nil {
+		return managerError(c, err)
+	}
+
+	var req struct {
+		Name    string `json:"name"`
+		Picture string `json:"picture"`
+	}
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid request body",
+		})
+	}
+
+	name := strings.TrimSpace(req.Name)
+	if name == "" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Category name is required",
+		})
+	}
+
+	category, err := s.DB.CreateCategory(name, strings.TrimSpace(req.Picture))
+	if err != nil {
+		return categoryError(c, err, "Failed to create category")
+	}
+
+	return c.Status(fiber.StatusCreated).JSON(fiber.Map{
+		"message":  "Category created successfully",
+		"category": s.categoryResponse(category),
+	})
+}
+
+// HandleUpdateCategory renames a category or changes its picture
+func (s *MenuService) HandleUpdateCategory(c *fiber.Ctx) error {
+	if err := s.Verifier.VerifyManagerRole(c.Get("Authorization")); err != nil {
+		return managerError(c, err)
+	}
+
+	var update struct {
+		Name    *string `json:"name"`
+		Picture *string `json:"picture"`
+	}
+	if err := c.BodyParser(&update); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid request body",
+		})
+	}
+
+	category, err := s.DB.GetCategoryByID(c.Params("id"))
+	if err != nil {
+		return categoryError(c, err, "Failed to fetch category")
+	}
+
+	if update.Name != nil {
+		name := strings.TrimSpace(*update.Name)
+		if name == "" {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": "Category name cannot be empty",
+			})
+		}
+		category.Name = name
+	}
+	if update.Picture != nil {
+		category.Picture = strings.TrimSpace(*update.Picture)
+	}
+
+	if err := s.DB.UpdateCategory(category); err != nil {
+		return categoryError(c, err, "Failed to update category")
+	}
+
+	return c.JSON(fiber.Map{
+		"message":  "Category updated successfully",
+		"category": s.categoryResponse(category),
+	})
+}
+
+// HandleReorderCategories sets the display order of all categories
+func (s *MenuService) HandleReorderCategories(c *fiber.Ctx) error {
+	if err := s.Verifier.VerifyManagerRole(c.Get("Authorization")); err != nil {
+		return managerError(c, err)
+	}
+
+	var req struct {
+		CategoryIDs []string `json:"category_ids"`
+	}
+	if err := c.BodyParser(&req); err != nil || len(req.CategoryIDs) == 0 {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "category_ids must list every category in display order",
+		})
+	}
+
+	if err := s.DB.ReorderCategories(req.CategoryIDs); err != nil {
+		return categoryError(c, err, "Failed to reorder categories")
+	}
+
+	return s.HandleGetCategories(c)
+}
+
+// HandleDeleteCategory deletes a category. A category with items is only
+// deleted when reassign_to names the category its items move to.
+func (s *MenuService) HandleDeleteCategory(c *fiber.Ctx) error {
+	if err := s.Verifier.VerifyManagerRole(c.Get("Authorization")); err != nil {
+		return managerError(c, err)
+	}
+
+	categoryID := c.Params("id")
+	reassignTo := c.Query("reassign_to")
+
+	// The image is looked up first since the row is gone once deleted
+	var image *models.Image
+	if s.Images != nil {
+		category, err := s.DB.GetCategoryByID(categoryID)
+		if err != nil {
+			return categoryError(c, err, "Failed to fetch category")
+		}
+		image = category.Image
+	}
+
+	moved, err := s.DB.DeleteCategory(categoryID, reassignTo)
+	if errors.Is(err, db.ErrCategoryNotEmpty) {
+		return c.Status(fiber.StatusConflict).JSON(fiber.Map{
+			"error": "Category still has items; pass reassign_to with the category to move them to",
+		})
+	}
+	if err != nil {
+		return categoryError(c, err, "Failed to delete category")
+	}
+	s.deleteImage(image)
+
+	return c.JSON(fiber.Map{
+		"message":     "Category deleted successfully",
+		"category_id": categoryID,
+		"items_moved": moved,
+	})
+}
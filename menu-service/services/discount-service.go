@@ -0,0 +1,145 @@
+package services
+
+import (
+	"errors"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+
+	"github.com/darkhyper24/blaban/menu-service/internal/db"
+	"github.com/darkhyper24/blaban/menu-service/internal/models"
+)
+
+// discountView is a discount with its status at the time of the request
+type discountView struct {
+	models.Discount
+	Status string `json:"status"`
+}
+
+func discountsResponse(discounts []models.Discount, now time.Time) []discountView {
+	views := make([]discountView, len(discounts))
+	for i, discount := range discounts {
+		views[i] = discountView{Discount: discount, Status: discount.StatusAt(now)}
+	}
+	return views
+}
+
+// HandleCreateDiscount schedules a discount on an item or a whole category.
+// Without starts_at it starts right away and without ends_at it runs until
+// cancelled; a recurrence limits it to a weekly window such as a happy hour.
+func (s *MenuService) HandleCreateDiscount(c *fiber.Ctx) error {
+	if err := s.Verifier.VerifyManagerRole(c.Get("Authorization")); err != nil {
+		return managerError(c, err)
+	}
+
+	var req struct {
+		ItemID     string             `json:"item_id"`
+		CategoryID string             `json:"category_id"`
+		Percentage float64            `json:"percentage"`
+		StartsAt   *time.Time         `json:"starts_at"`
+		EndsAt     *time.Time         `json:"ends_at"`
+		Recurrence *models.Recurrence `json:"recurrence"`
+	}
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid request body",
+		})
+	}
+
+	discount := models.Discount{
+		ItemID:     req.ItemID,
+		CategoryID: req.CategoryID,
+		Percentage: req.Percentage,
+		StartsAt:   req.StartsAt,
+		EndsAt:     req.EndsAt,
+		Recurrence: req.Recurrence,
+	}
+	if err := discount.Validate(); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+	now := time.Now()
+	if discount.EndsAt != nil && !discount.EndsAt.After(now) {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "ends_at must be in the future",
+		})
+	}
+
+	created, err := s.DB.CreateDiscount(discount)
+	if errors.Is(err, db.ErrMenuItemNotFound) {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": "Menu item not found",
+		})
+	}
+	if errors.Is(err, db.ErrCategoryNotFound) {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": "Category not found",
+		})
+	}
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to create discount: " + err.Error(),
+		})
+	}
+
+	return c.Status(fiber.StatusCreated).JSON(fiber.Map{
+		"message":  "Discount created successfully",
+		"discount": discountView{Discount: created, Status: created.StatusAt(now)},
+	})
+}
+
+// HandleListDiscounts lists running and upcoming discounts, or past ones when
+// history=true, optionally for one item or category
+func (s *MenuService) HandleListDiscounts(c *fiber.Ctx) error {
+	if err := s.Verifier.VerifyManagerRole(c.Get("Authorization")); err != nil {
+		return managerError(c, err)
+	}
+
+	filter := db.DiscountFilter{
+		ItemID:     c.Query("item_id"),
+		CategoryID: c.Query("category_id"),
+		History:    c.QueryBool("history"),
+		Limit:      c.QueryInt("limit", db.DefaultPageSize),
+	}
+	discounts, err := s.DB.ListDiscounts(filter)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to list discounts: " + err.Error(),
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"discounts": discountsResponse(discounts, time.Now()),
+		"history":   filter.History,
+	})
+}
+
+// HandleCancelDiscount ends a discount now. It stays in the history.
+func (s *MenuService) HandleCancelDiscount(c *fiber.Ctx) error {
+	if err := s.Verifier.VerifyManagerRole(c.Get("Authorization")); err != nil {
+		return managerError(c, err)
+	}
+
+	discount, err := s.DB.CancelDiscount(c.Params("id"))
+	if errors.Is(err, db.ErrDiscountNotFound) {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": "Discount not found",
+		})
+	}
+	if errors.Is(err, db.ErrDiscountEnded) {
+		return c.Status(fiber.StatusConflict).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to cancel discount: " + err.Error(),
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"message":  "Discount cancelled successfully",
+		"discount": discountView{Discount: discount, Status: discount.StatusAt(time.Now())},
+	})
+}
@@ -0,0 +1,264 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"log"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+
+	"github.com/darkhyper24/blaban/menu-service/internal/db"
+	"github.com/darkhyper24/blaban/menu-service/internal/images"
+	"github.com/darkhyper24/blaban/menu-service/internal/models"
+)
+
+// imageResponse is the JSON form of an image: the URL of every variant, or
+// nil when there is no image
+func (s *MenuService) imageResponse(image *models.Image) fiber.Map {
+	if image == nil || s.Images == nil {
+		return nil
+	}
+	return fiber.Map{
+		"url":                s.Images.URL(image.Full),
+		"webp_url":           s.Images.URL(image.FullWebP),
+		"thumbnail_url":      s.Images.URL(image.Thumbnail),
+		"thumbnail_webp_url": s.Images.URL(image.ThumbnailWebP),
+	}
+}
+
+// imageError maps upload errors to responses
+func imageError(c *fiber.Ctx, err error) error {
+	switch {
+	case errors.Is(err, images.ErrTooLarge):
+		return c.Status(fiber.StatusRequestEntityTooLarge).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	case errors.Is(err, images.ErrUnsupportedType):
+		return c.Status(fiber.StatusUnsupportedMediaType).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	case errors.Is(err, images.ErrInvalidImage), errors.Is(err, images.ErrTooManyPixels):
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+	return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+		"error": "Failed to process image: " + err.Error(),
+	})
+}
+
+// storageUnavailable responds that images cannot be stored
+func storageUnavailable(c *fiber.Ctx) error {
+	return c.Status(fiber.StatusServiceUnavailable).JSON(fiber.Map{
+		"error": "Image storage is not configured",
+	})
+}
+
+// readUpload reads the "image" file of a multipart request and renders its
+// variants under prefix
+func readUpload(c *fiber.Ctx, prefix string) ([]images.Variant, *models.Image, error) {
+	header, err := c.FormFile("image")
+	if err != nil {
+		return nil, nil, images.ErrInvalidImage
+	}
+	if header.Size > images.MaxUploadSize {
+		return nil, nil, images.ErrTooLarge
+	}
+
+	file, err := header.Open()
+	if err != nil {
+		return nil, nil, err
+	}
+	defer file.Close()
+
+	// Read one byte past the limit so an understated size is still caught
+	data, err := io.ReadAll(io.LimitReader(file, images.MaxUploadSize+1))
+	if err != nil {
+		return nil, nil, err
+	}
+	return images.Process(data, prefix+"/"+uuid.New().String())
+}
+
+// storeVariants uploads every variant, removing the ones already stored if
+// any upload fails
+func (s *MenuService) storeVariants(ctx context.Context, variants []images.Variant) error {
+	for i, variant := range variants {
+		err := s.Images.Put(ctx, variant.Key, bytes.NewReader(variant.Data), int64(len(variant.Data)), variant.ContentType)
+		if err != nil {
+			for _, stored := range variants[:i] {
+				s.Images.Delete(ctx, stored.Key)
+			}
+			return err
+		}
+	}
+	return nil
+}
+
+// deleteImage removes the stored variants of an image. Failures only leave
+// unreferenced files behind, so they are logged rather than returned.
+func (s *MenuService) deleteImage(image *models.Image) {
+	if image == nil || s.Images == nil {
+		return
+	}
+	for _, key := range image.Keys() {
+		if err := s.Images.Delete(context.Background(), key); err != nil {
+			log.Printf("Failed to delete image %s: %v", key, err)
+		}
+	}
+}
+
+// HandleUploadMenuItemImage replaces a menu item's image with the uploaded one
+func (s *MenuService) HandleUploadMenuItemImage(c *fiber.Ctx) error {
+	if err := s.Verifier.VerifyManagerRole(c.Get("Authorization")); err != nil {
+		return managerError(c, err)
+	}
+	if s.Images == nil {
+		return storageUnavailable(c)
+	}
+
+	itemID := c.Params("id")
+	if _, _, err := s.DB.GetMenuItemByID(itemID); err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": "Menu item not found",
+		})
+	}
+
+	variants, image, err := readUpload(c, "items/"+itemID)
+	if err != nil {
+		return imageError(c, err)
+	}
+	if err := s.storeVariants(c.Context(), variants); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to store image: " + err.Error(),
+		})
+	}
+
+	previous, err := s.DB.SetMenuItemImage(itemID, image)
+	if err != nil {
+		s.deleteImage(image)
+		if errors.Is(err, db.ErrMenuItemNotFound) {
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+				"error": "Menu item not found",
+			})
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to save image: " + err.Error(),
+		})
+	}
+	s.deleteImage(previous)
+
+	return c.JSON(fiber.Map{
+		"message": "Image uploaded successfully",
+		"item_id": itemID,
+		"image":   s.imageResponse(image),
+	})
+}
+
+// HandleDeleteMenuItemImage removes a menu item's image
+func (s *MenuService) HandleDeleteMenuItemImage(c *fiber.Ctx) error {
+	if err := s.Verifier.VerifyManagerRole(c.Get("Authorization")); err != nil {
+		return managerError(c, err)
+	}
+	if s.Images == nil {
+		return storageUnavailable(c)
+	}
+
+	itemID := c.Params("id")
+	previous, err := s.DB.SetMenuItemImage(itemID, nil)
+	if errors.Is(err, db.ErrMenuItemNotFound) {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": "Menu item not found",
+		})
+	}
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to delete image: " + err.Error(),
+		})
+	}
+	s.deleteImage(previous)
+
+	return c.JSON(fiber.Map{
+		"message": "Image deleted successfully",
+		"item_id": itemID,
+	})
+}
+
+// HandleUploadCategoryImage replaces a category's image with the uploaded one
+// and makes it the category's picture
+func (s *MenuService) HandleUploadCategoryImage(c *fiber.Ctx) error {
+	if err := s.Verifier.VerifyManagerRole(c.Get("Authorization")); err != nil {
+		return managerError(c, err)
+	}
+	if s.Images == nil {
+		return storageUnavailable(c)
+	}
+
+	category, err := s.DB.GetCategoryByID(c.Params("id"))
+	if err != nil {
+		return categoryError(c, err, "Failed to fetch category")
+	}
+
+	variants, image, err := readUpload(c, "categories/"+category.ID)
+	if err != nil {
+		return imageError(c, err)
+	}
+	if err := s.storeVariants(c.Context(), variants); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to store image: " + err.Error(),
+		})
+	}
+
+	picture := s.Images.URL(image.Full)
+	previous, err := s.DB.SetCategoryImage(category.ID, image, picture)
+	if err != nil {
+		s.deleteImage(image)
+		return categoryError(c, err, "Failed to save image")
+	}
+	s.deleteImage(previous)
+
+	category.Image = image
+	category.Picture = picture
+	return c.JSON(fiber.Map{
+		"message":  "Image uploaded successfully",
+		"category": s.categoryResponse(category),
+	})
+}
+
+// HandleDeleteCategoryImage removes a category's image. The picture is cleared
+// too unless it was since pointed somewhere else.
+func (s *MenuService) HandleDeleteCategoryImage(c *fiber.Ctx) error {
+	if err := s.Verifier.VerifyManagerRole(c.Get("Authorization")); err != nil {
+		return managerError(c, err)
+	}
+	if s.Images == nil {
+		return storageUnavailable(c)
+	}
+
+	category, err := s.DB.GetCategoryByID(c.Params("id"))
+	if err != nil {
+		return categoryError(c, err, "Failed to fetch category")
+	}
+	if category.Image == nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": "Category has no image",
+		})
+	}
+
+	if category.Picture == s.Images.URL(category.Image.Full) {
+		category.Picture = ""
+	}
+	previous, err := s.DB.SetCategoryImage(category.ID, nil, category.Picture)
+	if err != nil {
+		return categoryError(c, err, "Failed to delete image")
+	}
+	s.deleteImage(previous)
+
+	category.Image = nil
+	return c.JSON(fiber.Map{
+		"message":  "Image deleted successfully",
+		"category": s.categoryResponse(category),
+	})
+}
@@ -1,10 +1,19 @@
 package services
 
 import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
 	"github.com/gofiber/fiber/v2"
 	"github.com/google/uuid"
 
+	"github.com/darkhyper24/blaban/shared/auth"
+
 	"github.com/darkhyper24/blaban/menu-service/internal/db"
+	"github.com/darkhyper24/blaban/menu-service/internal/images"
 	"github.com/darkhyper24/blaban/menu-service/internal/models"
 )
 
@@ -12,15 +21,15 @@ import (
 type MenuService struct {
 	DB       db.MenuDBOperations
 	Verifier db.AuthVerifier
+	// Images stores uploaded images; uploads are refused while it is nil
+	Images images.BlobStore
 }
 
 //  creates a new menu service with DB dependency injected
 func NewMenuService(dbOps db.MenuDBOperations, verifier ...db.AuthVerifier) *MenuService {
 	var authVerifier db.AuthVerifier
-	if len(verifier) > 0 && verifier[0] != nil {
+	if len(verifier) > 0 {
 		authVerifier = verifier[0]
-	} else {
-		authVerifier = &db.DefaultAuthVerifier{}
 	}
 
 	return &MenuService{
@@ -29,7 +38,127 @@ func NewMenuService(dbOps db.MenuDBOperations, verifier ...db.AuthVerifier) *Men
 	}
 }
 
-// HandleGetCategories retrieves all menu categories
+// managerError responds to a failed VerifyManagerRole check. Managers who
+// have not completed multi-factor authentication get 403 rather than 401.
+func managerError(c *fiber.Ctx, err error) error {
+	status := fiber.StatusUnauthorized
+	if errors.Is(err, auth.ErrMFARequired) {
+		status = fiber.StatusForbidden
+	}
+	return c.Status(status).JSON(fiber.Map{
+		"error": err.Error(),
+	})
+}
+
+// parsePageRequest reads the limit, cursor and sort query parameters, using
+// defaultSort when no sort is given
+func parsePageRequest(c *fiber.Ctx, defaultSort string) (db.PageRequest, error) {
+	limit := 0
+	if raw := c.Query("limit"); raw != "" {
+		parsed, err := strconv.Atoi(raw)
+		if err != nil || parsed <= 0 {
+			return db.PageRequest{}, errors.New("limit must be a positive integer")
+		}
+		limit = parsed
+	}
+
+	page, err := db.NewPageRequest(limit, c.Query("cursor"), c.Query("sort", defaultSort))
+	if err != nil {
+		return db.PageRequest{}, errors.New("sort must be one of category, name, -name, price, -price, effective_price, -effective_price, newest or, when searching, relevance")
+	}
+	return page, nil
+}
+
+// paginationResponse describes a returned page to the client
+func paginationResponse(page db.PageRequest, info db.PageInfo) fiber.Map {
+	response := fiber.Map{
+		"limit":       page.Limit,
+		"sort":        page.Sort,
+		"total":       info.Total,
+		"next_cursor": nil,
+		"prev_cursor": nil,
+	}
+	if info.NextCursor != "" {
+		response["next_cursor"] = info.NextCursor
+	}
+	if info.PrevCursor != "" {
+		response["prev_cursor"] = info.PrevCursor
+	}
+	return response
+}
+
+// pageError maps a failed page query to a response, treating a bad cursor or
+// a sort the query does not support as a client error
+func pageError(c *fiber.Ctx, err error, message string) error {
+	if errors.Is(err, db.ErrInvalidCursor) {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid cursor",
+		})
+	}
+	if errors.Is(err, db.ErrInvalidSort) {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Sort relevance is only available when searching",
+		})
+	}
+	return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+		"error": message + ": " + err.Error(),
+	})
+}
+
+// validateMetadata checks an item's dietary metadata and returns its
+// allergens and dietary tags in normalized form
+func validateMetadata(allergens, tags []string, nutrition *models.Nutrition, spiceLevel int) ([]string, []string, error) {
+	allergens, err := models.NormalizeAllergens(allergens)
+	if err != nil {
+		return nil, nil, err
+	}
+	tags, err = models.NormalizeDietaryTags(tags)
+	if err != nil {
+		return nil, nil, err
+	}
+	if err := nutrition.Validate(); err != nil {
+		return nil, nil, err
+	}
+	if spiceLevel < 0 || spiceLevel > models.MaxSpiceLevel {
+		return nil, nil, fmt.Errorf("spice_level must be between 0 and %d", models.MaxSpiceLevel)
+	}
+	return allergens, tags, nil
+}
+
+// parseMetadataFilter reads the tags, exclude_allergens, max_spice_level and
+// max_calories query parameters. Lists are comma separated.
+func parseMetadataFilter(c *fiber.Ctx) (db.MetadataFilter, error) {
+	var filter db.MetadataFilter
+	var err error
+
+	if raw := c.Query("tags"); raw != "" {
+		if filter.Tags, err = models.NormalizeDietaryTags(strings.Split(raw, ",")); err != nil {
+			return filter, err
+		}
+	}
+	if raw := c.Query("exclude_allergens"); raw != "" {
+		if filter.ExcludeAllergens, err = models.NormalizeAllergens(strings.Split(raw, ",")); err != nil {
+			return filter, err
+		}
+	}
+	if raw := c.Query("max_spice_level"); raw != "" {
+		level, err := strconv.Atoi(raw)
+		if err != nil || level < 0 {
+			return filter, errors.New("max_spice_level must be a non-negative integer")
+		}
+		filter.MaxSpiceLevel = &level
+	}
+	if raw := c.Query("max_calories"); raw != "" {
+		calories, err := strconv.Atoi(raw)
+		if err != nil || calories < 0 {
+			return filter, errors.New("max_calories must be a non-negative integer")
+		}
+		filter.MaxCalories = &calories
+	}
+	return filter, nil
+}
+
+// HandleGetCategories retrieves all menu categories in display order
 func (s *MenuService) HandleGetCategories(c *fiber.Ctx) error {
 	categories, err := s.DB.GetAllCategories()
 	if err != nil {
@@ -40,11 +169,7 @@ func (s *MenuService) HandleGetCategories(c *fiber.Ctx) error {
 
 	categoriesMap := make([]fiber.Map, len(categories))
 	for i, category := range categories {
-		categoriesMap[i] = fiber.Map{
-			"id":      category.ID,
-			"name":    category.Name,
-			"picture": category.Picture,
-		}
+		categoriesMap[i] = s.categoryResponse(category)
 	}
 
 	response := fiber.Map{
@@ -54,50 +179,60 @@ func (s *MenuService) HandleGetCategories(c *fiber.Ctx) error {
 	return c.JSON(response)
 }
 
-// HandleGetMenu gets all menu items and the category they belong to
+// HandleGetMenu gets a page of menu items grouped by the category they belong
+// to. Categories appear in the order their first item has on the page.
 func (s *MenuService) HandleGetMenu(c *fiber.Ctx) error {
-	items, categoryNames, err := s.DB.GetAllMenuItems()
+	page, err := parsePageRequest(c, db.SortCategory)
 	if err != nil {
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-			"error": "Failed to fetch menu items: " + err.Error(),
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": err.Error(),
 		})
 	}
 
-	menuByCategory := make(map[string]fiber.Map)
+	items, categoryNames, info, err := s.DB.GetAllMenuItems(page)
+	if err != nil {
+		return pageError(c, err, "Failed to fetch menu items")
+	}
 
-	for i, item := range items {
-		categoryName := categoryNames[i]
+	categories := []fiber.Map{}
+	categoryIndex := make(map[string]int)
 
-		if _, exists := menuByCategory[item.CategoryID]; !exists {
-			menuByCategory[item.CategoryID] = fiber.Map{
+	for i, item := range items {
+		index, exists := categoryIndex[item.CategoryID]
+		if !exists {
+			index = len(categories)
+			categoryIndex[item.CategoryID] = index
+			categories = append(categories, fiber.Map{
 				"id":    item.CategoryID,
-				"name":  categoryName,
+				"name":  categoryNames[i],
 				"items": []fiber.Map{},
-			}
+			})
 		}
 
-		categoryItems := menuByCategory[item.CategoryID]["items"].([]fiber.Map)
-		categoryItems = append(categoryItems, fiber.Map{
+		categoryItems := categories[index]["items"].([]fiber.Map)
+		categories[index]["items"] = append(categoryItems, fiber.Map{
 			"id":              item.ID,
 			"name":            item.Name,
+			"description":     item.Description,
 			"price":           item.Price,
 			"effective_price": item.GetEffectivePrice(),
 			"is_available":    item.IsAvailable,
 			"quantity":        item.Quantity,
-			"has_discount":    item.HasDiscount,
-			"discount_value":  item.DiscountValue,
+			"track_stock":     item.TrackStock,
+			"has_discount":    item.CurrentDiscount() > 0,
+			"discount_value":  item.CurrentDiscount(),
+			"image":           s.imageResponse(item.Image),
+			"allergens":       item.Allergens,
+			"dietary_tags":    item.DietaryTags,
+			"nutrition":       item.Nutrition,
+			"spice_level":     item.SpiceLevel,
+			"has_options":     item.HasOptions,
 		})
-
-		menuByCategory[item.CategoryID]["items"] = categoryItems
-	}
-
-	categories := make([]fiber.Map, 0, len(menuByCategory))
-	for _, category := range menuByCategory {
-		categories = append(categories, category)
 	}
 
 	response := fiber.Map{
-		"menu": categories,
+		"menu":       categories,
+		"pagination": paginationResponse(page, info),
 	}
 
 	return c.JSON(response)
@@ -118,12 +253,22 @@ func (s *MenuService) HandleGetMenuItem(c *fiber.Ctx) error {
 		"item": fiber.Map{
 			"id":              item.ID,
 			"name":            item.Name,
+			"description":     item.Description,
 			"price":           item.Price,
 			"effective_price": item.GetEffectivePrice(),
 			"is_available":    item.IsAvailable,
 			"quantity":        item.Quantity,
-			"has_discount":    item.HasDiscount,
-			"discount_value":  item.DiscountValue,
+			"track_stock":     item.TrackStock,
+			"has_discount":    item.CurrentDiscount() > 0,
+			"discount_value":  item.CurrentDiscount(),
+			"image":           s.imageResponse(item.Image),
+			"allergens":       item.Allergens,
+			"dietary_tags":    item.DietaryTags,
+			"nutrition":       item.Nutrition,
+			"spice_level":     item.SpiceLevel,
+			"has_options":     item.HasOptions,
+			"option_groups":   optionGroupsResponse(item.OptionGroups),
+			"discounts":       discountsResponse(item.Discounts, time.Now()),
 			"category": fiber.Map{
 				"id":   item.CategoryID,
 				"name": categoryName,
@@ -134,60 +279,108 @@ func (s *MenuService) HandleGetMenuItem(c *fiber.Ctx) error {
 	return c.JSON(response)
 }
 
-// HandleSearchItems searches menu items by name
+// HandleSearchItems runs a full-text search over item names, descriptions and
+// categories, tolerating typos, and ranks the matches by relevance
 func (s *MenuService) HandleSearchItems(c *fiber.Ctx) error {
-	query := c.Query("q")
+	query := strings.TrimSpace(c.Query("q"))
 	if query == "" {
 		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
 			"error": "Search query is required",
 		})
 	}
 
-	items, categoryNames, categoryPics, err := s.DB.SearchMenuItems(query)
+	page, err := parsePageRequest(c, db.SortRelevance)
 	if err != nil {
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-			"error": "Failed to search menu items: " + err.Error(),
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": err.Error(),
 		})
 	}
 
+	results, info, err := s.DB.SearchMenuItems(query, page)
+	if err != nil {
+		return pageError(c, err, "Failed to search menu items")
+	}
+
 	searchResults := []fiber.Map{}
-	for i, item := range items {
+	for _, result := range results {
+		item := result.Item
 		searchResults = append(searchResults, fiber.Map{
 			"id":              item.ID,
 			"name":            item.Name,
+			"description":     item.Description,
 			"price":           item.Price,
 			"effective_price": item.GetEffectivePrice(),
-			"has_discount":    item.HasDiscount,
-			"discount_value":  item.DiscountValue,
+			"has_discount":    item.CurrentDiscount() > 0,
+			"discount_value":  item.CurrentDiscount(),
+			"image":           s.imageResponse(item.Image),
+			"allergens":       item.Allergens,
+			"dietary_tags":    item.DietaryTags,
+			"nutrition":       item.Nutrition,
+			"spice_level":     item.SpiceLevel,
+			"has_options":     item.HasOptions,
+			"highlight":       result.Highlight,
+			"snippet":         result.Snippet,
 			"category": fiber.Map{
 				"id":      item.CategoryID,
-				"name":    categoryNames[i],
-				"picture": categoryPics[i],
+				"name":    result.CategoryName,
+				"picture": result.CategoryPicture,
 			},
 		})
 	}
 
 	return c.JSON(fiber.Map{
-		"results": searchResults,
-		"count":   len(searchResults),
+		"results":    searchResults,
+		"count":      len(searchResults),
+		"pagination": paginationResponse(page, info),
 	})
 }
 
-// HandleCreateMenuItem creates a new menu item
-func (s *MenuService) HandleCreateMenuItem(c *fiber.Ctx) error {
-	err := s.Verifier.VerifyManagerRole(c.Get("Authorization"))
+// HandleSuggestItems returns autocomplete suggestions for a partially typed query
+func (s *MenuService) HandleSuggestItems(c *fiber.Ctx) error {
+	query := strings.TrimSpace(c.Query("q"))
+	if query == "" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Search query is required",
+		})
+	}
+
+	limit := c.QueryInt("limit", db.DefaultSuggestLimit)
+	if limit <= 0 {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "limit must be a positive integer",
+		})
+	}
+
+	suggestions, err := s.DB.SuggestMenuItems(query, limit)
 	if err != nil {
-		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
-			"error": err.Error(),
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to suggest menu items: " + err.Error(),
 		})
 	}
 
+	return c.JSON(fiber.Map{
+		"suggestions": suggestions,
+	})
+}
+
+// HandleCreateMenuItem creates a new menu item
+func (s *MenuService) HandleCreateMenuItem(c *fiber.Ctx) error {
+	if err := s.Verifier.VerifyManagerRole(c.Get("Authorization")); err != nil {
+		return managerError(c, err)
+	}
+
 	var requestItem struct {
-		Name         string  `json:"name"`
-		Price        float64 `json:"price"`
-		CategoryName string  `json:"category_name"`
-		Quantity     int     `json:"quantity"`
-		IsAvailable  bool    `json:"is_available"`
+		Name         string            `json:"name"`
+		Description  string            `json:"description"`
+		Price        float64           `json:"price"`
+		CategoryName string            `json:"category_name"`
+		Quantity     int               `json:"quantity"`
+		TrackStock   bool              `json:"track_stock"`
+		IsAvailable  bool              `json:"is_available"`
+		Allergens    []string          `json:"allergens"`
+		DietaryTags  []string          `json:"dietary_tags"`
+		Nutrition    *models.Nutrition `json:"nutrition"`
+		SpiceLevel   int               `json:"spice_level"`
 	}
 
 	if err := c.BodyParser(&requestItem); err != nil {
@@ -202,6 +395,13 @@ func (s *MenuService) HandleCreateMenuItem(c *fiber.Ctx) error {
 		})
 	}
 
+	allergens, tags, err := validateMetadata(requestItem.Allergens, requestItem.DietaryTags, requestItem.Nutrition, requestItem.SpiceLevel)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
 	categoryID, err := s.DB.GetCategoryID(requestItem.CategoryName)
 	if err != nil {
 		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
@@ -220,12 +420,18 @@ func (s *MenuService) HandleCreateMenuItem(c *fiber.Ctx) error {
 	item := models.MenuItem{
 		ID:            itemID,
 		Name:          requestItem.Name,
+		Description:   requestItem.Description,
 		Price:         requestItem.Price,
 		IsAvailable:   requestItem.IsAvailable,
 		Quantity:      requestItem.Quantity,
+		TrackStock:    requestItem.TrackStock,
 		HasDiscount:   false,
 		DiscountValue: 0,
 		CategoryID:    categoryID,
+		Allergens:     allergens,
+		DietaryTags:   tags,
+		Nutrition:     requestItem.Nutrition,
+		SpiceLevel:    requestItem.SpiceLevel,
 	}
 
 	_, err = s.DB.CreateMenuItem(item, categoryID)
@@ -240,10 +446,16 @@ func (s *MenuService) HandleCreateMenuItem(c *fiber.Ctx) error {
 		"item": fiber.Map{
 			"id":           itemID,
 			"name":         item.Name,
+			"description":  item.Description,
 			"price":        item.Price,
 			"is_available": item.IsAvailable,
 			"quantity":     item.Quantity,
+			"track_stock":  item.TrackStock,
 			"has_discount": false,
+			"allergens":    item.Allergens,
+			"dietary_tags": item.DietaryTags,
+			"nutrition":    item.Nutrition,
+			"spice_level":  item.SpiceLevel,
 			"category_id":  categoryID,
 		},
 	})
@@ -251,11 +463,8 @@ func (s *MenuService) HandleCreateMenuItem(c *fiber.Ctx) error {
 
 // HandleUpdateMenuItem updates an existing menu item
 func (s *MenuService) HandleUpdateMenuItem(c *fiber.Ctx) error {
-	err := s.Verifier.VerifyManagerRole(c.Get("Authorization"))
-	if err != nil {
-		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
-			"error": err.Error(),
-		})
+	if err := s.Verifier.VerifyManagerRole(c.Get("Authorization")); err != nil {
+		return managerError(c, err)
 	}
 
 	itemID := c.Params("id")
@@ -273,11 +482,17 @@ func (s *MenuService) HandleUpdateMenuItem(c *fiber.Ctx) error {
 	}
 
 	var update struct {
-		Name         string  `json:"name"`
-		Price        float64 `json:"price"`
-		CategoryName string  `json:"category_name"`
-		Quantity     *int    `json:"quantity"`
-		IsAvailable  *bool   `json:"is_available"`
+		Name         string            `json:"name"`
+		Description  *string           `json:"description"`
+		Price        float64           `json:"price"`
+		CategoryName string            `json:"category_name"`
+		Quantity     *int              `json:"quantity"`
+		TrackStock   *bool             `json:"track_stock"`
+		IsAvailable  *bool             `json:"is_available"`
+		Allergens    *[]string         `json:"allergens"`
+		DietaryTags  *[]string         `json:"dietary_tags"`
+		Nutrition    *models.Nutrition `json:"nutrition"`
+		SpiceLevel   *int              `json:"spice_level"`
 	}
 
 	if err := c.BodyParser(&update); err != nil {
@@ -302,18 +517,55 @@ func (s *MenuService) HandleUpdateMenuItem(c *fiber.Ctx) error {
 		currentItem.Name = update.Name
 	}
 
+	if update.Description != nil {
+		currentItem.Description = *update.Description
+	}
+
 	if update.Price > 0 {
 		currentItem.Price = update.Price
 	}
 
 	if update.Quantity != nil {
+		if *update.Quantity < 0 {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": "Quantity cannot be negative",
+			})
+		}
 		currentItem.Quantity = *update.Quantity
 	}
 
+	if update.TrackStock != nil {
+		currentItem.TrackStock = *update.TrackStock
+	}
+
 	if update.IsAvailable != nil {
 		currentItem.IsAvailable = *update.IsAvailable
 	}
 
+	if update.Allergens != nil {
+		currentItem.Allergens = *update.Allergens
+	}
+
+	if update.DietaryTags != nil {
+		currentItem.DietaryTags = *update.DietaryTags
+	}
+
+	if update.Nutrition != nil {
+		currentItem.Nutrition = update.Nutrition
+	}
+
+	if update.SpiceLevel != nil {
+		currentItem.SpiceLevel = *update.SpiceLevel
+	}
+
+	currentItem.Allergens, currentItem.DietaryTags, err = validateMetadata(
+		currentItem.Allergens, currentItem.DietaryTags, currentItem.Nutrition, currentItem.SpiceLevel)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
 	currentItem.CategoryID = updatedCategoryID
 
 	err = s.DB.UpdateMenuItem(currentItem)
@@ -328,12 +580,20 @@ func (s *MenuService) HandleUpdateMenuItem(c *fiber.Ctx) error {
 		"item": fiber.Map{
 			"id":              itemID,
 			"name":            currentItem.Name,
+			"description":     currentItem.Description,
 			"price":           currentItem.Price,
 			"effective_price": currentItem.GetEffectivePrice(),
 			"is_available":    currentItem.IsAvailable,
 			"quantity":        currentItem.Quantity,
-			"has_discount":    currentItem.HasDiscount,
-			"discount_value":  currentItem.DiscountValue,
+			"track_stock":     currentItem.TrackStock,
+			"has_discount":    currentItem.CurrentDiscount() > 0,
+			"discount_value":  currentItem.CurrentDiscount(),
+			"image":           s.imageResponse(currentItem.Image),
+			"allergens":       currentItem.Allergens,
+			"dietary_tags":    currentItem.DietaryTags,
+			"nutrition":       currentItem.Nutrition,
+			"spice_level":     currentItem.SpiceLevel,
+			"has_options":     currentItem.HasOptions,
 			"category": fiber.Map{
 				"id":   updatedCategoryID,
 				"name": categoryName,
@@ -344,11 +604,8 @@ func (s *MenuService) HandleUpdateMenuItem(c *fiber.Ctx) error {
 
 // HandleDeleteMenuItem deletes a menu item
 func (s *MenuService) HandleDeleteMenuItem(c *fiber.Ctx) error {
-	err := s.Verifier.VerifyManagerRole(c.Get("Authorization"))
-	if err != nil {
-		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
-			"error": err.Error(),
-		})
+	if err := s.Verifier.VerifyManagerRole(c.Get("Authorization")); err != nil {
+		return managerError(c, err)
 	}
 
 	itemID := c.Params("id")
@@ -358,7 +615,7 @@ func (s *MenuService) HandleDeleteMenuItem(c *fiber.Ctx) error {
 		})
 	}
 
-	_, _, err = s.DB.GetMenuItemByID(itemID)
+	item, _, err := s.DB.GetMenuItemByID(itemID)
 	if err != nil {
 		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
 			"error": "Menu item not found",
@@ -377,6 +634,7 @@ func (s *MenuService) HandleDeleteMenuItem(c *fiber.Ctx) error {
 			"error": "Failed to delete menu item",
 		})
 	}
+	s.deleteImage(item.Image)
 
 	return c.JSON(fiber.Map{
 		"message": "Menu item deleted successfully",
@@ -386,11 +644,8 @@ func (s *MenuService) HandleDeleteMenuItem(c *fiber.Ctx) error {
 
 // HandleAddDiscount applies or removes a discount from a menu item
 func (s *MenuService) HandleAddDiscount(c *fiber.Ctx) error {
-	err := s.Verifier.VerifyManagerRole(c.Get("Authorization"))
-	if err != nil {
-		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
-			"error": err.Error(),
-		})
+	if err := s.Verifier.VerifyManagerRole(c.Get("Authorization")); err != nil {
+		return managerError(c, err)
 	}
 
 	itemID := c.Params("id")
@@ -471,26 +726,46 @@ func (s *MenuService) HandleFilterItems(c *fiber.Ctx) error {
 	hasDiscount := c.Query("has_discount")
 	isAvailable := c.Query("is_available")
 
-	items, categoryNames, categoryPics, err := s.DB.FilterMenuItems(
-		categoryID, minPrice, maxPrice, hasDiscount, isAvailable)
+	metadata, err := parseMetadataFilter(c)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
 
+	page, err := parsePageRequest(c, db.SortCategory)
 	if err != nil {
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-			"error": "Failed to filter menu items: " + err.Error(),
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": err.Error(),
 		})
 	}
 
+	items, categoryNames, categoryPics, info, err := s.DB.FilterMenuItems(
+		categoryID, minPrice, maxPrice, hasDiscount, isAvailable, metadata, page)
+
+	if err != nil {
+		return pageError(c, err, "Failed to filter menu items")
+	}
+
 	results := []fiber.Map{}
 	for i, item := range items {
 		results = append(results, fiber.Map{
 			"id":              item.ID,
 			"name":            item.Name,
+			"description":     item.Description,
 			"price":           item.Price,
 			"effective_price": item.GetEffectivePrice(),
 			"is_available":    item.IsAvailable,
 			"quantity":        item.Quantity,
-			"has_discount":    item.HasDiscount,
-			"discount_value":  item.DiscountValue,
+			"track_stock":     item.TrackStock,
+			"has_discount":    item.CurrentDiscount() > 0,
+			"discount_value":  item.CurrentDiscount(),
+			"image":           s.imageResponse(item.Image),
+			"allergens":       item.Allergens,
+			"dietary_tags":    item.DietaryTags,
+			"nutrition":       item.Nutrition,
+			"spice_level":     item.SpiceLevel,
+			"has_options":     item.HasOptions,
 			"category": fiber.Map{
 				"id":      item.CategoryID,
 				"name":    categoryNames[i],
@@ -500,14 +775,19 @@ func (s *MenuService) HandleFilterItems(c *fiber.Ctx) error {
 	}
 
 	return c.JSON(fiber.Map{
-		"results": results,
-		"count":   len(results),
+		"results":    results,
+		"count":      len(results),
+		"pagination": paginationResponse(page, info),
 		"filters": fiber.Map{
-			"category_id":  categoryID,
-			"min_price":    minPrice,
-			"max_price":    maxPrice,
-			"has_discount": hasDiscount,
-			"is_available": isAvailable,
+			"category_id":       categoryID,
+			"min_price":         minPrice,
+			"max_price":         maxPrice,
+			"has_discount":      hasDiscount,
+			"is_available":      isAvailable,
+			"tags":              metadata.Tags,
+			"exclude_allergens": metadata.ExcludeAllergens,
+			"max_spice_level":   metadata.MaxSpiceLevel,
+			"max_calories":      metadata.MaxCalories,
 		},
 	})
 }
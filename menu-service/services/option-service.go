@@ -0,0 +1,121 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/gofiber/fiber/v2"
+
+	"github.com/darkhyper24/blaban/menu-service/internal/db"
+	"github.com/darkhyper24/blaban/menu-service/internal/models"
+)
+
+// optionGroupsResponse is the JSON form of an item's option groups, never null
+func optionGroupsResponse(groups []models.OptionGroup) []models.OptionGroup {
+	if groups == nil {
+		return []models.OptionGroup{}
+	}
+	return groups
+}
+
+// optionGroupRequest is an option group as managers send it. Options are
+// available and one option may be picked unless stated otherwise.
+type optionGroupRequest struct {
+	ID        string `json:"id"`
+	Name      string `json:"name"`
+	MinSelect int    `json:"min_select"`
+	MaxSelect *int   `json:"max_select"`
+	Options   []struct {
+		ID          string  `json:"id"`
+		Name        string  `json:"name"`
+		PriceDelta  float64 `json:"price_delta"`
+		IsAvailable *bool   `json:"is_available"`
+	} `json:"options"`
+}
+
+func (r optionGroupRequest) toModel() models.OptionGroup {
+	group := models.OptionGroup{
+		ID:        r.ID,
+		Name:      r.Name,
+		MinSelect: r.MinSelect,
+		MaxSelect: 1,
+		Options:   make([]models.Option, len(r.Options)),
+	}
+	if r.MaxSelect != nil {
+		group.MaxSelect = *r.MaxSelect
+	}
+	for i, option := range r.Options {
+		group.Options[i] = models.Option{
+			ID:          option.ID,
+			Name:        option.Name,
+			PriceDelta:  option.PriceDelta,
+			IsAvailable: option.IsAvailable == nil || *option.IsAvailable,
+		}
+	}
+	return group
+}
+
+// HandleSetItemOptions replaces the option groups of a menu item. Sending an
+// empty list removes them all.
+func (s *MenuService) HandleSetItemOptions(c *fiber.Ctx) error {
+	if err := s.Verifier.VerifyManagerRole(c.Get("Authorization")); err != nil {
+		return managerError(c, err)
+	}
+
+	var req struct {
+		OptionGroups []optionGroupRequest `json:"option_groups"`
+	}
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid request body",
+		})
+	}
+
+	requested := make([]models.OptionGroup, len(req.OptionGroups))
+	for i, group := range req.OptionGroups {
+		requested[i] = group.toModel()
+	}
+	if err := models.ValidateOptionGroups(requested); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	itemID := c.Params("id")
+	item, _, err := s.DB.GetMenuItemByID(itemID)
+	if err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": "Menu item not found",
+		})
+	}
+
+	// No selection may bring the price below zero
+	if lowest := item.GetEffectivePrice() + models.MinPriceDelta(requested); lowest < 0 {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": fmt.Sprintf("Options could lower the price to %.2f; it cannot go below zero", lowest),
+		})
+	}
+
+	groups, err := s.DB.SetItemOptionGroups(itemID, requested)
+	if errors.Is(err, db.ErrMenuItemNotFound) {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": "Menu item not found",
+		})
+	}
+	if errors.Is(err, models.ErrInvalidOptions) {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to save options: " + err.Error(),
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"message":       "Options updated successfully",
+		"item_id":       itemID,
+		"option_groups": optionGroupsResponse(groups),
+	})
+}
@@ -0,0 +1,189 @@
+package services
+
+import (
+	"errors"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+
+	"github.com/darkhyper24/blaban/menu-service/internal/db"
+	"github.com/darkhyper24/blaban/menu-service/internal/models"
+)
+
+// promoError maps promo code errors to a response
+func promoError(c *fiber.Ctx, err error) error {
+	status := fiber.StatusInternalServerError
+	switch {
+	case errors.Is(err, models.ErrInvalidPromoCode), errors.Is(err, models.ErrPromoNotApplicable):
+		status = fiber.StatusBadRequest
+	case errors.Is(err, db.ErrPromoCodeNotFound), errors.Is(err, db.ErrRedemptionNotFound),
+		errors.Is(err, db.ErrMenuItemNotFound):
+		status = fiber.StatusNotFound
+	case errors.Is(err, db.ErrPromoCodeExists), errors.Is(err, db.ErrPromoUsedUp),
+		errors.Is(err, db.ErrPromoUserLimit):
+		status = fiber.StatusConflict
+	}
+	return c.Status(status).JSON(fiber.Map{
+		"error": err.Error(),
+	})
+}
+
+// promoOrderRequest is an order a promo code is applied to, as other services send it
+type promoOrderRequest struct {
+	Code      string             `json:"code"`
+	UserID    string             `json:"user_id"`
+	Reference string             `json:"reference"`
+	Items     []models.PromoLine `json:"items"`
+}
+
+// parsePromoOrder reads a promoOrderRequest, returning what is wrong with it if anything
+func parsePromoOrder(c *fiber.Ctx) (promoOrderRequest, string) {
+	var req promoOrderRequest
+	if err := c.BodyParser(&req); err != nil {
+		return req, "Invalid request body"
+	}
+	if req.Code == "" || req.UserID == "" {
+		return req, "code and user_id are required"
+	}
+	return req, ""
+}
+
+// HandleCreatePromoCode adds a promo code
+func (s *MenuService) HandleCreatePromoCode(c *fiber.Ctx) error {
+	if err := s.Verifier.VerifyManagerRole(c.Get("Authorization")); err != nil {
+		return managerError(c, err)
+	}
+
+	var req struct {
+		Code           string     `json:"code"`
+		Kind           string     `json:"kind"`
+		Value          float64    `json:"value"`
+		MinOrderValue  float64    `json:"min_order_value"`
+		MaxUses        *int       `json:"max_uses"`
+		MaxUsesPerUser *int       `json:"max_uses_per_user"`
+		StartsAt       *time.Time `json:"starts_at"`
+		EndsAt         *time.Time `json:"ends_at"`
+		ItemIDs        []string   `json:"item_ids"`
+		CategoryIDs    []string   `json:"category_ids"`
+	}
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid request body",
+		})
+	}
+
+	promo := models.PromoCode{
+		Code:           req.Code,
+		Kind:           req.Kind,
+		Value:          req.Value,
+		MinOrderValue:  req.MinOrderValue,
+		MaxUses:        req.MaxUses,
+		MaxUsesPerUser: req.MaxUsesPerUser,
+		StartsAt:       req.StartsAt,
+		EndsAt:         req.EndsAt,
+		ItemIDs:        req.ItemIDs,
+		CategoryIDs:    req.CategoryIDs,
+		IsActive:       true,
+	}
+	if err := promo.Validate(); err != nil {
+		return promoError(c, err)
+	}
+
+	created, err := s.DB.CreatePromoCode(promo)
+	if err != nil {
+		return promoError(c, err)
+	}
+
+	return c.Status(fiber.StatusCreated).JSON(fiber.Map{
+		"message":    "Promo code created successfully",
+		"promo_code": created,
+	})
+}
+
+// HandleListPromoCodes lists every promo code with how often it is in use
+func (s *MenuService) HandleListPromoCodes(c *fiber.Ctx) error {
+	if err := s.Verifier.VerifyManagerRole(c.Get("Authorization")); err != nil {
+		return managerError(c, err)
+	}
+
+	promos, err := s.DB.ListPromoCodes()
+	if err != nil {
+		return promoError(c, err)
+	}
+	if promos == nil {
+		promos = []models.PromoCode{}
+	}
+
+	return c.JSON(fiber.Map{
+		"promo_codes": promos,
+	})
+}
+
+// HandleDeactivatePromoCode stops a promo code from being used
+func (s *MenuService) HandleDeactivatePromoCode(c *fiber.Ctx) error {
+	if err := s.Verifier.VerifyManagerRole(c.Get("Authorization")); err != nil {
+		return managerError(c, err)
+	}
+
+	promo, err := s.DB.DeactivatePromoCode(c.Params("id"))
+	if err != nil {
+		return promoError(c, err)
+	}
+
+	return c.JSON(fiber.Map{
+		"message":    "Promo code deactivated successfully",
+		"promo_code": promo,
+	})
+}
+
+// HandleQuotePromoCode works out what a code takes off an order without using it up
+func (s *MenuService) HandleQuotePromoCode(c *fiber.Ctx) error {
+	req, problem := parsePromoOrder(c)
+	if problem != "" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": problem,
+		})
+	}
+
+	quote, err := s.DB.QuotePromoCode(req.Code, req.UserID, req.Items)
+	if err != nil {
+		return promoError(c, err)
+	}
+
+	return c.JSON(fiber.Map{
+		"quote": quote,
+	})
+}
+
+// HandleRedeemPromoCode applies a code to an order and counts the use
+func (s *MenuService) HandleRedeemPromoCode(c *fiber.Ctx) error {
+	req, problem := parsePromoOrder(c)
+	if problem != "" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": problem,
+		})
+	}
+
+	redemption, err := s.DB.RedeemPromoCode(req.Code, req.UserID, req.Reference, req.Items)
+	if err != nil {
+		return promoError(c, err)
+	}
+
+	return c.Status(fiber.StatusCreated).JSON(fiber.Map{
+		"message":    "Promo code redeemed successfully",
+		"redemption": redemption,
+	})
+}
+
+// HandleReleasePromoRedemption gives a use of a promo code back
+func (s *MenuService) HandleReleasePromoRedemption(c *fiber.Ctx) error {
+	redemption, err := s.DB.ReleasePromoRedemption(c.Params("id"))
+	if err != nil {
+		return promoError(c, err)
+	}
+
+	return c.JSON(fiber.Map{
+		"message":    "Promo redemption released successfully",
+		"redemption": redemption,
+	})
+}
@@ -0,0 +1,117 @@
+package services
+
+import (
+	"errors"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+
+	"github.com/darkhyper24/blaban/menu-service/internal/db"
+	"github.com/darkhyper24/blaban/menu-service/internal/models"
+)
+
+const (
+	// DefaultReservationTTL is how long reserved stock is held when the caller does not say
+	DefaultReservationTTL = 10 * time.Minute
+	// MaxReservationTTL bounds how long stock can be held without being committed
+	MaxReservationTTL = time.Hour
+)
+
+// reservationResponse is the JSON form of a reservation
+func reservationResponse(reservation models.Reservation) fiber.Map {
+	items := reservation.Items
+	if items == nil {
+		items = []models.ReservationItem{}
+	}
+	return fiber.Map{
+		"id":         reservation.ID,
+		"reference":  reservation.Reference,
+		"status":     reservation.Status,
+		"items":      items,
+		"expires_at": reservation.ExpiresAt,
+		"created_at": reservation.CreatedAt,
+	}
+}
+
+// reservationError maps reservation errors to a response
+func reservationError(c *fiber.Ctx, err error) error {
+	status := fiber.StatusInternalServerError
+	switch {
+	case errors.Is(err, db.ErrInvalidReservation):
+		status = fiber.StatusBadRequest
+	case errors.Is(err, db.ErrMenuItemNotFound), errors.Is(err, db.ErrReservationNotFound):
+		status = fiber.StatusNotFound
+	case errors.Is(err, db.ErrInsufficientStock), errors.Is(err, db.ErrItemUnavailable),
+		errors.Is(err, db.ErrReservationReleased):
+		status = fiber.StatusConflict
+	case errors.Is(err, db.ErrReservationExpired):
+		status = fiber.StatusGone
+	}
+	return c.Status(status).JSON(fiber.Map{
+		"error": err.Error(),
+	})
+}
+
+// HandleReserveStock holds stock for a set of items until the reservation is
+// committed, released or expires
+func (s *MenuService) HandleReserveStock(c *fiber.Ctx) error {
+	var req struct {
+		Reference  string                   `json:"reference"`
+		TTLSeconds int                      `json:"ttl_seconds"`
+		Items      []models.ReservationItem `json:"items"`
+	}
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid request body",
+		})
+	}
+
+	ttl := DefaultReservationTTL
+	if req.TTLSeconds < 0 {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "ttl_seconds cannot be negative",
+		})
+	}
+	if req.TTLSeconds > 0 {
+		ttl = time.Duration(req.TTLSeconds) * time.Second
+	}
+	if ttl > MaxReservationTTL {
+		ttl = MaxReservationTTL
+	}
+
+	reservation, err := s.DB.ReserveStock(req.Items, req.Reference, ttl)
+	if err != nil {
+		return reservationError(c, err)
+	}
+
+	return c.Status(fiber.StatusCreated).JSON(fiber.Map{
+		"message":     "Stock reserved successfully",
+		"reservation": reservationResponse(reservation),
+	})
+}
+
+// HandleCommitReservation makes a reservation final
+func (s *MenuService) HandleCommitReservation(c *fiber.Ctx) error {
+	reservation, err := s.DB.CommitReservation(c.Params("id"))
+	if err != nil {
+		return reservationError(c, err)
+	}
+
+	return c.JSON(fiber.Map{
+		"message":     "Reservation committed successfully",
+		"reservation": reservationResponse(reservation),
+	})
+}
+
+// HandleReleaseReservation gives a reservation's stock back
+func (s *MenuService) HandleReleaseReservation(c *fiber.Ctx) error {
+	reservation, err := s.DB.ReleaseReservation(c.Params("id"))
+	if err != nil {
+		return reservationError(c, err)
+	}
+
+	return c.JSON(fiber.Map{
+		"message":     "Reservation released successfully",
+		"reservation": reservationResponse(reservation),
+	})
+}
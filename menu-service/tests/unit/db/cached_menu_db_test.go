@@ -0,0 +1,168 @@
+package db
+
+import (
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/darkhyper24/blaban/menu-service/internal/db"
+	"github.com/darkhyper24/blaban/menu-service/internal/models"
+	"github.com/darkhyper24/blaban/menu-service/tests/unit/db/mocks"
+	"github.com/go-redis/redis/v8"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+// unreachableRedis returns a client for a port nothing listens on
+func unreachableRedis() *redis.Client {
+	return redis.NewClient(&redis.Options{
+		Addr:        "127.0.0.1:1",
+		DialTimeout: 50 * time.Millisecond,
+		MaxRetries:  -1,
+	})
+}
+
+func TestCachedMenuDBFallsBackWhenRedisIsDown(t *testing.T) {
+	mockDB := new(mocks.MockDB)
+	cached := db.NewCachedMenuDB(mockDB, unreachableRedis(), time.Minute)
+
+	categories := []models.Category{{ID: "cat1", Name: "Appetizers", Picture: "appetizers.jpg"}}
+	mockDB.On("GetAllCategories").Return(categories, nil).Twice()
+
+	for i := 0; i < 2; i++ {
+		result, err := cached.GetAllCategories()
+		assert.NoError(t, err)
+		assert.Equal(t, categories, result)
+	}
+
+	mockDB.AssertExpectations(t)
+}
+
+func TestCachedMenuDBPropagatesDatabaseErrors(t *testing.T) {
+	mockDB := new(mocks.MockDB)
+	cached := db.NewCachedMenuDB(mockDB, unreachableRedis(), time.Minute)
+
+	mockDB.On("GetMenuItemByID", "missing").Return(models.MenuItem{}, "", errors.New("no rows in result set")).Once()
+
+	_, _, err := cached.GetMenuItemByID("missing")
+	assert.Error(t, err)
+
+	mockDB.AssertExpectations(t)
+}
+
+func TestCachedMenuDBSharesConcurrentLoads(t *testing.T) {
+	mockDB := new(mocks.MockDB)
+	cached := db.NewCachedMenuDB(mockDB, unreachableRedis(), time.Minute)
+
+	items := []models.MenuItem{{ID: "item1", Name: "Burger", Price: 12.99, CategoryID: "cat1"}}
+	page := db.PageRequest{Limit: db.DefaultPageSize, Sort: db.SortCategory}
+	mockDB.On("GetAllMenuItems", page).
+		Run(func(mock.Arguments) { time.Sleep(200 * time.Millisecond) }).
+		Return(items, []string{"Main Courses"}, db.PageInfo{Total: 1}, nil).
+		Once()
+
+	var wg sync.WaitGroup
+	start := make(chan struct{})
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			<-start
+			result, names, info, err := cached.GetAllMenuItems(db.PageRequest{})
+			assert.NoError(t, err)
+			assert.Equal(t, items, result)
+			assert.Equal(t, []string{"Main Courses"}, names)
+			assert.Equal(t, 1, info.Total)
+		}()
+	}
+	close(start)
+	wg.Wait()
+
+	mockDB.AssertExpectations(t)
+}
+
+func TestCachedMenuDBRejectsInvalidSort(t *testing.T) {
+	mockDB := new(mocks.MockDB)
+	cached := db.NewCachedMenuDB(mockDB, unreachableRedis(), time.Minute)
+
+	_, _, _, err := cached.GetAllMenuItems(db.PageRequest{Sort: "popularity"})
+	assert.ErrorIs(t, err, db.ErrInvalidSort)
+
+	mockDB.AssertNotCalled(t, "GetAllMenuItems", mock.Anything)
+}
+
+func TestCachedMenuDBWritesPassThrough(t *testing.T) {
+	mockDB := new(mocks.MockDB)
+	cached := db.NewCachedMenuDB(mockDB, unreachableRedis(), time.Minute)
+
+	item := models.MenuItem{ID: "item1", Name: "Burger", Price: 12.99, CategoryID: "cat1"}
+	mockDB.On("CreateMenuItem", item, "cat1").Return("item1", nil).Once()
+	mockDB.On("UpdateMenuItem", item).Return(nil).Once()
+	mockDB.On("UpdateItemDiscount", "item1", 10.0, true).Return(int64(1), nil).Once()
+	mockDB.On("DeleteMenuItem", "item1").Return(int64(1), nil).Once()
+
+	id, err := cached.CreateMenuItem(item, "cat1")
+	assert.NoError(t, err)
+	assert.Equal(t, "item1", id)
+
+	assert.NoError(t, cached.UpdateMenuItem(item))
+
+	rows, err := cached.UpdateItemDiscount("item1", 10, true)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(1), rows)
+
+	rows, err = cached.DeleteMenuItem("item1")
+	assert.NoError(t, err)
+	assert.Equal(t, int64(1), rows)
+
+	category := models.Category{ID: "cat2", Name: "Drinks"}
+	mockDB.On("CreateCategory", "Drinks", "").Return(category, nil).Once()
+	mockDB.On("UpdateCategory", category).Return(nil).Once()
+	mockDB.On("ReorderCategories", []string{"cat2", "cat1"}).Return(nil).Once()
+	mockDB.On("DeleteCategory", "cat2", "cat1").Return(int64(3), nil).Once()
+
+	created, err := cached.CreateCategory("Drinks", "")
+	assert.NoError(t, err)
+	assert.Equal(t, category, created)
+	assert.NoError(t, cached.UpdateCategory(category))
+	assert.NoError(t, cached.ReorderCategories([]string{"cat2", "cat1"}))
+
+	moved, err := cached.DeleteCategory("cat2", "cat1")
+	assert.NoError(t, err)
+	assert.Equal(t, int64(3), moved)
+
+	reserved := []models.ReservationItem{{ItemID: "item1", Quantity: 2}}
+	reservation := models.Reservation{ID: "res1", Status: models.ReservationHeld, Items: reserved}
+	mockDB.On("ReserveStock", reserved, "order1", time.Minute).Return(reservation, nil).Once()
+	mockDB.On("CommitReservation", "res1").Return(reservation, db.ErrReservationExpired).Once()
+	mockDB.On("ReleaseReservation", "res1").Return(reservation, nil).Once()
+	mockDB.On("ExpireReservations").Return([]models.Reservation{reservation}, nil).Once()
+
+	got, err := cached.ReserveStock(reserved, "order1", time.Minute)
+	assert.NoError(t, err)
+	assert.Equal(t, reservation, got)
+
+	_, err = cached.CommitReservation("res1")
+	assert.ErrorIs(t, err, db.ErrReservationExpired)
+
+	_, err = cached.ReleaseReservation("res1")
+	assert.NoError(t, err)
+
+	expired, err := cached.ExpireReservations()
+	assert.NoError(t, err)
+	assert.Len(t, expired, 1)
+
+	discount := models.Discount{CategoryID: "cat1", Percentage: 20}
+	mockDB.On("CreateDiscount", discount).Return(models.Discount{ID: "disc1", CategoryID: "cat1", Percentage: 20}, nil).Once()
+	mockDB.On("CancelDiscount", "disc1").Return(models.Discount{ID: "disc1", CategoryID: "cat1", Percentage: 20}, nil).Once()
+
+	createdDiscount, err := cached.CreateDiscount(discount)
+	assert.NoError(t, err)
+	assert.Equal(t, "disc1", createdDiscount.ID)
+
+	_, err = cached.CancelDiscount("disc1")
+	assert.NoError(t, err)
+
+	mockDB.AssertExpectations(t)
+}
@@ -1,6 +1,12 @@
 package mocks
 
 import (
+	"context"
+	"io"
+	"sync"
+	"time"
+
+	"github.com/darkhyper24/blaban/menu-service/internal/db"
 	"github.com/darkhyper24/blaban/menu-service/internal/models"
 	"github.com/jackc/pgx/v5/pgxpool"
 	"github.com/stretchr/testify/mock"
@@ -40,16 +46,52 @@ func (m *MockDB) GetAllCategories() ([]models.Category, error) {
 	return args.Get(0).([]models.Category), args.Error(1)
 }
 
+// GetCategoryByID mocks retrieving a category
+func (m *MockDB) GetCategoryByID(categoryID string) (models.Category, error) {
+	args := m.Called(categoryID)
+	return args.Get(0).(models.Category), args.Error(1)
+}
+
+// CreateCategory mocks creating a category
+func (m *MockDB) CreateCategory(name, picture string) (models.Category, error) {
+	args := m.Called(name, picture)
+	return args.Get(0).(models.Category), args.Error(1)
+}
+
+// UpdateCategory mocks updating a category
+func (m *MockDB) UpdateCategory(category models.Category) error {
+	args := m.Called(category)
+	return args.Error(0)
+}
+
+// ReorderCategories mocks changing category positions
+func (m *MockDB) ReorderCategories(categoryIDs []string) error {
+	args := m.Called(categoryIDs)
+	return args.Error(0)
+}
+
+// DeleteCategory mocks deleting a category
+func (m *MockDB) DeleteCategory(categoryID, reassignTo string) (int64, error) {
+	args := m.Called(categoryID, reassignTo)
+	return args.Get(0).(int64), args.Error(1)
+}
+
 // GetAllMenuItems mocks retrieving all menu items
-func (m *MockDB) GetAllMenuItems() ([]models.MenuItem, []string, error) {
-	args := m.Called()
-	return args.Get(0).([]models.MenuItem), args.Get(1).([]string), args.Error(2)
+func (m *MockDB) GetAllMenuItems(page db.PageRequest) ([]models.MenuItem, []string, db.PageInfo, error) {
+	args := m.Called(page)
+	return args.Get(0).([]models.MenuItem), args.Get(1).([]string), args.Get(2).(db.PageInfo), args.Error(3)
 }
 
 // SearchMenuItems mocks searching for menu items
-func (m *MockDB) SearchMenuItems(query string) ([]models.MenuItem, []string, []string, error) {
-	args := m.Called(query)
-	return args.Get(0).([]models.MenuItem), args.Get(1).([]string), args.Get(2).([]string), args.Error(3)
+func (m *MockDB) SearchMenuItems(query string, page db.PageRequest) ([]models.SearchResult, db.PageInfo, error) {
+	args := m.Called(query, page)
+	return args.Get(0).([]models.SearchResult), args.Get(1).(db.PageInfo), args.Error(2)
+}
+
+// SuggestMenuItems mocks autocomplete suggestions
+func (m *MockDB) SuggestMenuItems(prefix string, limit int) ([]models.Suggestion, error) {
+	args := m.Called(prefix, limit)
+	return args.Get(0).([]models.Suggestion), args.Error(1)
 }
 
 // CreateMenuItem mocks creating a menu item
@@ -76,13 +118,151 @@ func (m *MockDB) UpdateItemDiscount(itemID string, discountValue float64, active
 	return args.Get(0).(int64), args.Error(1)
 }
 
+// SetMenuItemImage mocks replacing an item's image
+func (m *MockDB) SetMenuItemImage(itemID string, image *models.Image) (*models.Image, error) {
+	args := m.Called(itemID, image)
+	previous, _ := args.Get(0).(*models.Image)
+	return previous, args.Error(1)
+}
+
+// SetCategoryImage mocks replacing a category's image
+func (m *MockDB) SetCategoryImage(categoryID string, image *models.Image, picture string) (*models.Image, error) {
+	args := m.Called(categoryID, image, picture)
+	previous, _ := args.Get(0).(*models.Image)
+	return previous, args.Error(1)
+}
+
+// SetItemOptionGroups mocks replacing an item's option groups
+func (m *MockDB) SetItemOptionGroups(itemID string, groups []models.OptionGroup) ([]models.OptionGroup, error) {
+	args := m.Called(itemID, groups)
+	saved, _ := args.Get(0).([]models.OptionGroup)
+	return saved, args.Error(1)
+}
+
 // FilterMenuItems mocks filtering menu items
-func (m *MockDB) FilterMenuItems(categoryID, minPrice, maxPrice, hasDiscount, isAvailable string) ([]models.MenuItem, []string, []string, error) {
-	args := m.Called(categoryID, minPrice, maxPrice, hasDiscount, isAvailable)
-	return args.Get(0).([]models.MenuItem), args.Get(1).([]string), args.Get(2).([]string), args.Error(3)
+func (m *MockDB) FilterMenuItems(categoryID, minPrice, maxPrice, hasDiscount, isAvailable string, metadata db.MetadataFilter, page db.PageRequest) ([]models.MenuItem, []string, []string, db.PageInfo, error) {
+	args := m.Called(categoryID, minPrice, maxPrice, hasDiscount, isAvailable, metadata, page)
+	return args.Get(0).([]models.MenuItem), args.Get(1).([]string), args.Get(2).([]string), args.Get(3).(db.PageInfo), args.Error(4)
+}
+
+// ReserveStock mocks reserving stock
+func (m *MockDB) ReserveStock(items []models.ReservationItem, reference string, ttl time.Duration) (models.Reservation, error) {
+	args := m.Called(items, reference, ttl)
+	return args.Get(0).(models.Reservation), args.Error(1)
+}
+
+// CommitReservation mocks committing a reservation
+func (m *MockDB) CommitReservation(reservationID string) (models.Reservation, error) {
+	args := m.Called(reservationID)
+	return args.Get(0).(models.Reservation), args.Error(1)
+}
+
+// ReleaseReservation mocks releasing a reservation
+func (m *MockDB) ReleaseReservation(reservationID string) (models.Reservation, error) {
+	args := m.Called(reservationID)
+	return args.Get(0).(models.Reservation), args.Error(1)
+}
+
+// ExpireReservations mocks expiring overdue reservations
+func (m *MockDB) ExpireReservations() ([]models.Reservation, error) {
+	args := m.Called()
+	expired, _ := args.Get(0).([]models.Reservation)
+	return expired, args.Error(1)
+}
+
+// CreateDiscount mocks scheduling a discount
+func (m *MockDB) CreateDiscount(discount models.Discount) (models.Discount, error) {
+	args := m.Called(discount)
+	return args.Get(0).(models.Discount), args.Error(1)
+}
+
+// ListDiscounts mocks listing discounts
+func (m *MockDB) ListDiscounts(filter db.DiscountFilter) ([]models.Discount, error) {
+	args := m.Called(filter)
+	discounts, _ := args.Get(0).([]models.Discount)
+	return discounts, args.Error(1)
+}
+
+// CancelDiscount mocks cancelling a discount
+func (m *MockDB) CancelDiscount(discountID string) (models.Discount, error) {
+	args := m.Called(discountID)
+	return args.Get(0).(models.Discount), args.Error(1)
+}
+
+// CreatePromoCode mocks adding a promo code
+func (m *MockDB) CreatePromoCode(promo models.PromoCode) (models.PromoCode, error) {
+	args := m.Called(promo)
+	return args.Get(0).(models.PromoCode), args.Error(1)
+}
+
+// ListPromoCodes mocks listing promo codes
+func (m *MockDB) ListPromoCodes() ([]models.PromoCode, error) {
+	args := m.Called()
+	promos, _ := args.Get(0).([]models.PromoCode)
+	return promos, args.Error(1)
+}
+
+// DeactivatePromoCode mocks deactivating a promo code
+func (m *MockDB) DeactivatePromoCode(promoID string) (models.PromoCode, error) {
+	args := m.Called(promoID)
+	return args.Get(0).(models.PromoCode), args.Error(1)
+}
+
+// QuotePromoCode mocks pricing a promo code against an order
+func (m *MockDB) QuotePromoCode(code, userID string, lines []models.PromoLine) (models.PromoQuote, error) {
+	args := m.Called(code, userID, lines)
+	return args.Get(0).(models.PromoQuote), args.Error(1)
+}
+
+// RedeemPromoCode mocks redeeming a promo code
+func (m *MockDB) RedeemPromoCode(code, userID, reference string, lines []models.PromoLine) (models.PromoRedemption, error) {
+	args := m.Called(code, userID, reference, lines)
+	return args.Get(0).(models.PromoRedemption), args.Error(1)
+}
+
+// ReleasePromoRedemption mocks releasing a promo redemption
+func (m *MockDB) ReleasePromoRedemption(redemptionID string) (models.PromoRedemption, error) {
+	args := m.Called(redemptionID)
+	return args.Get(0).(models.PromoRedemption), args.Error(1)
 }
 
 // GetPool mocks access to the connection pool
 func (m *MockDB) GetPool() *pgxpool.Pool {
 	return nil // We won't use this in these tests
 }
+
+// MemoryBlobStore implements images.BlobStore in memory for testing
+type MemoryBlobStore struct {
+	mu    sync.Mutex
+	Files map[string][]byte
+}
+
+// NewMemoryBlobStore creates an empty in-memory store
+func NewMemoryBlobStore() *MemoryBlobStore {
+	return &MemoryBlobStore{Files: make(map[string][]byte)}
+}
+
+// Put keeps the file in memory
+func (s *MemoryBlobStore) Put(ctx context.Context, key string, r io.Reader, size int64, contentType string) error {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.Files[key] = data
+	return nil
+}
+
+// Delete forgets the file
+func (s *MemoryBlobStore) Delete(ctx context.Context, key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.Files, key)
+	return nil
+}
+
+// URL returns a fake URL for the key
+func (s *MemoryBlobStore) URL(key string) string {
+	return "https://images.test/" + key
+}
@@ -0,0 +1,94 @@
+package db
+
+import (
+	"context"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/darkhyper24/blaban/menu-service/internal/db"
+	"github.com/darkhyper24/blaban/menu-service/internal/models"
+	"github.com/darkhyper24/blaban/menu-service/migrations"
+	"github.com/darkhyper24/blaban/shared/migrate"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/stdlib"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// testMenuDB connects to the database in MENU_TEST_DATABASE_URL and brings its
+// schema up to date. Tests that need Postgres are skipped without one.
+func testMenuDB(t *testing.T) *db.MenuDB {
+	t.Helper()
+	databaseURL := os.Getenv("MENU_TEST_DATABASE_URL")
+	if databaseURL == "" {
+		t.Skip("MENU_TEST_DATABASE_URL is not set")
+	}
+	t.Setenv("DATABASE_URL", databaseURL)
+
+	menuDB, err := db.NewMenuDB()
+	require.NoError(t, err)
+	t.Cleanup(menuDB.Close)
+
+	sqlDB := stdlib.OpenDBFromPool(menuDB.Pool)
+	defer sqlDB.Close()
+	migrator, err := migrate.New(sqlDB, migrations.FS, "menu-service")
+	require.NoError(t, err)
+	require.NoError(t, migrator.Up(context.Background()))
+	return menuDB
+}
+
+// stockedItem adds an item that tracks stock and returns its ID
+func stockedItem(t *testing.T, menuDB *db.MenuDB, quantity int) string {
+	t.Helper()
+	ctx := context.Background()
+	categoryID := uuid.NewString()
+	itemID := uuid.NewString()
+
+	_, err := menuDB.Pool.Exec(ctx, `INSERT INTO category (category_id, name) VALUES ($1, $1)`, categoryID)
+	require.NoError(t, err)
+	_, err = menuDB.Pool.Exec(ctx, `
+        INSERT INTO items (item_id, name, price, quantity, track_stock, category_id)
+        VALUES ($1, $1, 10, $2, true, $3)
+    `, itemID, quantity, categoryID)
+	require.NoError(t, err)
+
+	t.Cleanup(func() {
+		menuDB.Pool.Exec(context.Background(), `DELETE FROM items WHERE item_id = $1`, itemID)
+		menuDB.Pool.Exec(context.Background(), `DELETE FROM category WHERE category_id = $1`, categoryID)
+	})
+	return itemID
+}
+
+func itemQuantity(t *testing.T, menuDB *db.MenuDB, itemID string) int {
+	t.Helper()
+	var quantity int
+	err := menuDB.Pool.QueryRow(context.Background(), `SELECT quantity FROM items WHERE item_id = $1`, itemID).Scan(&quantity)
+	require.NoError(t, err)
+	return quantity
+}
+
+// An order placed commits its reservation straight away; cancelling it while
+// it is still pending must put the stock back
+func TestReleaseCommittedReservationRestoresStock(t *testing.T) {
+	menuDB := testMenuDB(t)
+	itemID := stockedItem(t, menuDB, 5)
+
+	// Order created
+	reservation, err := menuDB.ReserveStock([]models.ReservationItem{{ItemID: itemID, Quantity: 2}}, uuid.NewString(), time.Minute)
+	require.NoError(t, err)
+	_, err = menuDB.CommitReservation(reservation.ID)
+	require.NoError(t, err)
+	assert.Equal(t, 3, itemQuantity(t, menuDB, itemID))
+
+	// Order cancelled while pending
+	released, err := menuDB.ReleaseReservation(reservation.ID)
+	require.NoError(t, err)
+	assert.Equal(t, models.ReservationReleased, released.Status)
+	assert.Equal(t, 5, itemQuantity(t, menuDB, itemID))
+
+	// A second cancellation request gives nothing more back
+	_, err = menuDB.ReleaseReservation(reservation.ID)
+	require.NoError(t, err)
+	assert.Equal(t, 5, itemQuantity(t, menuDB, itemID))
+}
@@ -0,0 +1,78 @@
+package models
+
+import (
+	"testing"
+	"time"
+
+	"github.com/darkhyper24/blaban/menu-service/internal/models"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPromoCodeApply(t *testing.T) {
+	now := time.Now()
+	lines := []models.PromoLine{
+		{ItemID: "burger", CategoryID: "mains", Quantity: 2, UnitPrice: 10},
+		{ItemID: "cola", CategoryID: "drinks", Quantity: 1, UnitPrice: 2.5},
+	}
+
+	// Test case 1: A percentage scoped to a category only discounts that category
+	t.Run("Percentage on a category", func(t *testing.T) {
+		promo := models.PromoCode{Code: "MAINS10", Kind: models.PromoPercentage, Value: 10, CategoryIDs: []string{"mains"}, IsActive: true}
+
+		quote, err := promo.Apply(lines, now)
+
+		assert.NoError(t, err)
+		assert.Equal(t, 22.5, quote.Subtotal)
+		assert.Equal(t, 20.0, quote.EligibleSubtotal)
+		assert.Equal(t, 2.0, quote.Discount)
+		assert.Equal(t, 20.5, quote.Total)
+	})
+
+	// Test case 2: A fixed amount never exceeds what it covers
+	t.Run("Fixed amount is capped", func(t *testing.T) {
+		promo := models.PromoCode{Code: "COLA5", Kind: models.PromoFixed, Value: 5, ItemIDs: []string{"cola"}, IsActive: true}
+
+		quote, err := promo.Apply(lines, now)
+
+		assert.NoError(t, err)
+		assert.Equal(t, 2.5, quote.Discount)
+		assert.Equal(t, 20.0, quote.Total)
+	})
+
+	// Test case 3: Codes that cannot be used
+	past := now.Add(-time.Hour)
+	future := now.Add(time.Hour)
+	for name, promo := range map[string]models.PromoCode{
+		"Inactive":         {Code: "OFF", Kind: models.PromoFixed, Value: 1},
+		"Not started":      {Code: "SOON", Kind: models.PromoFixed, Value: 1, StartsAt: &future, IsActive: true},
+		"Expired":          {Code: "OLD", Kind: models.PromoFixed, Value: 1, EndsAt: &past, IsActive: true},
+		"Below minimum":    {Code: "BIG", Kind: models.PromoFixed, Value: 1, MinOrderValue: 50, IsActive: true},
+		"Nothing in scope": {Code: "DESSERT", Kind: models.PromoFixed, Value: 1, CategoryIDs: []string{"desserts"}, IsActive: true},
+	} {
+		t.Run(name, func(t *testing.T) {
+			_, err := promo.Apply(lines, now)
+			assert.ErrorIs(t, err, models.ErrPromoNotApplicable)
+		})
+	}
+}
+
+func TestPromoCodeValidate(t *testing.T) {
+	promo := models.PromoCode{Code: " summer24 ", Kind: models.PromoPercentage, Value: 15, ItemIDs: []string{"a", " a", ""}}
+	assert.NoError(t, promo.Validate())
+	assert.Equal(t, "SUMMER24", promo.Code, "Codes should be case-insensitive")
+	assert.Equal(t, []string{"a"}, promo.ItemIDs, "Scope should be deduplicated")
+
+	zero := 0
+	for name, promo := range map[string]models.PromoCode{
+		"Unknown kind":       {Code: "X", Kind: "bogo", Value: 1},
+		"Percentage of 100":  {Code: "X", Kind: models.PromoPercentage, Value: 100},
+		"Negative amount":    {Code: "X", Kind: models.PromoFixed, Value: -1},
+		"Spaces in code":     {Code: "TWO WORDS", Kind: models.PromoFixed, Value: 1},
+		"Zero usage limit":   {Code: "X", Kind: models.PromoFixed, Value: 1, MaxUses: &zero},
+		"Negative min order": {Code: "X", Kind: models.PromoFixed, Value: 1, MinOrderValue: -5},
+	} {
+		t.Run(name, func(t *testing.T) {
+			assert.ErrorIs(t, promo.Validate(), models.ErrInvalidPromoCode)
+		})
+	}
+}
@@ -1,21 +1,33 @@
 package services
 
 import (
+	"bytes"
 	"encoding/json"
 	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	"image/png"
+	"mime/multipart"
 	"net/http"
 	"net/http/httptest"
 	"strings"
 	"testing"
+	"time"
 
+	"github.com/darkhyper24/blaban/menu-service/internal/db"
 	"github.com/darkhyper24/blaban/menu-service/internal/models"
 	"github.com/darkhyper24/blaban/menu-service/services"
 	"github.com/darkhyper24/blaban/menu-service/tests/unit/db/mocks"
+	"github.com/darkhyper24/blaban/shared/auth"
 	"github.com/gofiber/fiber/v2"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
 )
 
+// defaultPage is the page handlers request when no paging parameters are given
+var defaultPage = db.PageRequest{Limit: db.DefaultPageSize, Sort: db.SortCategory}
+
 func TestHandleGetCategories(t *testing.T) {
 	app := fiber.New()
 	mockDB := new(mocks.MockDB)
@@ -127,7 +139,7 @@ func TestHandleGetMenu(t *testing.T) {
 
 	// Test case 1: Successful retrieval of menu
 	t.Run("Get menu successfully", func(t *testing.T) {
-		mockDB.On("GetAllMenuItems").Return(items, categoryNames, nil).Once()
+		mockDB.On("GetAllMenuItems", defaultPage).Return(items, categoryNames, db.PageInfo{Total: 3}, nil).Once()
 
 		app.Get("/api/menu", menuService.HandleGetMenu)
 
@@ -184,7 +196,7 @@ func TestHandleGetMenu(t *testing.T) {
 
 	// Test case 2: Database error
 	t.Run("Database error", func(t *testing.T) {
-		mockDB.On("GetAllMenuItems").Return([]models.MenuItem{}, []string{}, errors.New("database connection failed")).Once()
+		mockDB.On("GetAllMenuItems", defaultPage).Return([]models.MenuItem{}, []string{}, db.PageInfo{}, errors.New("database connection failed")).Once()
 
 		req := httptest.NewRequest(http.MethodGet, "/api/menu", nil)
 		resp, err := app.Test(req)
@@ -201,6 +213,78 @@ func TestHandleGetMenu(t *testing.T) {
 		assert.Contains(t, errorMsg, "Failed to fetch menu items", "Error message should indicate fetch failure")
 	})
 
+	// Test case 3: Paging parameters are passed through and cursors returned
+	t.Run("Get menu page", func(t *testing.T) {
+		page := db.PageRequest{Limit: 2, Cursor: "abc", Sort: db.SortPriceDesc}
+		mockDB.On("GetAllMenuItems", page).Return(
+			[]models.MenuItem{items[2], items[0]},
+			[]string{"Desserts", "Fast Food"},
+			db.PageInfo{Total: 3, NextCursor: "next", PrevCursor: "prev"},
+			nil).Once()
+
+		req := httptest.NewRequest(http.MethodGet, "/api/menu?limit=2&cursor=abc&sort=-price", nil)
+		resp, err := app.Test(req)
+
+		assert.NoError(t, err)
+		assert.Equal(t, fiber.StatusOK, resp.StatusCode)
+
+		var result map[string]interface{}
+		err = json.NewDecoder(resp.Body).Decode(&result)
+		assert.NoError(t, err)
+
+		menu := result["menu"].([]interface{})
+		assert.Len(t, menu, 2, "Menu should contain 2 categories")
+		assert.Equal(t, "Desserts", menu[0].(map[string]interface{})["name"], "Categories should keep the order of the page")
+		assert.Equal(t, "Fast Food", menu[1].(map[string]interface{})["name"], "Categories should keep the order of the page")
+
+		pagination := result["pagination"].(map[string]interface{})
+		assert.Equal(t, float64(2), pagination["limit"])
+		assert.Equal(t, "-price", pagination["sort"])
+		assert.Equal(t, float64(3), pagination["total"])
+		assert.Equal(t, "next", pagination["next_cursor"])
+		assert.Equal(t, "prev", pagination["prev_cursor"])
+	})
+
+	// Test case 4: Limits above the maximum are clamped
+	t.Run("Limit is clamped", func(t *testing.T) {
+		page := db.PageRequest{Limit: db.MaxPageSize, Sort: db.SortCategory}
+		mockDB.On("GetAllMenuItems", page).Return([]models.MenuItem{}, []string{}, db.PageInfo{}, nil).Once()
+
+		req := httptest.NewRequest(http.MethodGet, "/api/menu?limit=1000", nil)
+		resp, err := app.Test(req)
+
+		assert.NoError(t, err)
+		assert.Equal(t, fiber.StatusOK, resp.StatusCode)
+	})
+
+	// Test case 5: Invalid paging parameters
+	t.Run("Invalid paging parameters", func(t *testing.T) {
+		for _, query := range []string{"sort=popularity", "limit=abc", "limit=-1"} {
+			req := httptest.NewRequest(http.MethodGet, "/api/menu?"+query, nil)
+			resp, err := app.Test(req)
+
+			assert.NoError(t, err)
+			assert.Equal(t, fiber.StatusBadRequest, resp.StatusCode, query)
+		}
+	})
+
+	// Test case 6: Cursor that does not match the sort
+	t.Run("Invalid cursor", func(t *testing.T) {
+		page := db.PageRequest{Limit: db.DefaultPageSize, Cursor: "bogus", Sort: db.SortCategory}
+		mockDB.On("GetAllMenuItems", page).Return([]models.MenuItem(nil), []string(nil), db.PageInfo{}, db.ErrInvalidCursor).Once()
+
+		req := httptest.NewRequest(http.MethodGet, "/api/menu?cursor=bogus", nil)
+		resp, err := app.Test(req)
+
+		assert.NoError(t, err)
+		assert.Equal(t, fiber.StatusBadRequest, resp.StatusCode)
+
+		var result map[string]interface{}
+		err = json.NewDecoder(resp.Body).Decode(&result)
+		assert.NoError(t, err)
+		assert.Equal(t, "Invalid cursor", result["error"])
+	})
+
 	// Verify that all expected mock calls were made
 	mockDB.AssertExpectations(t)
 }
@@ -300,14 +384,27 @@ func TestHandleSearchItems(t *testing.T) {
 		},
 	}
 
-	categoryNames := []string{"Fast Food", "Fast Food"}
-	categoryPics := []string{"fast-food.jpg", "fast-food.jpg"}
+	searchResults := []models.SearchResult{
+		{
+			Item:            searchItems[0],
+			CategoryName:    "Fast Food",
+			CategoryPicture: "fast-food.jpg",
+			Highlight:       "Cheese <mark>Burger</mark>",
+		},
+		{
+			Item:            searchItems[1],
+			CategoryName:    "Fast Food",
+			CategoryPicture: "fast-food.jpg",
+			Highlight:       "Veggie <mark>Burger</mark>",
+		},
+	}
+	searchPage := db.PageRequest{Limit: db.DefaultPageSize, Sort: db.SortRelevance}
 
 	app.Get("/api/menu/search", menuService.HandleSearchItems)
 
 	// Test case 1: Successful search
 	t.Run("Search items successfully", func(t *testing.T) {
-		mockDB.On("SearchMenuItems", "burger").Return(searchItems, categoryNames, categoryPics, nil).Once()
+		mockDB.On("SearchMenuItems", "burger", searchPage).Return(searchResults, db.PageInfo{Total: 2}, nil).Once()
 
 		req := httptest.NewRequest(http.MethodGet, "/api/menu/search?q=burger", nil)
 		resp, err := app.Test(req)
@@ -338,6 +435,11 @@ func TestHandleSearchItems(t *testing.T) {
 		assert.Equal(t, "cat1", category["id"], "Category ID should match")
 		assert.Equal(t, "Fast Food", category["name"], "Category name should match")
 		assert.Equal(t, "fast-food.jpg", category["picture"], "Category picture should match")
+		assert.Equal(t, "Cheese <mark>Burger</mark>", firstResult["highlight"], "Highlighted name should be returned")
+
+		pagination := result["pagination"].(map[string]interface{})
+		assert.Equal(t, "relevance", pagination["sort"], "Search should sort by relevance by default")
+		assert.Equal(t, float64(2), pagination["total"], "Total should match")
 	})
 
 	// Test case 2: Empty search query
@@ -359,10 +461,9 @@ func TestHandleSearchItems(t *testing.T) {
 
 	// Test case 3: Database error
 	t.Run("Database error", func(t *testing.T) {
-		mockDB.On("SearchMenuItems", "error").Return(
-			[]models.MenuItem{},
-			[]string{},
-			[]string{},
+		mockDB.On("SearchMenuItems", "error", searchPage).Return(
+			[]models.SearchResult{},
+			db.PageInfo{},
 			errors.New("database connection failed")).Once()
 
 		req := httptest.NewRequest(http.MethodGet, "/api/menu/search?q=error", nil)
@@ -382,10 +483,9 @@ func TestHandleSearchItems(t *testing.T) {
 
 	// Test case 4: No results found (empty results but not an error)
 	t.Run("No search results", func(t *testing.T) {
-		mockDB.On("SearchMenuItems", "nonexistent").Return(
-			[]models.MenuItem{},
-			[]string{},
-			[]string{},
+		mockDB.On("SearchMenuItems", "nonexistent", searchPage).Return(
+			[]models.SearchResult{},
+			db.PageInfo{},
 			nil).Once()
 
 		req := httptest.NewRequest(http.MethodGet, "/api/menu/search?q=nonexistent", nil)
@@ -404,6 +504,74 @@ func TestHandleSearchItems(t *testing.T) {
 		assert.Equal(t, float64(0), result["count"], "Count should be 0")
 	})
 
+	// Test case 5: Explicit sort overrides relevance
+	t.Run("Search sorted by price", func(t *testing.T) {
+		page := db.PageRequest{Limit: 5, Sort: db.SortPrice}
+		mockDB.On("SearchMenuItems", "burger", page).Return(searchResults, db.PageInfo{Total: 2}, nil).Once()
+
+		req := httptest.NewRequest(http.MethodGet, "/api/menu/search?q=burger&sort=price&limit=5", nil)
+		resp, err := app.Test(req)
+
+		assert.NoError(t, err)
+		assert.Equal(t, fiber.StatusOK, resp.StatusCode)
+	})
+
+	mockDB.AssertExpectations(t)
+}
+
+func TestHandleSuggestItems(t *testing.T) {
+	app := fiber.New()
+	mockDB := new(mocks.MockDB)
+	menuService := services.NewMenuService(mockDB)
+
+	app.Get("/api/menu/suggest", menuService.HandleSuggestItems)
+
+	// Test case 1: Suggestions for a prefix
+	t.Run("Suggest items successfully", func(t *testing.T) {
+		suggestions := []models.Suggestion{
+			{ID: "item1", Name: "Chicken Shawarma", CategoryID: "cat1", CategoryName: "Sandwiches"},
+			{ID: "item2", Name: "Chicken Burger", CategoryID: "cat1", CategoryName: "Sandwiches"},
+		}
+		mockDB.On("SuggestMenuItems", "chick", db.DefaultSuggestLimit).Return(suggestions, nil).Once()
+
+		req := httptest.NewRequest(http.MethodGet, "/api/menu/suggest?q=chick", nil)
+		resp, err := app.Test(req)
+
+		assert.NoError(t, err)
+		assert.Equal(t, fiber.StatusOK, resp.StatusCode)
+
+		var result map[string]interface{}
+		err = json.NewDecoder(resp.Body).Decode(&result)
+		assert.NoError(t, err)
+
+		results, ok := result["suggestions"].([]interface{})
+		assert.True(t, ok, "Response should have a 'suggestions' array field")
+		assert.Len(t, results, 2, "Suggestions should contain 2 items")
+		first := results[0].(map[string]interface{})
+		assert.Equal(t, "Chicken Shawarma", first["name"], "Suggestion name should match")
+		assert.Equal(t, "Sandwiches", first["category_name"], "Suggestion category should match")
+	})
+
+	// Test case 2: Missing query
+	t.Run("Empty query", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, "/api/menu/suggest?q=%20", nil)
+		resp, err := app.Test(req)
+
+		assert.NoError(t, err)
+		assert.Equal(t, fiber.StatusBadRequest, resp.StatusCode)
+	})
+
+	// Test case 3: Database error
+	t.Run("Database error", func(t *testing.T) {
+		mockDB.On("SuggestMenuItems", "sh", 3).Return([]models.Suggestion{}, errors.New("database connection failed")).Once()
+
+		req := httptest.NewRequest(http.MethodGet, "/api/menu/suggest?q=sh&limit=3", nil)
+		resp, err := app.Test(req)
+
+		assert.NoError(t, err)
+		assert.Equal(t, fiber.StatusInternalServerError, resp.StatusCode)
+	})
+
 	mockDB.AssertExpectations(t)
 }
 
@@ -606,6 +774,65 @@ func TestHandleCreateMenuItem(t *testing.T) {
 		assert.Contains(t, errorMsg, "Failed to create menu item", "Error message should indicate creation failure")
 	})
 
+	// Test case: Dietary metadata is normalized
+	t.Run("Create menu item with metadata", func(t *testing.T) {
+		mockAuth.On("VerifyManagerRole", "valid-token").Return(nil).Once()
+		mockDB.On("GetCategoryID", "Burgers").Return(categoryID, nil).Once()
+		mockDB.On("CreateMenuItem", mock.MatchedBy(func(item models.MenuItem) bool {
+			return assert.ObjectsAreEqual([]string{"milk", "tree_nuts"}, item.Allergens) &&
+				assert.ObjectsAreEqual([]string{"vegetarian"}, item.DietaryTags) &&
+				item.Nutrition != nil && item.Nutrition.Calories == 640 && item.SpiceLevel == 2
+		}), categoryID).Return(itemID, nil).Once()
+
+		requestBody := `{
+			"name": "Paneer Burger",
+			"price": 11.5,
+			"category_name": "Burgers",
+			"allergens": ["Milk", "tree-nuts", "milk"],
+			"dietary_tags": ["Vegetarian"],
+			"nutrition": {"calories": 640, "protein_g": 28, "carbs_g": 55, "fat_g": 31.5},
+			"spice_level": 2
+		}`
+
+		req := httptest.NewRequest(http.MethodPost, "/api/menu", strings.NewReader(requestBody))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("Authorization", "valid-token")
+		resp, err := app.Test(req)
+
+		assert.NoError(t, err)
+		assert.Equal(t, fiber.StatusCreated, resp.StatusCode)
+
+		var result map[string]interface{}
+		err = json.NewDecoder(resp.Body).Decode(&result)
+		assert.NoError(t, err)
+
+		item := result["item"].(map[string]interface{})
+		assert.Equal(t, []interface{}{"milk", "tree_nuts"}, item["allergens"], "Allergens should be normalized")
+		assert.Equal(t, float64(2), item["spice_level"], "Spice level should match")
+		nutrition := item["nutrition"].(map[string]interface{})
+		assert.Equal(t, 31.5, nutrition["fat_g"], "Nutrition should match")
+	})
+
+	// Test case: Invalid metadata
+	for name, body := range map[string]string{
+		"Unknown allergen":   `{"name": "Burger", "price": 10, "category_name": "Burgers", "allergens": ["pineapple"]}`,
+		"Unknown tag":        `{"name": "Burger", "price": 10, "category_name": "Burgers", "dietary_tags": ["keto"]}`,
+		"Spice out of range": `{"name": "Burger", "price": 10, "category_name": "Burgers", "spice_level": 5}`,
+		"Negative nutrition": `{"name": "Burger", "price": 10, "category_name": "Burgers", "nutrition": {"calories": -1}}`,
+	} {
+		t.Run(name, func(t *testing.T) {
+			mockAuth.On("VerifyManagerRole", "valid-token").Return(nil).Once()
+
+			req := httptest.NewRequest(http.MethodPost, "/api/menu", strings.NewReader(body))
+			req.Header.Set("Content-Type", "application/json")
+			req.Header.Set("Authorization", "valid-token")
+			resp, err := app.Test(req)
+
+			assert.NoError(t, err)
+			assert.Equal(t, fiber.StatusBadRequest, resp.StatusCode)
+		})
+	}
+
 	mockDB.AssertExpectations(t)
 	mockAuth.AssertExpectations(t)
 }
@@ -1057,6 +1284,38 @@ func TestHandleAddDiscount(t *testing.T) {
 		mockAuth.AssertExpectations(t)
 	})
 
+	// Test case 3b: Manager without a second factor
+	t.Run("Manager without MFA", func(t *testing.T) {
+		app := fiber.New()
+		mockDB := new(mocks.MockDB)
+		mockAuth := new(mocks.MockAuthVerifier)
+		menuService := services.NewMenuService(mockDB, mockAuth)
+		app.Post("/api/menu/:id/discount", menuService.HandleAddDiscount)
+
+		mockAuth.On("VerifyManagerRole", "unenrolled-token").Return(auth.ErrMFANotEnrolled).Once()
+
+		requestBody := `{
+			"discount_value": 20.0,
+			"active": true
+		}`
+
+		req := httptest.NewRequest(http.MethodPost, "/api/menu/item-123/discount", strings.NewReader(requestBody))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("Authorization", "unenrolled-token")
+		resp, err := app.Test(req)
+
+		assert.NoError(t, err)
+		assert.Equal(t, fiber.StatusForbidden, resp.StatusCode)
+
+		var result map[string]interface{}
+		err = json.NewDecoder(resp.Body).Decode(&result)
+		assert.NoError(t, err)
+		assert.Equal(t, auth.ErrMFANotEnrolled.Error(), result["error"])
+
+		mockDB.AssertExpectations(t)
+		mockAuth.AssertExpectations(t)
+	})
+
 	// Test case 4: Missing item ID
 	t.Run("Missing item ID", func(t *testing.T) {
 		app := fiber.New()
@@ -1352,8 +1611,8 @@ func TestHandleFilterItems(t *testing.T) {
 		categoryNames := []string{"Fast Food"}
 		categoryPics := []string{"fastfood.jpg"}
 
-		mockDB.On("FilterMenuItems", "cat1", "10.0", "20.0", "true", "true").
-			Return(filteredItems, categoryNames, categoryPics, nil).Once()
+		mockDB.On("FilterMenuItems", "cat1", "10.0", "20.0", "true", "true", db.MetadataFilter{}, defaultPage).
+			Return(filteredItems, categoryNames, categoryPics, db.PageInfo{Total: len(filteredItems)}, nil).Once()
 
 		req := httptest.NewRequest(http.MethodGet, "/api/menu/filter?category_id=cat1&min_price=10.0&max_price=20.0&has_discount=true&is_available=true", nil)
 		resp, err := app.Test(req)
@@ -1436,8 +1695,8 @@ func TestHandleFilterItems(t *testing.T) {
 		categoryNames := []string{"Fast Food", "Fast Food", "Desserts"}
 		categoryPics := []string{"fastfood.jpg", "fastfood.jpg", "desserts.jpg"}
 
-		mockDB.On("FilterMenuItems", "", "", "", "", "").
-			Return(allItems, categoryNames, categoryPics, nil).Once()
+		mockDB.On("FilterMenuItems", "", "", "", "", "", db.MetadataFilter{}, defaultPage).
+			Return(allItems, categoryNames, categoryPics, db.PageInfo{Total: len(allItems)}, nil).Once()
 
 		req := httptest.NewRequest(http.MethodGet, "/api/menu/filter", nil)
 		resp, err := app.Test(req)
@@ -1489,8 +1748,8 @@ func TestHandleFilterItems(t *testing.T) {
 		categoryNames := []string{"Fast Food", "Fast Food"}
 		categoryPics := []string{"fastfood.jpg", "fastfood.jpg"}
 
-		mockDB.On("FilterMenuItems", "cat1", "", "", "", "").
-			Return(categoryItems, categoryNames, categoryPics, nil).Once()
+		mockDB.On("FilterMenuItems", "cat1", "", "", "", "", db.MetadataFilter{}, defaultPage).
+			Return(categoryItems, categoryNames, categoryPics, db.PageInfo{Total: len(categoryItems)}, nil).Once()
 
 		req := httptest.NewRequest(http.MethodGet, "/api/menu/filter?category_id=cat1", nil)
 		resp, err := app.Test(req)
@@ -1536,8 +1795,8 @@ func TestHandleFilterItems(t *testing.T) {
 		categoryNames := []string{"Fast Food"}
 		categoryPics := []string{"fastfood.jpg"}
 
-		mockDB.On("FilterMenuItems", "", "15.0", "20.0", "", "").
-			Return(priceRangeItems, categoryNames, categoryPics, nil).Once()
+		mockDB.On("FilterMenuItems", "", "15.0", "20.0", "", "", db.MetadataFilter{}, defaultPage).
+			Return(priceRangeItems, categoryNames, categoryPics, db.PageInfo{Total: len(priceRangeItems)}, nil).Once()
 
 		req := httptest.NewRequest(http.MethodGet, "/api/menu/filter?min_price=15.0&max_price=20.0", nil)
 		resp, err := app.Test(req)
@@ -1584,8 +1843,8 @@ func TestHandleFilterItems(t *testing.T) {
 		categoryNames := []string{"Fast Food"}
 		categoryPics := []string{"fastfood.jpg"}
 
-		mockDB.On("FilterMenuItems", "", "", "", "true", "true").
-			Return(discountedItems, categoryNames, categoryPics, nil).Once()
+		mockDB.On("FilterMenuItems", "", "", "", "true", "true", db.MetadataFilter{}, defaultPage).
+			Return(discountedItems, categoryNames, categoryPics, db.PageInfo{Total: len(discountedItems)}, nil).Once()
 
 		req := httptest.NewRequest(http.MethodGet, "/api/menu/filter?has_discount=true&is_available=true", nil)
 		resp, err := app.Test(req)
@@ -1621,8 +1880,8 @@ func TestHandleFilterItems(t *testing.T) {
 		menuService := services.NewMenuService(mockDB)
 		app.Get("/api/menu/filter", menuService.HandleFilterItems)
 
-		mockDB.On("FilterMenuItems", "nonexistent", "", "", "", "").
-			Return([]models.MenuItem{}, []string{}, []string{}, nil).Once()
+		mockDB.On("FilterMenuItems", "nonexistent", "", "", "", "", db.MetadataFilter{}, defaultPage).
+			Return([]models.MenuItem{}, []string{}, []string{}, db.PageInfo{}, nil).Once()
 
 		req := httptest.NewRequest(http.MethodGet, "/api/menu/filter?category_id=nonexistent", nil)
 		resp, err := app.Test(req)
@@ -1649,8 +1908,8 @@ func TestHandleFilterItems(t *testing.T) {
 		menuService := services.NewMenuService(mockDB)
 		app.Get("/api/menu/filter", menuService.HandleFilterItems)
 
-		mockDB.On("FilterMenuItems", "", "", "", "", "").
-			Return([]models.MenuItem{}, []string{}, []string{}, errors.New("database connection failed")).Once()
+		mockDB.On("FilterMenuItems", "", "", "", "", "", db.MetadataFilter{}, defaultPage).
+			Return([]models.MenuItem{}, []string{}, []string{}, db.PageInfo{}, errors.New("database connection failed")).Once()
 
 		req := httptest.NewRequest(http.MethodGet, "/api/menu/filter", nil)
 		resp, err := app.Test(req)
@@ -1668,4 +1927,1137 @@ func TestHandleFilterItems(t *testing.T) {
 
 		mockDB.AssertExpectations(t)
 	})
+
+	// Test case 8: Dietary metadata filters
+	t.Run("Filter by dietary metadata", func(t *testing.T) {
+		app := fiber.New()
+		mockDB := new(mocks.MockDB)
+		menuService := services.NewMenuService(mockDB)
+		app.Get("/api/menu/filter", menuService.HandleFilterItems)
+
+		spice, calories := 1, 700
+		metadata := db.MetadataFilter{
+			Tags:             []string{"vegan", "vegetarian"},
+			ExcludeAllergens: []string{"peanut", "tree_nuts"},
+			MaxSpiceLevel:    &spice,
+			MaxCalories:      &calories,
+		}
+		items := []models.MenuItem{
+			{ID: "item5", Name: "Falafel Wrap", Price: 8, CategoryID: "cat2", DietaryTags: []string{"vegan", "vegetarian"}},
+		}
+		mockDB.On("FilterMenuItems", "", "", "", "", "", metadata, defaultPage).
+			Return(items, []string{"Wraps"}, []string{""}, db.PageInfo{Total: 1}, nil).Once()
+
+		req := httptest.NewRequest(http.MethodGet, "/api/menu/filter?tags=vegetarian,Vegan&exclude_allergens=peanut,tree%20nuts&max_spice_level=1&max_calories=700", nil)
+		resp, err := app.Test(req)
+
+		assert.NoError(t, err)
+		assert.Equal(t, fiber.StatusOK, resp.StatusCode)
+
+		var result map[string]interface{}
+		err = json.NewDecoder(resp.Body).Decode(&result)
+		assert.NoError(t, err)
+
+		filters := result["filters"].(map[string]interface{})
+		assert.Equal(t, []interface{}{"vegan", "vegetarian"}, filters["tags"], "Tags filter should be normalized")
+		assert.Equal(t, []interface{}{"peanut", "tree_nuts"}, filters["exclude_allergens"], "Allergen filter should be normalized")
+
+		item := result["results"].([]interface{})[0].(map[string]interface{})
+		assert.Equal(t, []interface{}{"vegan", "vegetarian"}, item["dietary_tags"], "Item tags should be returned")
+
+		mockDB.AssertExpectations(t)
+	})
+
+	// Test case 9: Unknown dietary filters
+	t.Run("Invalid dietary filters", func(t *testing.T) {
+		app := fiber.New()
+		mockDB := new(mocks.MockDB)
+		menuService := services.NewMenuService(mockDB)
+		app.Get("/api/menu/filter", menuService.HandleFilterItems)
+
+		for _, query := range []string{"tags=keto", "exclude_allergens=pineapple", "max_spice_level=hot", "max_calories=-5"} {
+			req := httptest.NewRequest(http.MethodGet, "/api/menu/filter?"+query, nil)
+			resp, err := app.Test(req)
+
+			assert.NoError(t, err)
+			assert.Equal(t, fiber.StatusBadRequest, resp.StatusCode, query)
+		}
+
+		mockDB.AssertNotCalled(t, "FilterMenuItems")
+	})
+}
+
+func TestHandleCreateCategory(t *testing.T) {
+	app := fiber.New()
+	mockDB := new(mocks.MockDB)
+	mockAuth := new(mocks.MockAuthVerifier)
+	menuService := services.NewMenuService(mockDB, mockAuth)
+
+	app.Post("/api/categories", menuService.HandleCreateCategory)
+
+	// Test case 1: Successful creation
+	t.Run("Create category successfully", func(t *testing.T) {
+		mockAuth.On("VerifyManagerRole", "valid-token").Return(nil).Once()
+		mockDB.On("CreateCategory", "Drinks", "drinks.jpg").
+			Return(models.Category{ID: "cat4", Name: "Drinks", Picture: "drinks.jpg", Position: 3}, nil).Once()
+
+		req := httptest.NewRequest(http.MethodPost, "/api/categories", strings.NewReader(`{"name": " Drinks ", "picture": "drinks.jpg"}`))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("Authorization", "valid-token")
+		resp, err := app.Test(req)
+
+		assert.NoError(t, err)
+		assert.Equal(t, fiber.StatusCreated, resp.StatusCode)
+
+		var result map[string]interface{}
+		err = json.NewDecoder(resp.Body).Decode(&result)
+		assert.NoError(t, err)
+
+		category := result["category"].(map[string]interface{})
+		assert.Equal(t, "cat4", category["id"], "Category ID should match")
+		assert.Equal(t, "Drinks", category["name"], "Category name should match")
+		assert.Equal(t, float64(3), category["position"], "Category should be placed last")
+	})
+
+	// Test case 2: Not a manager
+	t.Run("Unauthorized", func(t *testing.T) {
+		mockAuth.On("VerifyManagerRole", "customer-token").Return(errors.New("manager role required")).Once()
+
+		req := httptest.NewRequest(http.MethodPost, "/api/categories", strings.NewReader(`{"name": "Drinks"}`))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("Authorization", "customer-token")
+		resp, err := app.Test(req)
+
+		assert.NoError(t, err)
+		assert.Equal(t, fiber.StatusUnauthorized, resp.StatusCode)
+	})
+
+	// Test case 3: Missing name
+	t.Run("Missing name", func(t *testing.T) {
+		mockAuth.On("VerifyManagerRole", "valid-token").Return(nil).Once()
+
+		req := httptest.NewRequest(http.MethodPost, "/api/categories", strings.NewReader(`{"name": "  "}`))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("Authorization", "valid-token")
+		resp, err := app.Test(req)
+
+		assert.NoError(t, err)
+		assert.Equal(t, fiber.StatusBadRequest, resp.StatusCode)
+	})
+
+	// Test case 4: Duplicate name
+	t.Run("Duplicate name", func(t *testing.T) {
+		mockAuth.On("VerifyManagerRole", "valid-token").Return(nil).Once()
+		mockDB.On("CreateCategory", "Desserts", "").Return(models.Category{}, db.ErrCategoryExists).Once()
+
+		req := httptest.NewRequest(http.MethodPost, "/api/categories", strings.NewReader(`{"name": "Desserts"}`))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("Authorization", "valid-token")
+		resp, err := app.Test(req)
+
+		assert.NoError(t, err)
+		assert.Equal(t, fiber.StatusConflict, resp.StatusCode)
+	})
+
+	mockDB.AssertExpectations(t)
+	mockAuth.AssertExpectations(t)
+}
+
+func TestHandleUpdateCategory(t *testing.T) {
+	app := fiber.New()
+	mockDB := new(mocks.MockDB)
+	mockAuth := new(mocks.MockAuthVerifier)
+	menuService := services.NewMenuService(mockDB, mockAuth)
+
+	app.Patch("/api/categories/:id", menuService.HandleUpdateCategory)
+
+	existing := models.Category{ID: "cat1", Name: "Appetizers", Picture: "appetizers.jpg", Position: 0}
+
+	// Test case 1: Rename keeps the picture
+	t.Run("Rename category", func(t *testing.T) {
+		mockAuth.On("VerifyManagerRole", "valid-token").Return(nil).Once()
+		mockDB.On("GetCategoryByID", "cat1").Return(existing, nil).Once()
+		mockDB.On("UpdateCategory", models.Category{ID: "cat1", Name: "Starters", Picture: "appetizers.jpg", Position: 0}).Return(nil).Once()
+
+		req := httptest.NewRequest(http.MethodPatch, "/api/categories/cat1", strings.NewReader(`{"name": "Starters"}`))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("Authorization", "valid-token")
+		resp, err := app.Test(req)
+
+		assert.NoError(t, err)
+		assert.Equal(t, fiber.StatusOK, resp.StatusCode)
+	})
+
+	// Test case 2: Picture update keeps the name
+	t.Run("Update picture", func(t *testing.T) {
+		mockAuth.On("VerifyManagerRole", "valid-token").Return(nil).Once()
+		mockDB.On("GetCategoryByID", "cat1").Return(existing, nil).Once()
+		mockDB.On("UpdateCategory", models.Category{ID: "cat1", Name: "Appetizers", Picture: "starters.jpg", Position: 0}).Return(nil).Once()
+
+		req := httptest.NewRequest(http.MethodPatch, "/api/categories/cat1", strings.NewReader(`{"picture": "starters.jpg"}`))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("Authorization", "valid-token")
+		resp, err := app.Test(req)
+
+		assert.NoError(t, err)
+		assert.Equal(t, fiber.StatusOK, resp.StatusCode)
+	})
+
+	// Test case 3: Unknown category
+	t.Run("Category not found", func(t *testing.T) {
+		mockAuth.On("VerifyManagerRole", "valid-token").Return(nil).Once()
+		mockDB.On("GetCategoryByID", "missing").Return(models.Category{}, db.ErrCategoryNotFound).Once()
+
+		req := httptest.NewRequest(http.MethodPatch, "/api/categories/missing", strings.NewReader(`{"name": "Starters"}`))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("Authorization", "valid-token")
+		resp, err := app.Test(req)
+
+		assert.NoError(t, err)
+		assert.Equal(t, fiber.StatusNotFound, resp.StatusCode)
+	})
+
+	mockDB.AssertExpectations(t)
+	mockAuth.AssertExpectations(t)
+}
+
+func TestHandleReorderCategories(t *testing.T) {
+	app := fiber.New()
+	mockDB := new(mocks.MockDB)
+	mockAuth := new(mocks.MockAuthVerifier)
+	menuService := services.NewMenuService(mockDB, mockAuth)
+
+	app.Put("/api/categories/order", menuService.HandleReorderCategories)
+
+	// Test case 1: Successful reorder returns the new order
+	t.Run("Reorder categories", func(t *testing.T) {
+		mockAuth.On("VerifyManagerRole", "valid-token").Return(nil).Once()
+		mockDB.On("ReorderCategories", []string{"cat2", "cat1"}).Return(nil).Once()
+		mockDB.On("GetAllCategories").Return([]models.Category{
+			{ID: "cat2", Name: "Main Courses", Position: 0},
+			{ID: "cat1", Name: "Appetizers", Position: 1},
+		}, nil).Once()
+
+		req := httptest.NewRequest(http.MethodPut, "/api/categories/order", strings.NewReader(`{"category_ids": ["cat2", "cat1"]}`))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("Authorization", "valid-token")
+		resp, err := app.Test(req)
+
+		assert.NoError(t, err)
+		assert.Equal(t, fiber.StatusOK, resp.StatusCode)
+
+		var result map[string]interface{}
+		err = json.NewDecoder(resp.Body).Decode(&result)
+		assert.NoError(t, err)
+
+		categories := result["categories"].([]interface{})
+		assert.Equal(t, "cat2", categories[0].(map[string]interface{})["id"], "Categories should be in the new order")
+	})
+
+	// Test case 2: Incomplete order
+	t.Run("Incomplete order", func(t *testing.T) {
+		mockAuth.On("VerifyManagerRole", "valid-token").Return(nil).Once()
+		mockDB.On("ReorderCategories", []string{"cat2"}).Return(db.ErrInvalidCategoryOrder).Once()
+
+		req := httptest.NewRequest(http.MethodPut, "/api/categories/order", strings.NewReader(`{"category_ids": ["cat2"]}`))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("Authorization", "valid-token")
+		resp, err := app.Test(req)
+
+		assert.NoError(t, err)
+		assert.Equal(t, fiber.StatusBadRequest, resp.StatusCode)
+	})
+
+	mockDB.AssertExpectations(t)
+	mockAuth.AssertExpectations(t)
+}
+
+func TestHandleDeleteCategory(t *testing.T) {
+	app := fiber.New()
+	mockDB := new(mocks.MockDB)
+	mockAuth := new(mocks.MockAuthVerifier)
+	menuService := services.NewMenuService(mockDB, mockAuth)
+
+	app.Delete("/api/categories/:id", menuService.HandleDeleteCategory)
+
+	// Test case 1: Category with items is rejected without reassign_to
+	t.Run("Reject category with items", func(t *testing.T) {
+		mockAuth.On("VerifyManagerRole", "valid-token").Return(nil).Once()
+		mockDB.On("DeleteCategory", "cat1", "").Return(int64(0), db.ErrCategoryNotEmpty).Once()
+
+		req := httptest.NewRequest(http.MethodDelete, "/api/categories/cat1", nil)
+		req.Header.Set("Authorization", "valid-token")
+		resp, err := app.Test(req)
+
+		assert.NoError(t, err)
+		assert.Equal(t, fiber.StatusConflict, resp.StatusCode)
+	})
+
+	// Test case 2: Items are moved to another category
+	t.Run("Reassign items and delete", func(t *testing.T) {
+		mockAuth.On("VerifyManagerRole", "valid-token").Return(nil).Once()
+		mockDB.On("DeleteCategory", "cat1", "cat2").Return(int64(4), nil).Once()
+
+		req := httptest.NewRequest(http.MethodDelete, "/api/categories/cat1?reassign_to=cat2", nil)
+		req.Header.Set("Authorization", "valid-token")
+		resp, err := app.Test(req)
+
+		assert.NoError(t, err)
+		assert.Equal(t, fiber.StatusOK, resp.StatusCode)
+
+		var result map[string]interface{}
+		err = json.NewDecoder(resp.Body).Decode(&result)
+		assert.NoError(t, err)
+		assert.Equal(t, float64(4), result["items_moved"], "Moved item count should be returned")
+	})
+
+	// Test case 3: Reassign target does not exist
+	t.Run("Unknown reassign target", func(t *testing.T) {
+		mockAuth.On("VerifyManagerRole", "valid-token").Return(nil).Once()
+		mockDB.On("DeleteCategory", "cat1", "missing").Return(int64(0), db.ErrReassignTargetMissing).Once()
+
+		req := httptest.NewRequest(http.MethodDelete, "/api/categories/cat1?reassign_to=missing", nil)
+		req.Header.Set("Authorization", "valid-token")
+		resp, err := app.Test(req)
+
+		assert.NoError(t, err)
+		assert.Equal(t, fiber.StatusBadRequest, resp.StatusCode)
+	})
+
+	// Test case 4: Not a manager
+	t.Run("Unauthorized", func(t *testing.T) {
+		mockAuth.On("VerifyManagerRole", "").Return(errors.New("missing token")).Once()
+
+		req := httptest.NewRequest(http.MethodDelete, "/api/categories/cat1", nil)
+		resp, err := app.Test(req)
+
+		assert.NoError(t, err)
+		assert.Equal(t, fiber.StatusUnauthorized, resp.StatusCode)
+	})
+
+	mockDB.AssertExpectations(t)
+	mockAuth.AssertExpectations(t)
+}
+
+// imageUploadRequest builds a multipart request carrying data as the "image" file
+func imageUploadRequest(t *testing.T, target, filename string, data []byte) *http.Request {
+	var body bytes.Buffer
+	writer := multipart.NewWriter(&body)
+	part, err := writer.CreateFormFile("image", filename)
+	assert.NoError(t, err)
+	_, err = part.Write(data)
+	assert.NoError(t, err)
+	assert.NoError(t, writer.Close())
+
+	req := httptest.NewRequest(http.MethodPut, target, &body)
+	req.Header.Set("Content-Type", writer.FormDataContentType())
+	req.Header.Set("Authorization", "valid-token")
+	return req
+}
+
+// testPNG encodes an opaque width by height PNG
+func testPNG(t *testing.T, width, height int) []byte {
+	img := image.NewRGBA(image.Rect(0, 0, width, height))
+	for x := 0; x < width; x++ {
+		for y := 0; y < height; y++ {
+			img.Set(x, y, color.RGBA{R: uint8(x), G: uint8(y), B: 128, A: 255})
+		}
+	}
+	var buf bytes.Buffer
+	assert.NoError(t, png.Encode(&buf, img))
+	return buf.Bytes()
+}
+
+func TestHandleUploadMenuItemImage(t *testing.T) {
+	mockDB := new(mocks.MockDB)
+	mockAuth := new(mocks.MockAuthVerifier)
+	store := mocks.NewMemoryBlobStore()
+	menuService := services.NewMenuService(mockDB, mockAuth)
+	menuService.Images = store
+
+	app := fiber.New()
+	app.Put("/api/menu/:id/image", menuService.HandleUploadMenuItemImage)
+
+	// Test case 1: Upload replaces the previous image
+	t.Run("Upload image successfully", func(t *testing.T) {
+		previous := &models.Image{
+			Full:          "items/item1/old/full.jpg",
+			FullWebP:      "items/item1/old/full.webp",
+			Thumbnail:     "items/item1/old/thumb.jpg",
+			ThumbnailWebP: "items/item1/old/thumb.webp",
+		}
+		for _, key := range previous.Keys() {
+			store.Files[key] = []byte("old")
+		}
+
+		mockAuth.On("VerifyManagerRole", "valid-token").Return(nil).Once()
+		mockDB.On("GetMenuItemByID", "item1").Return(models.MenuItem{ID: "item1"}, "Main", nil).Once()
+		mockDB.On("SetMenuItemImage", "item1", mock.AnythingOfType("*models.Image")).Return(previous, nil).Once()
+
+		resp, err := app.Test(imageUploadRequest(t, "/api/menu/item1/image", "burger.png", testPNG(t, 1600, 800)), -1)
+
+		assert.NoError(t, err)
+		assert.Equal(t, fiber.StatusOK, resp.StatusCode)
+
+		var result map[string]interface{}
+		err = json.NewDecoder(resp.Body).Decode(&result)
+		assert.NoError(t, err)
+
+		urls := result["image"].(map[string]interface{})
+		assert.Contains(t, urls["url"], "https://images.test/items/item1/", "URL should come from the store")
+		assert.True(t, strings.HasSuffix(urls["url"].(string), "/full.jpg"), "Opaque images should be stored as JPEG")
+		assert.True(t, strings.HasSuffix(urls["thumbnail_webp_url"].(string), "/thumb.webp"), "A WebP thumbnail should be rendered")
+
+		assert.Len(t, store.Files, 4, "Only the new variants should remain stored")
+		for _, key := range previous.Keys() {
+			assert.NotContains(t, store.Files, key, "Previous variants should be deleted")
+		}
+
+		stored := mockDB.Calls[len(mockDB.Calls)-1].Arguments.Get(1).(*models.Image)
+		full, _, err := image.DecodeConfig(bytes.NewReader(store.Files[stored.Full]))
+		assert.NoError(t, err)
+		assert.Equal(t, 1200, full.Width, "Full size should be scaled down")
+		thumb, _, err := image.DecodeConfig(bytes.NewReader(store.Files[stored.Thumbnail]))
+		assert.NoError(t, err)
+		assert.Equal(t, 320, thumb.Width, "Thumbnail should be scaled down")
+		assert.Equal(t, 160, thumb.Height, "Thumbnail should keep the aspect ratio")
+	})
+
+	// Test case 2: Not an image
+	t.Run("Unsupported type", func(t *testing.T) {
+		mockAuth.On("VerifyManagerRole", "valid-token").Return(nil).Once()
+		mockDB.On("GetMenuItemByID", "item1").Return(models.MenuItem{ID: "item1"}, "Main", nil).Once()
+
+		resp, err := app.Test(imageUploadRequest(t, "/api/menu/item1/image", "burger.png", []byte("<svg onload=alert(1)>")))
+
+		assert.NoError(t, err)
+		assert.Equal(t, fiber.StatusUnsupportedMediaType, resp.StatusCode)
+	})
+
+	// Test case 3: Item does not exist
+	t.Run("Item not found", func(t *testing.T) {
+		mockAuth.On("VerifyManagerRole", "valid-token").Return(nil).Once()
+		mockDB.On("GetMenuItemByID", "missing").Return(models.MenuItem{}, "", errors.New("no rows")).Once()
+
+		resp, err := app.Test(imageUploadRequest(t, "/api/menu/missing/image", "burger.png", testPNG(t, 10, 10)))
+
+		assert.NoError(t, err)
+		assert.Equal(t, fiber.StatusNotFound, resp.StatusCode)
+	})
+
+	// Test case 4: Not a manager
+	t.Run("Unauthorized", func(t *testing.T) {
+		mockAuth.On("VerifyManagerRole", "valid-token").Return(errors.New("manager role required")).Once()
+
+		resp, err := app.Test(imageUploadRequest(t, "/api/menu/item1/image", "burger.png", testPNG(t, 10, 10)))
+
+		assert.NoError(t, err)
+		assert.Equal(t, fiber.StatusUnauthorized, resp.StatusCode)
+	})
+
+	// Test case 5: No image store configured
+	t.Run("Storage not configured", func(t *testing.T) {
+		noStore := services.NewMenuService(mockDB, mockAuth)
+		app := fiber.New()
+		app.Put("/api/menu/:id/image", noStore.HandleUploadMenuItemImage)
+		mockAuth.On("VerifyManagerRole", "valid-token").Return(nil).Once()
+
+		resp, err := app.Test(imageUploadRequest(t, "/api/menu/item1/image", "burger.png", testPNG(t, 10, 10)))
+
+		assert.NoError(t, err)
+		assert.Equal(t, fiber.StatusServiceUnavailable, resp.StatusCode)
+	})
+
+	mockDB.AssertExpectations(t)
+	mockAuth.AssertExpectations(t)
+}
+
+func TestHandleUploadCategoryImage(t *testing.T) {
+	mockDB := new(mocks.MockDB)
+	mockAuth := new(mocks.MockAuthVerifier)
+	store := mocks.NewMemoryBlobStore()
+	menuService := services.NewMenuService(mockDB, mockAuth)
+	menuService.Images = store
+
+	app := fiber.New()
+	app.Put("/api/categories/:id/image", menuService.HandleUploadCategoryImage)
+
+	// Test case 1: Upload becomes the category picture
+	t.Run("Upload image successfully", func(t *testing.T) {
+		mockAuth.On("VerifyManagerRole", "valid-token").Return(nil).Once()
+		mockDB.On("GetCategoryByID", "cat1").Return(models.Category{ID: "cat1", Name: "Desserts"}, nil).Once()
+		mockDB.On("SetCategoryImage", "cat1", mock.AnythingOfType("*models.Image"),
+			mock.MatchedBy(func(picture string) bool { return strings.HasPrefix(picture, "https://images.test/categories/cat1/") })).
+			Return(nil, nil).Once()
+
+		resp, err := app.Test(imageUploadRequest(t, "/api/categories/cat1/image", "cake.png", testPNG(t, 40, 40)))
+
+		assert.NoError(t, err)
+		assert.Equal(t, fiber.StatusOK, resp.StatusCode)
+
+		var result map[string]interface{}
+		err = json.NewDecoder(resp.Body).Decode(&result)
+		assert.NoError(t, err)
+
+		category := result["category"].(map[string]interface{})
+		urls := category["image"].(map[string]interface{})
+		assert.Equal(t, urls["url"], category["picture"], "Picture should point at the uploaded image")
+		assert.Len(t, store.Files, 4, "All variants should be stored")
+	})
+
+	// Test case 2: Storing fails and the new variants are removed
+	t.Run("Save failure cleans up", func(t *testing.T) {
+		store.Files = make(map[string][]byte)
+		mockAuth.On("VerifyManagerRole", "valid-token").Return(nil).Once()
+		mockDB.On("GetCategoryByID", "cat2").Return(models.Category{ID: "cat2"}, nil).Once()
+		mockDB.On("SetCategoryImage", "cat2", mock.AnythingOfType("*models.Image"), mock.AnythingOfType("string")).
+			Return(nil, db.ErrCategoryNotFound).Once()
+
+		resp, err := app.Test(imageUploadRequest(t, "/api/categories/cat2/image", "cake.png", testPNG(t, 40, 40)))
+
+		assert.NoError(t, err)
+		assert.Equal(t, fiber.StatusNotFound, resp.StatusCode)
+		assert.Empty(t, store.Files, "Variants of a rejected upload should be deleted")
+	})
+
+	mockDB.AssertExpectations(t)
+	mockAuth.AssertExpectations(t)
+}
+
+func TestHandleSetItemOptions(t *testing.T) {
+	mockDB := new(mocks.MockDB)
+	mockAuth := new(mocks.MockAuthVerifier)
+	menuService := services.NewMenuService(mockDB, mockAuth)
+
+	app := fiber.New()
+	app.Put("/api/menu/:id/options", menuService.HandleSetItemOptions)
+	app.Get("/api/menu/:id", menuService.HandleGetMenuItem)
+
+	burger := models.MenuItem{ID: "item1", Name: "Burger", Price: 10, CategoryID: "cat1"}
+
+	// Test case 1: Options are saved with defaults applied
+	t.Run("Set options successfully", func(t *testing.T) {
+		expected := []models.OptionGroup{
+			{Name: "Size", MinSelect: 1, MaxSelect: 1, Options: []models.Option{
+				{Name: "Regular", IsAvailable: true},
+				{Name: "Large", PriceDelta: 2.5, IsAvailable: true},
+			}},
+			{ID: "grp-extras", Name: "Extras", MaxSelect: 2, Options: []models.Option{
+				{ID: "opt-cheese", Name: "Extra cheese", PriceDelta: 1, IsAvailable: false},
+				{Name: "No onions", IsAvailable: true},
+			}},
+		}
+		saved := []models.OptionGroup{{ID: "grp-size", Name: "Size", MinSelect: 1, MaxSelect: 1}}
+
+		mockAuth.On("VerifyManagerRole", "valid-token").Return(nil).Once()
+		mockDB.On("GetMenuItemByID", "item1").Return(burger, "Burgers", nil).Once()
+		mockDB.On("SetItemOptionGroups", "item1", expected).Return(saved, nil).Once()
+
+		body := `{"option_groups": [
+			{"name": " Size ", "min_select": 1, "options": [{"name": "Regular"}, {"name": "Large", "price_delta": 2.5}]},
+			{"id": "grp-extras", "name": "Extras", "max_select": 2, "options": [
+				{"id": "opt-cheese", "name": "Extra cheese", "price_delta": 1, "is_available": false},
+				{"name": "No onions"}
+			]}
+		]}`
+		req := httptest.NewRequest(http.MethodPut, "/api/menu/item1/options", strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("Authorization", "valid-token")
+		resp, err := app.Test(req)
+
+		assert.NoError(t, err)
+		assert.Equal(t, fiber.StatusOK, resp.StatusCode)
+
+		var result map[string]interface{}
+		err = json.NewDecoder(resp.Body).Decode(&result)
+		assert.NoError(t, err)
+		groups := result["option_groups"].([]interface{})
+		assert.Equal(t, "grp-size", groups[0].(map[string]interface{})["id"], "Saved groups should be returned")
+	})
+
+	// Test case 2: Invalid groups
+	for name, body := range map[string]string{
+		"Min above max":     `{"option_groups": [{"name": "Size", "min_select": 2, "max_select": 1, "options": [{"name": "A"}, {"name": "B"}]}]}`,
+		"More than options": `{"option_groups": [{"name": "Size", "max_select": 3, "options": [{"name": "A"}, {"name": "B"}]}]}`,
+		"No options":        `{"option_groups": [{"name": "Size", "options": []}]}`,
+		"Duplicate option":  `{"option_groups": [{"name": "Size", "max_select": 2, "options": [{"name": "Large"}, {"name": "large"}]}]}`,
+	} {
+		t.Run(name, func(t *testing.T) {
+			mockAuth.On("VerifyManagerRole", "valid-token").Return(nil).Once()
+
+			req := httptest.NewRequest(http.MethodPut, "/api/menu/item1/options", strings.NewReader(body))
+			req.Header.Set("Content-Type", "application/json")
+			req.Header.Set("Authorization", "valid-token")
+			resp, err := app.Test(req)
+
+			assert.NoError(t, err)
+			assert.Equal(t, fiber.StatusBadRequest, resp.StatusCode)
+		})
+	}
+
+	// Test case 3: Options would make the item free or cheaper
+	t.Run("Price below zero", func(t *testing.T) {
+		mockAuth.On("VerifyManagerRole", "valid-token").Return(nil).Once()
+		mockDB.On("GetMenuItemByID", "item1").Return(burger, "Burgers", nil).Once()
+
+		body := `{"option_groups": [{"name": "Size", "options": [{"name": "Kids", "price_delta": -12}]}]}`
+		req := httptest.NewRequest(http.MethodPut, "/api/menu/item1/options", strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("Authorization", "valid-token")
+		resp, err := app.Test(req)
+
+		assert.NoError(t, err)
+		assert.Equal(t, fiber.StatusBadRequest, resp.StatusCode)
+	})
+
+	// Test case 4: Not a manager
+	t.Run("Unauthorized", func(t *testing.T) {
+		mockAuth.On("VerifyManagerRole", "customer-token").Return(errors.New("manager role required")).Once()
+
+		req := httptest.NewRequest(http.MethodPut, "/api/menu/item1/options", strings.NewReader(`{"option_groups": []}`))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("Authorization", "customer-token")
+		resp, err := app.Test(req)
+
+		assert.NoError(t, err)
+		assert.Equal(t, fiber.StatusUnauthorized, resp.StatusCode)
+	})
+
+	// Test case 5: Item responses carry their option groups
+	t.Run("Get item with options", func(t *testing.T) {
+		withOptions := burger
+		withOptions.HasOptions = true
+		withOptions.OptionGroups = []models.OptionGroup{{ID: "grp-size", Name: "Size", MinSelect: 1, MaxSelect: 1, Options: []models.Option{
+			{ID: "opt-large", Name: "Large", PriceDelta: 2.5, IsAvailable: true},
+		}}}
+		mockDB.On("GetMenuItemByID", "item1").Return(withOptions, "Burgers", nil).Once()
+
+		resp, err := app.Test(httptest.NewRequest(http.MethodGet, "/api/menu/item1", nil))
+
+		assert.NoError(t, err)
+		assert.Equal(t, fiber.StatusOK, resp.StatusCode)
+
+		var result map[string]interface{}
+		err = json.NewDecoder(resp.Body).Decode(&result)
+		assert.NoError(t, err)
+
+		item := result["item"].(map[string]interface{})
+		assert.Equal(t, true, item["has_options"], "Item should report having options")
+		group := item["option_groups"].([]interface{})[0].(map[string]interface{})
+		option := group["options"].([]interface{})[0].(map[string]interface{})
+		assert.Equal(t, "opt-large", option["id"], "Option ID should match")
+		assert.Equal(t, 2.5, option["price_delta"], "Option price delta should match")
+	})
+
+	mockDB.AssertExpectations(t)
+	mockAuth.AssertExpectations(t)
+}
+
+func TestHandleReserveStock(t *testing.T) {
+	mockDB := new(mocks.MockDB)
+	menuService := services.NewMenuService(mockDB)
+
+	app := fiber.New()
+	app.Post("/internal/reservations", menuService.HandleReserveStock)
+
+	items := []models.ReservationItem{{ItemID: "item1", Quantity: 2}}
+
+	reserve := func(body string) *http.Response {
+		req := httptest.NewRequest(http.MethodPost, "/internal/reservations", strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		resp, err := app.Test(req)
+		assert.NoError(t, err)
+		return resp
+	}
+
+	// Test case 1: Stock is reserved for the default TTL
+	t.Run("Reserve stock successfully", func(t *testing.T) {
+		reservation := models.Reservation{ID: "res1", Reference: "order1", Status: models.ReservationHeld, Items: items}
+		mockDB.On("ReserveStock", items, "order1", services.DefaultReservationTTL).Return(reservation, nil).Once()
+
+		resp := reserve(`{"reference": "order1", "items": [{"item_id": "item1", "quantity": 2}]}`)
+		assert.Equal(t, fiber.StatusCreated, resp.StatusCode)
+
+		var result map[string]interface{}
+		err := json.NewDecoder(resp.Body).Decode(&result)
+		assert.NoError(t, err)
+		body := result["reservation"].(map[string]interface{})
+		assert.Equal(t, "res1", body["id"], "Reservation ID should be returned")
+		assert.Equal(t, models.ReservationHeld, body["status"], "Reservation should be held")
+	})
+
+	// Test case 2: Long TTLs are capped
+	t.Run("TTL is capped", func(t *testing.T) {
+		mockDB.On("ReserveStock", items, "", services.MaxReservationTTL).Return(models.Reservation{ID: "res2", Items: items}, nil).Once()
+
+		resp := reserve(`{"ttl_seconds": 86400, "items": [{"item_id": "item1", "quantity": 2}]}`)
+		assert.Equal(t, fiber.StatusCreated, resp.StatusCode)
+	})
+
+	// Test case 3: Reservation errors map to status codes
+	for name, tc := range map[string]struct {
+		err    error
+		status int
+	}{
+		"Insufficient stock": {fmt.Errorf("%w: Burger has 1 left", db.ErrInsufficientStock), fiber.StatusConflict},
+		"Item unavailable":   {fmt.Errorf("%w: Burger", db.ErrItemUnavailable), fiber.StatusConflict},
+		"Item not found":     {db.ErrMenuItemNotFound, fiber.StatusNotFound},
+		"No items":           {db.ErrInvalidReservation, fiber.StatusBadRequest},
+	} {
+		t.Run(name, func(t *testing.T) {
+			mockDB.On("ReserveStock", items, "order1", services.DefaultReservationTTL).Return(models.Reservation{}, tc.err).Once()
+
+			resp := reserve(`{"reference": "order1", "items": [{"item_id": "item1", "quantity": 2}]}`)
+			assert.Equal(t, tc.status, resp.StatusCode)
+
+			var result map[string]interface{}
+			err := json.NewDecoder(resp.Body).Decode(&result)
+			assert.NoError(t, err)
+			assert.Equal(t, tc.err.Error(), result["error"])
+		})
+	}
+
+	// Test case 4: Negative TTL
+	t.Run("Negative TTL", func(t *testing.T) {
+		resp := reserve(`{"ttl_seconds": -5, "items": [{"item_id": "item1", "quantity": 2}]}`)
+		assert.Equal(t, fiber.StatusBadRequest, resp.StatusCode)
+	})
+
+	mockDB.AssertExpectations(t)
+}
+
+func TestHandleCommitAndReleaseReservation(t *testing.T) {
+	mockDB := new(mocks.MockDB)
+	menuService := services.NewMenuService(mockDB)
+
+	app := fiber.New()
+	app.Post("/internal/reservations/:id/commit", menuService.HandleCommitReservation)
+	app.Post("/internal/reservations/:id/release", menuService.HandleReleaseReservation)
+
+	post := func(target string) *http.Response {
+		resp, err := app.Test(httptest.NewRequest(http.MethodPost, target, nil))
+		assert.NoError(t, err)
+		return resp
+	}
+
+	// Test case 1: Held reservation is committed
+	t.Run("Commit reservation", func(t *testing.T) {
+		mockDB.On("CommitReservation", "res1").Return(models.Reservation{ID: "res1", Status: models.ReservationCommitted}, nil).Once()
+
+		resp := post("/internal/reservations/res1/commit")
+		assert.Equal(t, fiber.StatusOK, resp.StatusCode)
+
+		var result map[string]interface{}
+		err := json.NewDecoder(resp.Body).Decode(&result)
+		assert.NoError(t, err)
+		body := result["reservation"].(map[string]interface{})
+		assert.Equal(t, models.ReservationCommitted, body["status"])
+		assert.Equal(t, []interface{}{}, body["items"], "Items should never be null")
+	})
+
+	// Test case 2: Commit errors map to status codes
+	for name, tc := range map[string]struct {
+		err    error
+		status int
+	}{
+		"Expired":   {db.ErrReservationExpired, fiber.StatusGone},
+		"Released":  {db.ErrReservationReleased, fiber.StatusConflict},
+		"Not found": {db.ErrReservationNotFound, fiber.StatusNotFound},
+	} {
+		t.Run("Commit "+name, func(t *testing.T) {
+			mockDB.On("CommitReservation", "res2").Return(models.Reservation{ID: "res2"}, tc.err).Once()
+
+			resp := post("/internal/reservations/res2/commit")
+			assert.Equal(t, tc.status, resp.StatusCode)
+		})
+	}
+
+	// Test case 3: Reservation is released
+	t.Run("Release reservation", func(t *testing.T) {
+		mockDB.On("ReleaseReservation", "res1").Return(models.Reservation{ID: "res1", Status: models.ReservationReleased}, nil).Once()
+
+		resp := post("/internal/reservations/res1/release")
+		assert.Equal(t, fiber.StatusOK, resp.StatusCode)
+	})
+
+	// Test case 4: Unknown reservation
+	t.Run("Release unknown reservation", func(t *testing.T) {
+		mockDB.On("ReleaseReservation", "missing").Return(models.Reservation{}, db.ErrReservationNotFound).Once()
+
+		resp := post("/internal/reservations/missing/release")
+		assert.Equal(t, fiber.StatusNotFound, resp.StatusCode)
+	})
+
+	// Test case 5: Committed stock comes back when its order is cancelled
+	t.Run("Release committed reservation", func(t *testing.T) {
+		mockDB.On("ReleaseReservation", "res3").Return(models.Reservation{ID: "res3", Status: models.ReservationReleased}, nil).Once()
+
+		resp := post("/internal/reservations/res3/release")
+		assert.Equal(t, fiber.StatusOK, resp.StatusCode)
+	})
+
+	mockDB.AssertExpectations(t)
+}
+
+func TestHandleCreateDiscount(t *testing.T) {
+	mockDB := new(mocks.MockDB)
+	mockAuth := new(mocks.MockAuthVerifier)
+	menuService := services.NewMenuService(mockDB, mockAuth)
+
+	app := fiber.New()
+	app.Post("/api/discounts", menuService.HandleCreateDiscount)
+
+	create := func(body, token string) *http.Response {
+		req := httptest.NewRequest(http.MethodPost, "/api/discounts", strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("Authorization", token)
+		resp, err := app.Test(req)
+		assert.NoError(t, err)
+		return resp
+	}
+
+	// Test case 1: Recurring category discount is created with sorted weekdays
+	t.Run("Create happy hour", func(t *testing.T) {
+		expected := models.Discount{
+			CategoryID: "cat1",
+			Percentage: 20,
+			Recurrence: &models.Recurrence{Weekdays: []int{1, 2, 3, 4, 5}, StartTime: "15:00", EndTime: "17:00"},
+		}
+		created := expected
+		created.ID = "disc1"
+
+		mockAuth.On("VerifyManagerRole", "valid-token").Return(nil).Once()
+		mockDB.On("CreateDiscount", expected).Return(created, nil).Once()
+
+		resp := create(`{"category_id": "cat1", "percentage": 20,
+			"recurrence": {"weekdays": [5, 4, 3, 2, 1, 1], "start_time": "15:00", "end_time": "17:00"}}`, "valid-token")
+		assert.Equal(t, fiber.StatusCreated, resp.StatusCode)
+
+		var result map[string]interface{}
+		err := json.NewDecoder(resp.Body).Decode(&result)
+		assert.NoError(t, err)
+		discount := result["discount"].(map[string]interface{})
+		assert.Equal(t, "disc1", discount["id"], "Created discount should be returned")
+		assert.Contains(t, []interface{}{models.DiscountActive, models.DiscountInactive}, discount["status"])
+	})
+
+	// Test case 2: Invalid discounts
+	for name, body := range map[string]string{
+		"Item and category": `{"item_id": "item1", "category_id": "cat1", "percentage": 10}`,
+		"No target":         `{"percentage": 10}`,
+		"Percentage of 100": `{"item_id": "item1", "percentage": 100}`,
+		"Ends before start": `{"item_id": "item1", "percentage": 10, "starts_at": "2030-01-02T00:00:00Z", "ends_at": "2030-01-01T00:00:00Z"}`,
+		"Already ended":     `{"item_id": "item1", "percentage": 10, "ends_at": "2020-01-01T00:00:00Z"}`,
+		"Bad weekday":       `{"item_id": "item1", "percentage": 10, "recurrence": {"weekdays": [7], "start_time": "15:00", "end_time": "17:00"}}`,
+		"Bad time":          `{"item_id": "item1", "percentage": 10, "recurrence": {"weekdays": [1], "start_time": "3pm", "end_time": "17:00"}}`,
+	} {
+		t.Run(name, func(t *testing.T) {
+			mockAuth.On("VerifyManagerRole", "valid-token").Return(nil).Once()
+
+			resp := create(body, "valid-token")
+			assert.Equal(t, fiber.StatusBadRequest, resp.StatusCode)
+		})
+	}
+
+	// Test case 3: Unknown item
+	t.Run("Item not found", func(t *testing.T) {
+		mockAuth.On("VerifyManagerRole", "valid-token").Return(nil).Once()
+		mockDB.On("CreateDiscount", models.Discount{ItemID: "missing", Percentage: 10}).Return(models.Discount{}, db.ErrMenuItemNotFound).Once()
+
+		resp := create(`{"item_id": "missing", "percentage": 10}`, "valid-token")
+		assert.Equal(t, fiber.StatusNotFound, resp.StatusCode)
+	})
+
+	// Test case 4: Not a manager
+	t.Run("Unauthorized", func(t *testing.T) {
+		mockAuth.On("VerifyManagerRole", "customer-token").Return(errors.New("manager role required")).Once()
+
+		resp := create(`{"item_id": "item1", "percentage": 10}`, "customer-token")
+		assert.Equal(t, fiber.StatusUnauthorized, resp.StatusCode)
+	})
+
+	mockDB.AssertExpectations(t)
+	mockAuth.AssertExpectations(t)
+}
+
+func TestHandleListAndCancelDiscounts(t *testing.T) {
+	mockDB := new(mocks.MockDB)
+	mockAuth := new(mocks.MockAuthVerifier)
+	menuService := services.NewMenuService(mockDB, mockAuth)
+
+	app := fiber.New()
+	app.Get("/api/discounts", menuService.HandleListDiscounts)
+	app.Delete("/api/discounts/:id", menuService.HandleCancelDiscount)
+
+	ended := time.Now().Add(-time.Hour)
+
+	// Test case 1: History lists past discounts with their status
+	t.Run("List history", func(t *testing.T) {
+		past := []models.Discount{{ID: "disc1", ItemID: "item1", Percentage: 15, EndsAt: &ended}}
+		mockAuth.On("VerifyManagerRole", "valid-token").Return(nil).Once()
+		mockDB.On("ListDiscounts", db.DiscountFilter{ItemID: "item1", History: true, Limit: db.DefaultPageSize}).Return(past, nil).Once()
+
+		req := httptest.NewRequest(http.MethodGet, "/api/discounts?item_id=item1&history=true", nil)
+		req.Header.Set("Authorization", "valid-token")
+		resp, err := app.Test(req)
+
+		assert.NoError(t, err)
+		assert.Equal(t, fiber.StatusOK, resp.StatusCode)
+
+		var result map[string]interface{}
+		err = json.NewDecoder(resp.Body).Decode(&result)
+		assert.NoError(t, err)
+		discount := result["discounts"].([]interface{})[0].(map[string]interface{})
+		assert.Equal(t, "disc1", discount["id"])
+		assert.Equal(t, models.DiscountEnded, discount["status"])
+	})
+
+	// Test case 2: Cancelling keeps the discount in the history
+	t.Run("Cancel discount", func(t *testing.T) {
+		cancelled := time.Now()
+		mockAuth.On("VerifyManagerRole", "valid-token").Return(nil).Once()
+		mockDB.On("CancelDiscount", "disc2").Return(models.Discount{ID: "disc2", CategoryID: "cat1", Percentage: 10, CancelledAt: &cancelled}, nil).Once()
+
+		req := httptest.NewRequest(http.MethodDelete, "/api/discounts/disc2", nil)
+		req.Header.Set("Authorization", "valid-token")
+		resp, err := app.Test(req)
+
+		assert.NoError(t, err)
+		assert.Equal(t, fiber.StatusOK, resp.StatusCode)
+
+		var result map[string]interface{}
+		err = json.NewDecoder(resp.Body).Decode(&result)
+		assert.NoError(t, err)
+		assert.Equal(t, models.DiscountCancelled, result["discount"].(map[string]interface{})["status"])
+	})
+
+	// Test case 3: Cancel errors
+	for name, tc := range map[string]struct {
+		err    error
+		status int
+	}{
+		"Not found":     {db.ErrDiscountNotFound, fiber.StatusNotFound},
+		"Already ended": {db.ErrDiscountEnded, fiber.StatusConflict},
+	} {
+		t.Run(name, func(t *testing.T) {
+			mockAuth.On("VerifyManagerRole", "valid-token").Return(nil).Once()
+			mockDB.On("CancelDiscount", "disc3").Return(models.Discount{}, tc.err).Once()
+
+			req := httptest.NewRequest(http.MethodDelete, "/api/discounts/disc3", nil)
+			req.Header.Set("Authorization", "valid-token")
+			resp, err := app.Test(req)
+
+			assert.NoError(t, err)
+			assert.Equal(t, tc.status, resp.StatusCode)
+		})
+	}
+
+	mockDB.AssertExpectations(t)
+	mockAuth.AssertExpectations(t)
+}
+
+func TestScheduledDiscountsResolveAtRequestTime(t *testing.T) {
+	mockDB := new(mocks.MockDB)
+	menuService := services.NewMenuService(mockDB)
+
+	app := fiber.New()
+	app.Get("/api/menu/:id", menuService.HandleGetMenuItem)
+
+	now := time.Now().In(models.RestaurantLocation)
+	everyDay := []int{0, 1, 2, 3, 4, 5, 6}
+	window := func(from, to time.Duration) *models.Recurrence {
+		return &models.Recurrence{
+			Weekdays:  everyDay,
+			StartTime: now.Add(from).Format("15:04"),
+			EndTime:   now.Add(to).Format("15:04"),
+		}
+	}
+	future := now.Add(24 * time.Hour)
+
+	item := models.MenuItem{
+		ID:            "item1",
+		Name:          "Burger",
+		Price:         20,
+		CategoryID:    "cat1",
+		HasDiscount:   true,
+		DiscountValue: 10,
+		Discounts: []models.Discount{
+			// Running now around the clock, including across midnight
+			{ID: "happy-hour", CategoryID: "cat1", Percentage: 25, Recurrence: window(-time.Hour, time.Hour)},
+			// Later today, so not yet
+			{ID: "later", ItemID: "item1", Percentage: 50, Recurrence: window(time.Hour, 2*time.Hour)},
+			// Starts tomorrow
+			{ID: "tomorrow", ItemID: "item1", Percentage: 40, StartsAt: &future},
+		},
+	}
+	mockDB.On("GetMenuItemByID", "item1").Return(item, "Burgers", nil).Once()
+
+	resp, err := app.Test(httptest.NewRequest(http.MethodGet, "/api/menu/item1", nil))
+	assert.NoError(t, err)
+	assert.Equal(t, fiber.StatusOK, resp.StatusCode)
+
+	var result map[string]interface{}
+	err = json.NewDecoder(resp.Body).Decode(&result)
+	assert.NoError(t, err)
+
+	body := result["item"].(map[string]interface{})
+	assert.Equal(t, true, body["has_discount"])
+	assert.Equal(t, 25.0, body["discount_value"], "The largest running discount should win")
+	assert.Equal(t, 15.0, body["effective_price"])
+
+	statuses := map[string]interface{}{}
+	for _, discount := range body["discounts"].([]interface{}) {
+		discount := discount.(map[string]interface{})
+		statuses[discount["id"].(string)] = discount["status"]
+	}
+	assert.Equal(t, map[string]interface{}{
+		"happy-hour": models.DiscountActive,
+		"later":      models.DiscountInactive,
+		"tomorrow":   models.DiscountUpcoming,
+	}, statuses)
+
+	mockDB.AssertExpectations(t)
+}
+
+func TestHandleCreatePromoCode(t *testing.T) {
+	mockDB := new(mocks.MockDB)
+	mockAuth := new(mocks.MockAuthVerifier)
+	menuService := services.NewMenuService(mockDB, mockAuth)
+
+	app := fiber.New()
+	app.Post("/api/promo-codes", menuService.HandleCreatePromoCode)
+
+	create := func(body string) *http.Response {
+		req := httptest.NewRequest(http.MethodPost, "/api/promo-codes", strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("Authorization", "valid-token")
+		resp, err := app.Test(req)
+		assert.NoError(t, err)
+		return resp
+	}
+
+	maxUses := 100
+	expected := models.PromoCode{
+		Code:        "WELCOME5",
+		Kind:        models.PromoFixed,
+		Value:       5,
+		MaxUses:     &maxUses,
+		ItemIDs:     []string{},
+		CategoryIDs: []string{"cat1"},
+		IsActive:    true,
+	}
+
+	// Test case 1: Code is normalized and created
+	t.Run("Create promo code", func(t *testing.T) {
+		created := expected
+		created.ID = "promo1"
+		mockAuth.On("VerifyManagerRole", "valid-token").Return(nil).Once()
+		mockDB.On("CreatePromoCode", expected).Return(created, nil).Once()
+
+		resp := create(`{"code": "welcome5", "kind": "fixed", "value": 5, "max_uses": 100, "category_ids": ["cat1"]}`)
+		assert.Equal(t, fiber.StatusCreated, resp.StatusCode)
+
+		var result map[string]interface{}
+		err := json.NewDecoder(resp.Body).Decode(&result)
+		assert.NoError(t, err)
+		assert.Equal(t, "WELCOME5", result["promo_code"].(map[string]interface{})["code"])
+	})
+
+	// Test case 2: Duplicate code
+	t.Run("Code already exists", func(t *testing.T) {
+		mockAuth.On("VerifyManagerRole", "valid-token").Return(nil).Once()
+		mockDB.On("CreatePromoCode", expected).Return(models.PromoCode{}, db.ErrPromoCodeExists).Once()
+
+		resp := create(`{"code": "WELCOME5", "kind": "fixed", "value": 5, "max_uses": 100, "category_ids": ["cat1"]}`)
+		assert.Equal(t, fiber.StatusConflict, resp.StatusCode)
+	})
+
+	// Test case 3: Invalid code
+	t.Run("Invalid kind", func(t *testing.T) {
+		mockAuth.On("VerifyManagerRole", "valid-token").Return(nil).Once()
+
+		resp := create(`{"code": "FREE", "kind": "free_item", "value": 5}`)
+		assert.Equal(t, fiber.StatusBadRequest, resp.StatusCode)
+	})
+
+	mockDB.AssertExpectations(t)
+	mockAuth.AssertExpectations(t)
+}
+
+func TestHandleRedeemPromoCode(t *testing.T) {
+	mockDB := new(mocks.MockDB)
+	menuService := services.NewMenuService(mockDB)
+
+	app := fiber.New()
+	app.Post("/internal/promo-codes/redeem", menuService.HandleRedeemPromoCode)
+	app.Post("/internal/promo-codes/redemptions/:id/release", menuService.HandleReleasePromoRedemption)
+
+	lines := []models.PromoLine{{ItemID: "item1", Quantity: 2, UnitPrice: 10}}
+	body := `{"code": "WELCOME5", "user_id": "user1", "reference": "order1", "items": [{"item_id": "item1", "quantity": 2, "unit_price": 10}]}`
+
+	redeem := func(body string) *http.Response {
+		req := httptest.NewRequest(http.MethodPost, "/internal/promo-codes/redeem", strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		resp, err := app.Test(req)
+		assert.NoError(t, err)
+		return resp
+	}
+
+	// Test case 1: Code is redeemed
+	t.Run("Redeem promo code", func(t *testing.T) {
+		redemption := models.PromoRedemption{ID: "red1", UserID: "user1", Reference: "order1",
+			Quote: models.PromoQuote{Code: "WELCOME5", Subtotal: 20, Discount: 5, Total: 15}}
+		mockDB.On("RedeemPromoCode", "WELCOME5", "user1", "order1", lines).Return(redemption, nil).Once()
+
+		resp := redeem(body)
+		assert.Equal(t, fiber.StatusCreated, resp.StatusCode)
+
+		var result map[string]interface{}
+		err := json.NewDecoder(resp.Body).Decode(&result)
+		assert.NoError(t, err)
+		quote := result["redemption"].(map[string]interface{})["quote"].(map[string]interface{})
+		assert.Equal(t, 5.0, quote["discount"])
+		assert.Equal(t, 15.0, quote["total"])
+	})
+
+	// Test case 2: Redemption errors map to status codes
+	for name, tc := range map[string]struct {
+		err    error
+		status int
+	}{
+		"Used up":        {db.ErrPromoUsedUp, fiber.StatusConflict},
+		"User limit":     {db.ErrPromoUserLimit, fiber.StatusConflict},
+		"Unknown code":   {db.ErrPromoCodeNotFound, fiber.StatusNotFound},
+		"Not applicable": {fmt.Errorf("%w: WELCOME5 has expired", models.ErrPromoNotApplicable), fiber.StatusBadRequest},
+	} {
+		t.Run(name, func(t *testing.T) {
+			mockDB.On("RedeemPromoCode", "WELCOME5", "user1", "order1", lines).Return(models.PromoRedemption{}, tc.err).Once()
+
+			resp := redeem(body)
+			assert.Equal(t, tc.status, resp.StatusCode)
+
+			var result map[string]interface{}
+			err := json.NewDecoder(resp.Body).Decode(&result)
+			assert.NoError(t, err)
+			assert.Equal(t, tc.err.Error(), result["error"])
+		})
+	}
+
+	// Test case 3: The user is needed for per-user limits
+	t.Run("Missing user", func(t *testing.T) {
+		resp := redeem(`{"code": "WELCOME5", "items": [{"item_id": "item1", "quantity": 2, "unit_price": 10}]}`)
+		assert.Equal(t, fiber.StatusBadRequest, resp.StatusCode)
+	})
+
+	// Test case 4: Releasing gives the use back
+	t.Run("Release redemption", func(t *testing.T) {
+		released := time.Now()
+		mockDB.On("ReleasePromoRedemption", "red1").Return(models.PromoRedemption{ID: "red1", ReleasedAt: &released}, nil).Once()
+
+		resp, err := app.Test(httptest.NewRequest(http.MethodPost, "/internal/promo-codes/redemptions/red1/release", nil))
+		assert.NoError(t, err)
+		assert.Equal(t, fiber.StatusOK, resp.StatusCode)
+	})
+
+	mockDB.AssertExpectations(t)
 }
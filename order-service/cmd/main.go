@@ -3,15 +3,22 @@ package main
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"log"
+	"math"
 	"net/http"
+	"net/url"
+	"os"
+	"time"
 
 	mqtt "github.com/eclipse/paho.mqtt.golang"
+	"github.com/google/uuid"
 
 	"github.com/darkhyper24/blaban/order-service/internal/models"
 	"github.com/darkhyper24/blaban/order-service/internal/orders"
+	"github.com/darkhyper24/blaban/shared/auth"
 	"github.com/gofiber/fiber/v2"
 	"github.com/gofiber/fiber/v2/middleware/cors"
 	"github.com/gofiber/fiber/v2/middleware/logger"
@@ -21,6 +28,10 @@ import (
 
 var mqttClient mqtt.Client
 var orderService *orders.OrderService
+var menuClient *http.Client
+var menuServiceURL string
+var userClient *http.Client
+var userServiceURL string
 
 func main() {
 	app := fiber.New()
@@ -29,9 +40,13 @@ func main() {
 	app.Use(logger.New())
 
 	// Connect to MongoDB
+	mongoURI := os.Getenv("MONGO_URI")
+	if mongoURI == "" {
+		mongoURI = "mongodb://localhost:27017"
+	}
 	client, err := mongo.Connect(
 		context.Background(),
-		options.Client().ApplyURI("mongodb://localhost:27017"),
+		options.Client().ApplyURI(mongoURI),
 	)
 	if err != nil {
 		log.Fatalf("Failed to connect to MongoDB: %v", err)
@@ -52,6 +67,18 @@ func main() {
 	db := client.Database("orderdb")
 	collection := db.Collection("orders")
 
+	if len(os.Args) > 1 && os.Args[1] == "migrate" {
+		if err := runMigrations(db, os.Args[2:]); err != nil {
+			log.Fatalf("Migration failed: %v", err)
+		}
+		return
+	}
+
+	// Create any missing indexes before serving
+	if err := runMigrations(db, []string{"up"}); err != nil {
+		log.Fatalf("Migration failed: %v", err)
+	}
+
 	// Initialize MQTT client
 	mqttOpts := mqtt.NewClientOptions().
 		AddBroker("tcp://localhost:1883").
@@ -74,10 +101,37 @@ func main() {
 	}
 	orderService = orders.NewOrderService(collection, mqttClient)
 
+	// Service-to-service calls authenticate with client-credentials tokens
+	creds := auth.ClientCredentialsFromEnv(auth.ScopeMenuRead, auth.ScopeAddressesRead, auth.ScopeStockReserve, auth.ScopePromoRedeem)
+	menuClient = auth.NewServiceClient(creds)
+	menuServiceURL = os.Getenv("MENU_SERVICE_URL")
+	if menuServiceURL == "" {
+		menuServiceURL = "http://localhost:8083"
+	}
+	userClient = auth.NewServiceClient(creds)
+	userServiceURL = os.Getenv("USER_SERVICE_URL")
+	if userServiceURL == "" {
+		userServiceURL = "http://localhost:8081"
+	}
+
+	// Access tokens are verified locally against auth-service's signing keys
+	verifier, err := auth.VerifierFromEnv()
+	if err != nil {
+		log.Fatalf("Failed to configure token verification: %v", err)
+	}
+
 	// Order routes
-	app.Get("/api/orders", handleGetOrders)
-	app.Get("/api/orders/:id", handleGetOrder)
-	app.Post("/api/orders", handleCreateOrder)
+	orderRoutes := app.Group("/api/orders", auth.Authenticate(verifier))
+	orderRoutes.Get("/", handleGetOrders)
+	orderRoutes.Get("/:id", handleGetOrder)
+	orderRoutes.Post("/", auth.RequireVerifiedEmail(), handleCreateOrder)
+	orderRoutes.Post("/promo/validate", handleValidatePromoCode)
+	orderRoutes.Patch("/:id/status", auth.RequirePermission(auth.PermOrdersTransition), handleUpdateOrderStatus)
+
+	// Personal data export and erasure, driven by user-service
+	internal := app.Group("/internal", auth.Authenticate(verifier))
+	internal.Get("/users/:id/data", auth.RequireScope(auth.ScopeUserDataRead), handleExportUserData)
+	internal.Delete("/users/:id/data", auth.RequireScope(auth.ScopeUserDataErase), handleEraseUserData)
 
 	// Test route to check if the service is running
 	app.Get("/health", func(c *fiber.Ctx) error {
@@ -93,11 +147,17 @@ func main() {
 }
 
 func handleGetOrders(c *fiber.Ctx) error {
-	userID, err := verifyToken(c.Get("Authorization"))
-	if err != nil {
-		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
-			"error": err.Error(),
-		})
+	principal := auth.PrincipalFrom(c)
+	userID := principal.UserID
+
+	// Staff with orders:read_all may list every customer's orders
+	if c.QueryBool("all") {
+		if !principal.HasPermission(auth.PermOrdersReadAll) {
+			return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
+				"error": "insufficient permissions: requires " + auth.PermOrdersReadAll,
+			})
+		}
+		userID = ""
 	}
 
 	orders, err := orderService.GetOrders(c.Context(), userID)
@@ -119,11 +179,10 @@ func handleGetOrders(c *fiber.Ctx) error {
 }
 
 func handleGetOrder(c *fiber.Ctx) error {
-	userID, err := verifyToken(c.Get("Authorization"))
-	if err != nil {
-		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
-			"error": err.Error(),
-		})
+	principal := auth.PrincipalFrom(c)
+	userID := principal.UserID
+	if principal.HasPermission(auth.PermOrdersReadAll) {
+		userID = ""
 	}
 
 	orderID := c.Params("id")
@@ -142,13 +201,37 @@ func handleGetOrder(c *fiber.Ctx) error {
 	return c.JSON(order)
 }
 
-func handleCreateOrder(c *fiber.Ctx) error {
-	userID, err := verifyToken(c.Get("Authorization"))
-	if err != nil {
-		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
-			"error": err.Error(),
-		})
+// priceOrderItems validates items against the menu and fills in their names
+// and prices. Errors are meant for the customer.
+func priceOrderItems(items []models.OrderItem) error {
+	if err := orders.ValidateItems(items); err != nil {
+		return err
 	}
+	for i, item := range items {
+		menuItem, err := getMenuItem(item.ItemID)
+		if err != nil {
+			return fmt.Errorf("Invalid menu item ID: %s", item.ItemID)
+		}
+		options, delta, err := orders.ApplyOptions(item.Options, menuItem.OptionGroups)
+		if err != nil {
+			return fmt.Errorf("%s: %v", menuItem.Name, err)
+		}
+		price := math.Round((menuItem.Price+delta)*100) / 100
+		if price < 0 {
+			return fmt.Errorf("%s: the selected options make the price negative", menuItem.Name)
+		}
+
+		// Update item details from menu
+		items[i].Name = menuItem.Name
+		items[i].BasePrice = menuItem.Price
+		items[i].Price = price
+		items[i].Options = options
+	}
+	return nil
+}
+
+func handleCreateOrder(c *fiber.Ctx) error {
+	userID := auth.PrincipalFrom(c).UserID
 
 	var order models.Order
 	if err := c.BodyParser(&order); err != nil {
@@ -157,35 +240,148 @@ func handleCreateOrder(c *fiber.Ctx) error {
 		})
 	}
 
-	// Validate and fetch menu items
-	for i, item := range order.Items {
-		menuItem, err := getMenuItem(item.ItemID)
+	if err := priceOrderItems(order.Items); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	order.UserID = userID // Set the authenticated user's ID
+
+	// The delivery address is always copied from the customer's address book
+	order.DeliveryAddress = nil
+	if order.AddressID != "" {
+		address, err := getDeliveryAddress(userID, order.AddressID)
 		if err != nil {
-			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-				"error": fmt.Sprintf("Invalid menu item ID: %s", item.ItemID),
+			if errors.Is(err, errAddressNotFound) {
+				return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+					"error": fmt.Sprintf("Invalid address ID: %s", order.AddressID),
+				})
+			}
+			log.Printf("Failed to fetch delivery address: %v", err)
+			return c.Status(fiber.StatusBadGateway).JSON(fiber.Map{
+				"error": "Failed to fetch delivery address",
 			})
 		}
-		// Update item details from menu
-		order.Items[i].Name = menuItem.Name
-		order.Items[i].Price = menuItem.Price
+		order.DeliveryAddress = address
 	}
 
-	order.UserID = userID // Set the authenticated user's ID
+	// Stock is held before the order is saved so two customers cannot buy the last one
+	order.ID = uuid.NewString()
+	reservationID, err := reserveStock(order.ID, order.Items)
+	if err != nil {
+		if isOutOfStock(err) {
+			return c.Status(fiber.StatusConflict).JSON(fiber.Map{
+				"error": err.Error(),
+			})
+		}
+		log.Printf("Failed to reserve stock: %v", err)
+		return c.Status(fiber.StatusBadGateway).JSON(fiber.Map{
+			"error": "Failed to reserve stock",
+		})
+	}
+	order.ReservationID = reservationID
+
+	// Discounts only ever come from a redeemed promo code
+	order.Discount = 0
+	order.PromoRedemptionID = ""
+	if order.PromoCode != "" {
+		redemption, err := redeemPromoCode(order.PromoCode, userID, order.ID, order.Items)
+		if err != nil {
+			if err := releaseReservation(reservationID); err != nil {
+				log.Printf("Failed to release stock: %v", err)
+			}
+			return promoError(c, err)
+		}
+		order.PromoCode = redemption.Quote.Code
+		order.PromoRedemptionID = redemption.ID
+		order.Discount = redemption.Quote.Discount
+	}
 
 	if err := orderService.CreateOrder(c.Context(), &order); err != nil {
+		if err := releaseReservation(reservationID); err != nil {
+			log.Printf("Failed to release stock: %v", err)
+		}
+		if order.PromoRedemptionID != "" {
+			if err := releasePromoRedemption(order.PromoRedemptionID); err != nil {
+				log.Printf("Failed to release promo code: %v", err)
+			}
+		}
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
 			"error": "Failed to create order",
 		})
 	}
+	// The order is saved either way; an uncommitted reservation only risks expiring
+	if err := commitReservation(reservationID); err != nil {
+		log.Printf("Failed to commit stock reservation for order %s: %v", order.ID, err)
+	}
 	orderService.ScheduleOrderCompletion(order.ID)
 
 	return c.Status(fiber.StatusCreated).JSON(order)
 }
 
+func handleUpdateOrderStatus(c *fiber.Ctx) error {
+	var req struct {
+		Status string `json:"status"`
+	}
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid request body",
+		})
+	}
+
+	if !orders.IsValidStatus(req.Status) {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": fmt.Sprintf("Invalid status: %s", req.Status),
+		})
+	}
+
+	order, previous, err := orderService.TransitionOrder(c.Context(), c.Params("id"), req.Status)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+				"error": "Order not found",
+			})
+		}
+		if errors.Is(err, orders.ErrInvalidTransition) {
+			return c.Status(fiber.StatusConflict).JSON(fiber.Map{
+				"error": err.Error(),
+			})
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to update order status: " + err.Error(),
+		})
+	}
+
+	// The stock was committed when the order was placed; menu-service puts it back
+	if orders.ReturnsStock(previous, order.Status) {
+		if order.ReservationID != "" {
+			if err := releaseReservation(order.ReservationID); err != nil {
+				log.Printf("Failed to release stock for order %s: %v", order.ID, err)
+			}
+		}
+		if order.PromoRedemptionID != "" {
+			if err := releasePromoRedemption(order.PromoRedemptionID); err != nil {
+				log.Printf("Failed to release promo code for order %s: %v", order.ID, err)
+			}
+		}
+	}
+
+	return c.JSON(order)
+}
+
+// menuItem is a menu item as order pricing needs it
+type menuItem struct {
+	ID           string
+	Name         string
+	Price        float64
+	OptionGroups []orders.MenuOptionGroup
+}
+
 // Helper function to validate menu items
-func getMenuItem(itemID string) (*models.OrderItem, error) {
-	menuURL := fmt.Sprintf("http://localhost:8083/api/menu/%s", itemID)
-	resp, err := http.Get(menuURL)
+func getMenuItem(itemID string) (*menuItem, error) {
+	menuURL := fmt.Sprintf("%s/internal/menu/%s", menuServiceURL, itemID)
+	resp, err := menuClient.Get(menuURL)
 	if err != nil {
 		return nil, err
 	}
@@ -197,10 +393,11 @@ func getMenuItem(itemID string) (*models.OrderItem, error) {
 
 	var response struct {
 		Item struct {
-			ID             string  `json:"id"`
-			Name           string  `json:"name"`
-			Price          float64 `json:"price"`
-			EffectivePrice float64 `json:"effective_price"`
+			ID             string                   `json:"id"`
+			Name           string                   `json:"name"`
+			Price          float64                  `json:"price"`
+			EffectivePrice float64                  `json:"effective_price"`
+			OptionGroups   []orders.MenuOptionGroup `json:"option_groups"`
 		} `json:"item"`
 	}
 
@@ -219,48 +416,40 @@ func getMenuItem(itemID string) (*models.OrderItem, error) {
 		price = response.Item.EffectivePrice
 	}
 
-	return &models.OrderItem{
-		ItemID: response.Item.ID,
-		Name:   response.Item.Name,
-		Price:  price,
+	return &menuItem{
+		ID:           response.Item.ID,
+		Name:         response.Item.Name,
+		Price:        price,
+		OptionGroups: response.Item.OptionGroups,
 	}, nil
 }
 
-func verifyToken(authHeader string) (string, error) {
-	if authHeader == "" {
-		return "", fiber.NewError(fiber.StatusUnauthorized, "Missing authorization header")
-	}
-
-	tokenString := authHeader
-	if len(authHeader) > 7 && authHeader[:7] == "Bearer " {
-		tokenString = authHeader[7:]
-	}
-
-	client := &http.Client{}
-	req, err := http.NewRequest("GET", "http://localhost:8082/api/auth/verify", nil)
-	if err != nil {
-		return "", err
-	}
-	req.Header.Add("Authorization", "Bearer "+tokenString)
+var errAddressNotFound = errors.New("address not found")
 
-	resp, err := client.Do(req)
+// getDeliveryAddress snapshots one of the user's saved addresses from user-service
+func getDeliveryAddress(userID, addressID string) (*models.DeliveryAddress, error) {
+	addressURL := fmt.Sprintf("%s/internal/users/%s/addresses/%s", userServiceURL, url.PathEscape(userID), url.PathEscape(addressID))
+	resp, err := userClient.Get(addressURL)
 	if err != nil {
-		return "", err
+		return nil, err
 	}
 	defer resp.Body.Close()
 
-	var authResp struct {
-		Valid  bool   `json:"valid"`
-		UserID string `json:"user_id"`
+	if resp.StatusCode == http.StatusNotFound {
+		return nil, errAddressNotFound
 	}
-
-	if err := json.NewDecoder(resp.Body).Decode(&authResp); err != nil {
-		return "", err
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("user service returned status %d", resp.StatusCode)
 	}
 
-	if !authResp.Valid {
-		return "", fiber.NewError(fiber.StatusUnauthorized, "Invalid token")
+	var response struct {
+		Address models.DeliveryAddress `json:"address"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&response); err != nil {
+		return nil, err
 	}
 
-	return authResp.UserID, nil
+	address := response.Address
+	address.CapturedAt = time.Now()
+	return &address, nil
 }
@@ -0,0 +1,168 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+
+	"github.com/darkhyper24/blaban/order-service/internal/models"
+	"github.com/darkhyper24/blaban/shared/auth"
+	"github.com/gofiber/fiber/v2"
+)
+
+// errPromoRejected is returned when menu-service refuses a promo code for an
+// order. It carries the status and message to pass on to the customer.
+type errPromoRejected struct {
+	status  int
+	message string
+}
+
+func (e *errPromoRejected) Error() string {
+	return e.message
+}
+
+// promoQuote is what a promo code takes off an order
+type promoQuote struct {
+	Code     string  `json:"code"`
+	Subtotal float64 `json:"subtotal"`
+	Discount float64 `json:"discount"`
+	Total    float64 `json:"total"`
+}
+
+// promoRedemption is a use of a promo code recorded by menu-service
+type promoRedemption struct {
+	ID    string     `json:"id"`
+	Quote promoQuote `json:"quote"`
+}
+
+// promoLine is an order line as menu-service prices promo codes against it
+type promoLine struct {
+	ItemID    string  `json:"item_id"`
+	Quantity  int     `json:"quantity"`
+	UnitPrice float64 `json:"unit_price"`
+}
+
+// postPromo sends a priced order to a menu-service promo endpoint and decodes
+// the field named key from a successful response into out
+func postPromo(path, code, userID, reference string, items []models.OrderItem, key string, out interface{}) error {
+	request := struct {
+		Code      string      `json:"code"`
+		UserID    string      `json:"user_id"`
+		Reference string      `json:"reference,omitempty"`
+		Items     []promoLine `json:"items"`
+	}{Code: code, UserID: userID, Reference: reference, Items: make([]promoLine, len(items))}
+	for i, item := range items {
+		request.Items[i] = promoLine{ItemID: item.ItemID, Quantity: item.Quantity, UnitPrice: item.Price}
+	}
+
+	body, err := json.Marshal(request)
+	if err != nil {
+		return err
+	}
+	resp, err := menuClient.Post(menuServiceURL+path, "application/json", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	var response map[string]json.RawMessage
+	decodeErr := json.NewDecoder(resp.Body).Decode(&response)
+
+	switch resp.StatusCode {
+	case http.StatusOK, http.StatusCreated:
+		if decodeErr != nil {
+			return decodeErr
+		}
+		return json.Unmarshal(response[key], out)
+	case http.StatusBadRequest, http.StatusNotFound, http.StatusConflict:
+		var message string
+		json.Unmarshal(response["error"], &message)
+		return &errPromoRejected{status: resp.StatusCode, message: message}
+	}
+	return fmt.Errorf("menu service returned status %d", resp.StatusCode)
+}
+
+// quotePromoCode asks menu-service what a code takes off an order without using it
+func quotePromoCode(code, userID string, items []models.OrderItem) (*promoQuote, error) {
+	var quote promoQuote
+	if err := postPromo("/internal/promo-codes/quote", code, userID, "", items, "quote", &quote); err != nil {
+		return nil, err
+	}
+	return &quote, nil
+}
+
+// redeemPromoCode uses a code for an order
+func redeemPromoCode(code, userID, orderID string, items []models.OrderItem) (*promoRedemption, error) {
+	var redemption promoRedemption
+	if err := postPromo("/internal/promo-codes/redeem", code, userID, orderID, items, "redemption", &redemption); err != nil {
+		return nil, err
+	}
+	return &redemption, nil
+}
+
+// releasePromoRedemption gives a use of a promo code back. Releasing twice is harmless.
+func releasePromoRedemption(redemptionID string) error {
+	target := fmt.Sprintf("%s/internal/promo-codes/redemptions/%s/release", menuServiceURL, url.PathEscape(redemptionID))
+	resp, err := menuClient.Post(target, "application/json", nil)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("failed to release promo redemption %s: status %d", redemptionID, resp.StatusCode)
+	}
+	return nil
+}
+
+// promoError responds to a failed promo code call, passing rejections on to the customer
+func promoError(c *fiber.Ctx, err error) error {
+	var rejected *errPromoRejected
+	if errors.As(err, &rejected) {
+		return c.Status(rejected.status).JSON(fiber.Map{
+			"error": rejected.message,
+		})
+	}
+	log.Printf("Failed to apply promo code: %v", err)
+	return c.Status(fiber.StatusBadGateway).JSON(fiber.Map{
+		"error": "Failed to apply promo code",
+	})
+}
+
+// handleValidatePromoCode prices a cart the way an order would be priced and
+// shows what a promo code takes off it, without using the code
+func handleValidatePromoCode(c *fiber.Ctx) error {
+	var req struct {
+		PromoCode string             `json:"promo_code"`
+		Items     []models.OrderItem `json:"items"`
+	}
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid request body",
+		})
+	}
+	if req.PromoCode == "" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "promo_code is required",
+		})
+	}
+	if err := priceOrderItems(req.Items); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	quote, err := quotePromoCode(req.PromoCode, auth.PrincipalFrom(c).UserID, req.Items)
+	if err != nil {
+		return promoError(c, err)
+	}
+
+	return c.JSON(fiber.Map{
+		"valid": true,
+		"quote": quote,
+	})
+}
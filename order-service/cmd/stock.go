@@ -0,0 +1,110 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+
+	"github.com/darkhyper24/blaban/order-service/internal/models"
+)
+
+// errOutOfStock is returned when menu-service cannot reserve an order's items.
+// Its message is the one menu-service gave, e.g. which item ran out.
+type errOutOfStock struct {
+	message string
+}
+
+func (e *errOutOfStock) Error() string {
+	return e.message
+}
+
+// reservationItem is how many of one menu item an order needs
+type reservationItem struct {
+	ItemID   string `json:"item_id"`
+	Quantity int    `json:"quantity"`
+}
+
+// reserveStock holds stock for an order's items in menu-service and returns
+// the reservation ID
+func reserveStock(reference string, items []models.OrderItem) (string, error) {
+	// The same item with different options is one stock line
+	quantities := make(map[string]int, len(items))
+	var request struct {
+		Reference string            `json:"reference"`
+		Items     []reservationItem `json:"items"`
+	}
+	request.Reference = reference
+	for _, item := range items {
+		if _, seen := quantities[item.ItemID]; !seen {
+			request.Items = append(request.Items, reservationItem{ItemID: item.ItemID})
+		}
+		quantities[item.ItemID] += item.Quantity
+	}
+	for i := range request.Items {
+		request.Items[i].Quantity = quantities[request.Items[i].ItemID]
+	}
+
+	body, err := json.Marshal(request)
+	if err != nil {
+		return "", err
+	}
+	resp, err := menuClient.Post(menuServiceURL+"/internal/reservations", "application/json", bytes.NewReader(body))
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	var response struct {
+		Error       string `json:"error"`
+		Reservation struct {
+			ID string `json:"id"`
+		} `json:"reservation"`
+	}
+	decodeErr := json.NewDecoder(resp.Body).Decode(&response)
+
+	switch resp.StatusCode {
+	case http.StatusCreated:
+		return response.Reservation.ID, decodeErr
+	case http.StatusConflict, http.StatusBadRequest, http.StatusNotFound:
+		return "", &errOutOfStock{message: response.Error}
+	}
+	return "", fmt.Errorf("menu service returned status %d: %s", resp.StatusCode, response.Error)
+}
+
+// commitReservation makes an order's reservation final once the order is saved
+func commitReservation(reservationID string) error {
+	return postReservation(reservationID, "commit")
+}
+
+// releaseReservation gives an order's stock back to the menu, committed or
+// not. Releasing twice is harmless.
+func releaseReservation(reservationID string) error {
+	return postReservation(reservationID, "release")
+}
+
+func postReservation(reservationID, action string) error {
+	target := fmt.Sprintf("%s/internal/reservations/%s/%s", menuServiceURL, url.PathEscape(reservationID), action)
+	resp, err := menuClient.Post(target, "application/json", nil)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		var response struct {
+			Error string `json:"error"`
+		}
+		json.NewDecoder(resp.Body).Decode(&response)
+		return fmt.Errorf("failed to %s reservation %s: status %d: %s", action, reservationID, resp.StatusCode, response.Error)
+	}
+	return nil
+}
+
+// isOutOfStock reports whether err means the order's items could not be reserved
+func isOutOfStock(err error) bool {
+	var outOfStock *errOutOfStock
+	return errors.As(err, &outOfStock)
+}
@@ -0,0 +1,46 @@
+package main
+
+import (
+	"log"
+
+	"github.com/darkhyper24/blaban/order-service/internal/models"
+	"github.com/darkhyper24/blaban/shared/userdata"
+	"github.com/gofiber/fiber/v2"
+)
+
+const serviceName = "order-service"
+
+// handleExportUserData returns every order placed by a user
+func handleExportUserData(c *fiber.Ctx) error {
+	orders, err := orderService.GetOrders(c.Context(), c.Params("id"))
+	if err != nil {
+		log.Printf("Failed to export orders: %v", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to export orders",
+		})
+	}
+	if orders == nil {
+		orders = make([]models.Order, 0)
+	}
+
+	return c.JSON(userdata.Export{
+		Service: serviceName,
+		Data:    fiber.Map{"orders": orders},
+	})
+}
+
+// handleEraseUserData anonymises a user's orders, which are retained for accounting
+func handleEraseUserData(c *fiber.Ctx) error {
+	anonymised, err := orderService.AnonymiseUserOrders(c.Context(), c.Params("id"))
+	if err != nil {
+		log.Printf("Failed to anonymise orders: %v", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to anonymise orders",
+		})
+	}
+
+	return c.JSON(userdata.ErasureResult{
+		Service:    serviceName,
+		Anonymised: anonymised,
+	})
+}
@@ -0,0 +1,31 @@
+package db
+
+import (
+	"github.com/darkhyper24/blaban/shared/mongomigrate"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// IndexMigrations are the versioned index definitions of the order database
+var IndexMigrations = []mongomigrate.Migration{
+	{
+		Version:    1,
+		Name:       "create_orders_indexes",
+		Collection: "orders",
+		Indexes: []mongo.IndexModel{
+			{
+				Keys:    bson.D{{Key: "id", Value: 1}},
+				Options: options.Index().SetName("orders_id_unique").SetUnique(true),
+			},
+			{
+				Keys:    bson.D{{Key: "user_id", Value: 1}, {Key: "created_at", Value: -1}},
+				Options: options.Index().SetName("orders_user_id_created_at"),
+			},
+			{
+				Keys:    bson.D{{Key: "status", Value: 1}, {Key: "created_at", Value: -1}},
+				Options: options.Index().SetName("orders_status_created_at"),
+			},
+		},
+	},
+}
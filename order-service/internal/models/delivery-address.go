@@ -0,0 +1,21 @@
+package models
+
+import "time"
+
+// DeliveryAddress is a copy of a saved address taken when the order was placed.
+// Later edits to the address book do not change it.
+type DeliveryAddress struct {
+	Label        string    `json:"label" bson:"label"`
+	Recipient    string    `json:"recipient" bson:"recipient"`
+	Phone        string    `json:"phone,omitempty" bson:"phone,omitempty"`
+	Line1        string    `json:"line1" bson:"line1"`
+	Line2        string    `json:"line2,omitempty" bson:"line2,omitempty"`
+	City         string    `json:"city" bson:"city"`
+	Region       string    `json:"region,omitempty" bson:"region,omitempty"`
+	PostalCode   string    `json:"postal_code,omitempty" bson:"postal_code,omitempty"`
+	Country      string    `json:"country" bson:"country"`
+	Latitude     *float64  `json:"latitude,omitempty" bson:"latitude,omitempty"`
+	Longitude    *float64  `json:"longitude,omitempty" bson:"longitude,omitempty"`
+	Instructions string    `json:"instructions,omitempty" bson:"instructions,omitempty"`
+	CapturedAt   time.Time `json:"captured_at" bson:"captured_at"`
+}
@@ -1,8 +1,22 @@
 package models
 
 type OrderItem struct {
-	ItemID   string  `json:"item_id" bson:"item_id"`
-	Name     string  `json:"name" bson:"name"`
-	Quantity int     `json:"quantity" bson:"quantity"`
-	Price    float64 `json:"price" bson:"price"`
+	ItemID   string `json:"item_id" bson:"item_id"`
+	Name     string `json:"name" bson:"name"`
+	Quantity int    `json:"quantity" bson:"quantity"`
+	// Price is the unit price including the selected options; BasePrice is
+	// the item's own price before them
+	Price     float64          `json:"price" bson:"price"`
+	BasePrice float64          `json:"base_price,omitempty" bson:"base_price,omitempty"`
+	Options   []SelectedOption `json:"options,omitempty" bson:"options,omitempty"`
+}
+
+// SelectedOption is an option picked for an order item. Customers only send
+// the option ID; the rest is copied from the menu when the order is placed.
+type SelectedOption struct {
+	OptionID   string  `json:"option_id" bson:"option_id"`
+	GroupID    string  `json:"group_id" bson:"group_id"`
+	GroupName  string  `json:"group_name" bson:"group_name"`
+	Name       string  `json:"name" bson:"name"`
+	PriceDelta float64 `json:"price_delta" bson:"price_delta"`
 }
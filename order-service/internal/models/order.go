@@ -4,12 +4,21 @@ import (
 	"time"
 )
 
+// Order is a customer's order. Total is Subtotal less the Discount of the
+// redeemed PromoCode.
 type Order struct {
-	ID        string      `json:"id" bson:"id"`
-	UserID    string      `json:"user_id" bson:"user_id"`
-	Items     []OrderItem `json:"items" bson:"items"`
-	Total     float64     `json:"total" bson:"total"`
-	Status    string      `json:"status" bson:"status"`
-	CreatedAt time.Time   `json:"created_at" bson:"created_at"`
-	UpdatedAt time.Time   `json:"updated_at" bson:"updated_at"`
+	ID                string           `json:"id" bson:"id"`
+	UserID            string           `json:"user_id" bson:"user_id"`
+	Items             []OrderItem      `json:"items" bson:"items"`
+	Subtotal          float64          `json:"subtotal" bson:"subtotal"`
+	Discount          float64          `json:"discount,omitempty" bson:"discount,omitempty"`
+	PromoCode         string           `json:"promo_code,omitempty" bson:"promo_code,omitempty"`
+	PromoRedemptionID string           `json:"promo_redemption_id,omitempty" bson:"promo_redemption_id,omitempty"`
+	Total             float64          `json:"total" bson:"total"`
+	Status            string           `json:"status" bson:"status"`
+	AddressID         string           `json:"address_id,omitempty" bson:"address_id,omitempty"`
+	DeliveryAddress   *DeliveryAddress `json:"delivery_address,omitempty" bson:"delivery_address,omitempty"`
+	ReservationID     string           `json:"reservation_id,omitempty" bson:"reservation_id,omitempty"`
+	CreatedAt         time.Time        `json:"created_at" bson:"created_at"`
+	UpdatedAt         time.Time        `json:"updated_at" bson:"updated_at"`
 }
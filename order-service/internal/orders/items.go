@@ -0,0 +1,24 @@
+package orders
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/darkhyper24/blaban/order-service/internal/models"
+)
+
+var ErrInvalidItems = errors.New("invalid order items")
+
+// ValidateItems checks that an order has items and that every line orders a
+// positive quantity. Every error wraps ErrInvalidItems.
+func ValidateItems(items []models.OrderItem) error {
+	if len(items) == 0 {
+		return fmt.Errorf("%w: an order needs at least one item", ErrInvalidItems)
+	}
+	for _, item := range items {
+		if item.Quantity <= 0 {
+			return fmt.Errorf("%w: quantity of %s must be at least 1", ErrInvalidItems, item.ItemID)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,76 @@
+package orders
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/darkhyper24/blaban/order-service/internal/models"
+)
+
+var ErrInvalidOptions = errors.New("invalid options")
+
+// MenuOptionGroup is an option group of a menu item as menu-service returns it
+type MenuOptionGroup struct {
+	ID        string       `json:"id"`
+	Name      string       `json:"name"`
+	MinSelect int          `json:"min_select"`
+	MaxSelect int          `json:"max_select"`
+	Options   []MenuOption `json:"options"`
+}
+
+// MenuOption is one choice in a MenuOptionGroup
+type MenuOption struct {
+	ID          string  `json:"id"`
+	Name        string  `json:"name"`
+	PriceDelta  float64 `json:"price_delta"`
+	IsAvailable bool    `json:"is_available"`
+}
+
+// ApplyOptions checks the options selected for an item against its option
+// groups on the menu. It returns the selection with names and price deltas
+// copied from the menu, in menu order, and the sum of the deltas. Every error
+// wraps ErrInvalidOptions.
+func ApplyOptions(selected []models.SelectedOption, groups []MenuOptionGroup) ([]models.SelectedOption, float64, error) {
+	picked := make(map[string]bool, len(selected))
+	for _, option := range selected {
+		if picked[option.OptionID] {
+			return nil, 0, fmt.Errorf("%w: option %s is selected twice", ErrInvalidOptions, option.OptionID)
+		}
+		picked[option.OptionID] = true
+	}
+
+	var applied []models.SelectedOption
+	var delta float64
+	for _, group := range groups {
+		count := 0
+		for _, option := range group.Options {
+			if !picked[option.ID] {
+				continue
+			}
+			if !option.IsAvailable {
+				return nil, 0, fmt.Errorf("%w: %s is not available", ErrInvalidOptions, option.Name)
+			}
+			delete(picked, option.ID)
+			count++
+			delta += option.PriceDelta
+			applied = append(applied, models.SelectedOption{
+				OptionID:   option.ID,
+				GroupID:    group.ID,
+				GroupName:  group.Name,
+				Name:       option.Name,
+				PriceDelta: option.PriceDelta,
+			})
+		}
+		if count < group.MinSelect {
+			return nil, 0, fmt.Errorf("%w: choose at least %d for %s", ErrInvalidOptions, group.MinSelect, group.Name)
+		}
+		if count > group.MaxSelect {
+			return nil, 0, fmt.Errorf("%w: choose at most %d for %s", ErrInvalidOptions, group.MaxSelect, group.Name)
+		}
+	}
+
+	for optionID := range picked {
+		return nil, 0, fmt.Errorf("%w: option %s does not belong to this item", ErrInvalidOptions, optionID)
+	}
+	return applied, delta, nil
+}
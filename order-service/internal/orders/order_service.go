@@ -3,17 +3,78 @@ package orders
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
+	"math"
 	"time"
 
 	"github.com/darkhyper24/blaban/order-service/internal/models"
+	"github.com/darkhyper24/blaban/shared/userdata"
 	mqtt "github.com/eclipse/paho.mqtt.golang"
 	"github.com/google/uuid"
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/mongo"
 )
 
+// Order statuses an order can move through
+const (
+	StatusPending        = "pending"
+	StatusPreparing      = "preparing"
+	StatusReady          = "ready"
+	StatusOutForDelivery = "out_for_delivery"
+	StatusCompleted      = "completed"
+	StatusCancelled      = "cancelled"
+)
+
+var validStatuses = []string{
+	StatusPending, StatusPreparing, StatusReady, StatusOutForDelivery, StatusCompleted, StatusCancelled,
+}
+
+// ErrInvalidTransition is returned when an order cannot move to the requested status
+var ErrInvalidTransition = errors.New("invalid status transition")
+
+// transitions lists the statuses each status can move to. Completed and
+// cancelled orders are final.
+var transitions = map[string][]string{
+	StatusPending:        {StatusPreparing, StatusCancelled},
+	StatusPreparing:      {StatusReady, StatusCancelled},
+	StatusReady:          {StatusOutForDelivery, StatusCompleted, StatusCancelled},
+	StatusOutForDelivery: {StatusCompleted},
+}
+
+// IsValidStatus reports whether status is a known order status
+func IsValidStatus(status string) bool {
+	for _, s := range validStatuses {
+		if s == status {
+			return true
+		}
+	}
+	return false
+}
+
+// CanTransition reports whether an order in status from may move to status to
+func CanTransition(from, to string) bool {
+	for _, next := range transitions[from] {
+		if next == to {
+			return true
+		}
+	}
+	return false
+}
+
+// IsFinal reports whether an order in status can no longer change
+func IsFinal(status string) bool {
+	return status == StatusCompleted || status == StatusCancelled
+}
+
+// ReturnsStock reports whether an order moving from status from to status to
+// gives its stock and promo use back. Only orders cancelled before the
+// kitchen started on them do.
+func ReturnsStock(from, to string) bool {
+	return from == StatusPending && to == StatusCancelled
+}
+
 type OrderService struct {
 	collection *mongo.Collection
 	mqttClient mqtt.Client
@@ -66,35 +127,89 @@ func (s *OrderService) GetOrder(ctx context.Context, id string, userID string) (
 
 func (s *OrderService) CreateOrder(ctx context.Context, order *models.Order) error {
 	now := time.Now()
-	order.ID = uuid.NewString()
+	if order.ID == "" {
+		order.ID = uuid.NewString()
+	}
 	order.CreatedAt = now
 	order.UpdatedAt = now
 	order.Status = "pending"
 
 	// Calculate total
-	var total float64
+	var subtotal float64
 	for _, item := range order.Items {
 		// Don't overwrite the original item ID
-		total += item.Price * float64(item.Quantity)
+		subtotal += item.Price * float64(item.Quantity)
 	}
-	order.Total = total
+	order.Subtotal = math.Round(subtotal*100) / 100
+	order.Total = math.Round((subtotal-order.Discount)*100) / 100
 
 	_, err := s.collection.InsertOne(ctx, order)
 	return err
 }
 
-// UpdateOrderStatus updates the status of an order
-func (s *OrderService) UpdateOrderStatus(ctx context.Context, orderID, status string) error {
-	filter := bson.M{"id": orderID}
+// AnonymiseUserOrders detaches a user's orders from their account and drops the
+// delivery address. The orders themselves are kept because they are needed for accounting.
+func (s *OrderService) AnonymiseUserOrders(ctx context.Context, userID string) (int64, error) {
+	result, err := s.collection.UpdateMany(ctx, bson.M{"user_id": userID}, bson.M{
+		"$set": bson.M{
+			"user_id":       userdata.ErasedUserID,
+			"anonymised_at": time.Now(),
+			"updated_at":    time.Now(),
+		},
+		"$unset": bson.M{"address_id": "", "delivery_address": ""},
+	})
+	if err != nil {
+		return 0, err
+	}
+	return result.ModifiedCount, nil
+}
+
+// UpdateOrderStatus moves an order from status from to status to. It returns
+// ErrInvalidTransition if the order is no longer in status from.
+func (s *OrderService) UpdateOrderStatus(ctx context.Context, orderID, from, to string) error {
+	filter := bson.M{"id": orderID, "status": from}
 	update := bson.M{
 		"$set": bson.M{
-			"status":     status,
+			"status":     to,
 			"updated_at": time.Now(),
 		},
 	}
 
-	_, err := s.collection.UpdateOne(ctx, filter, update)
-	return err
+	result, err := s.collection.UpdateOne(ctx, filter, update)
+	if err != nil {
+		return err
+	}
+	if result.MatchedCount == 0 {
+		return fmt.Errorf("%w: order %s is no longer %s", ErrInvalidTransition, orderID, from)
+	}
+	return nil
+}
+
+// TransitionOrder moves an order to a new status and notifies the customer.
+// It returns the updated order and the status it moved from. Moves the
+// transition table does not allow return ErrInvalidTransition.
+func (s *OrderService) TransitionOrder(ctx context.Context, orderID, status string) (*models.Order, string, error) {
+	order, err := s.GetOrder(ctx, orderID, "")
+	if err != nil {
+		return nil, "", err
+	}
+	previous := order.Status
+	if !CanTransition(previous, status) {
+		return nil, "", fmt.Errorf("%w: cannot move a %s order to %s", ErrInvalidTransition, previous, status)
+	}
+
+	if err := s.UpdateOrderStatus(ctx, orderID, previous, status); err != nil {
+		return nil, "", err
+	}
+	order.Status = status
+	order.UpdatedAt = time.Now()
+
+	message := fmt.Sprintf("Your order #%s is now %s", orderID[:6], status)
+	if err := s.publishOrderStatusUpdate(orderID, status, message, nil); err != nil {
+		log.Printf("Failed to publish order status update: %v", err)
+	}
+
+	return order, previous, nil
 }
 
 // ScheduleOrderCompletion handles the automatic order status updates
@@ -104,7 +219,16 @@ func (s *OrderService) ScheduleOrderCompletion(orderID string) {
 		time.Sleep(30 * time.Second)
 
 		ctx := context.Background()
-		if err := s.UpdateOrderStatus(ctx, orderID, "completed"); err != nil {
+		current, err := s.GetOrder(ctx, orderID, "")
+		if err != nil {
+			log.Printf("Failed to fetch order %s for completion: %v", orderID, err)
+			return
+		}
+		// Orders cancelled or completed by staff in the meantime stay as they are
+		if IsFinal(current.Status) {
+			return
+		}
+		if err := s.UpdateOrderStatus(ctx, orderID, current.Status, StatusCompleted); err != nil {
 			log.Printf("Failed to update order status: %v", err)
 			return
 		}
@@ -0,0 +1,36 @@
+package orders
+
+import (
+	"testing"
+
+	"github.com/darkhyper24/blaban/order-service/internal/models"
+	"github.com/darkhyper24/blaban/order-service/internal/orders"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValidateItems(t *testing.T) {
+	tests := []struct {
+		name  string
+		items []models.OrderItem
+		valid bool
+	}{
+		{"Single item", []models.OrderItem{{ItemID: "item1", Quantity: 1}}, true},
+		{"Several items", []models.OrderItem{{ItemID: "item1", Quantity: 2}, {ItemID: "item2", Quantity: 5}}, true},
+		{"No items", nil, false},
+		{"Zero quantity", []models.OrderItem{{ItemID: "item1", Quantity: 0}}, false},
+		{"Negative quantity", []models.OrderItem{{ItemID: "item1", Quantity: -1}}, false},
+		// A negative line must not offset another line of the same item
+		{"Negative line after a positive one", []models.OrderItem{{ItemID: "item1", Quantity: 3}, {ItemID: "item1", Quantity: -2}}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := orders.ValidateItems(tt.items)
+			if tt.valid {
+				assert.NoError(t, err)
+			} else {
+				assert.ErrorIs(t, err, orders.ErrInvalidItems)
+			}
+		})
+	}
+}
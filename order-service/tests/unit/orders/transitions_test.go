@@ -0,0 +1,51 @@
+package orders
+
+import (
+	"testing"
+
+	"github.com/darkhyper24/blaban/order-service/internal/orders"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCanTransition(t *testing.T) {
+	tests := []struct {
+		name    string
+		from    string
+		to      string
+		allowed bool
+	}{
+		{"Pending to preparing", orders.StatusPending, orders.StatusPreparing, true},
+		{"Pending to cancelled", orders.StatusPending, orders.StatusCancelled, true},
+		{"Preparing to ready", orders.StatusPreparing, orders.StatusReady, true},
+		{"Ready to out for delivery", orders.StatusReady, orders.StatusOutForDelivery, true},
+		{"Out for delivery to completed", orders.StatusOutForDelivery, orders.StatusCompleted, true},
+		{"Pending straight to completed", orders.StatusPending, orders.StatusCompleted, false},
+		{"Backwards from preparing to pending", orders.StatusPreparing, orders.StatusPending, false},
+		{"Out for delivery to cancelled", orders.StatusOutForDelivery, orders.StatusCancelled, false},
+		{"Completed to pending", orders.StatusCompleted, orders.StatusPending, false},
+		{"Completed to cancelled", orders.StatusCompleted, orders.StatusCancelled, false},
+		{"Cancelled to preparing", orders.StatusCancelled, orders.StatusPreparing, false},
+		{"Same status", orders.StatusReady, orders.StatusReady, false},
+		{"Unknown status", "lost", orders.StatusPending, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.allowed, orders.CanTransition(tt.from, tt.to))
+		})
+	}
+}
+
+func TestIsFinal(t *testing.T) {
+	assert.True(t, orders.IsFinal(orders.StatusCompleted))
+	assert.True(t, orders.IsFinal(orders.StatusCancelled))
+	assert.False(t, orders.IsFinal(orders.StatusPending))
+	assert.False(t, orders.IsFinal(orders.StatusOutForDelivery))
+}
+
+func TestReturnsStock(t *testing.T) {
+	assert.True(t, orders.ReturnsStock(orders.StatusPending, orders.StatusCancelled))
+	assert.False(t, orders.ReturnsStock(orders.StatusPreparing, orders.StatusCancelled))
+	assert.False(t, orders.ReturnsStock(orders.StatusPending, orders.StatusPreparing))
+	assert.False(t, orders.ReturnsStock(orders.StatusCancelled, orders.StatusCancelled))
+}
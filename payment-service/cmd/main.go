@@ -2,8 +2,13 @@ package main
 
 import (
 	"context"
+	"errors"
 	"log"
+	"os"
+	"strings"
 
+	"github.com/darkhyper24/blaban/payment-service/internal/payments"
+	"github.com/darkhyper24/blaban/shared/auth"
 	"github.com/gofiber/fiber/v2"
 	"github.com/gofiber/fiber/v2/middleware/cors"
 	"github.com/gofiber/fiber/v2/middleware/logger"
@@ -11,26 +16,57 @@ import (
 	"go.mongodb.org/mongo-driver/mongo/options"
 )
 
+var paymentService *payments.PaymentService
+
 func main() {
 	app := fiber.New()
 
 	app.Use(cors.New())
 	app.Use(logger.New())
 
+	mongoURI := os.Getenv("MONGO_URI")
+	if mongoURI == "" {
+		mongoURI = "mongodb://mongo:27017"
+	}
 	client, err := mongo.Connect(
 		context.Background(),
-		options.Client().ApplyURI("mongodb://mongo:27017"),
+		options.Client().ApplyURI(mongoURI),
 	)
 	if err != nil {
 		log.Fatal(err)
 	}
 	defer client.Disconnect(context.Background())
 
+	db := client.Database("paymentdb")
+	if len(os.Args) > 1 && os.Args[1] == "migrate" {
+		if err := runMigrations(db, os.Args[2:]); err != nil {
+			log.Fatalf("Migration failed: %v", err)
+		}
+		return
+	}
+
+	// Create any missing indexes before serving
+	if err := runMigrations(db, []string{"up"}); err != nil {
+		log.Fatalf("Migration failed: %v", err)
+	}
+	paymentService = payments.NewPaymentService(db.Collection("payments"))
+
+	verifier, err := auth.VerifierFromEnv()
+	if err != nil {
+		log.Fatalf("Failed to configure token verification: %v", err)
+	}
+
 	// Payment routes
 	app.Post("/api/payments", handleCreatePayment)
 	app.Get("/api/payments/:id", handleGetPayment)
 	app.Post("/api/payments/webhook", handlePaymentWebhook)
 	app.Get("/api/payments/order/:orderId", handleGetPaymentByOrder)
+	app.Post("/api/payments/:id/refund", auth.Authenticate(verifier), auth.RequirePermission(auth.PermPaymentsRefund), handleRefundPayment)
+
+	// Personal data export and erasure, driven by user-service
+	internal := app.Group("/internal", auth.Authenticate(verifier))
+	internal.Get("/users/:id/data", auth.RequireScope(auth.ScopeUserDataRead), handleExportUserData)
+	internal.Delete("/users/:id/data", auth.RequireScope(auth.ScopeUserDataErase), handleEraseUserData)
 
 	app.Get("/health", func(c *fiber.Ctx) error {
 		return c.JSON(fiber.Map{"status": "ok"})
@@ -60,3 +96,39 @@ func handleGetPaymentByOrder(c *fiber.Ctx) error {
 	orderId := c.Params("orderId")
 	return c.SendString("Get payment for order ID: " + orderId)
 }
+
+func handleRefundPayment(c *fiber.Ctx) error {
+	var req struct {
+		Reason string `json:"reason"`
+	}
+	// The reason is optional, so an empty body is accepted
+	if len(c.Body()) > 0 {
+		if err := c.BodyParser(&req); err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": "Invalid request body",
+			})
+		}
+	}
+
+	err := paymentService.RefundPayment(c.Context(), c.Params("id"), auth.PrincipalFrom(c).UserID, strings.TrimSpace(req.Reason))
+	switch {
+	case errors.Is(err, payments.ErrPaymentNotFound):
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	case errors.Is(err, payments.ErrNotRefundable):
+		return c.Status(fiber.StatusConflict).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	case err != nil:
+		log.Printf("Failed to refund payment: %v", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to refund payment",
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"id":     c.Params("id"),
+		"status": payments.StatusRefunded,
+	})
+}
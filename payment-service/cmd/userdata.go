@@ -0,0 +1,42 @@
+package main
+
+import (
+	"log"
+
+	"github.com/darkhyper24/blaban/shared/userdata"
+	"github.com/gofiber/fiber/v2"
+)
+
+const serviceName = "payment-service"
+
+// handleExportUserData returns every payment made by a user
+func handleExportUserData(c *fiber.Ctx) error {
+	userPayments, err := paymentService.UserPayments(c.Context(), c.Params("id"))
+	if err != nil {
+		log.Printf("Failed to export payments: %v", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to export payments",
+		})
+	}
+
+	return c.JSON(userdata.Export{
+		Service: serviceName,
+		Data:    fiber.Map{"payments": userPayments},
+	})
+}
+
+// handleEraseUserData anonymises a user's payments, which are retained for accounting
+func handleEraseUserData(c *fiber.Ctx) error {
+	anonymised, err := paymentService.AnonymiseUserPayments(c.Context(), c.Params("id"))
+	if err != nil {
+		log.Printf("Failed to anonymise payments: %v", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to anonymise payments",
+		})
+	}
+
+	return c.JSON(userdata.ErasureResult{
+		Service:    serviceName,
+		Anonymised: anonymised,
+	})
+}
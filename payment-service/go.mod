@@ -3,12 +3,14 @@ module github.com/darkhyper24/blaban/payment-service
 go 1.24.1
 
 require (
+	github.com/darkhyper24/blaban/shared v0.0.0
 	github.com/gofiber/fiber/v2 v2.52.6
 	go.mongodb.org/mongo-driver v1.17.3
 )
 
 require (
 	github.com/andybalholm/brotli v1.1.0 // indirect
+	github.com/golang-jwt/jwt/v4 v4.5.2 // indirect
 	github.com/golang/snappy v0.0.4 // indirect
 	github.com/google/uuid v1.6.0 // indirect
 	github.com/klauspost/compress v1.17.9 // indirect
@@ -29,3 +31,5 @@ require (
 	golang.org/x/sys v0.28.0 // indirect
 	golang.org/x/text v0.17.0 // indirect
 )
+
+replace github.com/darkhyper24/blaban/shared => ../shared
@@ -0,0 +1,91 @@
+package payments
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/darkhyper24/blaban/shared/userdata"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// Payment statuses involved in refunds
+const (
+	StatusCompleted = "completed"
+	StatusRefunded  = "refunded"
+)
+
+var (
+	ErrPaymentNotFound = errors.New("payment not found")
+	ErrNotRefundable   = errors.New("only completed payments can be refunded")
+)
+
+type PaymentService struct {
+	collection *mongo.Collection
+}
+
+func NewPaymentService(collection *mongo.Collection) *PaymentService {
+	return &PaymentService{collection: collection}
+}
+
+// RefundPayment marks a completed payment as refunded by actorID
+func (s *PaymentService) RefundPayment(ctx context.Context, paymentID, actorID, reason string) error {
+	now := time.Now()
+	result, err := s.collection.UpdateOne(ctx, bson.M{"id": paymentID, "status": StatusCompleted}, bson.M{
+		"$set": bson.M{
+			"status":        StatusRefunded,
+			"refunded_at":   now,
+			"refunded_by":   actorID,
+			"refund_reason": reason,
+			"updated_at":    now,
+		},
+	})
+	if err != nil {
+		return err
+	}
+	if result.MatchedCount > 0 {
+		return nil
+	}
+
+	count, err := s.collection.CountDocuments(ctx, bson.M{"id": paymentID})
+	if err != nil {
+		return err
+	}
+	if count == 0 {
+		return ErrPaymentNotFound
+	}
+	return ErrNotRefundable
+}
+
+// UserPayments returns every payment made by a user, newest first
+func (s *PaymentService) UserPayments(ctx context.Context, userID string) ([]bson.M, error) {
+	cursor, err := s.collection.Find(ctx, bson.M{"user_id": userID},
+		options.Find().SetSort(bson.D{{Key: "created_at", Value: -1}}).SetProjection(bson.M{"_id": 0}))
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	payments := []bson.M{}
+	if err := cursor.All(ctx, &payments); err != nil {
+		return nil, err
+	}
+	return payments, nil
+}
+
+// AnonymiseUserPayments detaches a user's payments from their account. Payment
+// records are retained for accounting.
+func (s *PaymentService) AnonymiseUserPayments(ctx context.Context, userID string) (int64, error) {
+	result, err := s.collection.UpdateMany(ctx, bson.M{"user_id": userID}, bson.M{
+		"$set": bson.M{
+			"user_id":       userdata.ErasedUserID,
+			"anonymised_at": time.Now(),
+		},
+	})
+	if err != nil {
+		return 0, err
+	}
+	return result.ModifiedCount, nil
+}
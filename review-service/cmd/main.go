@@ -1,21 +1,119 @@
 package main
 
 import (
+	"context"
+	"errors"
 	"log"
+	"os"
 
+	"github.com/darkhyper24/blaban/review-service/internal/reviews"
+	"github.com/darkhyper24/blaban/shared/auth"
 	"github.com/gofiber/fiber/v2"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
 )
 
+var reviewService *reviews.ReviewService
+
 func main() {
+	mongoURI := os.Getenv("MONGO_URI")
+	if mongoURI == "" {
+		mongoURI = "mongodb://mongo:27017"
+	}
+	client, err := mongo.Connect(context.Background(), options.Client().ApplyURI(mongoURI))
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer client.Disconnect(context.Background())
+
+	db := client.Database("reviewdb")
+	if len(os.Args) > 1 && os.Args[1] == "migrate" {
+		if err := runMigrations(db, os.Args[2:]); err != nil {
+			log.Fatalf("Migration failed: %v", err)
+		}
+		return
+	}
+
+	// Create any missing indexes before serving
+	if err := runMigrations(db, []string{"up"}); err != nil {
+		log.Fatalf("Migration failed: %v", err)
+	}
+	reviewService = reviews.NewReviewService(db.Collection("reviews"))
+
 	app := fiber.New()
 
+	verifier, err := auth.VerifierFromEnv()
+	if err != nil {
+		log.Fatalf("Failed to configure token verification: %v", err)
+	}
+
 	app.Get("/api/reviews", func(c *fiber.Ctx) error {
 		return c.SendString("Reviews service")
 	})
 
+	// Only customers with a verified email address may post reviews
+	app.Post("/api/reviews", auth.Authenticate(verifier), auth.RequireVerifiedEmail(), handleCreateReview)
+	// Authors may delete their own reviews; reviews:moderate allows deleting any
+	app.Delete("/api/reviews/:id", auth.Authenticate(verifier), handleDeleteReview)
+
+	// Personal data export and erasure, driven by user-service
+	internal := app.Group("/internal", auth.Authenticate(verifier))
+	internal.Get("/users/:id/data", auth.RequireScope(auth.ScopeUserDataRead), handleExportUserData)
+	internal.Delete("/users/:id/data", auth.RequireScope(auth.ScopeUserDataErase), handleEraseUserData)
+
 	app.Get("/health", func(c *fiber.Ctx) error {
 		return c.JSON(fiber.Map{"status": "ok"})
 	})
 
 	log.Fatal(app.Listen(":8086"))
 }
+
+func handleCreateReview(c *fiber.Ctx) error {
+	var in reviews.ReviewInput
+	if err := c.BodyParser(&in); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid request body",
+		})
+	}
+	if err := in.Validate(); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	review, err := reviewService.CreateReview(c.Context(), auth.PrincipalFrom(c).UserID, in)
+	if err != nil {
+		log.Printf("Failed to store review: %v", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to store review",
+		})
+	}
+
+	return c.Status(fiber.StatusCreated).JSON(review)
+}
+
+func handleDeleteReview(c *fiber.Ctx) error {
+	principal := auth.PrincipalFrom(c)
+	authorID := principal.UserID
+	if principal.HasPermission(auth.PermReviewsModerate) {
+		authorID = ""
+	} else if authorID == "" {
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
+			"error": "insufficient permissions: requires " + auth.PermReviewsModerate,
+		})
+	}
+
+	if err := reviewService.DeleteReview(c.Context(), c.Params("id"), authorID); err != nil {
+		if errors.Is(err, reviews.ErrReviewNotFound) {
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+				"error": err.Error(),
+			})
+		}
+		log.Printf("Failed to delete review: %v", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to delete review",
+		})
+	}
+
+	return c.SendStatus(fiber.StatusNoContent)
+}
@@ -0,0 +1,20 @@
+package main
+
+import (
+	"context"
+	"os"
+
+	"github.com/darkhyper24/blaban/review-service/internal/db"
+	"github.com/darkhyper24/blaban/shared/migrate"
+	"github.com/darkhyper24/blaban/shared/mongomigrate"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// runMigrations handles "review-service migrate <status|up|down [n]|to <version>>"
+func runMigrations(database *mongo.Database, args []string) error {
+	migrator, err := mongomigrate.New(database, db.IndexMigrations)
+	if err != nil {
+		return err
+	}
+	return migrate.Run(context.Background(), migrator, args, os.Stdout)
+}
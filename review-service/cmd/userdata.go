@@ -0,0 +1,42 @@
+package main
+
+import (
+	"log"
+
+	"github.com/darkhyper24/blaban/shared/userdata"
+	"github.com/gofiber/fiber/v2"
+)
+
+const serviceName = "review-service"
+
+// handleExportUserData returns every review written by a user
+func handleExportUserData(c *fiber.Ctx) error {
+	userReviews, err := reviewService.UserReviews(c.Context(), c.Params("id"))
+	if err != nil {
+		log.Printf("Failed to export reviews: %v", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to export reviews",
+		})
+	}
+
+	return c.JSON(userdata.Export{
+		Service: serviceName,
+		Data:    fiber.Map{"reviews": userReviews},
+	})
+}
+
+// handleEraseUserData anonymises a user's reviews
+func handleEraseUserData(c *fiber.Ctx) error {
+	anonymised, err := reviewService.AnonymiseUserReviews(c.Context(), c.Params("id"))
+	if err != nil {
+		log.Printf("Failed to anonymise reviews: %v", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to anonymise reviews",
+		})
+	}
+
+	return c.JSON(userdata.ErasureResult{
+		Service:    serviceName,
+		Anonymised: anonymised,
+	})
+}
@@ -0,0 +1,31 @@
+package db
+
+import (
+	"github.com/darkhyper24/blaban/shared/mongomigrate"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// IndexMigrations are the versioned index definitions of the review database
+var IndexMigrations = []mongomigrate.Migration{
+	{
+		Version:    1,
+		Name:       "create_reviews_indexes",
+		Collection: "reviews",
+		Indexes: []mongo.IndexModel{
+			{
+				Keys:    bson.D{{Key: "id", Value: 1}},
+				Options: options.Index().SetName("reviews_id_unique").SetUnique(true),
+			},
+			{
+				Keys:    bson.D{{Key: "item_id", Value: 1}, {Key: "created_at", Value: -1}},
+				Options: options.Index().SetName("reviews_item_id_created_at"),
+			},
+			{
+				Keys:    bson.D{{Key: "user_id", Value: 1}},
+				Options: options.Index().SetName("reviews_user_id"),
+			},
+		},
+	},
+}
@@ -0,0 +1,43 @@
+package reviews
+
+import (
+	"errors"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+const maxCommentLength = 1000
+
+// Review is a customer's rating of a menu item
+type Review struct {
+	ID        string    `bson:"id" json:"id"`
+	ItemID    string    `bson:"item_id" json:"item_id"`
+	UserID    string    `bson:"user_id" json:"user_id"`
+	Rating    int       `bson:"rating" json:"rating"`
+	Comment   string    `bson:"comment" json:"comment"`
+	CreatedAt time.Time `bson:"created_at" json:"created_at"`
+}
+
+// ReviewInput holds the fields of a review a customer can set
+type ReviewInput struct {
+	ItemID  string `json:"item_id"`
+	Rating  int    `json:"rating"`
+	Comment string `json:"comment"`
+}
+
+// Validate normalizes the input and checks every field
+func (in *ReviewInput) Validate() error {
+	in.ItemID = strings.TrimSpace(in.ItemID)
+	in.Comment = strings.TrimSpace(in.Comment)
+
+	switch {
+	case in.ItemID == "":
+		return errors.New("item_id is required")
+	case in.Rating < 1 || in.Rating > 5:
+		return errors.New("rating must be between 1 and 5")
+	case utf8.RuneCountInString(in.Comment) > maxCommentLength:
+		return errors.New("comment must be at most 1000 characters")
+	}
+	return nil
+}
@@ -0,0 +1,89 @@
+package reviews
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/darkhyper24/blaban/shared/userdata"
+	"github.com/google/uuid"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+var ErrReviewNotFound = errors.New("review not found")
+
+type ReviewService struct {
+	collection *mongo.Collection
+}
+
+func NewReviewService(collection *mongo.Collection) *ReviewService {
+	return &ReviewService{collection: collection}
+}
+
+// CreateReview stores a review by userID. The input must have been validated.
+func (s *ReviewService) CreateReview(ctx context.Context, userID string, in ReviewInput) (*Review, error) {
+	review := &Review{
+		ID:        uuid.NewString(),
+		ItemID:    in.ItemID,
+		UserID:    userID,
+		Rating:    in.Rating,
+		Comment:   in.Comment,
+		CreatedAt: time.Now(),
+	}
+	if _, err := s.collection.InsertOne(ctx, review); err != nil {
+		return nil, err
+	}
+	return review, nil
+}
+
+// DeleteReview removes a review. A non-empty authorID limits the delete to
+// that author's own reviews; moderators pass an empty authorID.
+func (s *ReviewService) DeleteReview(ctx context.Context, reviewID, authorID string) error {
+	filter := bson.M{"id": reviewID}
+	if authorID != "" {
+		filter["user_id"] = authorID
+	}
+
+	result, err := s.collection.DeleteOne(ctx, filter)
+	if err != nil {
+		return err
+	}
+	if result.DeletedCount == 0 {
+		return ErrReviewNotFound
+	}
+	return nil
+}
+
+// UserReviews returns every review written by a user, newest first
+func (s *ReviewService) UserReviews(ctx context.Context, userID string) ([]bson.M, error) {
+	cursor, err := s.collection.Find(ctx, bson.M{"user_id": userID},
+		options.Find().SetSort(bson.D{{Key: "created_at", Value: -1}}).SetProjection(bson.M{"_id": 0}))
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	reviews := []bson.M{}
+	if err := cursor.All(ctx, &reviews); err != nil {
+		return nil, err
+	}
+	return reviews, nil
+}
+
+// AnonymiseUserReviews detaches a user's reviews from their account so that
+// ratings stay in the item averages without identifying the author
+func (s *ReviewService) AnonymiseUserReviews(ctx context.Context, userID string) (int64, error) {
+	result, err := s.collection.UpdateMany(ctx, bson.M{"user_id": userID}, bson.M{
+		"$set": bson.M{
+			"user_id":       userdata.ErasedUserID,
+			"anonymised_at": time.Now(),
+		},
+		"$unset": bson.M{"user_name": ""},
+	})
+	if err != nil {
+		return 0, err
+	}
+	return result.ModifiedCount, nil
+}
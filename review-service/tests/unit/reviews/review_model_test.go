@@ -0,0 +1,42 @@
+package reviews
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/darkhyper24/blaban/review-service/internal/reviews"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestReviewInputValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		input   reviews.ReviewInput
+		wantErr string
+	}{
+		{"Valid", reviews.ReviewInput{ItemID: "item-1", Rating: 5, Comment: "Great"}, ""},
+		{"Without comment", reviews.ReviewInput{ItemID: "item-1", Rating: 1}, ""},
+		{"Missing item", reviews.ReviewInput{ItemID: "  ", Rating: 4}, "item_id is required"},
+		{"Rating too low", reviews.ReviewInput{ItemID: "item-1", Rating: 0}, "rating must be between 1 and 5"},
+		{"Rating too high", reviews.ReviewInput{ItemID: "item-1", Rating: 6}, "rating must be between 1 and 5"},
+		{"Comment too long", reviews.ReviewInput{ItemID: "item-1", Rating: 3, Comment: strings.Repeat("a", 1001)}, "comment must be at most 1000 characters"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.input.Validate()
+			if tt.wantErr == "" {
+				assert.NoError(t, err)
+			} else {
+				assert.EqualError(t, err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestReviewInputValidateTrims(t *testing.T) {
+	input := reviews.ReviewInput{ItemID: " item-1 ", Rating: 4, Comment: "  Tasty \n"}
+	assert.NoError(t, input.Validate())
+	assert.Equal(t, "item-1", input.ItemID)
+	assert.Equal(t, "Tasty", input.Comment)
+}
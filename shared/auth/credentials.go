@@ -0,0 +1,32 @@
+package auth
+
+import (
+	"os"
+	"strings"
+)
+
+const defaultAuthServiceURL = "http://localhost:8082"
+
+// ClientCredentials identify a service to auth-service
+type ClientCredentials struct {
+	AuthServiceURL string
+	ClientID       string
+	ClientSecret   string
+	Scopes         []string
+}
+
+// ClientCredentialsFromEnv reads AUTH_SERVICE_URL, SERVICE_CLIENT_ID and
+// SERVICE_CLIENT_SECRET and requests the given scopes
+func ClientCredentialsFromEnv(scopes ...string) ClientCredentials {
+	authServiceURL := os.Getenv("AUTH_SERVICE_URL")
+	if authServiceURL == "" {
+		authServiceURL = defaultAuthServiceURL
+	}
+
+	return ClientCredentials{
+		AuthServiceURL: strings.TrimRight(authServiceURL, "/"),
+		ClientID:       os.Getenv("SERVICE_CLIENT_ID"),
+		ClientSecret:   os.Getenv("SERVICE_CLIENT_SECRET"),
+		Scopes:         scopes,
+	}
+}
@@ -0,0 +1,83 @@
+package auth
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// IntrospectionVerifier verifies tokens through auth-service's RFC 7662
+// introspection endpoint, authenticating with the service's own credentials
+type IntrospectionVerifier struct {
+	creds  ClientCredentials
+	client *http.Client
+}
+
+// NewIntrospectionVerifier creates a verifier that introspects tokens as the client in creds
+func NewIntrospectionVerifier(creds ClientCredentials) *IntrospectionVerifier {
+	return &IntrospectionVerifier{
+		creds:  creds,
+		client: &http.Client{Timeout: 5 * time.Second},
+	}
+}
+
+// Verify introspects the token and returns its principal if it is active
+func (v *IntrospectionVerifier) Verify(token string) (*Principal, error) {
+	if token == "" {
+		return nil, ErrMissingToken
+	}
+
+	form := url.Values{"token": {token}, "token_type_hint": {"access_token"}}
+	req, err := http.NewRequest(http.MethodPost, v.creds.AuthServiceURL+"/oauth/introspect", strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create introspection request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.SetBasicAuth(url.QueryEscape(v.creds.ClientID), url.QueryEscape(v.creds.ClientSecret))
+
+	resp, err := v.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to verify token: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("failed to verify token: introspection returned status %d", resp.StatusCode)
+	}
+
+	var introspection struct {
+		Active        bool     `json:"active"`
+		UserID        string   `json:"user_id"`
+		ClientID      string   `json:"client_id"`
+		Scope         string   `json:"scope"`
+		Role          string   `json:"role"`
+		Permissions   []string `json:"permissions"`
+		AMR           []string `json:"amr"`
+		EmailVerified bool     `json:"email_verified"`
+		MFAEnrolled   bool     `json:"mfa_enrolled"`
+		TokenType     string   `json:"token_type"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&introspection); err != nil {
+		return nil, fmt.Errorf("failed to decode introspection response: %w", err)
+	}
+	if !introspection.Active || introspection.TokenType == "refresh_token" {
+		return nil, ErrInvalidToken
+	}
+
+	principal := &Principal{
+		UserID:        introspection.UserID,
+		Role:          introspection.Role,
+		Permissions:   introspection.Permissions,
+		ClientID:      introspection.ClientID,
+		AMR:           introspection.AMR,
+		EmailVerified: introspection.EmailVerified,
+		MFAEnrolled:   introspection.MFAEnrolled,
+	}
+	if principal.IsService() {
+		principal.Scopes = strings.Fields(introspection.Scope)
+	}
+	return principal, nil
+}
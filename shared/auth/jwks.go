@@ -0,0 +1,188 @@
+package auth
+
+import (
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// JWK is the RFC 7517 representation of an RSA public signing key
+type JWK struct {
+	KeyType   string `json:"kty"`
+	Use       string `json:"use"`
+	Algorithm string `json:"alg"`
+	KeyID     string `json:"kid"`
+	N         string `json:"n"`
+	E         string `json:"e"`
+}
+
+// JWKSet is the document served at auth-service's /.well-known/jwks.json
+type JWKSet struct {
+	Keys []JWK `json:"keys"`
+}
+
+// NewRSAJWK encodes an RSA public key as a JWK identified by kid
+func NewRSAJWK(kid string, key *rsa.PublicKey) JWK {
+	return JWK{
+		KeyType:   "RSA",
+		Use:       "sig",
+		Algorithm: "RS256",
+		KeyID:     kid,
+		N:         base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+		E:         base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+	}
+}
+
+// KeyID derives a stable key ID from the SHA-256 thumbprint of the public key
+func KeyID(key *rsa.PublicKey) (string, error) {
+	der, err := x509.MarshalPKIXPublicKey(key)
+	if err != nil {
+		return "", fmt.Errorf("failed to encode public key: %w", err)
+	}
+	sum := sha256.Sum256(der)
+	return base64.RawURLEncoding.EncodeToString(sum[:16]), nil
+}
+
+// PublicKey decodes the RSA public key of the JWK
+func (k JWK) PublicKey() (*rsa.PublicKey, error) {
+	if k.KeyType != "RSA" {
+		return nil, fmt.Errorf("unsupported key type %q", k.KeyType)
+	}
+	n, err := base64.RawURLEncoding.DecodeString(k.N)
+	if err != nil {
+		return nil, fmt.Errorf("invalid modulus: %w", err)
+	}
+	e, err := base64.RawURLEncoding.DecodeString(k.E)
+	if err != nil {
+		return nil, fmt.Errorf("invalid exponent: %w", err)
+	}
+	return &rsa.PublicKey{
+		N: new(big.Int).SetBytes(n),
+		E: int(new(big.Int).SetBytes(e).Int64()),
+	}, nil
+}
+
+var errUnknownKey = errors.New("unknown signing key")
+
+// jwksCache fetches auth-service's signing keys and keeps them for ttl. A
+// token signed with an unknown key triggers a refresh, at most once per
+// minRefresh, so key rotation is picked up without hammering auth-service.
+// Fetches run outside the lock so a slow auth-service never blocks callers
+// whose key is already cached.
+type jwksCache struct {
+	url        string
+	client     *http.Client
+	ttl        time.Duration
+	minRefresh time.Duration
+
+	mu         sync.Mutex
+	keys       map[string]*rsa.PublicKey
+	fetchedAt  time.Time
+	refreshing chan struct{}
+	refreshErr error
+}
+
+func newJWKSCache(url string) *jwksCache {
+	return &jwksCache{
+		url:        url,
+		client:     &http.Client{Timeout: 5 * time.Second},
+		ttl:        10 * time.Minute,
+		minRefresh: 30 * time.Second,
+	}
+}
+
+// key returns the public key with the given ID, refreshing the set when it is
+// stale or does not contain kid
+func (c *jwksCache) key(kid string) (*rsa.PublicKey, error) {
+	c.mu.Lock()
+	key, ok := c.keys[kid]
+	age := time.Since(c.fetchedAt)
+	switch {
+	case ok && age < c.ttl:
+		c.mu.Unlock()
+		return key, nil
+	case ok:
+		// Keep serving the known key while the set is refreshed, which also
+		// covers auth-service being unreachable
+		c.startRefresh()
+		c.mu.Unlock()
+		return key, nil
+	case c.keys != nil && age < c.minRefresh:
+		c.mu.Unlock()
+		return nil, errUnknownKey
+	}
+	done := c.startRefresh()
+	c.mu.Unlock()
+
+	<-done
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if key, ok := c.keys[kid]; ok {
+		return key, nil
+	}
+	if c.refreshErr != nil {
+		return nil, c.refreshErr
+	}
+	return nil, errUnknownKey
+}
+
+// startRefresh fetches the key set unless a fetch is already running and
+// returns a channel that is closed once it finishes. c.mu must be held.
+func (c *jwksCache) startRefresh() <-chan struct{} {
+	if c.refreshing != nil {
+		return c.refreshing
+	}
+
+	done := make(chan struct{})
+	c.refreshing = done
+	go func() {
+		keys, err := c.fetch()
+
+		c.mu.Lock()
+		if err == nil {
+			c.keys = keys
+			c.fetchedAt = time.Now()
+		}
+		c.refreshErr = err
+		c.refreshing = nil
+		c.mu.Unlock()
+		close(done)
+	}()
+	return done
+}
+
+func (c *jwksCache) fetch() (map[string]*rsa.PublicKey, error) {
+	resp, err := c.client.Get(c.url)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch signing keys: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("failed to fetch signing keys: status %d", resp.StatusCode)
+	}
+
+	var set JWKSet
+	if err := json.NewDecoder(resp.Body).Decode(&set); err != nil {
+		return nil, fmt.Errorf("failed to decode signing keys: %w", err)
+	}
+
+	keys := make(map[string]*rsa.PublicKey, len(set.Keys))
+	for _, jwk := range set.Keys {
+		key, err := jwk.PublicKey()
+		if err != nil {
+			continue
+		}
+		keys[jwk.KeyID] = key
+	}
+	return keys, nil
+}
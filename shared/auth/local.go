@@ -0,0 +1,138 @@
+package auth
+
+import (
+	"crypto/rsa"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/golang-jwt/jwt/v4"
+)
+
+// Issuer is the iss claim of every token signed by auth-service
+const Issuer = "auth-service"
+
+// Claims are the claims of an access token issued by auth-service
+type Claims struct {
+	UserID        string   `json:"user_id"`
+	Role          string   `json:"role"`
+	Permissions   []string `json:"permissions,omitempty"`
+	ClientID      string   `json:"client_id,omitempty"`
+	Scope         string   `json:"scope,omitempty"`
+	AMR           []string `json:"amr,omitempty"`
+	EmailVerified bool     `json:"email_verified,omitempty"`
+	MFAEnrolled   bool     `json:"mfa_enrolled,omitempty"`
+	jwt.RegisteredClaims
+}
+
+// Principal converts the claims into the principal they authenticate
+func (c *Claims) Principal() *Principal {
+	principal := &Principal{
+		UserID:        c.UserID,
+		Role:          c.Role,
+		Permissions:   c.Permissions,
+		ClientID:      c.ClientID,
+		AMR:           c.AMR,
+		EmailVerified: c.EmailVerified,
+		MFAEnrolled:   c.MFAEnrolled,
+	}
+	if principal.IsService() {
+		principal.Scopes = strings.Fields(c.Scope)
+	}
+	return principal
+}
+
+// LocalVerifier verifies RS256 access tokens without calling auth-service,
+// using either a fixed public key or auth-service's cached JWKS
+type LocalVerifier struct {
+	key  *rsa.PublicKey
+	jwks *jwksCache
+}
+
+// NewKeyVerifier creates a verifier that accepts tokens signed by the private half of key
+func NewKeyVerifier(key *rsa.PublicKey) *LocalVerifier {
+	return &LocalVerifier{key: key}
+}
+
+// NewJWKSVerifier creates a verifier that looks up signing keys in the JWKS served at jwksURL
+func NewJWKSVerifier(jwksURL string) *LocalVerifier {
+	return &LocalVerifier{jwks: newJWKSCache(jwksURL)}
+}
+
+// VerifierFromEnv creates a verifier from the PEM public key in
+// JWT_PUBLIC_KEY_FILE, or else from the JWKS of the auth-service at
+// AUTH_SERVICE_URL
+func VerifierFromEnv() (*LocalVerifier, error) {
+	if path := os.Getenv("JWT_PUBLIC_KEY_FILE"); path != "" {
+		pem, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read public key: %w", err)
+		}
+		key, err := jwt.ParseRSAPublicKeyFromPEM(pem)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse public key: %w", err)
+		}
+		return NewKeyVerifier(key), nil
+	}
+
+	baseURL := os.Getenv("AUTH_SERVICE_URL")
+	if baseURL == "" {
+		baseURL = defaultAuthServiceURL
+	}
+	return NewJWKSVerifier(strings.TrimRight(baseURL, "/") + "/.well-known/jwks.json"), nil
+}
+
+// Verify checks the signature, issuer and expiry of the token and returns its principal
+func (v *LocalVerifier) Verify(token string) (*Principal, error) {
+	if token == "" {
+		return nil, ErrMissingToken
+	}
+
+	parsed, err := jwt.ParseWithClaims(token, &Claims{}, v.keyFunc, jwt.WithValidMethods([]string{"RS256"}))
+	if err != nil {
+		return nil, ErrInvalidToken
+	}
+
+	claims, ok := parsed.Claims.(*Claims)
+	if !ok || !parsed.Valid || claims.ExpiresAt == nil || !claims.VerifyIssuer(Issuer, true) {
+		return nil, ErrInvalidToken
+	}
+	if claims.UserID == "" && claims.ClientID == "" {
+		return nil, ErrInvalidToken
+	}
+
+	return claims.Principal(), nil
+}
+
+// VerifyManagerRole checks that the Authorization header carries a token
+// allowed to edit the menu. It implements menu-service's db.AuthVerifier.
+func (v *LocalVerifier) VerifyManagerRole(authHeader string) error {
+	if authHeader == "" {
+		return ErrMissingToken
+	}
+
+	principal, err := v.Verify(BearerToken(authHeader))
+	if err != nil {
+		return fmt.Errorf("failed to verify token: %w", err)
+	}
+
+	if !principal.HasPermission(PermMenuWrite) {
+		return errors.New("only managers can perform this action")
+	}
+
+	// Price and discount changes always need a token issued after MFA
+	return principal.CheckMFA()
+}
+
+func (v *LocalVerifier) keyFunc(token *jwt.Token) (interface{}, error) {
+	if v.key != nil {
+		return v.key, nil
+	}
+
+	kid, _ := token.Header["kid"].(string)
+	if kid == "" {
+		return nil, errors.New("token has no key ID")
+	}
+	return v.jwks.key(kid)
+}
@@ -0,0 +1,123 @@
+package auth
+
+import (
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+const principalKey = "auth.principal"
+
+// Authenticate verifies the bearer token of every request and stores the
+// resulting principal on the context
+func Authenticate(v Verifier) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		principal, err := v.Verify(BearerToken(c.Get("Authorization")))
+		if err != nil {
+			return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+				"error": err.Error(),
+			})
+		}
+
+		c.Locals(principalKey, principal)
+		return c.Next()
+	}
+}
+
+// RequirePermission rejects requests whose principal lacks any of perms.
+// It must run after Authenticate.
+func RequirePermission(perms ...string) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		principal := PrincipalFrom(c)
+		if principal == nil {
+			return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+				"error": ErrMissingToken.Error(),
+			})
+		}
+
+		if !principal.HasAllPermissions(perms...) {
+			return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
+				"error": "insufficient permissions: requires " + strings.Join(perms, ", "),
+			})
+		}
+
+		return c.Next()
+	}
+}
+
+// RequireMFA rejects requests whose token was issued without a second factor.
+// It must run after Authenticate.
+func RequireMFA() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		principal := PrincipalFrom(c)
+		if principal == nil {
+			return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+				"error": ErrMissingToken.Error(),
+			})
+		}
+
+		if err := principal.CheckMFA(); err != nil {
+			return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
+				"error": err.Error(),
+			})
+		}
+
+		return c.Next()
+	}
+}
+
+// RequireVerifiedEmail rejects requests from users who have not verified
+// their email address yet. It must run after Authenticate.
+func RequireVerifiedEmail() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		principal := PrincipalFrom(c)
+		if principal == nil {
+			return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+				"error": ErrMissingToken.Error(),
+			})
+		}
+
+		if !principal.HasVerifiedEmail() {
+			return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
+				"error": ErrEmailNotVerified.Error(),
+			})
+		}
+
+		return c.Next()
+	}
+}
+
+// RequireScope rejects requests not made with a service token carrying every
+// scope in scopes. It must run after Authenticate.
+func RequireScope(scopes ...string) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		principal := PrincipalFrom(c)
+		if principal == nil {
+			return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+				"error": ErrMissingToken.Error(),
+			})
+		}
+
+		if !principal.IsService() {
+			return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
+				"error": "service token required",
+			})
+		}
+
+		for _, scope := range scopes {
+			if !principal.HasScope(scope) {
+				return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
+					"error": "insufficient scope: requires " + strings.Join(scopes, " "),
+				})
+			}
+		}
+
+		return c.Next()
+	}
+}
+
+// PrincipalFrom returns the principal stored by Authenticate, or nil
+func PrincipalFrom(c *fiber.Ctx) *Principal {
+	principal, _ := c.Locals(principalKey).(*Principal)
+	return principal
+}
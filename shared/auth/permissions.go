@@ -0,0 +1,53 @@
+package auth
+
+// Permissions understood by the services. The mapping from roles to
+// permissions lives in auth-service and is embedded in every access token.
+const (
+	PermMenuWrite        = "menu:write"
+	PermOrdersReadAll    = "orders:read_all"
+	PermOrdersTransition = "orders:transition"
+	PermReviewsModerate  = "reviews:moderate"
+	PermPaymentsRefund   = "payments:refund"
+	PermUsersManage      = "users:manage"
+	PermUsersView        = "users:view"
+)
+
+// Scopes granted to service clients through the client-credentials grant
+const (
+	ScopeMenuRead      = "menu:read"
+	ScopeUsersRead     = "users:read"
+	ScopeTokensIssue   = "tokens:issue"
+	ScopeTokensRevoke  = "tokens:revoke"
+	ScopeSessionsRead  = "sessions:read"
+	ScopeUserDataRead  = "userdata:read"
+	ScopeUserDataErase = "userdata:erase"
+	ScopeAddressesRead = "addresses:read"
+	ScopeStockReserve  = "stock:reserve"
+	ScopePromoRedeem   = "promo:redeem"
+)
+
+// Roles that can be assigned to an account
+const (
+	RoleCustomer = "user"
+	RoleManager  = "manager"
+	RoleKitchen  = "kitchen"
+	RoleCashier  = "cashier"
+	RoleDelivery = "delivery"
+	RoleAdmin    = "admin"
+)
+
+// StaffRoles lists the roles an admin can assign to an invited staff member
+var StaffRoles = []string{RoleManager, RoleKitchen, RoleCashier, RoleDelivery, RoleAdmin}
+
+// Roles lists every known role
+var Roles = []string{RoleCustomer, RoleManager, RoleKitchen, RoleCashier, RoleDelivery, RoleAdmin}
+
+// IsValidRole reports whether role is one of the known roles
+func IsValidRole(role string) bool {
+	for _, r := range Roles {
+		if r == role {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,90 @@
+package auth
+
+// Principal is the authenticated caller of a request. Users carry a role and
+// permissions; service clients carry a client ID and scopes.
+type Principal struct {
+	UserID        string   `json:"user_id"`
+	Role          string   `json:"role"`
+	Permissions   []string `json:"permissions"`
+	ClientID      string   `json:"client_id"`
+	Scopes        []string `json:"scopes"`
+	AMR           []string `json:"amr"`
+	EmailVerified bool     `json:"email_verified"`
+	// MFAEnrolled is set for users who had set up a second factor when the token was issued
+	MFAEnrolled bool `json:"mfa_enrolled"`
+}
+
+// AMRMultiFactor is the RFC 8176 method recorded once a user completed a second factor
+const AMRMultiFactor = "mfa"
+
+// CheckMFA returns nil if the user completed multi-factor authentication.
+// Users without a second factor get ErrMFANotEnrolled, so they know to set
+// one up; enrolled users who skipped it get ErrMFARequired.
+func (p *Principal) CheckMFA() error {
+	switch {
+	case p.HasMFA():
+		return nil
+	case p == nil || !p.MFAEnrolled:
+		return ErrMFANotEnrolled
+	}
+	return ErrMFARequired
+}
+
+// HasMFA reports whether the user completed multi-factor authentication
+func (p *Principal) HasMFA() bool {
+	if p == nil {
+		return false
+	}
+	for _, method := range p.AMR {
+		if method == AMRMultiFactor {
+			return true
+		}
+	}
+	return false
+}
+
+// HasVerifiedEmail reports whether the principal is a user with a verified email address
+func (p *Principal) HasVerifiedEmail() bool {
+	return p != nil && p.UserID != "" && p.EmailVerified
+}
+
+// IsService reports whether the principal is a service client rather than a user
+func (p *Principal) IsService() bool {
+	return p != nil && p.UserID == "" && p.ClientID != ""
+}
+
+// HasScope reports whether the principal's token was granted scope
+func (p *Principal) HasScope(scope string) bool {
+	if p == nil {
+		return false
+	}
+	for _, granted := range p.Scopes {
+		if granted == scope {
+			return true
+		}
+	}
+	return false
+}
+
+// HasPermission reports whether the principal was granted perm
+func (p *Principal) HasPermission(perm string) bool {
+	if p == nil {
+		return false
+	}
+	for _, granted := range p.Permissions {
+		if granted == perm {
+			return true
+		}
+	}
+	return false
+}
+
+// HasAllPermissions reports whether the principal was granted every permission in perms
+func (p *Principal) HasAllPermissions(perms ...string) bool {
+	for _, perm := range perms {
+		if !p.HasPermission(perm) {
+			return false
+		}
+	}
+	return true
+}
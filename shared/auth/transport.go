@@ -0,0 +1,122 @@
+package auth
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+// refreshMargin is how long before expiry a cached service token is replaced
+const refreshMargin = 30 * time.Second
+
+// ServiceTransport is an http.RoundTripper that obtains service tokens through
+// the client-credentials grant, caches them until shortly before they expire
+// and attaches them to every outgoing request
+type ServiceTransport struct {
+	Credentials ClientCredentials
+	Base        http.RoundTripper
+
+	mu     sync.Mutex
+	token  string
+	expiry time.Time
+}
+
+// NewServiceClient returns an HTTP client that authenticates as the service
+// described by creds
+func NewServiceClient(creds ClientCredentials) *http.Client {
+	return &http.Client{
+		Transport: &ServiceTransport{Credentials: creds},
+		Timeout:   10 * time.Second,
+	}
+}
+
+func (t *ServiceTransport) base() http.RoundTripper {
+	if t.Base != nil {
+		return t.Base
+	}
+	return http.DefaultTransport
+}
+
+// RoundTrip implements http.RoundTripper
+func (t *ServiceTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	token, err := t.Token()
+	if err != nil {
+		return nil, err
+	}
+
+	authReq := req.Clone(req.Context())
+	authReq.Header.Set("Authorization", "Bearer "+token)
+
+	resp, err := t.base().RoundTrip(authReq)
+	if err == nil && resp.StatusCode == http.StatusUnauthorized {
+		// The token may have been revoked or signed with a rotated key
+		t.invalidate(token)
+	}
+	return resp, err
+}
+
+// Token returns a cached service token, fetching a new one when needed
+func (t *ServiceTransport) Token() (string, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.token != "" && time.Now().Add(refreshMargin).Before(t.expiry) {
+		return t.token, nil
+	}
+
+	token, expiresIn, err := t.fetchToken()
+	if err != nil {
+		return "", err
+	}
+
+	t.token = token
+	t.expiry = time.Now().Add(expiresIn)
+	return t.token, nil
+}
+
+func (t *ServiceTransport) invalidate(token string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.token == token {
+		t.token = ""
+	}
+}
+
+func (t *ServiceTransport) fetchToken() (string, time.Duration, error) {
+	form := url.Values{"grant_type": {"client_credentials"}}
+	if len(t.Credentials.Scopes) > 0 {
+		form.Set("scope", strings.Join(t.Credentials.Scopes, " "))
+	}
+
+	req, err := http.NewRequest(http.MethodPost, t.Credentials.AuthServiceURL+"/oauth/token", strings.NewReader(form.Encode()))
+	if err != nil {
+		return "", 0, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.SetBasicAuth(url.QueryEscape(t.Credentials.ClientID), url.QueryEscape(t.Credentials.ClientSecret))
+
+	resp, err := t.base().RoundTrip(req)
+	if err != nil {
+		return "", 0, fmt.Errorf("failed to fetch service token: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return "", 0, fmt.Errorf("failed to fetch service token: status %d", resp.StatusCode)
+	}
+
+	var tokenResp struct {
+		AccessToken string `json:"access_token"`
+		ExpiresIn   int    `json:"expires_in"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&tokenResp); err != nil {
+		return "", 0, fmt.Errorf("failed to decode service token: %w", err)
+	}
+
+	return tokenResp.AccessToken, time.Duration(tokenResp.ExpiresIn) * time.Second, nil
+}
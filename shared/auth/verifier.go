@@ -0,0 +1,34 @@
+package auth
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+var (
+	// ErrMissingToken is returned when a request carries no bearer token
+	ErrMissingToken = errors.New("missing authorization header")
+	// ErrInvalidToken is returned when a token is malformed, expired or not signed by auth-service
+	ErrInvalidToken = errors.New("invalid token")
+	// ErrMFARequired is returned when an action needs a token issued after multi-factor authentication
+	ErrMFARequired = errors.New("multi-factor authentication required")
+	// ErrMFANotEnrolled is returned instead of ErrMFARequired when the user has
+	// not set up a second factor yet. It wraps ErrMFARequired.
+	ErrMFANotEnrolled = fmt.Errorf("%w: enrol a second factor at /api/users/mfa/enroll, then sign in again", ErrMFARequired)
+	// ErrEmailNotVerified is returned when an action needs a verified email address
+	ErrEmailNotVerified = errors.New("email address not verified")
+)
+
+// Verifier turns a bearer token into a Principal
+type Verifier interface {
+	Verify(token string) (*Principal, error)
+}
+
+// BearerToken extracts the token from an Authorization header value
+func BearerToken(authHeader string) string {
+	if len(authHeader) > 7 && strings.EqualFold(authHeader[:7], "Bearer ") {
+		return authHeader[7:]
+	}
+	return authHeader
+}
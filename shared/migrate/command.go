@@ -0,0 +1,82 @@
+package migrate
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+)
+
+// Runner is implemented by the SQL and MongoDB migrators so both can be driven
+// by the same migrate subcommand
+type Runner interface {
+	Status(ctx context.Context) ([]Status, error)
+	Up(ctx context.Context) error
+	Down(ctx context.Context, steps int) error
+	To(ctx context.Context, version int64) error
+}
+
+// Usage describes the arguments accepted by Run
+const Usage = "usage: migrate status | up | down [steps] | to <version>"
+
+// Run executes a migrate subcommand such as "up", "down 2", "to 3" or "status"
+func Run(ctx context.Context, runner Runner, args []string, out io.Writer) error {
+	if len(args) == 0 {
+		return errors.New(Usage)
+	}
+
+	switch args[0] {
+	case "status":
+		statuses, err := runner.Status(ctx)
+		if err != nil {
+			return err
+		}
+		for _, s := range statuses {
+			state := "pending"
+			if s.Applied {
+				state = "applied " + s.AppliedAt.Format("2006-01-02 15:04:05")
+			}
+			fmt.Fprintf(out, "%6d  %-40s %s\n", s.Version, s.Name, state)
+		}
+		return nil
+
+	case "up":
+		if err := runner.Up(ctx); err != nil {
+			return err
+		}
+		fmt.Fprintln(out, "migrations applied")
+		return nil
+
+	case "down":
+		steps := 1
+		if len(args) > 1 {
+			n, err := strconv.Atoi(args[1])
+			if err != nil || n < 1 {
+				return fmt.Errorf("invalid step count %q", args[1])
+			}
+			steps = n
+		}
+		if err := runner.Down(ctx, steps); err != nil {
+			return err
+		}
+		fmt.Fprintf(out, "rolled back %d migration(s)\n", steps)
+		return nil
+
+	case "to":
+		if len(args) < 2 {
+			return errors.New(Usage)
+		}
+		version, err := strconv.ParseInt(args[1], 10, 64)
+		if err != nil || version < 0 {
+			return fmt.Errorf("invalid version %q", args[1])
+		}
+		if err := runner.To(ctx, version); err != nil {
+			return err
+		}
+		fmt.Fprintf(out, "migrated to version %d\n", version)
+		return nil
+	}
+
+	return errors.New(Usage)
+}
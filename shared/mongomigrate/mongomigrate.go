@@ -0,0 +1,237 @@
+// Package mongomigrate applies versioned index definitions to MongoDB
+// collections, mirroring the SQL migrations in package migrate.
+package mongomigrate
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"time"
+
+	"github.com/darkhyper24/blaban/shared/migrate"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+const (
+	migrationsCollection = "schema_migrations"
+	locksCollection      = "schema_migrations_lock"
+	lockTTL              = 5 * time.Minute
+)
+
+// Migration creates a set of named indexes on one collection. Rolling it back
+// drops those indexes again.
+type Migration struct {
+	Version    int64
+	Name       string
+	Collection string
+	Indexes    []mongo.IndexModel
+}
+
+// Migrator applies index migrations, recording them in schema_migrations
+type Migrator struct {
+	db         *mongo.Database
+	migrations []Migration
+}
+
+// New validates the migrations and returns a migrator for db
+func New(db *mongo.Database, migrations []Migration) (*Migrator, error) {
+	sorted := append([]Migration(nil), migrations...)
+	sort.Slice(sorted, func(i, j int) bool {
+		return sorted[i].Version < sorted[j].Version
+	})
+
+	for i, m := range sorted {
+		if i > 0 && sorted[i-1].Version == m.Version {
+			return nil, fmt.Errorf("duplicate migration version %d", m.Version)
+		}
+		for _, index := range m.Indexes {
+			if index.Options == nil || index.Options.Name == nil {
+				return nil, fmt.Errorf("migration %d_%s: every index needs a name so it can be dropped", m.Version, m.Name)
+			}
+		}
+	}
+
+	return &Migrator{db: db, migrations: sorted}, nil
+}
+
+// Status reports every known migration and whether it has been applied
+func (m *Migrator) Status(ctx context.Context) ([]migrate.Status, error) {
+	applied, err := m.appliedVersions(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	statuses := make([]migrate.Status, 0, len(m.migrations))
+	for _, migration := range m.migrations {
+		appliedAt, ok := applied[migration.Version]
+		statuses = append(statuses, migrate.Status{
+			Version:   migration.Version,
+			Name:      migration.Name,
+			Applied:   ok,
+			AppliedAt: appliedAt,
+		})
+	}
+	return statuses, nil
+}
+
+// Up applies every pending migration
+func (m *Migrator) Up(ctx context.Context) error {
+	if len(m.migrations) == 0 {
+		return nil
+	}
+	return m.To(ctx, m.migrations[len(m.migrations)-1].Version)
+}
+
+// Down rolls back the most recently applied steps migrations
+func (m *Migrator) Down(ctx context.Context, steps int) error {
+	return m.withLock(ctx, func() error {
+		applied, err := m.appliedVersions(ctx)
+		if err != nil {
+			return err
+		}
+
+		for i := len(m.migrations) - 1; i >= 0 && steps > 0; i-- {
+			if _, ok := applied[m.migrations[i].Version]; !ok {
+				continue
+			}
+			if err := m.rollback(ctx, m.migrations[i]); err != nil {
+				return err
+			}
+			steps--
+		}
+		return nil
+	})
+}
+
+// To migrates up or down until version is the latest applied migration.
+// Version 0 rolls back every migration.
+func (m *Migrator) To(ctx context.Context, version int64) error {
+	if version != 0 && !m.hasVersion(version) {
+		return fmt.Errorf("unknown migration version %d", version)
+	}
+
+	return m.withLock(ctx, func() error {
+		applied, err := m.appliedVersions(ctx)
+		if err != nil {
+			return err
+		}
+
+		for i := len(m.migrations) - 1; i >= 0; i-- {
+			migration := m.migrations[i]
+			if _, ok := applied[migration.Version]; ok && migration.Version > version {
+				if err := m.rollback(ctx, migration); err != nil {
+					return err
+				}
+			}
+		}
+
+		for _, migration := range m.migrations {
+			if _, ok := applied[migration.Version]; !ok && migration.Version <= version {
+				if err := m.apply(ctx, migration); err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	})
+}
+
+func (m *Migrator) hasVersion(version int64) bool {
+	for _, migration := range m.migrations {
+		if migration.Version == version {
+			return true
+		}
+	}
+	return false
+}
+
+func (m *Migrator) apply(ctx context.Context, migration Migration) error {
+	if _, err := m.db.Collection(migration.Collection).Indexes().CreateMany(ctx, migration.Indexes); err != nil {
+		return fmt.Errorf("migration %d_%s up failed: %w", migration.Version, migration.Name, err)
+	}
+
+	_, err := m.db.Collection(migrationsCollection).InsertOne(ctx, bson.M{
+		"_id":        migration.Version,
+		"name":       migration.Name,
+		"applied_at": time.Now(),
+	})
+	return err
+}
+
+func (m *Migrator) rollback(ctx context.Context, migration Migration) error {
+	indexes := m.db.Collection(migration.Collection).Indexes()
+	for _, index := range migration.Indexes {
+		if _, err := indexes.DropOne(ctx, *index.Options.Name); err != nil && !isIndexNotFound(err) {
+			return fmt.Errorf("migration %d_%s down failed: %w", migration.Version, migration.Name, err)
+		}
+	}
+
+	_, err := m.db.Collection(migrationsCollection).DeleteOne(ctx, bson.M{"_id": migration.Version})
+	return err
+}
+
+func (m *Migrator) appliedVersions(ctx context.Context) (map[int64]time.Time, error) {
+	cursor, err := m.db.Collection(migrationsCollection).Find(ctx, bson.M{})
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	applied := map[int64]time.Time{}
+	for cursor.Next(ctx) {
+		var doc struct {
+			Version   int64     `bson:"_id"`
+			AppliedAt time.Time `bson:"applied_at"`
+		}
+		if err := cursor.Decode(&doc); err != nil {
+			return nil, err
+		}
+		applied[doc.Version] = doc.AppliedAt
+	}
+	return applied, cursor.Err()
+}
+
+// withLock holds a lease document for the duration of fn. MongoDB has no
+// advisory locks, so a stale lease is taken over once it expires.
+func (m *Migrator) withLock(ctx context.Context, fn func() error) (err error) {
+	locks := m.db.Collection(locksCollection)
+	owner, _ := os.Hostname()
+	owner = fmt.Sprintf("%s-%d", owner, time.Now().UnixNano())
+
+	for {
+		now := time.Now()
+		_, err := locks.UpdateOne(ctx,
+			bson.M{"_id": "migrate", "expires_at": bson.M{"$lt": now}},
+			bson.M{"$set": bson.M{"owner": owner, "expires_at": now.Add(lockTTL)}},
+			options.Update().SetUpsert(true),
+		)
+		if err == nil {
+			break
+		}
+		if !mongo.IsDuplicateKeyError(err) {
+			return fmt.Errorf("failed to acquire migration lock: %w", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(time.Second):
+		}
+	}
+
+	defer func() {
+		_, unlockErr := locks.DeleteOne(context.Background(), bson.M{"_id": "migrate", "owner": owner})
+		err = errors.Join(err, unlockErr)
+	}()
+
+	return fn()
+}
+
+func isIndexNotFound(err error) bool {
+	var cmdErr mongo.CommandError
+	return errors.As(err, &cmdErr) && cmdErr.Name == "IndexNotFound"
+}
@@ -0,0 +1,200 @@
+package auth
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/darkhyper24/blaban/shared/auth"
+	"github.com/golang-jwt/jwt/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func signToken(t *testing.T, key *rsa.PrivateKey, kid string, claims *auth.Claims) string {
+	token := jwt.NewWithClaims(jwt.SigningMethodRS256, claims)
+	token.Header["kid"] = kid
+	signed, err := token.SignedString(key)
+	require.NoError(t, err)
+	return signed
+}
+
+func userClaims(expiresIn time.Duration) *auth.Claims {
+	return &auth.Claims{
+		UserID:      "user-1",
+		Role:        auth.RoleManager,
+		Permissions: []string{auth.PermMenuWrite},
+		ClientID:    "blaban-web",
+		AMR:         []string{"pwd", "otp", auth.AMRMultiFactor},
+		RegisteredClaims: jwt.RegisteredClaims{
+			Subject:   "user-1",
+			Issuer:    auth.Issuer,
+			ExpiresAt: jwt.NewNumericDate(time.Now().Add(expiresIn)),
+		},
+	}
+}
+
+func TestLocalVerifierWithKey(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	otherKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+
+	verifier := auth.NewKeyVerifier(&key.PublicKey)
+
+	principal, err := verifier.Verify(signToken(t, key, "k1", userClaims(time.Minute)))
+	require.NoError(t, err)
+	assert.Equal(t, "user-1", principal.UserID)
+	assert.Equal(t, auth.RoleManager, principal.Role)
+	assert.True(t, principal.HasPermission(auth.PermMenuWrite))
+	assert.True(t, principal.HasMFA())
+	assert.False(t, principal.IsService())
+
+	service := &auth.Claims{
+		ClientID: "order-service",
+		Scope:    auth.ScopeMenuRead,
+		RegisteredClaims: jwt.RegisteredClaims{
+			Issuer:    auth.Issuer,
+			ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Minute)),
+		},
+	}
+	principal, err = verifier.Verify(signToken(t, key, "k1", service))
+	require.NoError(t, err)
+	assert.True(t, principal.IsService())
+	assert.True(t, principal.HasScope(auth.ScopeMenuRead))
+
+	wrongIssuer := userClaims(time.Minute)
+	wrongIssuer.Issuer = "someone-else"
+	hmacToken, err := jwt.NewWithClaims(jwt.SigningMethodHS256, userClaims(time.Minute)).SignedString([]byte("secret"))
+	require.NoError(t, err)
+
+	rejected := map[string]string{
+		"empty":        "",
+		"expired":      signToken(t, key, "k1", userClaims(-time.Minute)),
+		"wrong key":    signToken(t, otherKey, "k1", userClaims(time.Minute)),
+		"wrong issuer": signToken(t, key, "k1", wrongIssuer),
+		"hmac":         hmacToken,
+		"garbage":      "not-a-jwt",
+	}
+	for name, token := range rejected {
+		t.Run(name, func(t *testing.T) {
+			_, err := verifier.Verify(token)
+			assert.Error(t, err)
+		})
+	}
+}
+
+func TestLocalVerifierWithJWKS(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	kid, err := auth.KeyID(&key.PublicKey)
+	require.NoError(t, err)
+
+	var fetches int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&fetches, 1)
+		json.NewEncoder(w).Encode(auth.JWKSet{Keys: []auth.JWK{auth.NewRSAJWK(kid, &key.PublicKey)}})
+	}))
+	defer server.Close()
+
+	verifier := auth.NewJWKSVerifier(server.URL)
+	token := signToken(t, key, kid, userClaims(time.Minute))
+
+	for i := 0; i < 3; i++ {
+		principal, err := verifier.Verify(token)
+		require.NoError(t, err)
+		assert.Equal(t, "user-1", principal.UserID)
+	}
+	assert.Equal(t, int32(1), atomic.LoadInt32(&fetches), "keys should be cached")
+
+	// An unknown key ID must not trigger a refetch on every request
+	_, err = verifier.Verify(signToken(t, key, "unknown", userClaims(time.Minute)))
+	assert.Error(t, err)
+	assert.Equal(t, int32(1), atomic.LoadInt32(&fetches))
+}
+
+func TestLocalVerifierWithJWKSSharesConcurrentFetches(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	kid, err := auth.KeyID(&key.PublicKey)
+	require.NoError(t, err)
+
+	var fetches int32
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&fetches, 1)
+		<-release
+		json.NewEncoder(w).Encode(auth.JWKSet{Keys: []auth.JWK{auth.NewRSAJWK(kid, &key.PublicKey)}})
+	}))
+	defer server.Close()
+
+	verifier := auth.NewJWKSVerifier(server.URL)
+	token := signToken(t, key, kid, userClaims(time.Minute))
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := verifier.Verify(token)
+			assert.NoError(t, err)
+		}()
+	}
+	assert.Eventually(t, func() bool { return atomic.LoadInt32(&fetches) == 1 }, time.Second, 10*time.Millisecond)
+	close(release)
+	wg.Wait()
+
+	assert.Equal(t, int32(1), atomic.LoadInt32(&fetches), "concurrent verifications should share one fetch")
+}
+
+func TestLocalVerifierWithUnreachableJWKS(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+
+	verifier := auth.NewJWKSVerifier(server.URL)
+
+	_, err = verifier.Verify(signToken(t, key, "k1", userClaims(time.Minute)))
+	assert.Error(t, err)
+}
+
+func TestLocalVerifierVerifyManagerRole(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	verifier := auth.NewKeyVerifier(&key.PublicKey)
+
+	assert.NoError(t, verifier.VerifyManagerRole("Bearer "+signToken(t, key, "k1", userClaims(time.Minute))))
+	assert.ErrorIs(t, verifier.VerifyManagerRole(""), auth.ErrMissingToken)
+
+	// Managers who have not set up a second factor are told to enrol
+	notEnrolled := userClaims(time.Minute)
+	notEnrolled.AMR = []string{"pwd"}
+	err = verifier.VerifyManagerRole("Bearer " + signToken(t, key, "k1", notEnrolled))
+	assert.ErrorIs(t, err, auth.ErrMFANotEnrolled)
+	assert.ErrorIs(t, err, auth.ErrMFARequired)
+
+	noMFA := userClaims(time.Minute)
+	noMFA.AMR = []string{"pwd"}
+	noMFA.MFAEnrolled = true
+	err = verifier.VerifyManagerRole("Bearer " + signToken(t, key, "k1", noMFA))
+	assert.ErrorIs(t, err, auth.ErrMFARequired)
+	assert.NotErrorIs(t, err, auth.ErrMFANotEnrolled)
+
+	enrolled := userClaims(time.Minute)
+	enrolled.MFAEnrolled = true
+	assert.NoError(t, verifier.VerifyManagerRole("Bearer "+signToken(t, key, "k1", enrolled)))
+
+	customer := userClaims(time.Minute)
+	customer.Role = auth.RoleCustomer
+	customer.Permissions = nil
+	assert.EqualError(t, verifier.VerifyManagerRole("Bearer "+signToken(t, key, "k1", customer)), "only managers can perform this action")
+}
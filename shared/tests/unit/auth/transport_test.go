@@ -0,0 +1,67 @@
+package auth
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+
+	"github.com/darkhyper24/blaban/shared/auth"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestServiceTransport(t *testing.T) {
+	var tokenRequests int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/oauth/token":
+			atomic.AddInt32(&tokenRequests, 1)
+			clientID, secret, ok := r.BasicAuth()
+			if !ok || clientID != "order-service" || secret != "s3cret" {
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+			assert.Equal(t, "client_credentials", r.FormValue("grant_type"))
+			assert.Equal(t, auth.ScopeMenuRead, r.FormValue("scope"))
+			json.NewEncoder(w).Encode(map[string]interface{}{
+				"access_token": "service-token",
+				"token_type":   "bearer",
+				"expires_in":   900,
+			})
+		case "/internal/menu/item1":
+			assert.Equal(t, "Bearer service-token", r.Header.Get("Authorization"))
+			w.WriteHeader(http.StatusOK)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	client := auth.NewServiceClient(auth.ClientCredentials{
+		AuthServiceURL: server.URL,
+		ClientID:       "order-service",
+		ClientSecret:   "s3cret",
+		Scopes:         []string{auth.ScopeMenuRead},
+	})
+
+	t.Run("Token is cached across requests", func(t *testing.T) {
+		for i := 0; i < 3; i++ {
+			resp, err := client.Get(server.URL + "/internal/menu/item1")
+			assert.NoError(t, err)
+			assert.Equal(t, http.StatusOK, resp.StatusCode)
+			resp.Body.Close()
+		}
+		assert.Equal(t, int32(1), atomic.LoadInt32(&tokenRequests))
+	})
+
+	t.Run("Invalid credentials", func(t *testing.T) {
+		badClient := auth.NewServiceClient(auth.ClientCredentials{
+			AuthServiceURL: server.URL,
+			ClientID:       "order-service",
+			ClientSecret:   "wrong",
+		})
+		_, err := badClient.Get(server.URL + "/internal/menu/item1")
+		assert.Error(t, err)
+	})
+}
@@ -0,0 +1,50 @@
+package migrate
+
+import (
+	"testing"
+	"testing/fstest"
+
+	"github.com/darkhyper24/blaban/shared/migrate"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLoad(t *testing.T) {
+	t.Run("Pairs up and down files sorted by version", func(t *testing.T) {
+		fsys := fstest.MapFS{
+			"002_create_roles.up.sql":            {Data: []byte("CREATE TABLE roles ();")},
+			"002_create_roles.down.sql":          {Data: []byte("DROP TABLE roles;")},
+			"001_create_refresh_tokens.up.sql":   {Data: []byte("CREATE TABLE refresh_tokens ();")},
+			"001_create_refresh_tokens.down.sql": {Data: []byte("DROP TABLE refresh_tokens;")},
+			"migrations.go":                      {Data: []byte("package migrations")},
+		}
+
+		migrations, err := migrate.Load(fsys)
+
+		assert.NoError(t, err)
+		assert.Len(t, migrations, 2)
+		assert.Equal(t, int64(1), migrations[0].Version)
+		assert.Equal(t, "create_refresh_tokens", migrations[0].Name)
+		assert.Equal(t, "DROP TABLE refresh_tokens;", migrations[0].Down)
+		assert.Equal(t, int64(2), migrations[1].Version)
+		assert.Equal(t, "CREATE TABLE roles ();", migrations[1].Up)
+	})
+
+	t.Run("Missing up file", func(t *testing.T) {
+		fsys := fstest.MapFS{
+			"001_create_users.down.sql": {Data: []byte("DROP TABLE users;")},
+		}
+
+		_, err := migrate.Load(fsys)
+		assert.Error(t, err)
+	})
+
+	t.Run("Conflicting names for one version", func(t *testing.T) {
+		fsys := fstest.MapFS{
+			"001_create_users.up.sql": {Data: []byte("CREATE TABLE users ();")},
+			"001_create_items.up.sql": {Data: []byte("CREATE TABLE items ();")},
+		}
+
+		_, err := migrate.Load(fsys)
+		assert.Error(t, err)
+	})
+}
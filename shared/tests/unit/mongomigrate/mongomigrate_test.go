@@ -0,0 +1,45 @@
+package mongomigrate
+
+import (
+	"context"
+	"testing"
+
+	"github.com/darkhyper24/blaban/shared/mongomigrate"
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+func index(name string) mongo.IndexModel {
+	return mongo.IndexModel{Keys: bson.D{{Key: name, Value: 1}}, Options: options.Index().SetName(name)}
+}
+
+func TestNew(t *testing.T) {
+	t.Run("Rejects duplicate versions", func(t *testing.T) {
+		_, err := mongomigrate.New(nil, []mongomigrate.Migration{
+			{Version: 1, Name: "orders_user", Collection: "orders", Indexes: []mongo.IndexModel{index("user_id")}},
+			{Version: 1, Name: "orders_status", Collection: "orders", Indexes: []mongo.IndexModel{index("status")}},
+		})
+		assert.EqualError(t, err, "duplicate migration version 1")
+	})
+
+	t.Run("Rejects unnamed indexes", func(t *testing.T) {
+		_, err := mongomigrate.New(nil, []mongomigrate.Migration{
+			{Version: 1, Name: "orders_user", Collection: "orders", Indexes: []mongo.IndexModel{{Keys: bson.D{{Key: "user_id", Value: 1}}}}},
+		})
+		assert.Error(t, err)
+	})
+}
+
+func TestToRejectsUnknownVersion(t *testing.T) {
+	// The version is checked before the database is touched
+	migrator, err := mongomigrate.New(nil, []mongomigrate.Migration{
+		{Version: 1, Name: "orders_user", Collection: "orders", Indexes: []mongo.IndexModel{index("user_id")}},
+		{Version: 3, Name: "orders_status", Collection: "orders", Indexes: []mongo.IndexModel{index("status")}},
+	})
+	assert.NoError(t, err)
+
+	assert.EqualError(t, migrator.To(context.Background(), 2), "unknown migration version 2")
+	assert.EqualError(t, migrator.To(context.Background(), 4), "unknown migration version 4")
+}
@@ -0,0 +1,27 @@
+// Package userdata describes the contract between user-service and the services
+// holding personal data, used to export or erase everything about a user.
+//
+// Each participating service exposes two routes guarded by service scopes:
+//
+//	GET    <prefix>/users/:id/data  (auth.ScopeUserDataRead)   returns an Export
+//	DELETE <prefix>/users/:id/data  (auth.ScopeUserDataErase)  returns an ErasureResult
+//
+// Erasure must be idempotent so that an interrupted run can be retried.
+package userdata
+
+// ErasedUserID replaces the user id on records kept after erasure, e.g. orders
+// and payments that are retained for accounting
+const ErasedUserID = "erased-user"
+
+// Export is the personal data one service holds about a user
+type Export struct {
+	Service string      `json:"service"`
+	Data    interface{} `json:"data"`
+}
+
+// ErasureResult reports what a service did with a user's records
+type ErasureResult struct {
+	Service    string `json:"service"`
+	Deleted    int64  `json:"deleted"`
+	Anonymised int64  `json:"anonymised"`
+}
@@ -0,0 +1,148 @@
+package main
+
+import (
+	"errors"
+	"log"
+	"time"
+
+	"github.com/darkhyper24/blaban/shared/auth"
+	"github.com/darkhyper24/blaban/user-service/internal/addresses"
+	"github.com/gofiber/fiber/v2"
+)
+
+func addressResponse(a *addresses.Address) fiber.Map {
+	address := fiber.Map{
+		"id":           a.ID,
+		"label":        a.Label,
+		"recipient":    a.Recipient,
+		"phone":        a.Phone,
+		"line1":        a.Line1,
+		"line2":        a.Line2,
+		"city":         a.City,
+		"region":       a.Region,
+		"postal_code":  a.PostalCode,
+		"country":      a.Country,
+		"instructions": a.Instructions,
+		"is_default":   a.IsDefault,
+		"updated_at":   a.UpdatedAt.Format(time.RFC3339),
+	}
+	if a.Latitude != nil {
+		address["latitude"] = *a.Latitude
+		address["longitude"] = *a.Longitude
+	}
+	return address
+}
+
+func addressList(list []addresses.Address) []fiber.Map {
+	result := make([]fiber.Map, 0, len(list))
+	for i := range list {
+		result = append(result, addressResponse(&list[i]))
+	}
+	return result
+}
+
+func addressError(c *fiber.Ctx, err error) error {
+	switch {
+	case errors.Is(err, addresses.ErrAddressNotFound):
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	case errors.Is(err, addresses.ErrTooManyAddresses):
+		return c.Status(fiber.StatusConflict).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	log.Printf("Address operation failed: %v", err)
+	return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+		"error": "Failed to update address book",
+	})
+}
+
+// parseAddressInput reads and validates an address from the request body
+func parseAddressInput(c *fiber.Ctx) (addresses.AddressInput, error) {
+	var in addresses.AddressInput
+	if err := c.BodyParser(&in); err != nil {
+		return in, errors.New("Invalid request body")
+	}
+	return in, in.Validate()
+}
+
+// handleListAddresses returns the caller's address book, default address first
+func handleListAddresses(c *fiber.Ctx) error {
+	list, err := addressService.List(auth.PrincipalFrom(c).UserID)
+	if err != nil {
+		return addressError(c, err)
+	}
+
+	return c.JSON(fiber.Map{
+		"addresses": addressList(list),
+	})
+}
+
+func handleGetAddress(c *fiber.Ctx) error {
+	address, err := addressService.Get(auth.PrincipalFrom(c).UserID, c.Params("id"))
+	if err != nil {
+		return addressError(c, err)
+	}
+
+	return c.JSON(fiber.Map{
+		"address": addressResponse(address),
+	})
+}
+
+func handleCreateAddress(c *fiber.Ctx) error {
+	in, err := parseAddressInput(c)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	address, err := addressService.Create(auth.PrincipalFrom(c).UserID, in)
+	if err != nil {
+		return addressError(c, err)
+	}
+
+	return c.Status(fiber.StatusCreated).JSON(fiber.Map{
+		"address": addressResponse(address),
+	})
+}
+
+func handleUpdateAddress(c *fiber.Ctx) error {
+	in, err := parseAddressInput(c)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	address, err := addressService.Update(auth.PrincipalFrom(c).UserID, c.Params("id"), in)
+	if err != nil {
+		return addressError(c, err)
+	}
+
+	return c.JSON(fiber.Map{
+		"address": addressResponse(address),
+	})
+}
+
+func handleDeleteAddress(c *fiber.Ctx) error {
+	if err := addressService.Delete(auth.PrincipalFrom(c).UserID, c.Params("id")); err != nil {
+		return addressError(c, err)
+	}
+
+	return c.SendStatus(fiber.StatusNoContent)
+}
+
+// handleGetUserAddress lets order-service copy an address onto a new order
+func handleGetUserAddress(c *fiber.Ctx) error {
+	address, err := addressService.Get(c.Params("id"), c.Params("addressId"))
+	if err != nil {
+		return addressError(c, err)
+	}
+
+	return c.JSON(fiber.Map{
+		"address": addressResponse(address),
+	})
+}
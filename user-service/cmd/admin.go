@@ -0,0 +1,364 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/darkhyper24/blaban/shared/auth"
+	"github.com/darkhyper24/blaban/user-service/internal/mailer"
+	"github.com/darkhyper24/blaban/user-service/internal/users"
+	"github.com/gofiber/fiber/v2"
+)
+
+// cliActor is recorded as the actor of changes made through the command line
+const cliActor = "cli"
+
+func adminUserResponse(user *users.User) fiber.Map {
+	return fiber.Map{
+		"id":             user.ID,
+		"name":           user.Name,
+		"email":          user.Email,
+		"role":           user.Role,
+		"email_verified": user.EmailVerified,
+		"disabled":       user.Disabled,
+		"created_at":     user.CreatedAt.Format(time.RFC3339),
+	}
+}
+
+func adminError(c *fiber.Ctx, err error) error {
+	switch {
+	case errors.Is(err, users.ErrUserNotFound):
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	case errors.Is(err, users.ErrCannotModifySelf):
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	case errors.Is(err, users.ErrLastAdmin):
+		return c.Status(fiber.StatusConflict).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	case errors.Is(err, users.ErrEmailTaken):
+		return c.Status(fiber.StatusConflict).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	case errors.Is(err, users.ErrInvalidEmail):
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	log.Printf("Admin operation failed: %v", err)
+	return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+		"error": "Failed to update user",
+	})
+}
+
+// parseUserFilter reads the list filters from the query string. Dates may be
+// given as RFC 3339 timestamps or as plain YYYY-MM-DD days.
+func parseUserFilter(c *fiber.Ctx) (users.UserFilter, error) {
+	filter := users.UserFilter{
+		Role:   c.Query("role"),
+		Search: c.Query("q"),
+	}
+	if filter.Role != "" && !auth.IsValidRole(filter.Role) {
+		return filter, fmt.Errorf("role must be one of: %s", strings.Join(auth.Roles, ", "))
+	}
+
+	parseTime := func(name string) (time.Time, error) {
+		value := c.Query(name)
+		if value == "" {
+			return time.Time{}, nil
+		}
+		if t, err := time.Parse(time.RFC3339, value); err == nil {
+			return t.UTC(), nil
+		}
+		if t, err := time.Parse(time.DateOnly, value); err == nil {
+			return t, nil
+		}
+		return time.Time{}, fmt.Errorf("%s must be a date (YYYY-MM-DD) or an RFC 3339 timestamp", name)
+	}
+	parseBool := func(name string) (*bool, error) {
+		value := c.Query(name)
+		if value == "" {
+			return nil, nil
+		}
+		b, err := strconv.ParseBool(value)
+		if err != nil {
+			return nil, fmt.Errorf("%s must be true or false", name)
+		}
+		return &b, nil
+	}
+
+	var err error
+	if filter.CreatedAfter, err = parseTime("created_after"); err != nil {
+		return filter, err
+	}
+	if filter.CreatedBefore, err = parseTime("created_before"); err != nil {
+		return filter, err
+	}
+	if filter.EmailVerified, err = parseBool("verified"); err != nil {
+		return filter, err
+	}
+	if filter.Disabled, err = parseBool("disabled"); err != nil {
+		return filter, err
+	}
+	return filter, nil
+}
+
+// handleListUsers pages through accounts, newest first. Pass next_cursor back as
+// cursor to fetch the following page.
+func handleListUsers(c *fiber.Ctx) error {
+	filter, err := parseUserFilter(c)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	list, next, err := userService.ListUsers(filter, c.Query("cursor"), c.QueryInt("limit", users.DefaultPageSize))
+	if err != nil {
+		if errors.Is(err, users.ErrInvalidCursor) {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": err.Error(),
+			})
+		}
+		log.Printf("Failed to list users: %v", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to list users",
+		})
+	}
+
+	result := make([]fiber.Map, 0, len(list))
+	for i := range list {
+		result = append(result, adminUserResponse(&list[i]))
+	}
+
+	return c.JSON(fiber.Map{
+		"users":       result,
+		"next_cursor": next,
+	})
+}
+
+// handleGetUserDetail shows an account together with its linked identities and
+// open sessions as reported by auth-service
+func handleGetUserDetail(c *fiber.Ctx) error {
+	user, err := userService.GetUserByID(c.Params("id"))
+	if err != nil {
+		if errors.Is(err, users.ErrUserNotFound) {
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+				"error": err.Error(),
+			})
+		}
+		log.Printf("Failed to fetch user: %v", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to fetch user",
+		})
+	}
+
+	detail := adminUserResponse(user)
+	detail["bio"] = user.Bio
+
+	// The account is still shown when auth-service cannot be reached
+	activity, err := fetchAccountActivity(user.ID)
+	if err != nil {
+		log.Printf("Failed to fetch sessions of user %s: %v", user.ID, err)
+		detail["identities"] = nil
+		detail["active_sessions"] = nil
+	} else {
+		detail["identities"] = activity.Identities
+		detail["active_sessions"] = activity.ActiveSessions
+	}
+
+	return c.JSON(fiber.Map{
+		"user": detail,
+	})
+}
+
+// handleInviteStaff creates a staff account and emails the invitee a link to set their password
+func handleInviteStaff(c *fiber.Ctx) error {
+	var req struct {
+		Name  string `json:"name"`
+		Email string `json:"email"`
+		Role  string `json:"role"`
+	}
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid request body",
+		})
+	}
+
+	req.Name = strings.TrimSpace(req.Name)
+	if req.Name == "" || req.Email == "" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Name and email are required",
+		})
+	}
+	if !slices.Contains(auth.StaffRoles, req.Role) {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "role must be one of: " + strings.Join(auth.StaffRoles, ", "),
+		})
+	}
+
+	user, token, err := userService.InviteUser(auth.PrincipalFrom(c).UserID, req.Name, req.Email, req.Role)
+	if err != nil {
+		return adminError(c, err)
+	}
+
+	sendMail(mailer.Message{
+		To:      user.Email,
+		Subject: "You're invited to Blaban",
+		Body: "Hi " + user.Name + ",\n\n" +
+			"You have been invited to join Blaban as " + user.Role + ". Choose a password to activate your account:\n\n" +
+			appLink("/reset-password", token) + "\n\n" +
+			"The link expires in 7 days.",
+	})
+
+	return c.Status(fiber.StatusCreated).JSON(fiber.Map{
+		"user": adminUserResponse(user),
+	})
+}
+
+// handleChangeRole promotes or demotes a user. The new role applies from the user's next token refresh.
+func handleChangeRole(c *fiber.Ctx) error {
+	var req struct {
+		Role string `json:"role"`
+	}
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid request body",
+		})
+	}
+	if !auth.IsValidRole(req.Role) {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "role must be one of: " + strings.Join(auth.Roles, ", "),
+		})
+	}
+
+	user, err := userService.ChangeRole(auth.PrincipalFrom(c).UserID, c.Params("id"), req.Role)
+	if err != nil {
+		return adminError(c, err)
+	}
+
+	return c.JSON(fiber.Map{
+		"user": adminUserResponse(user),
+	})
+}
+
+// handleDisableUser disables an account and ends all of its sessions
+func handleDisableUser(c *fiber.Ctx) error {
+	user, err := userService.SetDisabled(auth.PrincipalFrom(c).UserID, c.Params("id"), true)
+	if err != nil {
+		return adminError(c, err)
+	}
+
+	if err := revokeSessions(user.ID); err != nil {
+		log.Printf("Failed to revoke sessions of disabled user %s: %v", user.ID, err)
+		return c.Status(fiber.StatusBadGateway).JSON(fiber.Map{
+			"error": "Account was disabled but existing sessions could not be ended",
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"user": adminUserResponse(user),
+	})
+}
+
+// handleEnableUser re-enables a disabled account
+func handleEnableUser(c *fiber.Ctx) error {
+	user, err := userService.SetDisabled(auth.PrincipalFrom(c).UserID, c.Params("id"), false)
+	if err != nil {
+		return adminError(c, err)
+	}
+
+	return c.JSON(fiber.Map{
+		"user": adminUserResponse(user),
+	})
+}
+
+// handleGetAuditLog lists who changed a user's role or status and when
+func handleGetAuditLog(c *fiber.Ctx) error {
+	entries, err := userService.GetAuditLog(c.Params("id"))
+	if err != nil {
+		log.Printf("Failed to fetch audit log: %v", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to fetch audit log",
+		})
+	}
+
+	audit := make([]fiber.Map, 0, len(entries))
+	for _, e := range entries {
+		audit = append(audit, fiber.Map{
+			"id":         e.ID,
+			"actor_id":   e.ActorID,
+			"action":     e.Action,
+			"old_value":  e.OldValue,
+			"new_value":  e.NewValue,
+			"created_at": e.CreatedAt.Format(time.RFC3339),
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"audit": audit,
+	})
+}
+
+// runSetRole handles "user-service set-role <email> <role>", used to appoint the first admin
+func runSetRole(args []string) error {
+	if len(args) != 2 {
+		return errors.New("usage: user-service set-role <email> <role>")
+	}
+	if !auth.IsValidRole(args[1]) {
+		return fmt.Errorf("unknown role %q, must be one of: %s", args[1], strings.Join(auth.Roles, ", "))
+	}
+
+	user, err := userService.GetUserByEmail(args[0])
+	if err != nil {
+		return err
+	}
+	if _, err := userService.ChangeRole(cliActor, user.ID, args[1]); err != nil {
+		return err
+	}
+
+	fmt.Printf("%s is now %s\n", user.Email, args[1])
+	return nil
+}
+
+// runSignupManagers handles "user-service signup-managers [--demote]". It lists
+// managers who registered themselves before signup was restricted to
+// customers, and with --demote makes them customers.
+func runSignupManagers(args []string) error {
+	demote := len(args) == 1 && args[0] == "--demote"
+	if len(args) > 1 || (len(args) == 1 && !demote) {
+		return errors.New("usage: user-service signup-managers [--demote]")
+	}
+
+	managers, err := userService.SelfRegisteredManagers()
+	if err != nil {
+		return err
+	}
+	for _, m := range managers {
+		if demote {
+			if _, err := userService.ChangeRole(cliActor, m.ID, auth.RoleCustomer); err != nil {
+				return fmt.Errorf("failed to demote %s: %w", m.Email, err)
+			}
+		}
+		fmt.Printf("%s\t%s\t%s\n", m.Email, m.Name, m.CreatedAt.Format(time.RFC3339))
+	}
+
+	switch {
+	case len(managers) == 0:
+		fmt.Println("No self-registered managers found")
+	case demote:
+		fmt.Printf("Demoted %d managers to %s\n", len(managers), auth.RoleCustomer)
+	default:
+		fmt.Println("Run with --demote to make them customers; restore a legitimate manager with set-role")
+	}
+	return nil
+}
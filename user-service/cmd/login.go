@@ -0,0 +1,162 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"log"
+	"strconv"
+	"time"
+
+	"github.com/darkhyper24/blaban/user-service/internal/loginguard"
+	"github.com/darkhyper24/blaban/user-service/internal/mailer"
+	"github.com/darkhyper24/blaban/user-service/internal/security"
+	"github.com/darkhyper24/blaban/user-service/internal/users"
+	"github.com/gofiber/fiber/v2"
+)
+
+// handleLogin checks a password login, throttling repeated failures per
+// account and per client IP
+func handleLogin(c *fiber.Ctx) error {
+	var req struct {
+		Email    string `json:"email"`
+		Password string `json:"password"`
+	}
+
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid request body",
+		})
+	}
+
+	ctx, cancel := context.WithTimeout(c.Context(), 2*time.Second)
+	defer cancel()
+
+	email := users.NormalizeEmail(req.Email)
+	ip := c.IP()
+
+	// Throttling fails open so a Redis outage does not lock everyone out
+	if err := loginGuard.Check(ctx, email, ip); err != nil {
+		var blocked *loginguard.BlockedError
+		if errors.As(err, &blocked) {
+			security.Log(security.Event{Type: security.EventLoginThrottled, Email: email, IP: ip, Detail: blocked.Reason})
+			c.Set(fiber.HeaderRetryAfter, strconv.Itoa(int(blocked.RetryAfter.Round(time.Second).Seconds())))
+			return c.Status(fiber.StatusTooManyRequests).JSON(fiber.Map{
+				"error": blocked.Error(),
+			})
+		}
+		log.Printf("Login throttling unavailable: %v", err)
+	}
+
+	user, err := userService.LoginUser(email, req.Password)
+	if errors.Is(err, users.ErrAccountDisabled) {
+		security.Log(security.Event{Type: security.EventLoginFailed, Email: email, IP: ip, Detail: "account_disabled"})
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+	if err != nil {
+		recordLoginFailure(ctx, email, ip)
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	if err := loginGuard.RecordSuccess(ctx, email); err != nil {
+		log.Printf("Failed to reset login failures: %v", err)
+	}
+	security.Log(security.Event{Type: security.EventLoginSucceeded, UserID: user.ID, Email: email, IP: ip})
+
+	// Users with MFA enabled must complete a second step before getting tokens
+	mfaEnabled, err := mfaService.IsEnabled(user.ID)
+	if err != nil {
+		log.Printf("Failed to check MFA status: %v", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to log in",
+		})
+	}
+	if mfaEnabled {
+		challenge, err := mfaService.CreateChallenge(user.ID)
+		if err != nil {
+			log.Printf("Failed to create MFA challenge: %v", err)
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+				"error": "Failed to log in",
+			})
+		}
+		return c.Status(fiber.StatusOK).JSON(fiber.Map{
+			"mfa_required": true,
+			"mfa_token":    challenge,
+			"methods":      []string{"totp", "recovery_code"},
+		})
+	}
+
+	return respondWithTokens(c, user, []string{amrPassword})
+}
+
+// recordLoginFailure counts a failed login and emails an unlock link when it
+// locks an existing account
+func recordLoginFailure(ctx context.Context, email, ip string) {
+	failure, err := loginGuard.RecordFailure(ctx, email, ip)
+	if err != nil {
+		log.Printf("Failed to record login failure: %v", err)
+		security.Log(security.Event{Type: security.EventLoginFailed, Email: email, IP: ip})
+		return
+	}
+
+	security.Log(security.Event{
+		Type:   security.EventLoginFailed,
+		Email:  email,
+		IP:     ip,
+		Detail: "account_failures=" + strconv.FormatInt(failure.AccountFailures, 10) + " ip_failures=" + strconv.FormatInt(failure.IPFailures, 10),
+	})
+	if !failure.Locked {
+		return
+	}
+
+	user, err := userService.GetUserByEmail(email)
+	if err != nil {
+		// Unknown emails are locked too, but there is nobody to notify
+		security.Log(security.Event{Type: security.EventAccountLocked, Email: email, IP: ip, Detail: "unknown_account"})
+		return
+	}
+
+	security.Log(security.Event{Type: security.EventAccountLocked, UserID: user.ID, Email: email, IP: ip})
+	sendMail(mailer.Message{
+		To:      user.Email,
+		Subject: "Your Blaban account was locked",
+		Body: "Hi " + user.Name + ",\n\n" +
+			"We locked your account after several failed sign-in attempts. It unlocks automatically in a few minutes, " +
+			"or you can unlock it now with the link below:\n\n" +
+			appLink("/unlock-account", failure.UnlockToken) + "\n\n" +
+			"If these attempts were not you, consider resetting your password.",
+	})
+}
+
+// handleUnlockAccount lifts a lockout from the link in the lockout email
+func handleUnlockAccount(c *fiber.Ctx) error {
+	var req struct {
+		Token string `json:"token"`
+	}
+	if err := c.BodyParser(&req); err != nil || req.Token == "" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "token is required",
+		})
+	}
+
+	email, err := loginGuard.Unlock(c.Context(), req.Token)
+	if err != nil {
+		if errors.Is(err, loginguard.ErrInvalidUnlockToken) {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": err.Error(),
+			})
+		}
+		log.Printf("Failed to unlock account: %v", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to unlock account",
+		})
+	}
+
+	security.Log(security.Event{Type: security.EventAccountUnlocked, Email: email, IP: c.IP()})
+	return c.JSON(fiber.Map{
+		"message": "Account unlocked",
+	})
+}
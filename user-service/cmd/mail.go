@@ -0,0 +1,23 @@
+package main
+
+import (
+	"log"
+	"net/url"
+
+	"github.com/darkhyper24/blaban/user-service/internal/mailer"
+)
+
+// sendMail delivers msg in the background so response times do not reveal
+// whether an account exists
+func sendMail(msg mailer.Message) {
+	go func() {
+		if err := mailService.Send(msg); err != nil {
+			log.Printf("Failed to send email %q: %v", msg.Subject, err)
+		}
+	}()
+}
+
+// appLink builds a link to a frontend page carrying a one-time token
+func appLink(path, token string) string {
+	return appBaseURL + path + "?token=" + url.QueryEscape(token)
+}
@@ -1,21 +1,45 @@
 package main
 
 import (
+	"context"
 	"database/sql"
-	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
 	"net/http"
 	"os"
 	"strings"
+	"time"
 
+	"github.com/darkhyper24/blaban/shared/auth"
+	"github.com/darkhyper24/blaban/user-service/internal/addresses"
+	"github.com/darkhyper24/blaban/user-service/internal/loginguard"
+	"github.com/darkhyper24/blaban/user-service/internal/mailer"
+	"github.com/darkhyper24/blaban/user-service/internal/mfa"
+	"github.com/darkhyper24/blaban/user-service/internal/passwords"
+	"github.com/darkhyper24/blaban/user-service/internal/privacy"
 	"github.com/darkhyper24/blaban/user-service/internal/users"
+	"github.com/darkhyper24/blaban/user-service/internal/verification"
+	"github.com/go-redis/redis/v8"
 	"github.com/gofiber/fiber/v2"
 	"github.com/gofiber/fiber/v2/middleware/cors"
 	"github.com/gofiber/fiber/v2/middleware/logger"
 	_ "github.com/lib/pq"
 )
 
+var (
+	userService        *users.UserService
+	mfaService         *mfa.MFAService
+	addressService     *addresses.AddressService
+	mailService        mailer.Mailer
+	loginGuard         *loginguard.Guard
+	verificationSigner *verification.Signer
+	privacyCoordinator *privacy.Coordinator
+	appBaseURL         string
+	authClient         *http.Client
+	creds              auth.ClientCredentials
+)
+
 func main() {
 
 	dsn := os.Getenv("USER_DB_DSN")
@@ -29,8 +53,86 @@ func main() {
 	}
 	defer db.Close()
 
-	userService := users.NewUserService(db)
-	app := fiber.New()
+	if len(os.Args) > 1 && os.Args[1] == "migrate" {
+		if err := runMigrations(db, os.Args[2:]); err != nil {
+			log.Fatal("migration failed: ", err)
+		}
+		return
+	}
+
+	// Apply pending migrations; replicas starting together queue on the migration lock
+	if err := runMigrations(db, []string{"up"}); err != nil {
+		log.Fatal("migration failed: ", err)
+	}
+
+	passwordPolicy, err := passwords.PolicyFromEnv()
+	if err != nil {
+		log.Fatal("invalid password policy:", err)
+	}
+	userService = users.NewUserService(db, passwordPolicy)
+
+	if len(os.Args) > 1 && os.Args[1] == "set-role" {
+		if err := runSetRole(os.Args[2:]); err != nil {
+			log.Fatal("set-role failed: ", err)
+		}
+		return
+	}
+	if len(os.Args) > 1 && os.Args[1] == "signup-managers" {
+		if err := runSignupManagers(os.Args[2:]); err != nil {
+			log.Fatal("signup-managers failed: ", err)
+		}
+		return
+	}
+	mfaService = mfa.NewMFAService(db)
+	addressService = addresses.NewAddressService(db)
+
+	redisHost := os.Getenv("REDIS_HOST")
+	if redisHost == "" {
+		redisHost = "localhost"
+	}
+	redisPort := os.Getenv("REDIS_PORT")
+	if redisPort == "" {
+		redisPort = "6379"
+	}
+	redisClient := redis.NewClient(&redis.Options{
+		Addr: redisHost + ":" + redisPort,
+	})
+	if err := redisClient.Ping(context.Background()).Err(); err != nil {
+		log.Printf("Warning: Redis connection failed, login throttling is disabled until it recovers: %v", err)
+	}
+	loginGuard = loginguard.NewGuard(redisClient, loginguard.DefaultConfig())
+
+	mailService, err = mailer.FromEnv()
+	if err != nil {
+		log.Fatal("failed to configure mailer:", err)
+	}
+	verificationSigner = verification.NewSigner(verificationSecret(os.Getenv("EMAIL_VERIFICATION_SECRET")), 48*time.Hour)
+	appBaseURL = strings.TrimRight(os.Getenv("APP_BASE_URL"), "/")
+	if appBaseURL == "" {
+		appBaseURL = "http://localhost:5173"
+	}
+
+	// Calls to auth-service authenticate with this service's client credentials
+	creds = auth.ClientCredentialsFromEnv(
+		auth.ScopeTokensIssue, auth.ScopeTokensRevoke, auth.ScopeSessionsRead,
+		auth.ScopeUserDataRead, auth.ScopeUserDataErase,
+	)
+	authClient = auth.NewServiceClient(creds)
+	privacyCoordinator = privacy.NewCoordinator(db, authClient, userService.DeleteAccount, dataParticipants()...)
+	verifier, err := auth.VerifierFromEnv()
+	if err != nil {
+		log.Fatal("failed to configure token verification:", err)
+	}
+
+	// Behind the gateway the client address arrives in a header such as
+	// X-Real-IP. It is only believed from the comma-separated TRUSTED_PROXIES;
+	// other callers are identified by their own address.
+	app := fiber.New(fiber.Config{
+		ProxyHeader:             os.Getenv("CLIENT_IP_HEADER"),
+		EnableTrustedProxyCheck: true,
+		TrustedProxies:          strings.Fields(strings.ReplaceAll(os.Getenv("TRUSTED_PROXIES"), ",", " ")),
+		EnableIPValidation:      true,
+	})
 
 	app.Use(cors.New())
 	app.Use(logger.New())
@@ -57,41 +159,50 @@ func main() {
 			})
 		}
 
-		// Set default role if not provided
-		if req.Role == "" {
-			req.Role = "user"
-		}
-
-		// Validate role is either "user" or "manager"
-		if req.Role != "user" && req.Role != "manager" {
-			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-				"error": "Invalid role. Must be either 'user' or 'manager'",
+		// Signup always creates customers; staff roles are granted by an admin
+		if req.Role != "" && req.Role != auth.RoleCustomer {
+			return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
+				"error": "Staff roles are assigned by an administrator",
 			})
 		}
 
-		user, err := userService.RegisterUser(req.Name, req.Email, req.Password, req.Role, req.Bio)
+		user, err := userService.RegisterUser(req.Name, req.Email, req.Password, auth.RoleCustomer, req.Bio)
 		if err != nil {
+			var policyErr *passwords.PolicyError
+			if errors.As(err, &policyErr) || errors.Is(err, users.ErrInvalidEmail) {
+				return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+					"error": err.Error(),
+				})
+			}
 			return c.Status(fiber.StatusConflict).JSON(fiber.Map{
 				"error": err.Error(),
 			})
 		}
 
+		// The account can log in right away but cannot order or review until verified
+		if err := sendVerificationEmail(user.ID); err != nil {
+			log.Printf("Failed to send verification email: %v", err)
+		}
+
 		return c.Status(fiber.StatusOK).JSON(fiber.Map{
 			"status": "registered",
 			"user": fiber.Map{
-				"id":    user.ID,
-				"name":  user.Name,
-				"email": user.Email,
-				"role":  user.Role,
-				"bio":   user.Bio,
+				"id":             user.ID,
+				"name":           user.Name,
+				"email":          user.Email,
+				"role":           user.Role,
+				"bio":            user.Bio,
+				"email_verified": user.EmailVerified,
 			},
 		})
 	})
 
-	app.Post("/api/users/login", func(c *fiber.Ctx) error {
+	app.Post("/api/users/login", handleLogin)
+	app.Post("/api/users/login/unlock", handleUnlockAccount)
+
+	app.Post("/api/users/logout", func(c *fiber.Ctx) error {
 		var req struct {
-			Email    string `json:"email"`
-			Password string `json:"password"`
+			RefreshToken string `json:"refresh_token"`
 		}
 
 		if err := c.BodyParser(&req); err != nil {
@@ -100,98 +211,114 @@ func main() {
 			})
 		}
 
-		user, err := userService.LoginUser(req.Email, req.Password)
-		if err != nil {
-			return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
-				"error": err.Error(),
-			})
-		}
-
-		// Call auth service to generate tokens
+		// Call auth service to revoke token
 		authResp, err := http.Post(
-			"http://auth-service:8082/api/auth/tokens",
+			creds.AuthServiceURL+"/api/auth/logout",
 			"application/json",
-			strings.NewReader(fmt.Sprintf(`{"user_id":"%s","role":"%s"}`, user.ID, user.Role)),
+			strings.NewReader(fmt.Sprintf(`{"refresh_token":"%s"}`, req.RefreshToken)),
 		)
 		if err != nil {
-			log.Printf("Failed to get auth tokens: %v", err)
 			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
 				"error": "Authentication service unavailable",
 			})
 		}
 		defer authResp.Body.Close()
 
-		var tokenResp map[string]interface{}
-		if err := json.NewDecoder(authResp.Body).Decode(&tokenResp); err != nil {
-			log.Printf("Failed to decode auth response: %v", err)
-			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-				"error": "Invalid authentication response",
+		if authResp.StatusCode != http.StatusOK {
+			return c.Status(authResp.StatusCode).JSON(fiber.Map{
+				"error": "Failed to logout",
 			})
 		}
 
-		return c.Status(fiber.StatusOK).JSON(fiber.Map{
-			"user": fiber.Map{
-				"id":    user.ID,
-				"name":  user.Name,
-				"email": user.Email,
-				"role":  user.Role,
-				"bio":   user.Bio,
-			},
-			"tokens": tokenResp,
+		return c.JSON(fiber.Map{
+			"message": "Successfully logged out",
 		})
 	})
 
-	app.Post("/api/users/logout", func(c *fiber.Ctx) error {
-		var req struct {
-			RefreshToken string `json:"refresh_token"`
-		}
+	app.Post("/api/users/login/mfa", handleLoginMFA)
+	app.Post("/api/users/password/reset", handleRequestPasswordReset)
+	app.Post("/api/users/password/reset/confirm", handleConfirmPasswordReset)
+	app.Post("/api/users/password/change", auth.Authenticate(verifier), handleChangePassword)
 
-		if err := c.BodyParser(&req); err != nil {
-			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-				"error": "Invalid request body",
-			})
-		}
+	mfaRoutes := app.Group("/api/users/mfa", auth.Authenticate(verifier))
+	mfaRoutes.Post("/enroll", handleMFAEnroll)
+	mfaRoutes.Post("/confirm", handleMFAConfirm)
+	mfaRoutes.Post("/recovery-codes", handleMFARegenerateRecoveryCodes)
+	mfaRoutes.Delete("/", handleMFADisable)
 
-		// Call auth service to revoke token
-		authResp, err := http.Post(
-			"http://localhost:8082/api/auth/logout",
-			"application/json",
-			strings.NewReader(fmt.Sprintf(`{"refresh_token":"%s"}`, req.RefreshToken)),
-		)
+	app.Get("/api/users/profile", auth.Authenticate(verifier), handleGetProfile)
+	app.Put("/api/users/profile", auth.Authenticate(verifier), handleUpdateProfile)
+	app.Post("/api/users/email/confirm", handleConfirmEmailChange)
+	app.Post("/api/users/email/verify", handleVerifyEmail)
+	app.Post("/api/users/email/verify/resend", auth.Authenticate(verifier), handleResendVerification)
+	app.Post("/api/users/me/export", auth.Authenticate(verifier), handleExportData)
+
+	addressRoutes := app.Group("/api/users/addresses", auth.Authenticate(verifier))
+	addressRoutes.Get("/", handleListAddresses)
+	addressRoutes.Post("/", handleCreateAddress)
+	addressRoutes.Get("/:id", handleGetAddress)
+	addressRoutes.Put("/:id", handleUpdateAddress)
+	addressRoutes.Delete("/:id", handleDeleteAddress)
+	app.Delete("/api/users/me", auth.Authenticate(verifier), handleDeleteAccount)
+
+	// Account lookup is open to managers, staff management is restricted to
+	// admins who signed in with a second factor
+	admin := app.Group("/api/users/admin", auth.Authenticate(verifier))
+	canView := auth.RequirePermission(auth.PermUsersView)
+	canManage := auth.RequirePermission(auth.PermUsersManage)
+	needMFA := auth.RequireMFA()
+	admin.Get("/users", canView, handleListUsers)
+	admin.Get("/users/:id", canView, handleGetUserDetail)
+	admin.Post("/invite", canManage, needMFA, handleInviteStaff)
+	admin.Put("/users/:id/role", canManage, needMFA, handleChangeRole)
+	admin.Post("/users/:id/disable", canManage, needMFA, handleDisableUser)
+	admin.Post("/users/:id/enable", canManage, needMFA, handleEnableUser)
+	admin.Get("/users/:id/audit", canManage, needMFA, handleGetAuditLog)
+	admin.Post("/users/:id/erase", canManage, needMFA, handleEraseUser)
+	admin.Get("/users/:id/erasure", canManage, needMFA, handleGetErasure)
+
+	// Internal routes used by other services
+	internal := app.Group("/internal", auth.Authenticate(verifier))
+	internal.Get("/users/:id/addresses/:addressId", auth.RequireScope(auth.ScopeAddressesRead), handleGetUserAddress)
+	internal.Get("/users/:id", auth.RequireScope(auth.ScopeUsersRead), func(c *fiber.Ctx) error {
+		user, err := userService.GetUserByID(c.Params("id"))
 		if err != nil {
+			if err == users.ErrUserNotFound {
+				return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+					"error": err.Error(),
+				})
+			}
+			log.Printf("Failed to fetch user: %v", err)
 			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-				"error": "Authentication service unavailable",
+				"error": "Failed to fetch user",
 			})
 		}
-		defer authResp.Body.Close()
 
-		if authResp.StatusCode != http.StatusOK {
-			return c.Status(authResp.StatusCode).JSON(fiber.Map{
-				"error": "Failed to logout",
+		mfaEnabled, err := mfaService.IsEnabled(user.ID)
+		if err != nil {
+			log.Printf("Failed to check MFA status: %v", err)
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+				"error": "Failed to fetch user",
 			})
 		}
 
 		return c.JSON(fiber.Map{
-			"message": "Successfully logged out",
+			"user": fiber.Map{
+				"id":             user.ID,
+				"name":           user.Name,
+				"email":          user.Email,
+				"role":           user.Role,
+				"bio":            user.Bio,
+				"email_verified": user.EmailVerified,
+				"disabled":       user.Disabled,
+				"mfa_enabled":    mfaEnabled,
+			},
 		})
 	})
 
-	app.Get("/api/users/profile", handleGetProfile)
-	app.Put("/api/users/profile", handleUpdateProfile)
-
 	app.Get("/health", func(c *fiber.Ctx) error {
 		return c.JSON(fiber.Map{"status": "ok"})
 	})
 
 	log.Fatal(app.Listen(":8081"))
 }
-
-func handleGetProfile(c *fiber.Ctx) error {
-	// TODO
-	return c.SendString("Get user profile")
-}
-
-func handleUpdateProfile(c *fiber.Ctx) error {
-	// TODO
-	return c.SendString("Update user profile")
-}
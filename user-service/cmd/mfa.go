@@ -0,0 +1,196 @@
+package main
+
+import (
+	"log"
+
+	"github.com/darkhyper24/blaban/shared/auth"
+	"github.com/darkhyper24/blaban/user-service/internal/mfa"
+	"github.com/darkhyper24/blaban/user-service/internal/users"
+	"github.com/gofiber/fiber/v2"
+)
+
+const mfaIssuer = "Blaban"
+
+// handleLoginMFA completes a login that was answered with mfa_required
+func handleLoginMFA(c *fiber.Ctx) error {
+	var req struct {
+		MFAToken     string `json:"mfa_token"`
+		Code         string `json:"code"`
+		RecoveryCode string `json:"recovery_code"`
+	}
+
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid request body",
+		})
+	}
+
+	if req.MFAToken == "" || (req.Code == "" && req.RecoveryCode == "") {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "mfa_token and either code or recovery_code are required",
+		})
+	}
+
+	userID, err := mfaService.ResolveChallenge(req.MFAToken)
+	if err != nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	if req.Code != "" {
+		err = mfaService.VerifyCode(userID, req.Code)
+	} else {
+		err = mfaService.UseRecoveryCode(userID, req.RecoveryCode)
+	}
+	if err != nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+			"error": mfa.ErrInvalidCode.Error(),
+		})
+	}
+
+	if err := mfaService.DeleteChallenge(req.MFAToken); err != nil {
+		log.Printf("Failed to delete MFA challenge: %v", err)
+	}
+
+	user, err := userService.GetUserByID(userID)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to log in",
+		})
+	}
+	if user.Disabled {
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
+			"error": users.ErrAccountDisabled.Error(),
+		})
+	}
+
+	return respondWithTokens(c, user, []string{amrPassword, amrOTP, amrMFA})
+}
+
+// handleMFAEnroll generates a TOTP secret for the authenticated user
+func handleMFAEnroll(c *fiber.Ctx) error {
+	user, err := userService.GetUserByID(auth.PrincipalFrom(c).UserID)
+	if err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": "User not found",
+		})
+	}
+
+	secret, err := mfaService.StartEnrollment(user.ID)
+	if err != nil {
+		if err == mfa.ErrAlreadyEnabled {
+			return c.Status(fiber.StatusConflict).JSON(fiber.Map{
+				"error": err.Error(),
+			})
+		}
+		log.Printf("Failed to start MFA enrollment: %v", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to start enrollment",
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"secret":      secret,
+		"otpauth_uri": mfa.ProvisioningURI(mfaIssuer, user.Email, secret),
+	})
+}
+
+// handleMFAConfirm enables MFA after the user enters a code from their authenticator
+func handleMFAConfirm(c *fiber.Ctx) error {
+	var req struct {
+		Code string `json:"code"`
+	}
+
+	if err := c.BodyParser(&req); err != nil || req.Code == "" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Verification code is required",
+		})
+	}
+
+	codes, err := mfaService.ConfirmEnrollment(auth.PrincipalFrom(c).UserID, req.Code)
+	if err != nil {
+		switch err {
+		case mfa.ErrInvalidCode, mfa.ErrNotEnrolled:
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": err.Error(),
+			})
+		case mfa.ErrAlreadyEnabled:
+			return c.Status(fiber.StatusConflict).JSON(fiber.Map{
+				"error": err.Error(),
+			})
+		}
+		log.Printf("Failed to confirm MFA enrollment: %v", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to confirm enrollment",
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"enabled":        true,
+		"recovery_codes": codes,
+	})
+}
+
+// handleMFARegenerateRecoveryCodes replaces the recovery codes after a fresh TOTP check
+func handleMFARegenerateRecoveryCodes(c *fiber.Ctx) error {
+	var req struct {
+		Code string `json:"code"`
+	}
+
+	if err := c.BodyParser(&req); err != nil || req.Code == "" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Verification code is required",
+		})
+	}
+
+	userID := auth.PrincipalFrom(c).UserID
+	if err := mfaService.VerifyCode(userID, req.Code); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	codes, err := mfaService.RegenerateRecoveryCodes(userID)
+	if err != nil {
+		log.Printf("Failed to regenerate recovery codes: %v", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to regenerate recovery codes",
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"recovery_codes": codes,
+	})
+}
+
+// handleMFADisable turns MFA off after a fresh TOTP check
+func handleMFADisable(c *fiber.Ctx) error {
+	var req struct {
+		Code string `json:"code"`
+	}
+
+	if err := c.BodyParser(&req); err != nil || req.Code == "" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Verification code is required",
+		})
+	}
+
+	userID := auth.PrincipalFrom(c).UserID
+	if err := mfaService.VerifyCode(userID, req.Code); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	if err := mfaService.Disable(userID); err != nil {
+		log.Printf("Failed to disable MFA: %v", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to disable multi-factor authentication",
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"enabled": false,
+	})
+}
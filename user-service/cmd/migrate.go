@@ -0,0 +1,19 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"os"
+
+	"github.com/darkhyper24/blaban/shared/migrate"
+	"github.com/darkhyper24/blaban/user-service/migrations"
+)
+
+// runMigrations handles "user-service migrate <status|up|down [n]|to <version>>"
+func runMigrations(database *sql.DB, args []string) error {
+	migrator, err := migrate.New(database, migrations.FS, "user-service")
+	if err != nil {
+		return err
+	}
+	return migrate.Run(context.Background(), migrator, args, os.Stdout)
+}
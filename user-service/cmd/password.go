@@ -0,0 +1,182 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"log"
+	"strconv"
+	"time"
+
+	"github.com/darkhyper24/blaban/shared/auth"
+	"github.com/darkhyper24/blaban/user-service/internal/loginguard"
+	"github.com/darkhyper24/blaban/user-service/internal/mailer"
+	"github.com/darkhyper24/blaban/user-service/internal/passwords"
+	"github.com/darkhyper24/blaban/user-service/internal/security"
+	"github.com/darkhyper24/blaban/user-service/internal/users"
+	"github.com/gofiber/fiber/v2"
+)
+
+// handleRequestPasswordReset emails a reset link. The response is the same
+// whether or not the email belongs to an account.
+func handleRequestPasswordReset(c *fiber.Ctx) error {
+	var req struct {
+		Email string `json:"email"`
+	}
+	if err := c.BodyParser(&req); err != nil || req.Email == "" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "email is required",
+		})
+	}
+
+	user, token, err := userService.CreatePasswordReset(req.Email)
+	switch {
+	case err == nil:
+		sendMail(mailer.Message{
+			To:      user.Email,
+			Subject: "Reset your Blaban password",
+			Body: "Hi " + user.Name + ",\n\n" +
+				"Use the link below to choose a new password. It expires in one hour.\n\n" +
+				appLink("/reset-password", token) + "\n\n" +
+				"If you did not ask to reset your password, you can ignore this email.",
+		})
+	case !errors.Is(err, users.ErrUserNotFound):
+		log.Printf("Failed to create password reset: %v", err)
+	}
+
+	return c.Status(fiber.StatusAccepted).JSON(fiber.Map{
+		"message": "If the email is registered, a reset link has been sent",
+	})
+}
+
+// handleConfirmPasswordReset sets a new password from a reset link and ends
+// every session of the account
+func handleConfirmPasswordReset(c *fiber.Ctx) error {
+	var req struct {
+		Token    string `json:"token"`
+		Password string `json:"password"`
+	}
+	if err := c.BodyParser(&req); err != nil || req.Token == "" || req.Password == "" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "token and password are required",
+		})
+	}
+
+	userID, err := userService.ResetPassword(req.Token, req.Password)
+	if err != nil {
+		var policyErr *passwords.PolicyError
+		if errors.Is(err, users.ErrInvalidResetToken) || errors.As(err, &policyErr) {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": err.Error(),
+			})
+		}
+		log.Printf("Failed to reset password: %v", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to reset password",
+		})
+	}
+
+	if err := revokeSessions(userID); err != nil {
+		log.Printf("Failed to revoke sessions after password reset for user %s: %v", userID, err)
+		return c.Status(fiber.StatusBadGateway).JSON(fiber.Map{
+			"error": "Password was reset but existing sessions could not be ended",
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"message": "Password has been reset",
+	})
+}
+
+// handleChangePassword replaces the caller's password after checking the
+// current one. Every session is ended and the caller receives a fresh token
+// pair, so only the device that made the change stays signed in.
+func handleChangePassword(c *fiber.Ctx) error {
+	var req struct {
+		CurrentPassword string `json:"current_password"`
+		NewPassword     string `json:"new_password"`
+	}
+	if err := c.BodyParser(&req); err != nil || req.CurrentPassword == "" || req.NewPassword == "" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "current_password and new_password are required",
+		})
+	}
+
+	principal := auth.PrincipalFrom(c)
+	user, err := userService.GetUserByID(principal.UserID)
+	if err != nil {
+		if errors.Is(err, users.ErrUserNotFound) {
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+				"error": err.Error(),
+			})
+		}
+		log.Printf("Failed to fetch user: %v", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to change password",
+		})
+	}
+	if user.Disabled {
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
+			"error": users.ErrAccountDisabled.Error(),
+		})
+	}
+
+	// Wrong current passwords count as failed logins, so a stolen access token
+	// cannot be used to guess the password
+	ctx, cancel := context.WithTimeout(c.Context(), 2*time.Second)
+	defer cancel()
+	// Throttled under the same key as logins, which use the normalized email
+	email := users.NormalizeEmail(user.Email)
+	ip := c.IP()
+	if err := loginGuard.Check(ctx, email, ip); err != nil {
+		var blocked *loginguard.BlockedError
+		if errors.As(err, &blocked) {
+			c.Set(fiber.HeaderRetryAfter, strconv.Itoa(int(blocked.RetryAfter.Round(time.Second).Seconds())))
+			return c.Status(fiber.StatusTooManyRequests).JSON(fiber.Map{
+				"error": blocked.Error(),
+			})
+		}
+		log.Printf("Login throttling unavailable: %v", err)
+	}
+
+	err = userService.ChangePassword(user.ID, req.CurrentPassword, req.NewPassword)
+	var policyErr *passwords.PolicyError
+	switch {
+	case errors.Is(err, users.ErrIncorrectPassword):
+		recordLoginFailure(ctx, email, ip)
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	case errors.As(err, &policyErr):
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	case err != nil:
+		log.Printf("Failed to change password: %v", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to change password",
+		})
+	}
+
+	if err := loginGuard.RecordSuccess(ctx, email); err != nil {
+		log.Printf("Failed to reset login failures: %v", err)
+	}
+	security.Log(security.Event{Type: security.EventPasswordChanged, UserID: user.ID, Email: user.Email, IP: ip})
+
+	if err := revokeSessions(user.ID); err != nil {
+		log.Printf("Failed to revoke sessions after password change for user %s: %v", user.ID, err)
+		return c.Status(fiber.StatusBadGateway).JSON(fiber.Map{
+			"error": "Password was changed but other sessions could not be ended",
+		})
+	}
+
+	sendMail(mailer.Message{
+		To:      user.Email,
+		Subject: "Your Blaban password was changed",
+		Body: "Hi " + user.Name + ",\n\n" +
+			"The password of your account was just changed and you were signed out on your other devices.\n\n" +
+			"If this was not you, reset your password right away from the sign-in page:\n\n" +
+			appBaseURL + "/login",
+	})
+
+	return respondWithTokens(c, user, principal.AMR)
+}
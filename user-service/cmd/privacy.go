@@ -0,0 +1,225 @@
+package main
+
+import (
+	"errors"
+	"log"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/darkhyper24/blaban/shared/auth"
+	"github.com/darkhyper24/blaban/shared/userdata"
+	"github.com/darkhyper24/blaban/user-service/internal/privacy"
+	"github.com/darkhyper24/blaban/user-service/internal/security"
+	"github.com/darkhyper24/blaban/user-service/internal/users"
+	"github.com/gofiber/fiber/v2"
+)
+
+// dataParticipants lists the services holding personal data besides user-service
+func dataParticipants() []privacy.Participant {
+	serviceURL := func(env, fallback string) string {
+		if u := os.Getenv(env); u != "" {
+			return strings.TrimRight(u, "/")
+		}
+		return fallback
+	}
+
+	return []privacy.Participant{
+		{Name: "auth-service", BaseURL: creds.AuthServiceURL + "/api/auth"},
+		{Name: "order-service", BaseURL: serviceURL("ORDER_SERVICE_URL", "http://localhost:8084") + "/internal"},
+		{Name: "review-service", BaseURL: serviceURL("REVIEW_SERVICE_URL", "http://localhost:8086") + "/internal"},
+		{Name: "payment-service", BaseURL: serviceURL("PAYMENT_SERVICE_URL", "http://localhost:8085") + "/internal"},
+	}
+}
+
+// erasureResponse reports the progress of an erasure per service
+func erasureResponse(c *fiber.Ctx, userID string, steps []privacy.Step) error {
+	status := "in_progress"
+	code := fiber.StatusAccepted
+	if privacy.Complete(steps) {
+		status = "erased"
+		code = fiber.StatusOK
+	}
+
+	return c.Status(code).JSON(fiber.Map{
+		"user_id": userID,
+		"status":  status,
+		"steps":   steps,
+	})
+}
+
+// handleExportData assembles everything the services hold about the caller
+// into one JSON archive
+func handleExportData(c *fiber.Ctx) error {
+	userID := auth.PrincipalFrom(c).UserID
+
+	profile, err := userService.GetProfile(userID)
+	if err != nil {
+		if errors.Is(err, users.ErrUserNotFound) {
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+				"error": err.Error(),
+			})
+		}
+		log.Printf("Failed to export profile: %v", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to export profile",
+		})
+	}
+	mfaEnabled, err := mfaService.IsEnabled(userID)
+	if err != nil {
+		log.Printf("Failed to export MFA status: %v", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to export profile",
+		})
+	}
+	entries, err := userService.GetAuditLog(userID)
+	if err != nil {
+		log.Printf("Failed to export audit log: %v", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to export profile",
+		})
+	}
+
+	book, err := addressService.List(userID)
+	if err != nil {
+		log.Printf("Failed to export addresses: %v", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to export profile",
+		})
+	}
+
+	remote, err := privacyCoordinator.Export(c.Context(), userID)
+	if err != nil {
+		log.Printf("Failed to export user data: %v", err)
+		return c.Status(fiber.StatusBadGateway).JSON(fiber.Map{
+			"error": "Some services could not be reached, please try again later",
+		})
+	}
+
+	local := userdata.Export{
+		Service: privacy.LocalService,
+		Data: fiber.Map{
+			"profile":     profileResponse(profile)["profile"],
+			"mfa_enabled": mfaEnabled,
+			"addresses":   addressList(book),
+			"audit_log":   entries,
+		},
+	}
+
+	security.Log(security.Event{Type: security.EventDataExported, UserID: userID, IP: c.IP()})
+
+	c.Set(fiber.HeaderContentDisposition, `attachment; filename="blaban-export-`+userID+`.json"`)
+	return c.JSON(fiber.Map{
+		"user_id":      userID,
+		"generated_at": time.Now().UTC().Format(time.RFC3339),
+		"services":     append([]userdata.Export{local}, remote...),
+	})
+}
+
+// handleDeleteAccount erases the caller's account after they confirm their
+// password. Personal data is deleted, orders, payments and reviews are kept
+// without a link to the account.
+func handleDeleteAccount(c *fiber.Ctx) error {
+	var req struct {
+		Password string `json:"password"`
+	}
+	if err := c.BodyParser(&req); err != nil || req.Password == "" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Password is required to delete the account",
+		})
+	}
+
+	userID := auth.PrincipalFrom(c).UserID
+	user, err := userService.GetUserByID(userID)
+	if err != nil {
+		if errors.Is(err, users.ErrUserNotFound) {
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+				"error": err.Error(),
+			})
+		}
+		log.Printf("Failed to fetch user: %v", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to delete account",
+		})
+	}
+
+	// A disabled account may only resume an erasure it already started
+	if _, err := userService.LoginUser(user.Email, req.Password); err != nil {
+		if !errors.Is(err, users.ErrAccountDisabled) {
+			return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+				"error": "Incorrect password",
+			})
+		}
+		steps, perr := privacyCoordinator.Progress(userID)
+		if perr != nil || len(steps) == 0 {
+			return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
+				"error": err.Error(),
+			})
+		}
+	}
+
+	return eraseAccount(c, userID, userID)
+}
+
+// handleEraseUser starts or resumes the erasure of an account on behalf of an admin
+func handleEraseUser(c *fiber.Ctx) error {
+	actorID := auth.PrincipalFrom(c).UserID
+	userID := c.Params("id")
+	if actorID == userID {
+		return adminError(c, users.ErrCannotModifySelf)
+	}
+
+	return eraseAccount(c, actorID, userID)
+}
+
+// handleGetErasure reports how far the erasure of an account has progressed
+func handleGetErasure(c *fiber.Ctx) error {
+	userID := c.Params("id")
+	steps, err := privacyCoordinator.Progress(userID)
+	if err != nil {
+		log.Printf("Failed to fetch erasure progress: %v", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to fetch erasure progress",
+		})
+	}
+	if len(steps) == 0 {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": "No erasure was requested for this user",
+		})
+	}
+
+	return erasureResponse(c, userID, steps)
+}
+
+// eraseAccount locks the account and runs the outstanding erasure steps. If the
+// account is already gone, only the remaining steps of an earlier run are retried.
+func eraseAccount(c *fiber.Ctx, actorID, userID string) error {
+	err := userService.BeginErasure(actorID, userID)
+	if err != nil && !errors.Is(err, users.ErrUserNotFound) {
+		log.Printf("Failed to lock account for erasure: %v", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to delete account",
+		})
+	}
+	if errors.Is(err, users.ErrUserNotFound) {
+		steps, perr := privacyCoordinator.Progress(userID)
+		if perr != nil || len(steps) == 0 {
+			return adminError(c, users.ErrUserNotFound)
+		}
+	} else {
+		security.Log(security.Event{Type: security.EventErasureStarted, UserID: userID, IP: c.IP(), Detail: "actor=" + actorID})
+	}
+
+	steps, err := privacyCoordinator.Erase(c.Context(), userID)
+	if err != nil {
+		log.Printf("Failed to erase user %s: %v", userID, err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to delete account",
+		})
+	}
+	if !privacy.Complete(steps) {
+		log.Printf("Erasure of user %s is incomplete, an admin can resume it", userID)
+	}
+
+	return erasureResponse(c, userID, steps)
+}
@@ -0,0 +1,175 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/darkhyper24/blaban/shared/auth"
+	"github.com/darkhyper24/blaban/user-service/internal/mailer"
+	"github.com/darkhyper24/blaban/user-service/internal/users"
+	"github.com/gofiber/fiber/v2"
+)
+
+func profileResponse(p *users.Profile) fiber.Map {
+	profile := fiber.Map{
+		"id":             p.ID,
+		"name":           p.Name,
+		"email":          p.Email,
+		"email_verified": p.EmailVerified,
+		"role":           p.Role,
+		"bio":            p.Bio,
+		"phone":          p.Phone,
+		"avatar_url":     p.AvatarURL,
+		"preferences":    p.Preferences,
+		"updated_at":     p.UpdatedAt.Format(time.RFC3339),
+	}
+	if p.PendingEmail != "" {
+		profile["pending_email"] = p.PendingEmail
+	}
+	return fiber.Map{"profile": profile}
+}
+
+// handleGetProfile returns the caller's profile with its ETag
+func handleGetProfile(c *fiber.Ctx) error {
+	profile, err := userService.GetProfile(auth.PrincipalFrom(c).UserID)
+	if err != nil {
+		if errors.Is(err, users.ErrUserNotFound) {
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+				"error": err.Error(),
+			})
+		}
+		log.Printf("Failed to fetch profile: %v", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to fetch profile",
+		})
+	}
+
+	c.Set(fiber.HeaderETag, profile.ETag())
+	return c.JSON(profileResponse(profile))
+}
+
+// handleUpdateProfile replaces the caller's profile. The If-Match header must
+// carry the ETag of the profile the client last read.
+func handleUpdateProfile(c *fiber.Ctx) error {
+	userID := auth.PrincipalFrom(c).UserID
+
+	ifMatch := strings.TrimSpace(c.Get(fiber.HeaderIfMatch))
+	if ifMatch == "" {
+		return c.Status(fiber.StatusPreconditionRequired).JSON(fiber.Map{
+			"error": "If-Match header is required",
+		})
+	}
+
+	var req struct {
+		Name        string          `json:"name"`
+		Email       string          `json:"email"`
+		Bio         string          `json:"bio"`
+		Phone       string          `json:"phone"`
+		AvatarURL   string          `json:"avatar_url"`
+		Preferences json.RawMessage `json:"preferences"`
+	}
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid request body",
+		})
+	}
+
+	update := users.ProfileUpdate{
+		Name:        req.Name,
+		Email:       req.Email,
+		Bio:         req.Bio,
+		Phone:       req.Phone,
+		AvatarURL:   req.AvatarURL,
+		Preferences: req.Preferences,
+	}
+	if err := update.Validate(); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	var version int
+	if ifMatch == "*" {
+		current, err := userService.GetProfile(userID)
+		if err != nil {
+			return profileError(c, err)
+		}
+		version = current.Version
+	} else {
+		var ok bool
+		if version, ok = users.ParseETag(ifMatch); !ok {
+			return c.Status(fiber.StatusPreconditionFailed).JSON(fiber.Map{
+				"error": users.ErrVersionMismatch.Error(),
+			})
+		}
+	}
+
+	profile, emailToken, err := userService.UpdateProfile(userID, version, update)
+	if err != nil {
+		return profileError(c, err)
+	}
+
+	if emailToken != "" {
+		sendMail(mailer.Message{
+			To:      profile.PendingEmail,
+			Subject: "Confirm your new Blaban email address",
+			Body: "Hi " + profile.Name + ",\n\n" +
+				"Use the link below to confirm " + profile.PendingEmail + " as your email address. It expires in 24 hours.\n\n" +
+				appLink("/confirm-email", emailToken) + "\n\n" +
+				"Until then you keep signing in with " + profile.Email + ".",
+		})
+	}
+
+	c.Set(fiber.HeaderETag, profile.ETag())
+	return c.JSON(profileResponse(profile))
+}
+
+// handleConfirmEmailChange applies a pending email change from its confirmation link
+func handleConfirmEmailChange(c *fiber.Ctx) error {
+	var req struct {
+		Token string `json:"token"`
+	}
+	if err := c.BodyParser(&req); err != nil || req.Token == "" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "token is required",
+		})
+	}
+
+	profile, err := userService.ConfirmEmailChange(req.Token)
+	if err != nil {
+		if errors.Is(err, users.ErrInvalidEmailChange) {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": err.Error(),
+			})
+		}
+		return profileError(c, err)
+	}
+
+	c.Set(fiber.HeaderETag, profile.ETag())
+	return c.JSON(profileResponse(profile))
+}
+
+func profileError(c *fiber.Ctx, err error) error {
+	switch {
+	case errors.Is(err, users.ErrUserNotFound):
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	case errors.Is(err, users.ErrVersionMismatch):
+		return c.Status(fiber.StatusPreconditionFailed).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	case errors.Is(err, users.ErrEmailTaken):
+		return c.Status(fiber.StatusConflict).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	log.Printf("Failed to update profile: %v", err)
+	return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+		"error": "Failed to update profile",
+	})
+}
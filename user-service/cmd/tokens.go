@@ -0,0 +1,123 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+
+	"github.com/darkhyper24/blaban/user-service/internal/users"
+	"github.com/gofiber/fiber/v2"
+)
+
+// Authentication methods references (RFC 8176) recorded in issued tokens
+const (
+	amrPassword = "pwd"
+	amrOTP      = "otp"
+	amrMFA      = "mfa"
+)
+
+// requestTokens asks auth-service to issue tokens for the user
+func requestTokens(user *users.User, amr []string) (map[string]interface{}, error) {
+	mfaEnrolled, err := mfaService.IsEnabled(user.ID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to check MFA status: %w", err)
+	}
+
+	body, err := json.Marshal(fiber.Map{
+		"user_id":        user.ID,
+		"role":           user.Role,
+		"amr":            amr,
+		"email_verified": user.EmailVerified,
+		"mfa_enrolled":   mfaEnrolled,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	authResp, err := authClient.Post(creds.AuthServiceURL+"/api/auth/tokens", "application/json", bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	defer authResp.Body.Close()
+
+	if authResp.StatusCode != http.StatusCreated {
+		return nil, fmt.Errorf("auth service refused to issue tokens: status %d", authResp.StatusCode)
+	}
+
+	var tokenResp map[string]interface{}
+	if err := json.NewDecoder(authResp.Body).Decode(&tokenResp); err != nil {
+		return nil, fmt.Errorf("failed to decode auth response: %w", err)
+	}
+	return tokenResp, nil
+}
+
+// respondWithTokens completes a login by returning the user and a fresh token pair
+func respondWithTokens(c *fiber.Ctx, user *users.User, amr []string) error {
+	tokenResp, err := requestTokens(user, amr)
+	if err != nil {
+		log.Printf("Failed to get auth tokens: %v", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Authentication service unavailable",
+		})
+	}
+
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{
+		"user": fiber.Map{
+			"id":             user.ID,
+			"name":           user.Name,
+			"email":          user.Email,
+			"role":           user.Role,
+			"bio":            user.Bio,
+			"email_verified": user.EmailVerified,
+		},
+		"tokens": tokenResp,
+	})
+}
+
+// revokeSessions asks auth-service to revoke every refresh token of the user
+func revokeSessions(userID string) error {
+	authResp, err := authClient.Post(creds.AuthServiceURL+"/api/auth/users/"+url.PathEscape(userID)+"/revoke", "application/json", nil)
+	if err != nil {
+		return err
+	}
+	defer authResp.Body.Close()
+
+	if authResp.StatusCode != http.StatusOK {
+		return fmt.Errorf("auth service refused to revoke sessions: status %d", authResp.StatusCode)
+	}
+	return nil
+}
+
+// accountActivity is auth-service's view of a user's sessions and sign-in methods
+type accountActivity struct {
+	ActiveSessions int `json:"active_sessions"`
+	Identities     []struct {
+		Provider    string `json:"provider"`
+		Email       string `json:"email,omitempty"`
+		CreatedAt   string `json:"created_at"`
+		LastLoginAt string `json:"last_login_at"`
+	} `json:"identities"`
+}
+
+// fetchAccountActivity asks auth-service how many sessions the user has open and
+// which identities are linked to the account
+func fetchAccountActivity(userID string) (*accountActivity, error) {
+	authResp, err := authClient.Get(creds.AuthServiceURL + "/api/auth/users/" + url.PathEscape(userID) + "/sessions")
+	if err != nil {
+		return nil, err
+	}
+	defer authResp.Body.Close()
+
+	if authResp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("auth service refused to report sessions: status %d", authResp.StatusCode)
+	}
+
+	var activity accountActivity
+	if err := json.NewDecoder(authResp.Body).Decode(&activity); err != nil {
+		return nil, err
+	}
+	return &activity, nil
+}
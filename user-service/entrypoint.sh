@@ -11,12 +11,8 @@ do
 done
 echo "Postgres is up!"
 
-echo "Running raw SQL migrations..."
-for file in $(ls /app/migrations/*.sql | sort); do
-  echo "Applying migration: $file"
-  psql -h db -U postgres -d userdb -f "$file"
-done
+echo "Running migrations..."
+/user-service migrate up
 
 echo "All migrations applied successfully."
 exec /user-service
-
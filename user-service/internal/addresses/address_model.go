@@ -0,0 +1,105 @@
+package addresses
+
+import (
+	"errors"
+	"regexp"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+const (
+	maxLabelLength        = 50
+	maxNameLength         = 100
+	maxLineLength         = 200
+	maxPostalCodeLength   = 20
+	maxInstructionsLength = 500
+
+	// MaxPerUser caps the size of an address book
+	MaxPerUser = 20
+)
+
+var (
+	phonePattern   = regexp.MustCompile(`^\+[1-9][0-9]{6,14}$`)
+	countryPattern = regexp.MustCompile(`^[A-Z]{2}$`)
+)
+
+// Address is a saved delivery address of a user
+type Address struct {
+	ID           string
+	UserID       string
+	Label        string
+	Recipient    string
+	Phone        string
+	Line1        string
+	Line2        string
+	City         string
+	Region       string
+	PostalCode   string
+	Country      string
+	Latitude     *float64
+	Longitude    *float64
+	Instructions string
+	IsDefault    bool
+	CreatedAt    time.Time
+	UpdatedAt    time.Time
+}
+
+// AddressInput holds the fields of an address a user can set
+type AddressInput struct {
+	Label        string   `json:"label"`
+	Recipient    string   `json:"recipient"`
+	Phone        string   `json:"phone"`
+	Line1        string   `json:"line1"`
+	Line2        string   `json:"line2"`
+	City         string   `json:"city"`
+	Region       string   `json:"region"`
+	PostalCode   string   `json:"postal_code"`
+	Country      string   `json:"country"`
+	Latitude     *float64 `json:"latitude"`
+	Longitude    *float64 `json:"longitude"`
+	Instructions string   `json:"instructions"`
+	IsDefault    bool     `json:"is_default"`
+}
+
+func tooLong(s string, max int) bool {
+	return utf8.RuneCountInString(s) > max
+}
+
+// Validate normalizes the input and checks every field
+func (in *AddressInput) Validate() error {
+	for _, f := range []*string{&in.Label, &in.Recipient, &in.Phone, &in.Line1, &in.Line2, &in.City, &in.Region, &in.PostalCode, &in.Instructions} {
+		*f = strings.TrimSpace(*f)
+	}
+	in.Country = strings.ToUpper(strings.TrimSpace(in.Country))
+
+	switch {
+	case in.Label == "" || tooLong(in.Label, maxLabelLength):
+		return errors.New("label is required and must be at most 50 characters")
+	case in.Recipient == "" || tooLong(in.Recipient, maxNameLength):
+		return errors.New("recipient is required and must be at most 100 characters")
+	case in.Phone != "" && !phonePattern.MatchString(in.Phone):
+		return errors.New("phone must be in international format, e.g. +201001234567")
+	case in.Line1 == "" || tooLong(in.Line1, maxLineLength):
+		return errors.New("line1 is required and must be at most 200 characters")
+	case tooLong(in.Line2, maxLineLength):
+		return errors.New("line2 must be at most 200 characters")
+	case in.City == "" || tooLong(in.City, maxNameLength):
+		return errors.New("city is required and must be at most 100 characters")
+	case tooLong(in.Region, maxNameLength):
+		return errors.New("region must be at most 100 characters")
+	case tooLong(in.PostalCode, maxPostalCodeLength):
+		return errors.New("postal_code must be at most 20 characters")
+	case !countryPattern.MatchString(in.Country):
+		return errors.New("country must be a two-letter ISO 3166 code")
+	case tooLong(in.Instructions, maxInstructionsLength):
+		return errors.New("instructions must be at most 500 characters")
+	case (in.Latitude == nil) != (in.Longitude == nil):
+		return errors.New("latitude and longitude must be given together")
+	case in.Latitude != nil && (*in.Latitude < -90 || *in.Latitude > 90):
+		return errors.New("latitude must be between -90 and 90")
+	case in.Longitude != nil && (*in.Longitude < -180 || *in.Longitude > 180):
+		return errors.New("longitude must be between -180 and 180")
+	}
+	return nil
+}
@@ -0,0 +1,202 @@
+package addresses
+
+import (
+	"database/sql"
+	"errors"
+
+	"github.com/google/uuid"
+)
+
+var (
+	ErrAddressNotFound  = errors.New("address not found")
+	ErrTooManyAddresses = errors.New("address book is full, remove an address first")
+)
+
+const addressColumns = `id, user_id, label, recipient, phone, line1, line2, city, region,
+        postal_code, country, latitude, longitude, instructions, is_default, created_at, updated_at`
+
+type AddressService struct {
+	db *sql.DB
+}
+
+func NewAddressService(db *sql.DB) *AddressService {
+	return &AddressService{db: db}
+}
+
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+func scanAddress(row rowScanner) (*Address, error) {
+	var a Address
+	var lat, lng sql.NullFloat64
+	err := row.Scan(&a.ID, &a.UserID, &a.Label, &a.Recipient, &a.Phone, &a.Line1, &a.Line2, &a.City, &a.Region,
+		&a.PostalCode, &a.Country, &lat, &lng, &a.Instructions, &a.IsDefault, &a.CreatedAt, &a.UpdatedAt)
+	if err != nil {
+		return nil, err
+	}
+	if lat.Valid && lng.Valid {
+		a.Latitude, a.Longitude = &lat.Float64, &lng.Float64
+	}
+	return &a, nil
+}
+
+// List returns the user's addresses, the default first and then oldest first
+func (as *AddressService) List(userID string) ([]Address, error) {
+	rows, err := as.db.Query(`
+        SELECT `+addressColumns+`
+        FROM addresses
+        WHERE user_id = $1
+        ORDER BY is_default DESC, created_at, id
+    `, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	list := []Address{}
+	for rows.Next() {
+		a, err := scanAddress(rows)
+		if err != nil {
+			return nil, err
+		}
+		list = append(list, *a)
+	}
+	return list, rows.Err()
+}
+
+// Get returns one of the user's addresses
+func (as *AddressService) Get(userID, id string) (*Address, error) {
+	row := as.db.QueryRow(`
+        SELECT `+addressColumns+`
+        FROM addresses
+        WHERE user_id = $1 AND id = $2
+    `, userID, id)
+	a, err := scanAddress(row)
+	if err == sql.ErrNoRows {
+		return nil, ErrAddressNotFound
+	}
+	return a, err
+}
+
+// clearDefault unsets the current default address of the user
+func clearDefault(tx *sql.Tx, userID string) error {
+	_, err := tx.Exec("UPDATE addresses SET is_default = FALSE, updated_at = NOW() WHERE user_id = $1 AND is_default", userID)
+	return err
+}
+
+// Create adds an address to the user's book. The first address becomes the default.
+func (as *AddressService) Create(userID string, in AddressInput) (*Address, error) {
+	tx, err := as.db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	// Lock the owner so concurrent creates cannot exceed the limit or race for the default
+	if _, err := tx.Exec("SELECT id FROM users WHERE id = $1 FOR UPDATE", userID); err != nil {
+		return nil, err
+	}
+	var count int
+	if err := tx.QueryRow("SELECT COUNT(*) FROM addresses WHERE user_id = $1", userID).Scan(&count); err != nil {
+		return nil, err
+	}
+	if count >= MaxPerUser {
+		return nil, ErrTooManyAddresses
+	}
+
+	isDefault := in.IsDefault || count == 0
+	if isDefault {
+		if err := clearDefault(tx, userID); err != nil {
+			return nil, err
+		}
+	}
+
+	id := uuid.NewString()
+	_, err = tx.Exec(`
+        INSERT INTO addresses (id, user_id, label, recipient, phone, line1, line2, city, region,
+                               postal_code, country, latitude, longitude, instructions, is_default)
+        VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15)
+    `, id, userID, in.Label, in.Recipient, in.Phone, in.Line1, in.Line2, in.City, in.Region,
+		in.PostalCode, in.Country, in.Latitude, in.Longitude, in.Instructions, isDefault)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	return as.Get(userID, id)
+}
+
+// Update replaces an address. Orders placed earlier keep their own copy of the old one.
+func (as *AddressService) Update(userID, id string, in AddressInput) (*Address, error) {
+	tx, err := as.db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	var wasDefault bool
+	err = tx.QueryRow("SELECT is_default FROM addresses WHERE user_id = $1 AND id = $2 FOR UPDATE", userID, id).Scan(&wasDefault)
+	if err == sql.ErrNoRows {
+		return nil, ErrAddressNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	// The default can be moved to another address but not simply removed
+	isDefault := in.IsDefault || wasDefault
+	if isDefault && !wasDefault {
+		if err := clearDefault(tx, userID); err != nil {
+			return nil, err
+		}
+	}
+
+	_, err = tx.Exec(`
+        UPDATE addresses
+        SET label = $3, recipient = $4, phone = $5, line1 = $6, line2 = $7, city = $8, region = $9,
+            postal_code = $10, country = $11, latitude = $12, longitude = $13, instructions = $14,
+            is_default = $15, updated_at = NOW()
+        WHERE user_id = $1 AND id = $2
+    `, userID, id, in.Label, in.Recipient, in.Phone, in.Line1, in.Line2, in.City, in.Region,
+		in.PostalCode, in.Country, in.Latitude, in.Longitude, in.Instructions, isDefault)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	return as.Get(userID, id)
+}
+
+// Delete removes an address. If it was the default, the oldest remaining address takes over.
+func (as *AddressService) Delete(userID, id string) error {
+	tx, err := as.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	var wasDefault bool
+	err = tx.QueryRow("DELETE FROM addresses WHERE user_id = $1 AND id = $2 RETURNING is_default", userID, id).Scan(&wasDefault)
+	if err == sql.ErrNoRows {
+		return ErrAddressNotFound
+	}
+	if err != nil {
+		return err
+	}
+
+	if wasDefault {
+		_, err := tx.Exec(`
+            UPDATE addresses SET is_default = TRUE, updated_at = NOW()
+            WHERE id = (SELECT id FROM addresses WHERE user_id = $1 ORDER BY created_at, id LIMIT 1)
+        `, userID)
+		if err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
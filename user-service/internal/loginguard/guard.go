@@ -0,0 +1,224 @@
+package loginguard
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+)
+
+var ErrInvalidUnlockToken = errors.New("unlock link is invalid or expired")
+
+// Config controls how aggressively failed logins are throttled
+type Config struct {
+	// MaxAccountFailures failed logins within FailureWindow lock the account
+	MaxAccountFailures int
+	// MaxIPFailures failed logins within FailureWindow block the client IP
+	MaxIPFailures int
+	FailureWindow time.Duration
+	// LockoutDuration is how long a locked account stays locked unless unlocked by email
+	LockoutDuration time.Duration
+	// BaseDelay is the wait imposed after the first failure, doubling with every
+	// further failure up to MaxDelay
+	BaseDelay time.Duration
+	MaxDelay  time.Duration
+}
+
+// DefaultConfig returns the limits used in production
+func DefaultConfig() Config {
+	return Config{
+		MaxAccountFailures: 5,
+		MaxIPFailures:      50,
+		FailureWindow:      15 * time.Minute,
+		LockoutDuration:    15 * time.Minute,
+		BaseDelay:          time.Second,
+		MaxDelay:           30 * time.Second,
+	}
+}
+
+// Reasons a login attempt is refused before credentials are checked
+const (
+	ReasonAccountLocked = "account_locked"
+	ReasonAccountDelay  = "account_delay"
+	ReasonIPBlocked     = "ip_blocked"
+)
+
+// BlockedError is returned by Check when the attempt must be refused
+type BlockedError struct {
+	Reason     string
+	RetryAfter time.Duration
+}
+
+func (e *BlockedError) Error() string {
+	if e.Reason == ReasonAccountLocked {
+		return "too many failed login attempts, the account is temporarily locked"
+	}
+	return "too many failed login attempts, please try again later"
+}
+
+// Failure describes the state after a failed login was recorded
+type Failure struct {
+	AccountFailures int64
+	IPFailures      int64
+	// Locked is set on the failure that locked the account
+	Locked bool
+	// UnlockToken unlocks the account early; only set together with Locked
+	UnlockToken string
+}
+
+// Guard tracks failed logins per account and per client IP in Redis.
+// Accounts are identified by email, hashed so addresses are not stored in Redis.
+type Guard struct {
+	redis  *redis.Client
+	config Config
+}
+
+func NewGuard(client *redis.Client, config Config) *Guard {
+	return &Guard{redis: client, config: config}
+}
+
+func accountKey(kind, email string) string {
+	sum := sha256.Sum256([]byte(email))
+	return "login:" + kind + ":" + hex.EncodeToString(sum[:])
+}
+
+func ipKey(ip string) string {
+	return "login:fail:ip:" + ip
+}
+
+func unlockKey(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return "login:unlock:" + hex.EncodeToString(sum[:])
+}
+
+// Check refuses attempts from blocked IPs, for locked accounts and for
+// accounts still serving a progressive delay. It applies equally to unknown
+// emails so responses do not reveal which accounts exist.
+func (g *Guard) Check(ctx context.Context, email, ip string) error {
+	pipe := g.redis.Pipeline()
+	ipFailures := pipe.Get(ctx, ipKey(ip))
+	ipTTL := pipe.PTTL(ctx, ipKey(ip))
+	lockTTL := pipe.PTTL(ctx, accountKey("lock", email))
+	delayTTL := pipe.PTTL(ctx, accountKey("delay", email))
+	if _, err := pipe.Exec(ctx); err != nil && err != redis.Nil {
+		return fmt.Errorf("failed to check login throttling: %w", err)
+	}
+
+	if n, err := ipFailures.Int64(); err == nil && n >= int64(g.config.MaxIPFailures) {
+		return &BlockedError{Reason: ReasonIPBlocked, RetryAfter: ipTTL.Val()}
+	}
+	if ttl := lockTTL.Val(); ttl > 0 {
+		return &BlockedError{Reason: ReasonAccountLocked, RetryAfter: ttl}
+	}
+	if ttl := delayTTL.Val(); ttl > 0 {
+		return &BlockedError{Reason: ReasonAccountDelay, RetryAfter: ttl}
+	}
+	return nil
+}
+
+// RecordFailure counts a failed login and applies the resulting delay or lockout
+func (g *Guard) RecordFailure(ctx context.Context, email, ip string) (*Failure, error) {
+	pipe := g.redis.TxPipeline()
+	accountFailures := pipe.Incr(ctx, accountKey("fail", email))
+	ipFailures := pipe.Incr(ctx, ipKey(ip))
+	if _, err := pipe.Exec(ctx); err != nil {
+		return nil, fmt.Errorf("failed to record login failure: %w", err)
+	}
+
+	failure := &Failure{
+		AccountFailures: accountFailures.Val(),
+		IPFailures:      ipFailures.Val(),
+	}
+
+	// Counters expire FailureWindow after the first failure
+	pipe = g.redis.Pipeline()
+	if failure.AccountFailures == 1 {
+		pipe.Expire(ctx, accountKey("fail", email), g.config.FailureWindow)
+	}
+	if failure.IPFailures == 1 {
+		pipe.Expire(ctx, ipKey(ip), g.config.FailureWindow)
+	}
+	pipe.Set(ctx, accountKey("delay", email), 1, g.delay(failure.AccountFailures))
+	if _, err := pipe.Exec(ctx); err != nil {
+		return nil, fmt.Errorf("failed to record login failure: %w", err)
+	}
+
+	if failure.AccountFailures < int64(g.config.MaxAccountFailures) {
+		return failure, nil
+	}
+
+	locked, err := g.redis.SetNX(ctx, accountKey("lock", email), 1, g.config.LockoutDuration).Result()
+	if err != nil {
+		return nil, fmt.Errorf("failed to lock account: %w", err)
+	}
+	if !locked {
+		return failure, nil
+	}
+
+	token, err := randomToken()
+	if err != nil {
+		return nil, err
+	}
+	if err := g.redis.Set(ctx, unlockKey(token), email, g.config.LockoutDuration).Err(); err != nil {
+		return nil, fmt.Errorf("failed to store unlock token: %w", err)
+	}
+
+	failure.Locked = true
+	failure.UnlockToken = token
+	return failure, nil
+}
+
+// RecordSuccess clears the account's failure count after a successful login.
+// The IP counter is kept so one valid account cannot mask guessing against others.
+func (g *Guard) RecordSuccess(ctx context.Context, email string) error {
+	return g.reset(ctx, email)
+}
+
+// Unlock lifts the lockout identified by an unlock token and returns the account's email
+func (g *Guard) Unlock(ctx context.Context, token string) (string, error) {
+	email, err := g.redis.GetDel(ctx, unlockKey(token)).Result()
+	if err != nil {
+		if err == redis.Nil {
+			return "", ErrInvalidUnlockToken
+		}
+		return "", fmt.Errorf("failed to unlock account: %w", err)
+	}
+
+	if err := g.reset(ctx, email); err != nil {
+		return "", err
+	}
+	return email, nil
+}
+
+func (g *Guard) reset(ctx context.Context, email string) error {
+	err := g.redis.Del(ctx, accountKey("fail", email), accountKey("delay", email), accountKey("lock", email)).Err()
+	if err != nil {
+		return fmt.Errorf("failed to reset login failures: %w", err)
+	}
+	return nil
+}
+
+// delay doubles with every failure, from BaseDelay up to MaxDelay
+func (g *Guard) delay(failures int64) time.Duration {
+	d := g.config.BaseDelay
+	for i := int64(1); i < failures && d < g.config.MaxDelay; i++ {
+		d *= 2
+	}
+	if d > g.config.MaxDelay {
+		d = g.config.MaxDelay
+	}
+	return d
+}
+
+func randomToken() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
@@ -0,0 +1,74 @@
+package mailer
+
+import (
+	"fmt"
+	"mime"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Message is a plain-text email
+type Message struct {
+	To      string
+	Subject string
+	Body    string
+}
+
+// Mailer delivers email
+type Mailer interface {
+	Send(msg Message) error
+}
+
+// FromEnv picks a backend from MAIL_BACKEND:
+//   - "smtp" sends through SMTP_HOST, SMTP_PORT, SMTP_USERNAME and SMTP_PASSWORD
+//   - "file" appends to the outbox file at MAIL_OUTBOX_FILE
+//   - anything else writes to stdout, which suits local development
+//
+// MAIL_FROM sets the sender address.
+func FromEnv() (Mailer, error) {
+	from := os.Getenv("MAIL_FROM")
+	if from == "" {
+		from = "no-reply@blaban.local"
+	}
+
+	switch os.Getenv("MAIL_BACKEND") {
+	case "smtp":
+		port := 587
+		if p := os.Getenv("SMTP_PORT"); p != "" {
+			var err error
+			if port, err = strconv.Atoi(p); err != nil {
+				return nil, fmt.Errorf("invalid SMTP_PORT: %w", err)
+			}
+		}
+		host := os.Getenv("SMTP_HOST")
+		if host == "" {
+			return nil, fmt.Errorf("SMTP_HOST is required for the smtp mail backend")
+		}
+		return NewSMTPMailer(host, port, os.Getenv("SMTP_USERNAME"), os.Getenv("SMTP_PASSWORD"), from), nil
+	case "file":
+		path := os.Getenv("MAIL_OUTBOX_FILE")
+		if path == "" {
+			path = "outbox.eml"
+		}
+		return NewFileMailer(path, from), nil
+	default:
+		return NewOutboxMailer(os.Stdout, from), nil
+	}
+}
+
+// format renders msg as an RFC 5322 message
+func format(from string, msg Message) []byte {
+	var b strings.Builder
+	b.WriteString("From: " + from + "\r\n")
+	b.WriteString("To: " + msg.To + "\r\n")
+	b.WriteString("Subject: " + mime.QEncoding.Encode("utf-8", msg.Subject) + "\r\n")
+	b.WriteString("Date: " + time.Now().Format(time.RFC1123Z) + "\r\n")
+	b.WriteString("MIME-Version: 1.0\r\n")
+	b.WriteString("Content-Type: text/plain; charset=utf-8\r\n")
+	b.WriteString("\r\n")
+	b.WriteString(strings.ReplaceAll(msg.Body, "\n", "\r\n"))
+	b.WriteString("\r\n")
+	return []byte(b.String())
+}
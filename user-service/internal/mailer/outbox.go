@@ -0,0 +1,60 @@
+package mailer
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"sync"
+)
+
+// OutboxMailer writes every message to a writer instead of delivering it
+type OutboxMailer struct {
+	mu   sync.Mutex
+	w    io.Writer
+	from string
+}
+
+// NewOutboxMailer creates a mailer that writes messages to w
+func NewOutboxMailer(w io.Writer, from string) *OutboxMailer {
+	return &OutboxMailer{w: w, from: from}
+}
+
+// Send writes msg to the outbox
+func (m *OutboxMailer) Send(msg Message) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, err := m.w.Write(append(format(m.from, msg), '\n')); err != nil {
+		return fmt.Errorf("failed to write email to outbox: %w", err)
+	}
+	return nil
+}
+
+// FileMailer appends every message to an outbox file
+type FileMailer struct {
+	mu   sync.Mutex
+	path string
+	from string
+}
+
+// NewFileMailer creates a mailer that appends messages to the file at path
+func NewFileMailer(path, from string) *FileMailer {
+	return &FileMailer{path: path, from: from}
+}
+
+// Send appends msg to the outbox file
+func (m *FileMailer) Send(msg Message) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	f, err := os.OpenFile(m.path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o600)
+	if err != nil {
+		return fmt.Errorf("failed to open outbox: %w", err)
+	}
+	defer f.Close()
+
+	if _, err := f.Write(append(format(m.from, msg), '\n')); err != nil {
+		return fmt.Errorf("failed to write email to outbox: %w", err)
+	}
+	return nil
+}
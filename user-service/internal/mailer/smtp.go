@@ -0,0 +1,36 @@
+package mailer
+
+import (
+	"fmt"
+	"net/smtp"
+	"strconv"
+)
+
+// SMTPMailer sends email through an SMTP server
+type SMTPMailer struct {
+	addr string
+	auth smtp.Auth
+	from string
+}
+
+// NewSMTPMailer creates a mailer for the server at host:port. Authentication
+// is skipped when username is empty.
+func NewSMTPMailer(host string, port int, username, password, from string) *SMTPMailer {
+	var auth smtp.Auth
+	if username != "" {
+		auth = smtp.PlainAuth("", username, password, host)
+	}
+	return &SMTPMailer{
+		addr: host + ":" + strconv.Itoa(port),
+		auth: auth,
+		from: from,
+	}
+}
+
+// Send delivers msg through the SMTP server
+func (m *SMTPMailer) Send(msg Message) error {
+	if err := smtp.SendMail(m.addr, m.auth, m.from, []string{msg.To}, format(m.from, msg)); err != nil {
+		return fmt.Errorf("failed to send email: %w", err)
+	}
+	return nil
+}
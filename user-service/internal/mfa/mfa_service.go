@@ -0,0 +1,275 @@
+package mfa
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"strings"
+	"time"
+)
+
+const (
+	recoveryCodeCount = 10
+	challengeTTL      = 5 * time.Minute
+
+	maxChallengeAttempts = 5
+)
+
+var (
+	ErrNotEnrolled      = errors.New("multi-factor authentication is not set up")
+	ErrAlreadyEnabled   = errors.New("multi-factor authentication is already enabled")
+	ErrInvalidCode      = errors.New("invalid verification code")
+	ErrInvalidChallenge = errors.New("login challenge is invalid or expired")
+)
+
+type MFAService struct {
+	db *sql.DB
+}
+
+func NewMFAService(db *sql.DB) *MFAService {
+	return &MFAService{db: db}
+}
+
+// StartEnrollment generates a new TOTP secret for the user. The secret only
+// takes effect once ConfirmEnrollment succeeds.
+func (ms *MFAService) StartEnrollment(userID string) (string, error) {
+	enabled, err := ms.IsEnabled(userID)
+	if err != nil {
+		return "", err
+	}
+	if enabled {
+		return "", ErrAlreadyEnabled
+	}
+
+	secret, err := GenerateSecret()
+	if err != nil {
+		return "", err
+	}
+
+	_, err = ms.db.Exec(`
+        INSERT INTO user_mfa (user_id, secret, enabled)
+        VALUES ($1, $2, false)
+        ON CONFLICT (user_id) DO UPDATE SET secret = EXCLUDED.secret, last_used_step = 0, created_at = NOW()
+    `, userID, secret)
+	if err != nil {
+		return "", err
+	}
+	return secret, nil
+}
+
+// ConfirmEnrollment enables MFA once the user proves their authenticator
+// produces valid codes, and returns a fresh set of recovery codes
+func (ms *MFAService) ConfirmEnrollment(userID, code string) ([]string, error) {
+	var enabled bool
+	if err := ms.db.QueryRow("SELECT enabled FROM user_mfa WHERE user_id = $1", userID).Scan(&enabled); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, ErrNotEnrolled
+		}
+		return nil, err
+	}
+	if enabled {
+		return nil, ErrAlreadyEnabled
+	}
+
+	if err := ms.verifyTOTP(userID, code); err != nil {
+		return nil, err
+	}
+
+	if _, err := ms.db.Exec("UPDATE user_mfa SET enabled = true, confirmed_at = NOW() WHERE user_id = $1", userID); err != nil {
+		return nil, err
+	}
+
+	return ms.RegenerateRecoveryCodes(userID)
+}
+
+// IsEnabled reports whether the user has confirmed MFA enrolment
+func (ms *MFAService) IsEnabled(userID string) (bool, error) {
+	var enabled bool
+	err := ms.db.QueryRow("SELECT enabled FROM user_mfa WHERE user_id = $1", userID).Scan(&enabled)
+	if err == sql.ErrNoRows {
+		return false, nil
+	}
+	return enabled, err
+}
+
+// VerifyCode checks a TOTP code for a user with MFA enabled
+func (ms *MFAService) VerifyCode(userID, code string) error {
+	enabled, err := ms.IsEnabled(userID)
+	if err != nil {
+		return err
+	}
+	if !enabled {
+		return ErrNotEnrolled
+	}
+	return ms.verifyTOTP(userID, code)
+}
+
+// verifyTOTP validates the code and records its time step so it cannot be replayed
+func (ms *MFAService) verifyTOTP(userID, code string) error {
+	var secret string
+	var lastUsedStep int64
+	err := ms.db.QueryRow("SELECT secret, last_used_step FROM user_mfa WHERE user_id = $1", userID).Scan(&secret, &lastUsedStep)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return ErrNotEnrolled
+		}
+		return err
+	}
+
+	step, ok := ValidateCode(secret, strings.TrimSpace(code), time.Now())
+	if !ok || step <= lastUsedStep {
+		return ErrInvalidCode
+	}
+
+	result, err := ms.db.Exec(
+		"UPDATE user_mfa SET last_used_step = $2 WHERE user_id = $1 AND last_used_step < $2",
+		userID, step)
+	if err != nil {
+		return err
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return ErrInvalidCode
+	}
+	return nil
+}
+
+// UseRecoveryCode consumes one of the user's unused recovery codes
+func (ms *MFAService) UseRecoveryCode(userID, code string) error {
+	result, err := ms.db.Exec(`
+        UPDATE mfa_recovery_codes SET used_at = NOW()
+        WHERE code_hash = $1 AND user_id = $2 AND used_at IS NULL
+    `, HashRecoveryCode(code), userID)
+	if err != nil {
+		return err
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return ErrInvalidCode
+	}
+	return nil
+}
+
+// RegenerateRecoveryCodes replaces the user's recovery codes. Only hashes are stored,
+// so the returned codes must be shown to the user now.
+func (ms *MFAService) RegenerateRecoveryCodes(userID string) ([]string, error) {
+	tx, err := ms.db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec("DELETE FROM mfa_recovery_codes WHERE user_id = $1", userID); err != nil {
+		return nil, err
+	}
+
+	codes := make([]string, 0, recoveryCodeCount)
+	for i := 0; i < recoveryCodeCount; i++ {
+		code, err := NewRecoveryCode()
+		if err != nil {
+			return nil, err
+		}
+		if _, err := tx.Exec("INSERT INTO mfa_recovery_codes (code_hash, user_id) VALUES ($1, $2)",
+			HashRecoveryCode(code), userID); err != nil {
+			return nil, err
+		}
+		codes = append(codes, code)
+	}
+
+	return codes, tx.Commit()
+}
+
+// Disable turns MFA off and discards the secret and recovery codes
+func (ms *MFAService) Disable(userID string) error {
+	tx, err := ms.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec("DELETE FROM mfa_recovery_codes WHERE user_id = $1", userID); err != nil {
+		return err
+	}
+	if _, err := tx.Exec("DELETE FROM user_mfa WHERE user_id = $1", userID); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// CreateChallenge starts the second login step after a correct password
+func (ms *MFAService) CreateChallenge(userID string) (string, error) {
+	token, err := randomToken(20)
+	if err != nil {
+		return "", err
+	}
+
+	_, err = ms.db.Exec("INSERT INTO mfa_challenges (token_hash, user_id, expires_at) VALUES ($1, $2, $3)",
+		hashToken(token), userID, time.Now().Add(challengeTTL))
+	if err != nil {
+		return "", err
+	}
+	return token, nil
+}
+
+// ResolveChallenge returns the user a challenge was issued to. Each call counts
+// as an attempt, and the challenge is discarded once too many have been made.
+func (ms *MFAService) ResolveChallenge(token string) (string, error) {
+	var userID string
+	var attempts int
+	var expiresAt time.Time
+	err := ms.db.QueryRow(`
+        UPDATE mfa_challenges SET attempts = attempts + 1
+        WHERE token_hash = $1
+        RETURNING user_id, attempts, expires_at
+    `, hashToken(token)).Scan(&userID, &attempts, &expiresAt)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return "", ErrInvalidChallenge
+		}
+		return "", err
+	}
+
+	if attempts > maxChallengeAttempts || time.Now().After(expiresAt) {
+		ms.DeleteChallenge(token)
+		return "", ErrInvalidChallenge
+	}
+	return userID, nil
+}
+
+// DeleteChallenge invalidates a challenge once the second step succeeded
+func (ms *MFAService) DeleteChallenge(token string) error {
+	_, err := ms.db.Exec("DELETE FROM mfa_challenges WHERE token_hash = $1", hashToken(token))
+	return err
+}
+
+// NewRecoveryCode returns a random single-use recovery code such as "k3f9-x2ma"
+func NewRecoveryCode() (string, error) {
+	code, err := randomToken(5)
+	if err != nil {
+		return "", err
+	}
+	return strings.ToLower(code[:4] + "-" + code[4:]), nil
+}
+
+// HashRecoveryCode returns the stored form of a recovery code. Case, spaces
+// and the dash are ignored, so "K3F9 X2MA" matches "k3f9-x2ma".
+func HashRecoveryCode(code string) string {
+	normalized := strings.ToLower(strings.Join(strings.Fields(strings.ReplaceAll(code, "-", " ")), ""))
+	if len(normalized) == 8 {
+		normalized = normalized[:4] + "-" + normalized[4:]
+	}
+	return hashToken(normalized)
+}
+
+func randomToken(n int) (string, error) {
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base32NoPadding.EncodeToString(b), nil
+}
+
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,133 @@
+package passwords
+
+import (
+	"bufio"
+	_ "embed"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+// bcrypt ignores everything past 72 bytes, so longer passwords would be silently truncated
+const maxPasswordBytes = 72
+
+//go:embed common_passwords.txt
+var commonPasswordList string
+
+var commonPasswords = parseList(commonPasswordList)
+
+func parseList(list string) map[string]bool {
+	entries := map[string]bool{}
+	scanner := bufio.NewScanner(strings.NewReader(list))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		entries[strings.ToLower(line)] = true
+	}
+	return entries
+}
+
+// PolicyError explains why a password was rejected
+type PolicyError struct {
+	Reason string
+}
+
+func (e *PolicyError) Error() string {
+	return e.Reason
+}
+
+// Policy decides which passwords are acceptable
+type Policy struct {
+	MinLength   int
+	BlockCommon bool
+	// HistorySize is how many previous passwords, besides the current one, cannot be reused
+	HistorySize int
+}
+
+func DefaultPolicy() Policy {
+	return Policy{
+		MinLength:   8,
+		BlockCommon: true,
+		HistorySize: 5,
+	}
+}
+
+// PolicyFromEnv reads PASSWORD_MIN_LENGTH, PASSWORD_BLOCK_COMMON and
+// PASSWORD_HISTORY_SIZE, falling back to DefaultPolicy for unset values
+func PolicyFromEnv() (Policy, error) {
+	policy := DefaultPolicy()
+
+	readInt := func(name string, min int, dst *int) error {
+		value := os.Getenv(name)
+		if value == "" {
+			return nil
+		}
+		n, err := strconv.Atoi(value)
+		if err != nil || n < min {
+			return fmt.Errorf("%s must be an integer of at least %d", name, min)
+		}
+		*dst = n
+		return nil
+	}
+	if err := readInt("PASSWORD_MIN_LENGTH", 8, &policy.MinLength); err != nil {
+		return policy, err
+	}
+	if err := readInt("PASSWORD_HISTORY_SIZE", 0, &policy.HistorySize); err != nil {
+		return policy, err
+	}
+	if value := os.Getenv("PASSWORD_BLOCK_COMMON"); value != "" {
+		block, err := strconv.ParseBool(value)
+		if err != nil {
+			return policy, fmt.Errorf("PASSWORD_BLOCK_COMMON must be true or false")
+		}
+		policy.BlockCommon = block
+	}
+	if policy.MinLength > maxPasswordBytes {
+		return policy, fmt.Errorf("PASSWORD_MIN_LENGTH must be at most %d", maxPasswordBytes)
+	}
+	return policy, nil
+}
+
+// Validate checks a new password. personal holds account details such as the
+// name and email address, which must not be used as the password.
+func (p Policy) Validate(password string, personal ...string) error {
+	if utf8.RuneCountInString(password) < p.MinLength {
+		return &PolicyError{Reason: fmt.Sprintf("password must be at least %d characters", p.MinLength)}
+	}
+	if len(password) > maxPasswordBytes {
+		return &PolicyError{Reason: fmt.Sprintf("password must be at most %d bytes", maxPasswordBytes)}
+	}
+
+	lower := strings.ToLower(password)
+	if p.BlockCommon && commonPasswords[lower] {
+		return &PolicyError{Reason: "password is too common, choose a less predictable one"}
+	}
+	for _, value := range personal {
+		value = strings.ToLower(strings.TrimSpace(value))
+		if value == "" {
+			continue
+		}
+		local, _, _ := strings.Cut(value, "@")
+		if lower == value || lower == local {
+			return &PolicyError{Reason: "password must not be your name or email address"}
+		}
+	}
+	return nil
+}
+
+// CheckReuse rejects password if it matches one of the given bcrypt hashes,
+// which should be the current password followed by the most recent ones
+func (p Policy) CheckReuse(password string, hashes []string) error {
+	for _, hash := range hashes {
+		if bcrypt.CompareHashAndPassword([]byte(hash), []byte(password)) == nil {
+			return &PolicyError{Reason: "password was used recently, choose a different one"}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,204 @@
+// Package privacy collects and erases a user's personal data across every
+// service that holds some, following the contract in shared/userdata.
+package privacy
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/darkhyper24/blaban/shared/userdata"
+)
+
+// Erasure step statuses
+const (
+	StepPending = "pending"
+	StepDone    = "done"
+	StepFailed  = "failed"
+)
+
+// LocalService names the step erasing the account itself
+const LocalService = "user-service"
+
+// Participant is a service holding personal data. Its data routes live under
+// BaseURL + "/users/:id/data".
+type Participant struct {
+	Name    string
+	BaseURL string
+}
+
+// Step is the progress of erasing a user's data in one service
+type Step struct {
+	Service    string    `json:"service"`
+	Status     string    `json:"status"`
+	Deleted    int64     `json:"deleted"`
+	Anonymised int64     `json:"anonymised"`
+	Detail     string    `json:"detail,omitempty"`
+	UpdatedAt  time.Time `json:"updated_at"`
+}
+
+// LocalEraser deletes the account held by user-service itself
+type LocalEraser func(userID string) (int64, error)
+
+type Coordinator struct {
+	db           *sql.DB
+	client       *http.Client
+	participants []Participant
+	eraseLocal   LocalEraser
+}
+
+// NewCoordinator talks to participants with client, which must carry a service
+// token granting auth.ScopeUserDataRead and auth.ScopeUserDataErase
+func NewCoordinator(db *sql.DB, client *http.Client, eraseLocal LocalEraser, participants ...Participant) *Coordinator {
+	return &Coordinator{
+		db:           db,
+		client:       client,
+		participants: participants,
+		eraseLocal:   eraseLocal,
+	}
+}
+
+func dataURL(p Participant, userID string) string {
+	return p.BaseURL + "/users/" + url.PathEscape(userID) + "/data"
+}
+
+// call sends a data request to a participant and decodes the JSON answer into out
+func (co *Coordinator) call(ctx context.Context, method string, p Participant, userID string, out interface{}) error {
+	req, err := http.NewRequestWithContext(ctx, method, dataURL(p, userID), nil)
+	if err != nil {
+		return err
+	}
+	resp, err := co.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("%s answered %d: %s", p.Name, resp.StatusCode, body)
+	}
+	return json.NewDecoder(resp.Body).Decode(out)
+}
+
+// Export collects the data every participant holds about userID. It fails if
+// any participant cannot be reached, since a partial archive would be misleading.
+func (co *Coordinator) Export(ctx context.Context, userID string) ([]userdata.Export, error) {
+	exports := make([]userdata.Export, 0, len(co.participants))
+	for _, p := range co.participants {
+		var export userdata.Export
+		if err := co.call(ctx, http.MethodGet, p, userID, &export); err != nil {
+			return nil, fmt.Errorf("export from %s: %w", p.Name, err)
+		}
+		export.Service = p.Name
+		exports = append(exports, export)
+	}
+	return exports, nil
+}
+
+// Erase runs every erasure step of userID that has not completed yet and
+// returns the progress of each. The account itself is only deleted once all
+// participants are done, so a failed run can be resumed by calling Erase again.
+func (co *Coordinator) Erase(ctx context.Context, userID string) ([]Step, error) {
+	services := []string{LocalService}
+	for _, p := range co.participants {
+		services = append(services, p.Name)
+	}
+	for _, service := range services {
+		if _, err := co.db.ExecContext(ctx, `
+            INSERT INTO account_erasures (user_id, service)
+            VALUES ($1, $2)
+            ON CONFLICT (user_id, service) DO NOTHING
+        `, userID, service); err != nil {
+			return nil, err
+		}
+	}
+
+	steps, err := co.Progress(userID)
+	if err != nil {
+		return nil, err
+	}
+	done := map[string]bool{}
+	for _, s := range steps {
+		done[s.Service] = s.Status == StepDone
+	}
+
+	remaining := false
+	for _, p := range co.participants {
+		if done[p.Name] {
+			continue
+		}
+		var result userdata.ErasureResult
+		if err := co.call(ctx, http.MethodDelete, p, userID, &result); err != nil {
+			remaining = true
+			if err := co.record(userID, p.Name, StepFailed, 0, 0, err.Error()); err != nil {
+				return nil, err
+			}
+			continue
+		}
+		if err := co.record(userID, p.Name, StepDone, result.Deleted, result.Anonymised, ""); err != nil {
+			return nil, err
+		}
+	}
+
+	if !remaining && !done[LocalService] {
+		deleted, err := co.eraseLocal(userID)
+		if err != nil {
+			if err := co.record(userID, LocalService, StepFailed, 0, 0, err.Error()); err != nil {
+				return nil, err
+			}
+		} else if err := co.record(userID, LocalService, StepDone, deleted, 0, ""); err != nil {
+			return nil, err
+		}
+	}
+
+	return co.Progress(userID)
+}
+
+func (co *Coordinator) record(userID, service, status string, deleted, anonymised int64, detail string) error {
+	_, err := co.db.Exec(`
+        UPDATE account_erasures
+        SET status = $3, deleted = $4, anonymised = $5, detail = $6, updated_at = NOW()
+        WHERE user_id = $1 AND service = $2
+    `, userID, service, status, deleted, anonymised, detail)
+	return err
+}
+
+// Progress returns the erasure steps recorded for userID, empty if no erasure was requested
+func (co *Coordinator) Progress(userID string) ([]Step, error) {
+	rows, err := co.db.Query(`
+        SELECT service, status, deleted, anonymised, detail, updated_at
+        FROM account_erasures
+        WHERE user_id = $1
+        ORDER BY service
+    `, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	steps := []Step{}
+	for rows.Next() {
+		var s Step
+		if err := rows.Scan(&s.Service, &s.Status, &s.Deleted, &s.Anonymised, &s.Detail, &s.UpdatedAt); err != nil {
+			return nil, err
+		}
+		steps = append(steps, s)
+	}
+	return steps, rows.Err()
+}
+
+// Complete reports whether every step in steps is done
+func Complete(steps []Step) bool {
+	for _, s := range steps {
+		if s.Status != StepDone {
+			return false
+		}
+	}
+	return len(steps) > 0
+}
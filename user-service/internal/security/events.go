@@ -0,0 +1,45 @@
+package security
+
+import (
+	"encoding/json"
+	"log"
+	"time"
+)
+
+// Security event types
+const (
+	EventLoginSucceeded  = "login_succeeded"
+	EventLoginFailed     = "login_failed"
+	EventLoginThrottled  = "login_throttled"
+	EventAccountLocked   = "account_locked"
+	EventAccountUnlocked = "account_unlocked"
+	EventDataExported    = "data_exported"
+	EventErasureStarted  = "erasure_started"
+	EventPasswordChanged = "password_changed"
+)
+
+// Event is a security relevant occurrence written to the security log
+type Event struct {
+	Type   string `json:"event"`
+	UserID string `json:"user_id,omitempty"`
+	Email  string `json:"email,omitempty"`
+	IP     string `json:"ip,omitempty"`
+	Detail string `json:"detail,omitempty"`
+}
+
+// Log writes the event as a single JSON line prefixed with "security:" so it
+// can be filtered out of the service log
+func Log(event Event) {
+	line, err := json.Marshal(struct {
+		Time string `json:"time"`
+		Event
+	}{
+		Time:  time.Now().UTC().Format(time.RFC3339),
+		Event: event,
+	})
+	if err != nil {
+		log.Printf("security: failed to encode event %q: %v", event.Type, err)
+		return
+	}
+	log.Printf("security: %s", line)
+}
@@ -0,0 +1,41 @@
+package users
+
+// BeginErasure locks the account while its data is being erased so that no
+// new sessions or orders can be created in the meantime
+func (us *UserService) BeginErasure(actorID, userID string) error {
+	tx, err := us.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	result, err := tx.Exec(`
+        UPDATE users
+        SET disabled = TRUE, disabled_at = COALESCE(disabled_at, NOW()), updated_at = NOW()
+        WHERE id = $1
+    `, userID)
+	if err != nil {
+		return err
+	}
+	if n, err := result.RowsAffected(); err != nil {
+		return err
+	} else if n == 0 {
+		return ErrUserNotFound
+	}
+
+	if err := recordAudit(tx, userID, actorID, AuditErasure, "", ""); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// DeleteAccount hard-deletes the account together with its MFA secrets, reset
+// tokens and pending email changes. The audit log, which only refers to the
+// user by id, is kept.
+func (us *UserService) DeleteAccount(userID string) (int64, error) {
+	result, err := us.db.Exec("DELETE FROM users WHERE id = $1", userID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
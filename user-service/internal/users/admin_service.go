@@ -0,0 +1,259 @@
+package users
+
+import (
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/darkhyper24/blaban/shared/auth"
+	"github.com/google/uuid"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// inviteTTL is how long an invited staff member has to set their password
+const inviteTTL = 7 * 24 * time.Hour
+
+var (
+	ErrCannotModifySelf = errors.New("admins cannot change their own role or status")
+	ErrLastAdmin        = errors.New("cannot demote or disable the last active admin")
+)
+
+func recordAudit(db execer, userID, actorID, action, oldValue, newValue string) error {
+	_, err := db.Exec(`
+        INSERT INTO user_audit_log (user_id, actor_id, action, old_value, new_value)
+        VALUES ($1, $2, $3, $4, $5)
+    `, userID, actorID, action, oldValue, newValue)
+	return err
+}
+
+// lockActiveAdmins locks the rows of every enabled admin and returns their IDs.
+// Callers that may remove an admin take this lock before locking the target
+// user, so concurrent changes cannot both remove what each sees as the last one.
+func lockActiveAdmins(tx *sql.Tx) ([]string, error) {
+	rows, err := tx.Query("SELECT id FROM users WHERE role = $1 AND NOT disabled ORDER BY id FOR UPDATE", auth.RoleAdmin)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	admins := []string{}
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		admins = append(admins, id)
+	}
+	return admins, rows.Err()
+}
+
+func isLastAdmin(admins []string, userID string) bool {
+	return len(admins) == 1 && admins[0] == userID
+}
+
+// InviteUser creates a staff account with the given role on behalf of actorID.
+// The account has no usable password; the returned token lets the invitee
+// set one through the password reset flow.
+func (us *UserService) InviteUser(actorID, name, email, role string) (*User, string, error) {
+	email, err := ValidateEmail(email)
+	if err != nil {
+		return nil, "", err
+	}
+
+	tx, err := us.db.Begin()
+	if err != nil {
+		return nil, "", err
+	}
+	defer tx.Rollback()
+
+	var taken bool
+	if err := tx.QueryRow("SELECT EXISTS (SELECT 1 FROM users WHERE email = $1)", email).Scan(&taken); err != nil {
+		return nil, "", err
+	}
+	if taken {
+		return nil, "", ErrEmailTaken
+	}
+
+	placeholder, err := randomToken()
+	if err != nil {
+		return nil, "", err
+	}
+	hashed, err := bcrypt.GenerateFromPassword([]byte(placeholder), bcrypt.DefaultCost)
+	if err != nil {
+		return nil, "", err
+	}
+
+	user := &User{ID: uuid.NewString(), Name: name, Email: email, Role: role}
+	_, err = tx.Exec("INSERT INTO users (id, name, email, password, role, bio) VALUES ($1, $2, $3, $4, $5, '')",
+		user.ID, user.Name, user.Email, string(hashed), user.Role)
+	if err != nil {
+		return nil, "", err
+	}
+
+	token, err := insertPasswordReset(tx, user.ID, inviteTTL)
+	if err != nil {
+		return nil, "", err
+	}
+	if err := recordAudit(tx, user.ID, actorID, AuditInvite, "", role); err != nil {
+		return nil, "", err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, "", err
+	}
+	return user, token, nil
+}
+
+// ChangeRole promotes or demotes a user on behalf of actorID. Tokens already
+// issued keep the old role until they are refreshed.
+func (us *UserService) ChangeRole(actorID, userID, role string) (*User, error) {
+	if actorID == userID {
+		return nil, ErrCannotModifySelf
+	}
+
+	tx, err := us.db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	var admins []string
+	if role != auth.RoleAdmin {
+		if admins, err = lockActiveAdmins(tx); err != nil {
+			return nil, err
+		}
+	}
+
+	var oldRole string
+	if err := tx.QueryRow("SELECT role FROM users WHERE id = $1 FOR UPDATE", userID).Scan(&oldRole); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, ErrUserNotFound
+		}
+		return nil, err
+	}
+	if isLastAdmin(admins, userID) {
+		return nil, ErrLastAdmin
+	}
+
+	if oldRole != role {
+		if _, err := tx.Exec("UPDATE users SET role = $2, version = version + 1, updated_at = NOW() WHERE id = $1", userID, role); err != nil {
+			return nil, err
+		}
+		if err := recordAudit(tx, userID, actorID, AuditRoleChange, oldRole, role); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	return us.GetUserByID(userID)
+}
+
+// SetDisabled disables or re-enables a user's account on behalf of actorID
+func (us *UserService) SetDisabled(actorID, userID string, disabled bool) (*User, error) {
+	if actorID == userID {
+		return nil, ErrCannotModifySelf
+	}
+
+	tx, err := us.db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	var admins []string
+	if disabled {
+		if admins, err = lockActiveAdmins(tx); err != nil {
+			return nil, err
+		}
+	}
+
+	var wasDisabled bool
+	if err := tx.QueryRow("SELECT disabled FROM users WHERE id = $1 FOR UPDATE", userID).Scan(&wasDisabled); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, ErrUserNotFound
+		}
+		return nil, err
+	}
+	if isLastAdmin(admins, userID) {
+		return nil, ErrLastAdmin
+	}
+
+	if wasDisabled != disabled {
+		_, err := tx.Exec(`
+            UPDATE users
+            SET disabled = $2, disabled_at = CASE WHEN $2 THEN NOW() END, updated_at = NOW()
+            WHERE id = $1
+        `, userID, disabled)
+		if err != nil {
+			return nil, err
+		}
+
+		action := AuditEnable
+		if disabled {
+			action = AuditDisable
+		}
+		if err := recordAudit(tx, userID, actorID, action, "", ""); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	return us.GetUserByID(userID)
+}
+
+// SelfRegisteredManagers returns the managers whose role was never granted by
+// an admin. Before signup was restricted to customers, anyone could register
+// as a manager.
+func (us *UserService) SelfRegisteredManagers() ([]User, error) {
+	rows, err := us.db.Query(`
+        SELECT id, name, email, role, disabled, created_at
+        FROM users u
+        WHERE role = $1 AND NOT EXISTS (
+            SELECT 1 FROM user_audit_log a
+            WHERE a.user_id = u.id AND a.action IN ($2, $3) AND a.new_value = $1
+        )
+        ORDER BY created_at
+    `, auth.RoleManager, AuditInvite, AuditRoleChange)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	managers := []User{}
+	for rows.Next() {
+		var u User
+		if err := rows.Scan(&u.ID, &u.Name, &u.Email, &u.Role, &u.Disabled, &u.CreatedAt); err != nil {
+			return nil, err
+		}
+		managers = append(managers, u)
+	}
+	return managers, rows.Err()
+}
+
+// GetAuditLog returns the audited changes to a user's account, newest first
+func (us *UserService) GetAuditLog(userID string) ([]AuditEntry, error) {
+	rows, err := us.db.Query(`
+        SELECT id, user_id, actor_id, action, old_value, new_value, created_at
+        FROM user_audit_log
+        WHERE user_id = $1
+        ORDER BY created_at DESC, id DESC
+    `, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	entries := []AuditEntry{}
+	for rows.Next() {
+		var e AuditEntry
+		if err := rows.Scan(&e.ID, &e.UserID, &e.ActorID, &e.Action, &e.OldValue, &e.NewValue, &e.CreatedAt); err != nil {
+			return nil, err
+		}
+		entries = append(entries, e)
+	}
+	return entries, rows.Err()
+}
@@ -0,0 +1,23 @@
+package users
+
+import "time"
+
+// Audited account changes
+const (
+	AuditInvite     = "invite"
+	AuditRoleChange = "role_change"
+	AuditDisable    = "disable"
+	AuditEnable     = "enable"
+	AuditErasure    = "erasure"
+)
+
+// AuditEntry records who changed an account, what changed and when
+type AuditEntry struct {
+	ID        int64
+	UserID    string
+	ActorID   string
+	Action    string
+	OldValue  string
+	NewValue  string
+	CreatedAt time.Time
+}
@@ -0,0 +1,67 @@
+package users
+
+import (
+	"database/sql"
+	"errors"
+	"time"
+)
+
+// VerificationResendInterval is the minimum time between two verification emails to the same account
+const VerificationResendInterval = time.Minute
+
+var (
+	ErrAlreadyVerified       = errors.New("email address is already verified")
+	ErrVerificationThrottled = errors.New("a verification email was sent recently, please wait before requesting another")
+	ErrStaleVerification     = errors.New("verification link does not match the account's current email address")
+)
+
+// ReserveVerificationEmail records that a verification email is about to be
+// sent to the user, refusing if one went out less than
+// VerificationResendInterval ago
+func (us *UserService) ReserveVerificationEmail(userID string) (*User, error) {
+	var user User
+	err := us.db.QueryRow(`
+        UPDATE users SET verification_sent_at = NOW()
+        WHERE id = $1 AND NOT email_verified
+          AND (verification_sent_at IS NULL OR verification_sent_at < $2)
+        RETURNING id, name, email
+    `, userID, time.Now().Add(-VerificationResendInterval)).Scan(&user.ID, &user.Name, &user.Email)
+	if err == nil {
+		return &user, nil
+	}
+	if err != sql.ErrNoRows {
+		return nil, err
+	}
+
+	// Work out why nothing was updated
+	existing, err := us.GetUserByID(userID)
+	if err != nil {
+		return nil, err
+	}
+	if existing.EmailVerified {
+		return nil, ErrAlreadyVerified
+	}
+	return nil, ErrVerificationThrottled
+}
+
+// MarkEmailVerified verifies the user's email, provided it is still the
+// address the verification link was sent to
+func (us *UserService) MarkEmailVerified(userID, email string) error {
+	result, err := us.db.Exec(`
+        UPDATE users
+        SET email_verified = TRUE, email_verified_at = COALESCE(email_verified_at, NOW())
+        WHERE id = $1 AND email = $2
+    `, userID, email)
+	if err != nil {
+		return err
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rows == 0 {
+		return ErrStaleVerification
+	}
+	return nil
+}
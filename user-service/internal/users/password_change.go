@@ -0,0 +1,99 @@
+package users
+
+import (
+	"database/sql"
+	"errors"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+var ErrIncorrectPassword = errors.New("current password is incorrect")
+
+// ChangePassword replaces the user's password after checking the current one
+func (us *UserService) ChangePassword(userID, currentPassword, newPassword string) error {
+	tx, err := us.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	var hashed string
+	if err := tx.QueryRow("SELECT password FROM users WHERE id = $1", userID).Scan(&hashed); err != nil {
+		if err == sql.ErrNoRows {
+			return ErrUserNotFound
+		}
+		return err
+	}
+	if bcrypt.CompareHashAndPassword([]byte(hashed), []byte(currentPassword)) != nil {
+		return ErrIncorrectPassword
+	}
+
+	if err := us.setPassword(tx, userID, newPassword); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// setPassword checks newPassword against the policy and the user's recent
+// passwords, stores it and moves the old hash into the history
+func (us *UserService) setPassword(tx *sql.Tx, userID, newPassword string) error {
+	var name, email, current string
+	err := tx.QueryRow("SELECT name, email, password FROM users WHERE id = $1 FOR UPDATE", userID).Scan(&name, &email, &current)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return ErrUserNotFound
+		}
+		return err
+	}
+	if err := us.policy.Validate(newPassword, name, email); err != nil {
+		return err
+	}
+
+	recent := []string{current}
+	if us.policy.HistorySize > 0 {
+		rows, err := tx.Query(`
+            SELECT password_hash FROM password_history
+            WHERE user_id = $1
+            ORDER BY created_at DESC, id DESC
+            LIMIT $2
+        `, userID, us.policy.HistorySize)
+		if err != nil {
+			return err
+		}
+		defer rows.Close()
+		for rows.Next() {
+			var hash string
+			if err := rows.Scan(&hash); err != nil {
+				return err
+			}
+			recent = append(recent, hash)
+		}
+		if err := rows.Err(); err != nil {
+			return err
+		}
+	}
+	if err := us.policy.CheckReuse(newPassword, recent); err != nil {
+		return err
+	}
+
+	hashed, err := bcrypt.GenerateFromPassword([]byte(newPassword), bcrypt.DefaultCost)
+	if err != nil {
+		return err
+	}
+	if _, err := tx.Exec("UPDATE users SET password = $2, updated_at = NOW() WHERE id = $1", userID, string(hashed)); err != nil {
+		return err
+	}
+
+	if _, err := tx.Exec("INSERT INTO password_history (user_id, password_hash) VALUES ($1, $2)", userID, current); err != nil {
+		return err
+	}
+	_, err = tx.Exec(`
+        DELETE FROM password_history
+        WHERE user_id = $1 AND id NOT IN (
+            SELECT id FROM password_history WHERE user_id = $1
+            ORDER BY created_at DESC, id DESC
+            LIMIT $2
+        )
+    `, userID, us.policy.HistorySize)
+	return err
+}
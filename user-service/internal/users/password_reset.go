@@ -0,0 +1,108 @@
+package users
+
+import (
+	"database/sql"
+	"errors"
+	"time"
+)
+
+const passwordResetTTL = time.Hour
+
+var ErrInvalidResetToken = errors.New("password reset link is invalid or expired")
+
+// CreatePasswordReset issues a single-use reset token for the account with
+// the given email. Only a hash of the token is stored.
+func (us *UserService) CreatePasswordReset(email string) (*User, string, error) {
+	var user User
+	err := us.db.QueryRow("SELECT id, name, email FROM users WHERE email = $1", NormalizeEmail(email)).Scan(&user.ID, &user.Name, &user.Email)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, "", ErrUserNotFound
+		}
+		return nil, "", err
+	}
+
+	token, err := insertPasswordReset(us.db, user.ID, passwordResetTTL)
+	if err != nil {
+		return nil, "", err
+	}
+	return &user, token, nil
+}
+
+type execer interface {
+	Exec(query string, args ...interface{}) (sql.Result, error)
+}
+
+// NewResetToken returns a random reset token and the hash it is stored under
+func NewResetToken() (token, hash string, err error) {
+	token, err = randomToken()
+	if err != nil {
+		return "", "", err
+	}
+	return token, HashResetToken(token), nil
+}
+
+// HashResetToken returns the hash a reset token is looked up by
+func HashResetToken(token string) string {
+	return hashToken(token)
+}
+
+func insertPasswordReset(db execer, userID string, ttl time.Duration) (string, error) {
+	token, hash, err := NewResetToken()
+	if err != nil {
+		return "", err
+	}
+
+	_, err = db.Exec(`
+        INSERT INTO password_resets (token_hash, user_id, expires_at)
+        VALUES ($1, $2, $3)
+    `, hash, userID, time.Now().Add(ttl))
+	if err != nil {
+		return "", err
+	}
+	return token, nil
+}
+
+// ResetPassword consumes a reset token and sets the user's new password. All
+// other outstanding reset tokens of the user are invalidated. Since the link
+// was delivered by email, completing it also verifies the address.
+func (us *UserService) ResetPassword(token, newPassword string) (string, error) {
+	tx, err := us.db.Begin()
+	if err != nil {
+		return "", err
+	}
+	defer tx.Rollback()
+
+	var userID string
+	err = tx.QueryRow(`
+        UPDATE password_resets SET used_at = NOW()
+        WHERE token_hash = $1 AND used_at IS NULL AND expires_at > NOW()
+        RETURNING user_id
+    `, HashResetToken(token)).Scan(&userID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return "", ErrInvalidResetToken
+		}
+		return "", err
+	}
+
+	// A rejected password rolls back, so the link can be used again
+	if err := us.setPassword(tx, userID, newPassword); err != nil {
+		return "", err
+	}
+	if _, err := tx.Exec(`
+        UPDATE users
+        SET email_verified = TRUE, email_verified_at = COALESCE(email_verified_at, NOW())
+        WHERE id = $1
+    `, userID); err != nil {
+		return "", err
+	}
+	if _, err := tx.Exec("DELETE FROM password_resets WHERE user_id = $1 AND used_at IS NULL", userID); err != nil {
+		return "", err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return "", err
+	}
+	return userID, nil
+}
@@ -0,0 +1,108 @@
+package users
+
+import (
+	"encoding/json"
+	"errors"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+const (
+	maxNameLength        = 100
+	maxBioLength         = 500
+	maxAvatarURLLength   = 2048
+	maxPreferencesLength = 4096
+)
+
+var phonePattern = regexp.MustCompile(`^\+[1-9][0-9]{6,14}$`)
+
+// Profile is the part of a user account the user can read and edit
+type Profile struct {
+	ID            string
+	Name          string
+	Email         string
+	PendingEmail  string
+	EmailVerified bool
+	Role          string
+	Bio           string
+	Phone         string
+	AvatarURL     string
+	Preferences   json.RawMessage
+	Version       int
+	UpdatedAt     time.Time
+}
+
+// ETag identifies the profile version for optimistic concurrency
+func (p *Profile) ETag() string {
+	return strconv.Quote(strconv.Itoa(p.Version))
+}
+
+// ParseETag returns the profile version an If-Match entity tag refers to.
+// Weak tags (W/"3") are accepted since a version fully identifies a profile.
+func ParseETag(tag string) (int, bool) {
+	tag = strings.TrimPrefix(strings.TrimSpace(tag), "W/")
+	unquoted, err := strconv.Unquote(tag)
+	if err != nil {
+		return 0, false
+	}
+	version, err := strconv.Atoi(unquoted)
+	if err != nil || version < 0 {
+		return 0, false
+	}
+	return version, true
+}
+
+// ProfileUpdate replaces the editable fields of a profile
+type ProfileUpdate struct {
+	Name        string
+	Email       string
+	Bio         string
+	Phone       string
+	AvatarURL   string
+	Preferences json.RawMessage
+}
+
+// Validate normalizes the update and checks every field
+func (u *ProfileUpdate) Validate() error {
+	u.Name = strings.TrimSpace(u.Name)
+	u.Phone = strings.TrimSpace(u.Phone)
+	u.AvatarURL = strings.TrimSpace(u.AvatarURL)
+
+	if u.Name == "" || utf8.RuneCountInString(u.Name) > maxNameLength {
+		return errors.New("name is required and must be at most 100 characters")
+	}
+	email, err := ValidateEmail(u.Email)
+	if err != nil {
+		return err
+	}
+	u.Email = email
+	if utf8.RuneCountInString(u.Bio) > maxBioLength {
+		return errors.New("bio must be at most 500 characters")
+	}
+	if u.Phone != "" && !phonePattern.MatchString(u.Phone) {
+		return errors.New("phone must be in international format, e.g. +201001234567")
+	}
+	if u.AvatarURL != "" {
+		parsed, err := url.Parse(u.AvatarURL)
+		if err != nil || (parsed.Scheme != "https" && parsed.Scheme != "http") || parsed.Host == "" || len(u.AvatarURL) > maxAvatarURLLength {
+			return errors.New("avatar_url must be an http(s) URL")
+		}
+	}
+
+	if len(u.Preferences) == 0 || string(u.Preferences) == "null" {
+		u.Preferences = json.RawMessage("{}")
+	}
+	var prefs map[string]interface{}
+	if err := json.Unmarshal(u.Preferences, &prefs); err != nil {
+		return errors.New("preferences must be a JSON object")
+	}
+	if len(u.Preferences) > maxPreferencesLength {
+		return errors.New("preferences must be at most 4096 bytes")
+	}
+
+	return nil
+}
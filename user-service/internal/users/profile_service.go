@@ -0,0 +1,187 @@
+package users
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"time"
+)
+
+const emailChangeTTL = 24 * time.Hour
+
+var (
+	ErrVersionMismatch    = errors.New("profile was modified by another request")
+	ErrEmailTaken         = errors.New("email is already in use")
+	ErrInvalidEmailChange = errors.New("email change link is invalid or expired")
+)
+
+const profileColumns = `id, name, email, COALESCE(pending_email, ''), email_verified, role, COALESCE(bio, ''),
+        COALESCE(phone, ''), COALESCE(avatar_url, ''), preferences, version, updated_at`
+
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+func scanProfile(row rowScanner) (*Profile, error) {
+	var p Profile
+	var prefs []byte
+	err := row.Scan(&p.ID, &p.Name, &p.Email, &p.PendingEmail, &p.EmailVerified, &p.Role, &p.Bio,
+		&p.Phone, &p.AvatarURL, &prefs, &p.Version, &p.UpdatedAt)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, ErrUserNotFound
+		}
+		return nil, err
+	}
+	p.Preferences = prefs
+	return &p, nil
+}
+
+// GetProfile returns the profile of the user
+func (us *UserService) GetProfile(userID string) (*Profile, error) {
+	return scanProfile(us.db.QueryRow("SELECT "+profileColumns+" FROM users WHERE id = $1", userID))
+}
+
+// UpdateProfile replaces the editable profile fields if the stored version
+// still equals version. A new email address is not applied directly: it is
+// kept as pending and the returned token must be passed to
+// ConfirmEmailChange. The token is empty when the email did not change.
+func (us *UserService) UpdateProfile(userID string, version int, update ProfileUpdate) (*Profile, string, error) {
+	tx, err := us.db.Begin()
+	if err != nil {
+		return nil, "", err
+	}
+	defer tx.Rollback()
+
+	var currentEmail string
+	var currentVersion int
+	err = tx.QueryRow("SELECT email, version FROM users WHERE id = $1 FOR UPDATE", userID).Scan(&currentEmail, &currentVersion)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, "", ErrUserNotFound
+		}
+		return nil, "", err
+	}
+	if currentVersion != version {
+		return nil, "", ErrVersionMismatch
+	}
+
+	var token string
+	if update.Email != NormalizeEmail(currentEmail) {
+		if token, err = requestEmailChange(tx, userID, update.Email); err != nil {
+			return nil, "", err
+		}
+	}
+
+	profile, err := scanProfile(tx.QueryRow(`
+        UPDATE users
+        SET name = $2, bio = $3, phone = NULLIF($4, ''), avatar_url = NULLIF($5, ''),
+            preferences = $6, version = version + 1, updated_at = NOW()
+        WHERE id = $1
+        RETURNING `+profileColumns,
+		userID, update.Name, update.Bio, update.Phone, update.AvatarURL, []byte(update.Preferences)))
+	if err != nil {
+		return nil, "", err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, "", err
+	}
+	return profile, token, nil
+}
+
+// requestEmailChange records newEmail as pending and returns the token that confirms it.
+// Earlier unconfirmed changes are discarded.
+func requestEmailChange(tx *sql.Tx, userID, newEmail string) (string, error) {
+	var taken bool
+	err := tx.QueryRow("SELECT EXISTS (SELECT 1 FROM users WHERE email = $1 AND id <> $2)", newEmail, userID).Scan(&taken)
+	if err != nil {
+		return "", err
+	}
+	if taken {
+		return "", ErrEmailTaken
+	}
+
+	token, err := randomToken()
+	if err != nil {
+		return "", err
+	}
+
+	if _, err := tx.Exec("DELETE FROM email_changes WHERE user_id = $1", userID); err != nil {
+		return "", err
+	}
+	_, err = tx.Exec(`
+        INSERT INTO email_changes (token_hash, user_id, new_email, expires_at)
+        VALUES ($1, $2, $3, $4)
+    `, hashToken(token), userID, newEmail, time.Now().Add(emailChangeTTL))
+	if err != nil {
+		return "", err
+	}
+
+	if _, err := tx.Exec("UPDATE users SET pending_email = $2 WHERE id = $1", userID, newEmail); err != nil {
+		return "", err
+	}
+	return token, nil
+}
+
+// ConfirmEmailChange applies the pending email change identified by token
+func (us *UserService) ConfirmEmailChange(token string) (*Profile, error) {
+	tx, err := us.db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	var userID, newEmail string
+	err = tx.QueryRow(`
+        DELETE FROM email_changes
+        WHERE token_hash = $1 AND expires_at > NOW()
+        RETURNING user_id, new_email
+    `, hashToken(token)).Scan(&userID, &newEmail)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, ErrInvalidEmailChange
+		}
+		return nil, err
+	}
+
+	// The address may have been claimed since the change was requested
+	var taken bool
+	err = tx.QueryRow("SELECT EXISTS (SELECT 1 FROM users WHERE email = $1 AND id <> $2)", newEmail, userID).Scan(&taken)
+	if err != nil {
+		return nil, err
+	}
+	if taken {
+		return nil, ErrEmailTaken
+	}
+
+	profile, err := scanProfile(tx.QueryRow(`
+        UPDATE users
+        SET email = $2, pending_email = NULL, email_verified = TRUE, email_verified_at = NOW(),
+            version = version + 1, updated_at = NOW()
+        WHERE id = $1
+        RETURNING `+profileColumns, userID, newEmail))
+	if err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	return profile, nil
+}
+
+func randomToken() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
+
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
@@ -1,10 +1,36 @@
 package users
 
+import (
+	"errors"
+	"net/mail"
+	"strings"
+	"time"
+)
+
+var ErrInvalidEmail = errors.New("email must be a valid email address")
+
 type User struct {
-	ID       string
-	Name     string
-	Email    string
-	Password string
-	Bio      string
-	Role     string
+	ID            string
+	Name          string
+	Email         string
+	Password      string
+	Bio           string
+	Role          string
+	EmailVerified bool
+	Disabled      bool
+	CreatedAt     time.Time
+}
+
+// NormalizeEmail returns the form email addresses are stored and looked up in
+func NormalizeEmail(email string) string {
+	return strings.ToLower(strings.TrimSpace(email))
+}
+
+// ValidateEmail normalizes email and checks that it is a bare address
+func ValidateEmail(email string) (string, error) {
+	email = NormalizeEmail(email)
+	if addr, err := mail.ParseAddress(email); err != nil || addr.Address != email {
+		return "", ErrInvalidEmail
+	}
+	return email, nil
 }
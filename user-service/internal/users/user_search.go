@@ -0,0 +1,133 @@
+package users
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Page sizes for ListUsers
+const (
+	DefaultPageSize = 20
+	MaxPageSize     = 100
+)
+
+var ErrInvalidCursor = errors.New("invalid cursor")
+
+// UserFilter narrows ListUsers. Zero values leave a criterion unset.
+type UserFilter struct {
+	Role          string
+	Search        string
+	CreatedAfter  time.Time
+	CreatedBefore time.Time
+	EmailVerified *bool
+	Disabled      *bool
+}
+
+// userCursor marks the last user of a page. Pages are ordered newest first.
+type userCursor struct {
+	CreatedAt time.Time `json:"t"`
+	ID        string    `json:"id"`
+}
+
+func encodeCursor(user User) string {
+	data, _ := json.Marshal(userCursor{CreatedAt: user.CreatedAt, ID: user.ID})
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+func decodeCursor(cursor string) (*userCursor, error) {
+	data, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+	var c userCursor
+	if err := json.Unmarshal(data, &c); err != nil || c.ID == "" {
+		return nil, ErrInvalidCursor
+	}
+	return &c, nil
+}
+
+// escapeLike escapes the LIKE wildcards in s so it is matched literally
+func escapeLike(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(s)
+}
+
+// ListUsers returns up to limit users matching filter, newest first, starting after cursor.
+// The returned cursor is empty on the last page.
+func (us *UserService) ListUsers(filter UserFilter, cursor string, limit int) ([]User, string, error) {
+	if limit <= 0 {
+		limit = DefaultPageSize
+	}
+	if limit > MaxPageSize {
+		limit = MaxPageSize
+	}
+
+	var conditions []string
+	var args []interface{}
+	arg := func(v interface{}) string {
+		args = append(args, v)
+		return fmt.Sprintf("$%d", len(args))
+	}
+
+	if filter.Role != "" {
+		conditions = append(conditions, "role = "+arg(filter.Role))
+	}
+	if search := strings.TrimSpace(filter.Search); search != "" {
+		pattern := arg("%" + escapeLike(search) + "%")
+		conditions = append(conditions, fmt.Sprintf("(name ILIKE %s OR email ILIKE %s)", pattern, pattern))
+	}
+	if !filter.CreatedAfter.IsZero() {
+		conditions = append(conditions, "created_at >= "+arg(filter.CreatedAfter))
+	}
+	if !filter.CreatedBefore.IsZero() {
+		conditions = append(conditions, "created_at < "+arg(filter.CreatedBefore))
+	}
+	if filter.EmailVerified != nil {
+		conditions = append(conditions, "email_verified = "+arg(*filter.EmailVerified))
+	}
+	if filter.Disabled != nil {
+		conditions = append(conditions, "disabled = "+arg(*filter.Disabled))
+	}
+	if cursor != "" {
+		after, err := decodeCursor(cursor)
+		if err != nil {
+			return nil, "", err
+		}
+		conditions = append(conditions, fmt.Sprintf("(created_at, id) < (%s, %s)", arg(after.CreatedAt), arg(after.ID)))
+	}
+
+	query := "SELECT id, name, email, role, COALESCE(bio, ''), email_verified, disabled, created_at FROM users"
+	if len(conditions) > 0 {
+		query += " WHERE " + strings.Join(conditions, " AND ")
+	}
+	// Fetch one extra row to know whether another page follows
+	query += " ORDER BY created_at DESC, id DESC LIMIT " + arg(limit+1)
+
+	rows, err := us.db.Query(query, args...)
+	if err != nil {
+		return nil, "", err
+	}
+	defer rows.Close()
+
+	list := []User{}
+	for rows.Next() {
+		var user User
+		if err := rows.Scan(&user.ID, &user.Name, &user.Email, &user.Role, &user.Bio, &user.EmailVerified, &user.Disabled, &user.CreatedAt); err != nil {
+			return nil, "", err
+		}
+		list = append(list, user)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, "", err
+	}
+
+	next := ""
+	if len(list) > limit {
+		list = list[:limit]
+		next = encodeCursor(list[limit-1])
+	}
+	return list, next, nil
+}
@@ -4,28 +4,53 @@ import (
 	"database/sql"
 	"errors"
 	"log"
+	"sync"
 
+	"github.com/darkhyper24/blaban/user-service/internal/passwords"
 	"github.com/google/uuid"
 	"golang.org/x/crypto/bcrypt"
 )
 
+var (
+	dummyHashOnce sync.Once
+	dummyHash     []byte
+)
+
+// dummyPasswordHash is compared against when no account matches a login
+func dummyPasswordHash() []byte {
+	dummyHashOnce.Do(func() {
+		dummyHash, _ = bcrypt.GenerateFromPassword([]byte("blaban-dummy-password"), bcrypt.DefaultCost)
+	})
+	return dummyHash
+}
+
 type UserService struct {
-	db *sql.DB
+	db     *sql.DB
+	policy passwords.Policy
 }
 
-func NewUserService(db *sql.DB) *UserService {
-	return &UserService{db: db}
+func NewUserService(db *sql.DB, policy passwords.Policy) *UserService {
+	dummyPasswordHash()
+	return &UserService{db: db, policy: policy}
 }
 
 func (us *UserService) RegisterUser(name, email, password string, role string, bio string) (*User, error) {
+	email, err := ValidateEmail(email)
+	if err != nil {
+		return nil, err
+	}
+
 	var count int
-	err := us.db.QueryRow("SELECT COUNT(*) FROM users WHERE email = $1", email).Scan(&count)
+	err = us.db.QueryRow("SELECT COUNT(*) FROM users WHERE email = $1", email).Scan(&count)
 	if err != nil {
 		return nil, err
 	}
 	if count > 0 {
 		return nil, errors.New("user already exists")
 	}
+	if err := us.policy.Validate(password, name, email); err != nil {
+		return nil, err
+	}
 
 	hashed, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
 	if err != nil {
@@ -49,26 +74,65 @@ func (us *UserService) RegisterUser(name, email, password string, role string, b
 }
 
 func (us *UserService) LoginUser(email, password string) (*User, error) {
-	row := us.db.QueryRow("SELECT id, name, password, role, bio FROM users WHERE email = $1", email)
+	email = NormalizeEmail(email)
+	row := us.db.QueryRow("SELECT id, name, password, role, COALESCE(bio, ''), email_verified, disabled FROM users WHERE email = $1", email)
 	var id, name, hashedPwd, role, bio string
-	if err := row.Scan(&id, &name, &hashedPwd, &role, &bio); err != nil {
-		if err == sql.ErrNoRows {
-			return nil, errors.New("invalid email or password")
+	var emailVerified, disabled bool
+	if err := row.Scan(&id, &name, &hashedPwd, &role, &bio, &emailVerified, &disabled); err != nil {
+		if err != sql.ErrNoRows {
+			log.Printf("Database error during login: %v", err)
 		}
-		log.Printf("Database error during login: %v", err)
+		// Spend the same bcrypt time as for a real account so response times
+		// do not reveal whether the email is registered
+		bcrypt.CompareHashAndPassword(dummyPasswordHash(), []byte(password))
 		return nil, errors.New("invalid email or password")
 	}
 
 	if err := bcrypt.CompareHashAndPassword([]byte(hashedPwd), []byte(password)); err != nil {
 		return nil, errors.New("invalid email or password")
 	}
+	if disabled {
+		return nil, ErrAccountDisabled
+	}
 
 	return &User{
-		ID:       id,
-		Name:     name,
-		Email:    email,
-		Password: hashedPwd,
-		Role:     role,
-		Bio:      bio,
+		ID:            id,
+		Name:          name,
+		Email:         email,
+		Password:      hashedPwd,
+		Role:          role,
+		Bio:           bio,
+		EmailVerified: emailVerified,
 	}, nil
 }
+
+var (
+	ErrUserNotFound    = errors.New("user not found")
+	ErrAccountDisabled = errors.New("account is disabled")
+)
+
+func (us *UserService) GetUserByID(id string) (*User, error) {
+	row := us.db.QueryRow("SELECT id, name, email, role, COALESCE(bio, ''), email_verified, disabled, created_at FROM users WHERE id = $1", id)
+	var user User
+	if err := row.Scan(&user.ID, &user.Name, &user.Email, &user.Role, &user.Bio, &user.EmailVerified, &user.Disabled, &user.CreatedAt); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, ErrUserNotFound
+		}
+		return nil, err
+	}
+	return &user, nil
+}
+
+// GetUserByEmail returns the account registered with email
+func (us *UserService) GetUserByEmail(email string) (*User, error) {
+	email = NormalizeEmail(email)
+	row := us.db.QueryRow("SELECT id, name, email, role, COALESCE(bio, ''), email_verified, disabled FROM users WHERE email = $1", email)
+	var user User
+	if err := row.Scan(&user.ID, &user.Name, &user.Email, &user.Role, &user.Bio, &user.EmailVerified, &user.Disabled); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, ErrUserNotFound
+		}
+		return nil, err
+	}
+	return &user, nil
+}
@@ -0,0 +1,9 @@
+DROP TABLE IF EXISTS email_changes;
+
+ALTER TABLE users
+    DROP COLUMN IF EXISTS updated_at,
+    DROP COLUMN IF EXISTS version,
+    DROP COLUMN IF EXISTS pending_email,
+    DROP COLUMN IF EXISTS preferences,
+    DROP COLUMN IF EXISTS avatar_url,
+    DROP COLUMN IF EXISTS phone;
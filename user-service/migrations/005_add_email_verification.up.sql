@@ -0,0 +1,7 @@
+ALTER TABLE users
+    ADD COLUMN IF NOT EXISTS email_verified BOOLEAN NOT NULL DEFAULT FALSE,
+    ADD COLUMN IF NOT EXISTS email_verified_at TIMESTAMP,
+    ADD COLUMN IF NOT EXISTS verification_sent_at TIMESTAMP;
+
+-- Accounts created before verification existed keep working
+UPDATE users SET email_verified = TRUE, email_verified_at = NOW() WHERE email_verified_at IS NULL;
@@ -0,0 +1,6 @@
+DROP INDEX IF EXISTS idx_users_email_trgm;
+DROP INDEX IF EXISTS idx_users_name_trgm;
+DROP INDEX IF EXISTS idx_users_created_at_id;
+
+ALTER TABLE users
+    DROP COLUMN IF EXISTS created_at;
@@ -0,0 +1,12 @@
+CREATE TABLE IF NOT EXISTS account_erasures (
+    user_id TEXT NOT NULL,
+    service TEXT NOT NULL,
+    status TEXT NOT NULL DEFAULT 'pending',
+    deleted BIGINT NOT NULL DEFAULT 0,
+    anonymised BIGINT NOT NULL DEFAULT 0,
+    detail TEXT NOT NULL DEFAULT '',
+    updated_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (user_id, service)
+);
+
+CREATE INDEX IF NOT EXISTS idx_account_erasures_pending ON account_erasures(status) WHERE status <> 'done';
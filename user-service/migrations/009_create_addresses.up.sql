@@ -0,0 +1,23 @@
+CREATE TABLE IF NOT EXISTS addresses (
+    id TEXT PRIMARY KEY,
+    user_id TEXT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    label TEXT NOT NULL,
+    recipient TEXT NOT NULL,
+    phone TEXT NOT NULL DEFAULT '',
+    line1 TEXT NOT NULL,
+    line2 TEXT NOT NULL DEFAULT '',
+    city TEXT NOT NULL,
+    region TEXT NOT NULL DEFAULT '',
+    postal_code TEXT NOT NULL DEFAULT '',
+    country TEXT NOT NULL,
+    latitude DOUBLE PRECISION,
+    longitude DOUBLE PRECISION,
+    instructions TEXT NOT NULL DEFAULT '',
+    is_default BOOLEAN NOT NULL DEFAULT FALSE,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    CHECK ((latitude IS NULL) = (longitude IS NULL))
+);
+
+CREATE INDEX IF NOT EXISTS idx_addresses_user_id ON addresses(user_id, created_at);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_addresses_user_default ON addresses(user_id) WHERE is_default;
@@ -0,0 +1,10 @@
+-- Emails used to be stored as typed. Lowercase them so lookups by the
+-- normalized address find every account. Accounts whose lowercased email
+-- collides with another account are left for an admin to merge.
+UPDATE users u
+SET email = LOWER(TRIM(u.email))
+WHERE u.email <> LOWER(TRIM(u.email))
+  AND NOT EXISTS (
+      SELECT 1 FROM users o
+      WHERE o.id <> u.id AND LOWER(TRIM(o.email)) = LOWER(TRIM(u.email))
+  );
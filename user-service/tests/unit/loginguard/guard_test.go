@@ -0,0 +1,176 @@
+package loginguard
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/darkhyper24/blaban/user-service/internal/loginguard"
+	"github.com/go-redis/redis/v8"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var testConfig = loginguard.Config{
+	MaxAccountFailures: 3,
+	MaxIPFailures:      5,
+	FailureWindow:      15 * time.Minute,
+	LockoutDuration:    10 * time.Minute,
+	BaseDelay:          time.Second,
+	MaxDelay:           4 * time.Second,
+}
+
+func newGuard(t *testing.T, config loginguard.Config) (*loginguard.Guard, *miniredis.Miniredis) {
+	t.Helper()
+	mr := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	t.Cleanup(func() { client.Close() })
+	return loginguard.NewGuard(client, config), mr
+}
+
+func requireBlocked(t *testing.T, err error, reason string) *loginguard.BlockedError {
+	t.Helper()
+	var blocked *loginguard.BlockedError
+	require.ErrorAs(t, err, &blocked)
+	assert.Equal(t, reason, blocked.Reason)
+	return blocked
+}
+
+func TestCheckAllowsUnknownAccount(t *testing.T) {
+	guard, _ := newGuard(t, testConfig)
+	assert.NoError(t, guard.Check(context.Background(), "jane@example.com", "10.0.0.1"))
+}
+
+func TestRecordFailureCountsPerAccountAndIP(t *testing.T) {
+	ctx := context.Background()
+	guard, _ := newGuard(t, testConfig)
+
+	failure, err := guard.RecordFailure(ctx, "jane@example.com", "10.0.0.1")
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), failure.AccountFailures)
+	assert.Equal(t, int64(1), failure.IPFailures)
+
+	failure, err = guard.RecordFailure(ctx, "jane@example.com", "10.0.0.2")
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), failure.AccountFailures)
+	assert.Equal(t, int64(1), failure.IPFailures)
+
+	failure, err = guard.RecordFailure(ctx, "john@example.com", "10.0.0.1")
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), failure.AccountFailures)
+	assert.Equal(t, int64(2), failure.IPFailures)
+}
+
+func TestRecordFailureBacksOffExponentially(t *testing.T) {
+	ctx := context.Background()
+	config := testConfig
+	config.MaxAccountFailures = 100
+	guard, mr := newGuard(t, config)
+
+	for _, want := range []time.Duration{time.Second, 2 * time.Second, 4 * time.Second, 4 * time.Second} {
+		_, err := guard.RecordFailure(ctx, "jane@example.com", "10.0.0.1")
+		require.NoError(t, err)
+
+		blocked := requireBlocked(t, guard.Check(ctx, "jane@example.com", "10.0.0.1"), loginguard.ReasonAccountDelay)
+		assert.Equal(t, want, blocked.RetryAfter)
+
+		mr.FastForward(want)
+		assert.NoError(t, guard.Check(ctx, "jane@example.com", "10.0.0.1"))
+	}
+}
+
+func TestRecordFailureLocksAccount(t *testing.T) {
+	ctx := context.Background()
+	guard, mr := newGuard(t, testConfig)
+
+	var failure *loginguard.Failure
+	var err error
+	for i := 0; i < testConfig.MaxAccountFailures; i++ {
+		failure, err = guard.RecordFailure(ctx, "jane@example.com", "10.0.0.1")
+		require.NoError(t, err)
+	}
+	assert.True(t, failure.Locked)
+	assert.NotEmpty(t, failure.UnlockToken)
+
+	// Only the failure that locked the account carries an unlock token
+	again, err := guard.RecordFailure(ctx, "jane@example.com", "10.0.0.1")
+	require.NoError(t, err)
+	assert.False(t, again.Locked)
+	assert.Empty(t, again.UnlockToken)
+
+	mr.FastForward(testConfig.MaxDelay)
+	blocked := requireBlocked(t, guard.Check(ctx, "jane@example.com", "10.0.0.2"), loginguard.ReasonAccountLocked)
+	assert.Equal(t, testConfig.LockoutDuration-testConfig.MaxDelay, blocked.RetryAfter)
+
+	mr.FastForward(testConfig.LockoutDuration)
+	assert.NoError(t, guard.Check(ctx, "jane@example.com", "10.0.0.2"))
+}
+
+func TestUnlock(t *testing.T) {
+	ctx := context.Background()
+	guard, _ := newGuard(t, testConfig)
+
+	var failure *loginguard.Failure
+	var err error
+	for i := 0; i < testConfig.MaxAccountFailures; i++ {
+		failure, err = guard.RecordFailure(ctx, "jane@example.com", "10.0.0.1")
+		require.NoError(t, err)
+	}
+
+	email, err := guard.Unlock(ctx, failure.UnlockToken)
+	require.NoError(t, err)
+	assert.Equal(t, "jane@example.com", email)
+	assert.NoError(t, guard.Check(ctx, "jane@example.com", "10.0.0.1"))
+
+	_, err = guard.Unlock(ctx, failure.UnlockToken)
+	assert.ErrorIs(t, err, loginguard.ErrInvalidUnlockToken)
+}
+
+func TestCheckBlocksIPAcrossAccounts(t *testing.T) {
+	ctx := context.Background()
+	guard, mr := newGuard(t, testConfig)
+
+	for i := 0; i < testConfig.MaxIPFailures; i++ {
+		_, err := guard.RecordFailure(ctx, string(rune('a'+i))+"@example.com", "10.0.0.1")
+		require.NoError(t, err)
+	}
+
+	blocked := requireBlocked(t, guard.Check(ctx, "new@example.com", "10.0.0.1"), loginguard.ReasonIPBlocked)
+	assert.Equal(t, testConfig.FailureWindow, blocked.RetryAfter)
+	assert.NoError(t, guard.Check(ctx, "new@example.com", "10.0.0.2"))
+
+	mr.FastForward(testConfig.FailureWindow)
+	assert.NoError(t, guard.Check(ctx, "new@example.com", "10.0.0.1"))
+}
+
+func TestRecordSuccessClearsOnlyAccountFailures(t *testing.T) {
+	ctx := context.Background()
+	guard, _ := newGuard(t, testConfig)
+
+	for i := 0; i < 2; i++ {
+		_, err := guard.RecordFailure(ctx, "jane@example.com", "10.0.0.1")
+		require.NoError(t, err)
+	}
+	require.NoError(t, guard.RecordSuccess(ctx, "jane@example.com"))
+	assert.NoError(t, guard.Check(ctx, "jane@example.com", "10.0.0.1"))
+
+	failure, err := guard.RecordFailure(ctx, "jane@example.com", "10.0.0.1")
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), failure.AccountFailures)
+	assert.Equal(t, int64(3), failure.IPFailures)
+}
+
+func TestFailureCountersExpireAfterWindow(t *testing.T) {
+	ctx := context.Background()
+	guard, mr := newGuard(t, testConfig)
+
+	_, err := guard.RecordFailure(ctx, "jane@example.com", "10.0.0.1")
+	require.NoError(t, err)
+	mr.FastForward(testConfig.FailureWindow)
+
+	failure, err := guard.RecordFailure(ctx, "jane@example.com", "10.0.0.1")
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), failure.AccountFailures)
+	assert.Equal(t, int64(1), failure.IPFailures)
+}
@@ -0,0 +1,104 @@
+package passwords
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/darkhyper24/blaban/user-service/internal/passwords"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/bcrypt"
+)
+
+func TestPolicyValidate(t *testing.T) {
+	policy := passwords.DefaultPolicy()
+
+	tests := []struct {
+		name     string
+		password string
+		personal []string
+		wantErr  string
+	}{
+		{"Acceptable", "correct horse battery", nil, ""},
+		{"Too short", "s3cr3t!", nil, "password must be at least 8 characters"},
+		{"Counts characters, not bytes", "пароль12", nil, ""},
+		{"Too long for bcrypt", strings.Repeat("a", 73), nil, "password must be at most 72 bytes"},
+		{"Common", "password1", nil, "password is too common, choose a less predictable one"},
+		{"Common in another case", "QwertyUIOP", nil, "password is too common, choose a less predictable one"},
+		{"Name", "Jane Doe!", []string{"jane doe!", "jane@example.com"}, "password must not be your name or email address"},
+		{"Email", "Jane@Example.com", []string{"Jane", "jane@example.com"}, "password must not be your name or email address"},
+		{"Email local part", "janedoe123", []string{"Jane", " janedoe123@example.com "}, "password must not be your name or email address"},
+		{"Empty personal values are ignored", "correct horse battery", []string{"", "  "}, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := policy.Validate(tt.password, tt.personal...)
+			if tt.wantErr == "" {
+				assert.NoError(t, err)
+				return
+			}
+			var policyErr *passwords.PolicyError
+			require.ErrorAs(t, err, &policyErr)
+			assert.Equal(t, tt.wantErr, policyErr.Reason)
+		})
+	}
+}
+
+func TestPolicyValidateCommonPasswordsCanBeAllowed(t *testing.T) {
+	policy := passwords.DefaultPolicy()
+	policy.BlockCommon = false
+	assert.NoError(t, policy.Validate("password1"))
+}
+
+func TestPolicyCheckReuse(t *testing.T) {
+	policy := passwords.DefaultPolicy()
+	hash := func(password string) string {
+		h, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.MinCost)
+		require.NoError(t, err)
+		return string(h)
+	}
+	history := []string{hash("first password"), hash("second password")}
+
+	var policyErr *passwords.PolicyError
+	assert.ErrorAs(t, policy.CheckReuse("second password", history), &policyErr)
+	assert.NoError(t, policy.CheckReuse("third password", history))
+	assert.NoError(t, policy.CheckReuse("first password", nil))
+}
+
+func TestPolicyFromEnv(t *testing.T) {
+	tests := []struct {
+		name    string
+		env     map[string]string
+		want    passwords.Policy
+		wantErr string
+	}{
+		{"Defaults", nil, passwords.DefaultPolicy(), ""},
+		{
+			"Overrides",
+			map[string]string{"PASSWORD_MIN_LENGTH": "12", "PASSWORD_HISTORY_SIZE": "0", "PASSWORD_BLOCK_COMMON": "false"},
+			passwords.Policy{MinLength: 12, BlockCommon: false, HistorySize: 0},
+			"",
+		},
+		{"Minimum length below 8", map[string]string{"PASSWORD_MIN_LENGTH": "6"}, passwords.Policy{}, "PASSWORD_MIN_LENGTH must be an integer of at least 8"},
+		{"Minimum length above bcrypt limit", map[string]string{"PASSWORD_MIN_LENGTH": "80"}, passwords.Policy{}, "PASSWORD_MIN_LENGTH must be at most 72"},
+		{"Negative history", map[string]string{"PASSWORD_HISTORY_SIZE": "-1"}, passwords.Policy{}, "PASSWORD_HISTORY_SIZE must be an integer of at least 0"},
+		{"Invalid boolean", map[string]string{"PASSWORD_BLOCK_COMMON": "sometimes"}, passwords.Policy{}, "PASSWORD_BLOCK_COMMON must be true or false"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for _, name := range []string{"PASSWORD_MIN_LENGTH", "PASSWORD_HISTORY_SIZE", "PASSWORD_BLOCK_COMMON"} {
+				t.Setenv(name, tt.env[name])
+			}
+
+			policy, err := passwords.PolicyFromEnv()
+			if tt.wantErr != "" {
+				assert.EqualError(t, err, tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, policy)
+		})
+	}
+}
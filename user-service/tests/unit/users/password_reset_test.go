@@ -0,0 +1,66 @@
+package users
+
+import (
+	"encoding/hex"
+	"testing"
+
+	"github.com/darkhyper24/blaban/user-service/internal/users"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewResetToken(t *testing.T) {
+	token, hash, err := users.NewResetToken()
+	require.NoError(t, err)
+
+	raw, err := hex.DecodeString(token)
+	require.NoError(t, err)
+	assert.Len(t, raw, 32)
+
+	assert.Equal(t, users.HashResetToken(token), hash)
+	assert.NotEqual(t, token, hash, "only the hash may be stored")
+}
+
+func TestNewResetTokenIsUnique(t *testing.T) {
+	seen := make(map[string]bool)
+	for i := 0; i < 100; i++ {
+		token, _, err := users.NewResetToken()
+		require.NoError(t, err)
+		assert.False(t, seen[token], "token issued twice")
+		seen[token] = true
+	}
+}
+
+func TestHashResetToken(t *testing.T) {
+	token, hash, err := users.NewResetToken()
+	require.NoError(t, err)
+
+	assert.Equal(t, hash, users.HashResetToken(token), "hash must be stable between issuing and redeeming")
+	assert.Len(t, hash, 64)
+	assert.NotEqual(t, hash, users.HashResetToken(token[:len(token)-1]), "a truncated link must not match")
+	assert.NotEqual(t, hash, users.HashResetToken(""))
+}
+
+func TestValidateEmail(t *testing.T) {
+	tests := []struct {
+		name  string
+		email string
+		want  string
+		err   error
+	}{
+		{"Lowercased", "Jane.Doe@Example.COM", "jane.doe@example.com", nil},
+		{"Trimmed", "  jane@example.com\n", "jane@example.com", nil},
+		{"Display name", "Jane <jane@example.com>", "", users.ErrInvalidEmail},
+		{"Missing domain", "jane@", "", users.ErrInvalidEmail},
+		{"Not an address", "jane", "", users.ErrInvalidEmail},
+		{"Empty", "", "", users.ErrInvalidEmail},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			email, err := users.ValidateEmail(tt.email)
+			assert.ErrorIs(t, err, tt.err)
+			assert.Equal(t, tt.want, email)
+		})
+	}
+}
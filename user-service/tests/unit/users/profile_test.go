@@ -0,0 +1,75 @@
+package users
+
+import (
+	"testing"
+
+	"github.com/darkhyper24/blaban/user-service/internal/users"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseETag(t *testing.T) {
+	tests := []struct {
+		name    string
+		tag     string
+		version int
+		ok      bool
+	}{
+		{"Strong tag", `"3"`, 3, true},
+		{"Weak tag", `W/"3"`, 3, true},
+		{"Surrounding whitespace", ` "12" `, 12, true},
+		{"Unquoted", `3`, 0, false},
+		{"Not a number", `"abc"`, 0, false},
+		{"Negative", `"-1"`, 0, false},
+		{"Lowercase weak prefix", `w/"3"`, 0, false},
+		{"Empty", ``, 0, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			version, ok := users.ParseETag(tt.tag)
+			assert.Equal(t, tt.ok, ok)
+			assert.Equal(t, tt.version, version)
+		})
+	}
+}
+
+func TestProfileETagRoundTrips(t *testing.T) {
+	profile := users.Profile{Version: 7}
+
+	version, ok := users.ParseETag(profile.ETag())
+
+	assert.True(t, ok)
+	assert.Equal(t, 7, version)
+}
+
+func TestProfileUpdateValidate(t *testing.T) {
+	t.Run("Normalizes the email", func(t *testing.T) {
+		update := users.ProfileUpdate{Name: " Mona ", Email: "  Mona@Example.COM "}
+
+		assert.NoError(t, update.Validate())
+		assert.Equal(t, "mona@example.com", update.Email)
+		assert.Equal(t, "Mona", update.Name)
+		assert.JSONEq(t, `{}`, string(update.Preferences))
+	})
+
+	tests := []struct {
+		name   string
+		update users.ProfileUpdate
+	}{
+		{"Missing name", users.ProfileUpdate{Email: "mona@example.com"}},
+		{"Invalid email", users.ProfileUpdate{Name: "Mona", Email: "not-an-email"}},
+		{"Email with display name", users.ProfileUpdate{Name: "Mona", Email: "Mona <mona@example.com>"}},
+		{"Local phone number", users.ProfileUpdate{Name: "Mona", Email: "mona@example.com", Phone: "01001234567"}},
+		{"Avatar without scheme", users.ProfileUpdate{Name: "Mona", Email: "mona@example.com", AvatarURL: "example.com/a.png"}},
+		{"Preferences not an object", users.ProfileUpdate{Name: "Mona", Email: "mona@example.com", Preferences: []byte(`[1]`)}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Error(t, tt.update.Validate())
+		})
+	}
+}
+
+func TestNormalizeEmail(t *testing.T) {
+	assert.Equal(t, "mona@example.com", users.NormalizeEmail("  Mona@Example.com\n"))
+}
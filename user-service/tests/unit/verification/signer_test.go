@@ -0,0 +1,77 @@
+package verification
+
+import (
+	"encoding/base64"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/darkhyper24/blaban/user-service/internal/verification"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var secret = []byte("test-verification-secret")
+
+func TestSignerRoundTrip(t *testing.T) {
+	signer := verification.NewSigner(secret, time.Hour)
+
+	token, err := signer.Sign("user-1", "jane@example.com")
+	require.NoError(t, err)
+
+	userID, email, err := signer.Verify(token)
+	require.NoError(t, err)
+	assert.Equal(t, "user-1", userID)
+	assert.Equal(t, "jane@example.com", email)
+}
+
+func TestSignerRejectsExpiredToken(t *testing.T) {
+	signer := verification.NewSigner(secret, -time.Minute)
+
+	token, err := signer.Sign("user-1", "jane@example.com")
+	require.NoError(t, err)
+
+	_, _, err = signer.Verify(token)
+	assert.ErrorIs(t, err, verification.ErrInvalidToken)
+}
+
+func TestSignerRejectsOtherSecret(t *testing.T) {
+	token, err := verification.NewSigner(secret, time.Hour).Sign("user-1", "jane@example.com")
+	require.NoError(t, err)
+
+	_, _, err = verification.NewSigner([]byte("another-secret"), time.Hour).Verify(token)
+	assert.ErrorIs(t, err, verification.ErrInvalidToken)
+}
+
+func TestSignerRejectsTamperedPayload(t *testing.T) {
+	signer := verification.NewSigner(secret, time.Hour)
+
+	token, err := signer.Sign("user-1", "jane@example.com")
+	require.NoError(t, err)
+	_, sig, _ := strings.Cut(token, ".")
+
+	forged := base64.RawURLEncoding.EncodeToString([]byte(`{"uid":"user-2","email":"jane@example.com","exp":9999999999}`))
+	_, _, err = signer.Verify(forged + "." + sig)
+	assert.ErrorIs(t, err, verification.ErrInvalidToken)
+}
+
+func TestSignerRejectsMalformedTokens(t *testing.T) {
+	signer := verification.NewSigner(secret, time.Hour)
+
+	for _, token := range []string{"", "no-separator", ".", "abc.!!!", "abc.def"} {
+		t.Run(token, func(t *testing.T) {
+			_, _, err := signer.Verify(token)
+			assert.ErrorIs(t, err, verification.ErrInvalidToken)
+		})
+	}
+}
+
+func TestRandomSecret(t *testing.T) {
+	a, err := verification.RandomSecret()
+	require.NoError(t, err)
+	b, err := verification.RandomSecret()
+	require.NoError(t, err)
+
+	assert.Len(t, a, 32)
+	assert.NotEqual(t, a, b)
+}